@@ -70,6 +70,11 @@ run_tests() {
         echo -e "${RED}Tests failed${NC}"
         exit 1
     fi
+    # The SQLite store and SSH gateway are only built with their tags
+    if ! go test -tags "sqlite ssh" ./...; then
+        echo -e "${RED}Tagged tests failed${NC}"
+        exit 1
+    fi
     echo -e "${GREEN}Tests passed${NC}"
 }
 
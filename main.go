@@ -2,11 +2,16 @@
 package main
 
 import (
+	"bufio"
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
-	"netcat/internal"
+	"netcat/pkg/chat"
 )
 
 // Message types for different kinds of messages
@@ -17,30 +22,419 @@ const (
 	MessageTypeError
 )
 
+// usage is printed by help and -h
+const usage = `Usage:
+  TCPChat [serve] [flags] [port]       Run the chat server (port 8989 by default)
+  TCPChat connect [flags] <host:port>  Chat with a server from the terminal
+  TCPChat admin [flags] <command>      Run a command on a running server
+  TCPChat bench [flags] [host:port]    Simulate load against a running server
+  TCPChat help                         Show this help
+
+Run TCPChat <command> -h for the flags of a command.
+`
+
+// subcommands run the words that may start the command line
+var subcommands = map[string]func(args []string){
+	"serve":   runServe,
+	"connect": runConnect,
+	"admin":   runAdmin,
+	"bench":   runBench,
+}
+
 func main() {
-	// Parse command line arguments
-	port := "8989" // default port
-	useUI := false
+	args := os.Args[1:]
+	if len(args) > 0 && (args[0] == "help" || args[0] == "-h" || args[0] == "-help" || args[0] == "--help") {
+		fmt.Print(usage)
+		return
+	}
+	if len(args) > 0 {
+		if run, ok := subcommands[args[0]]; ok {
+			run(args[1:])
+			return
+		}
+	}
+
+	// Before subcommands, -connect and -discover picked client mode
+	if hasFlag(args, "connect", "discover") {
+		runConnect(args)
+		return
+	}
+	runServe(args)
+}
+
+// newFlagSet returns the flag set of a subcommand, which prints its usage
+// line and its flags when the command line does not parse
+func newFlagSet(name, usageLine string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s\n\nFlags:\n", usageLine)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseInterspersed parses flags that may come after positional
+// arguments, like ./TCPChat 8989 -ui, and returns the positional ones
+func parseInterspersed(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		fs.Parse(args)
+		if fs.NArg() == 0 {
+			return positional
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
 
-	for i := 1; i < len(os.Args); i++ {
-		switch os.Args[i] {
-		case "-ui":
-			useUI = true
-		default:
-			if len(os.Args) > 2 {
-				fmt.Println("[USAGE]: ./TCPChat $port")
-				return
+// hasFlag reports whether args set one of the named flags
+func hasFlag(args []string, names ...string) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			return false
+		}
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		for _, n := range names {
+			if name == n {
+				return true
 			}
-			port = os.Args[i]
 		}
 	}
+	return false
+}
+
+// flagValue returns the value args give the named flag, "" if none
+func flagValue(args []string, name string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		flagName, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if flagName != name {
+			continue
+		}
+		if hasValue {
+			return value
+		}
+		if i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// expandTLS rewrites the -tls <cert> <key> of earlier versions, which
+// takes two values, as -tls-cert <cert> -tls-key <key>
+func expandTLS(args []string) []string {
+	var expanded []string
+	for i := 0; i < len(args); i++ {
+		if args[i] == "-tls" && i+2 < len(args) {
+			expanded = append(expanded, "-tls-cert", args[i+1], "-tls-key", args[i+2])
+			i += 2
+			continue
+		}
+		expanded = append(expanded, args[i])
+	}
+	return expanded
+}
+
+// loadConfigFile applies the -config file in args to config before the
+// flags are parsed, so they override it wherever -config is given, and
+// keeps its path for reloads. It returns the port the file names.
+func loadConfigFile(args []string, config *chat.Config) string {
+	path := flagValue(args, "config")
+	if path == "" {
+		return ""
+	}
+	port, err := chat.LoadConfigFile(path, config)
+	if err != nil {
+		log.Fatal(err)
+	}
+	config.ConfigFile = path
+	return port
+}
+
+// listFlag collects the values of a flag that may be repeated
+type listFlag struct {
+	values *[]string
+}
+
+func (f listFlag) String() string {
+	if f.values == nil {
+		return ""
+	}
+	return strings.Join(*f.values, ",")
+}
+
+func (f listFlag) Set(value string) error {
+	*f.values = append(*f.values, value)
+	return nil
+}
+
+// splitFlag sets a list from one comma-separated value
+func splitFlag(values *[]string) func(string) error {
+	return func(value string) error {
+		*values = strings.Split(value, ",")
+		return nil
+	}
+}
+
+// floodFlag parses <messages>/<window>, e.g. 10/2s; 0 disables the limit
+func floodFlag(burst *int, window *time.Duration) func(string) error {
+	return func(value string) error {
+		b, w, _ := strings.Cut(value, "/")
+		n, err := strconv.Atoi(b)
+		if err != nil {
+			return fmt.Errorf("expected <messages>/<window>, e.g. 10/2s")
+		}
+		*burst = n
+		if w == "" {
+			return nil
+		}
+		d, err := time.ParseDuration(w)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid window %q, expected a duration such as 2s", w)
+		}
+		*window = d
+		return nil
+	}
+}
+
+// flagOverrides returns a function that puts the flags given on the
+// command line back over a reloaded config file, for the settings a
+// reload can change
+func flagOverrides(fs *flag.FlagSet, config *chat.Config) func(*chat.Config) {
+	given := *config
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	return func(c *chat.Config) {
+		if set["max-clients"] {
+			c.MaxClients = given.MaxClients
+		}
+		if set["max-per-ip"] {
+			c.MaxConnsPerIP = given.MaxConnsPerIP
+		}
+		if set["flood"] {
+			c.FloodBurst, c.FloodWindow = given.FloodBurst, given.FloodWindow
+		}
+		if set["bot-flood"] {
+			c.BotFloodBurst, c.BotFloodWindow = given.BotFloodBurst, given.BotFloodWindow
+		}
+		if set["default-rooms"] {
+			c.DefaultRooms = given.DefaultRooms
+		}
+		if set["persistent-rooms"] {
+			c.PersistentRooms = given.PersistentRooms
+		}
+	}
+}
 
-	// Create and start server
-	server := internal.NewServer()
+// runServe runs the chat server
+func runServe(args []string) {
+	config := chat.DefaultConfig()
+	port := "8989"
+	filePort := loadConfigFile(args, config)
+	if filePort != "" {
+		port = filePort
+	}
+	listen := config.Listen
+	portFlag := ""
+	useUI := false
+	passwdUser := ""
+
+	fs := newFlagSet("serve", "TCPChat [serve] [flags] [port]")
+	fs.String("config", "", "read settings from a TOML `file`; other flags override it")
+	fs.StringVar(&portFlag, "port", "", "`port` or address to listen on (default 8989)")
+	fs.Var(listFlag{&listen}, "listen", "serve another `address` too, e.g. [::1]:8989 (repeatable)")
+	fs.BoolVar(&useUI, "ui", false, "show the operator's terminal UI")
+	fs.StringVar(&config.UITheme, "theme", config.UITheme, "color theme of the terminal UI: default, light, mono or a custom `name`")
+	fs.Func("themes", "load custom UI themes from a JSON `file`", func(path string) error {
+		themes, err := chat.LoadThemes(path)
+		config.UIThemes = themes
+		return err
+	})
+	fs.StringVar(&config.LogFile, "log", config.LogFile, "write the activity log to `file`; empty for stderr")
+	fs.StringVar(&config.LogLevel, "log-level", config.LogLevel, "drop log records below `level`: debug, info, warn or error")
+	fs.StringVar(&config.LogFormat, "log-format", config.LogFormat, "log `format`: text or json")
+	fs.StringVar(&config.AuditFile, "audit", config.AuditFile, "write moderation actions to `file`")
+	fs.IntVar(&config.MaxClients, "max-clients", config.MaxClients, "most `clients` connected at once")
+	fs.IntVar(&config.MaxConnsPerIP, "max-per-ip", config.MaxConnsPerIP, "most `connections` from one address (0 = no limit)")
+
+	fs.StringVar(&config.TLSCert, "tls-cert", config.TLSCert, "serve TLS with the certificate in `file` (with -tls-key)")
+	fs.StringVar(&config.TLSKey, "tls-key", config.TLSKey, "private key `file` of -tls-cert")
+	fs.BoolVar(&config.TLSOptional, "tls-optional", config.TLSOptional, "with TLS, keep serving plaintext clients on the same port")
+	fs.Var(listFlag{&config.AllowNetworks}, "allow", "only accept connections from `cidr` (repeatable)")
+	fs.Var(listFlag{&config.DenyNetworks}, "deny", "refuse connections from `cidr` (repeatable)")
+	fs.BoolVar(&config.ProxyProtocol, "proxy-protocol", config.ProxyProtocol, "expect a PROXY protocol header on every connection")
+	fs.Func("proxy-trusted", "accept PROXY headers only from `cidr` (repeatable, implies -proxy-protocol)", func(cidr string) error {
+		config.ProxyProtocol = true
+		config.ProxyTrusted = append(config.ProxyTrusted, cidr)
+		return nil
+	})
+
+	fs.StringVar(&config.Lobby, "lobby", config.Lobby, "`room` new clients join (default general)")
+	fs.Func("default-rooms", "create the `rooms` (comma-separated) at startup", splitFlag(&config.DefaultRooms))
+	fs.Func("persistent-rooms", "never delete the empty `rooms` (comma-separated)", splitFlag(&config.PersistentRooms))
+	fs.DurationVar(&config.RoomIdleTimeout, "room-idle", config.RoomIdleTimeout, "delete rooms empty for this `duration`, e.g. 24h")
+	fs.StringVar(&config.MOTDFile, "motd", config.MOTDFile, "show the message of the day in `file` after login")
+	fs.StringVar(&config.BanFile, "bans", config.BanFile, "keep banned addresses in `file`")
+
+	fs.StringVar(&config.AccountsFile, "accounts", config.AccountsFile, "keep accounts in `file`")
+	fs.BoolVar(&config.AuthRequired, "auth", config.AuthRequired, "require a password after the name prompt")
+	fs.BoolVar(&config.JoinChallenge, "challenge", config.JoinChallenge, "make new connections solve a sum before the name prompt")
+	fs.Func("role", "grant a role as `name=role`, e.g. alice=admin (repeatable)", func(value string) error {
+		name, roleName, _ := strings.Cut(value, "=")
+		role, err := chat.ParseRole(roleName)
+		if err != nil {
+			return err
+		}
+		if config.Roles == nil {
+			config.Roles = make(map[string]chat.Role)
+		}
+		config.Roles[name] = role
+		return nil
+	})
+	fs.StringVar(&passwdUser, "passwd", "", "set the password of `name` from stdin and exit, like admin passwd")
+
+	fs.StringVar(&config.Store, "store", config.Store, "keep messages, rooms and accounts in `store`: memory, file or sqlite")
+	fs.Func("db", "use the SQLite database in `file` (implies -store sqlite)", func(path string) error {
+		config.Store = chat.StoreSQLite
+		config.DatabasePath = path
+		return nil
+	})
+	fs.StringVar(&config.RoomsFile, "rooms-file", config.RoomsFile, "keep created rooms in `file`")
+	fs.StringVar(&config.HistoryFile, "history-file", config.HistoryFile, "keep history in a JSON-lines `file`")
+	fs.IntVar(&config.HistoryLimit, "history-limit", config.HistoryLimit, "`messages` kept per room")
+	fs.Func("room-history", "keep `room=messages` for one room (repeatable)", func(value string) error {
+		room, limit, _ := strings.Cut(value, "=")
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			return fmt.Errorf("expected <room>=<messages>")
+		}
+		if config.RoomHistoryLimits == nil {
+			config.RoomHistoryLimits = make(map[string]int)
+		}
+		config.RoomHistoryLimits[room] = n
+		return nil
+	})
+	fs.IntVar(&config.HistoryReplay, "history", config.HistoryReplay, "`messages` replayed on join (0 = all)")
+	fs.Func("retention", "prune history older than `age[/messages]`, e.g. 30d/10000", func(value string) error {
+		rule, err := chat.ParseRetentionRule(value)
+		config.Retention = rule
+		return err
+	})
+	fs.Func("room-retention", "prune one room by `room=age[/messages]` (repeatable)", func(value string) error {
+		room, spec, _ := strings.Cut(value, "=")
+		rule, err := chat.ParseRetentionRule(spec)
+		if err != nil {
+			return err
+		}
+		if config.RoomRetention == nil {
+			config.RoomRetention = make(map[string]chat.RetentionRule)
+		}
+		config.RoomRetention[room] = rule
+		return nil
+	})
+	fs.DurationVar(&config.RetentionInterval, "retention-interval", config.RetentionInterval, "prune history every `duration`")
+	fs.StringVar(&config.ExportDir, "export-dir", config.ExportDir, "write /export files to `dir`")
+
+	fs.Func("flood", "flood limit as `messages/window`, e.g. 10/2s; 0 disables it", floodFlag(&config.FloodBurst, &config.FloodWindow))
+	fs.Func("bot-flood", "flood limit of bots as `messages/window`", floodFlag(&config.BotFloodBurst, &config.BotFloodWindow))
+	fs.DurationVar(&config.IdleTimeout, "idle", config.IdleTimeout, "disconnect clients idle for this `duration`, e.g. 10m")
+	fs.DurationVar(&config.AutoAway, "auto-away", config.AutoAway, "mark clients away after this `duration` without input")
+	fs.DurationVar(&config.FlushInterval, "flush-interval", config.FlushInterval, "hold outbound batches open this `duration`, e.g. 5ms")
+	fs.Func("sanitize", "`policy` for input with escape sequences: strip or reject", func(policy string) error {
+		if policy != chat.SanitizeStrip && policy != chat.SanitizeReject {
+			return fmt.Errorf("expected strip or reject")
+		}
+		config.SanitizePolicy = policy
+		return nil
+	})
+	fs.BoolVar(&config.NoFunCommands, "no-fun", config.NoFunCommands, "leave out /roll, /flip and /8ball")
+	fs.Func("unfurl", "post the titles of links to `hosts` (comma-separated)", func(hosts string) error {
+		config.UnfurlLinks = true
+		config.UnfurlAllow = strings.Split(hosts, ",")
+		return nil
+	})
+
+	fs.Func("cluster", "relay rooms through `url`, e.g. nats://localhost:4222 or redis://localhost:6379", func(url string) error {
+		config.ClusterURL = url
+		config.ClusterBackend, _, _ = strings.Cut(url, "://")
+		return nil
+	})
+	fs.Func("bridges", "relay rooms to Slack, Discord or MQTT as set in a JSON `file`", func(path string) error {
+		bridges, err := chat.LoadBridgeConfigs(path)
+		config.Bridges = bridges
+		return err
+	})
+	fs.Func("smtp", "send email notifications through `host:port` (login from TCPCHAT_SMTP_USER and TCPCHAT_SMTP_PASSWORD)", func(addr string) error {
+		config.SMTPAddr = addr
+		config.SMTPUser = os.Getenv("TCPCHAT_SMTP_USER")
+		config.SMTPPassword = os.Getenv("TCPCHAT_SMTP_PASSWORD")
+		return nil
+	})
+	fs.StringVar(&config.SMTPFrom, "smtp-from", config.SMTPFrom, "sender `address` of email notifications")
+	fs.StringVar(&config.HTTPAddr, "http", config.HTTPAddr, "serve feeds and webhooks on `addr`")
+	fs.StringVar(&config.WSAddr, "ws", config.WSAddr, "serve WebSocket clients on `addr`")
+	fs.StringVar(&config.IRCAddr, "irc", config.IRCAddr, "serve IRC clients on `addr`")
+	fs.StringVar(&config.SSHAddr, "ssh", config.SSHAddr, "serve SSH clients on `addr` (needs -tags ssh)")
+	fs.StringVar(&config.SSHHostKey, "ssh-host-key", config.SSHHostKey, "SSH host key `file`")
+	fs.StringVar(&config.WebhookToken, "webhook-token", config.WebhookToken, "accept webhook posts with `token`")
+	fs.StringVar(&config.WebhookName, "webhook-name", config.WebhookName, "`name` webhook posts appear from")
+	fs.Var(listFlag{&config.PublicRooms}, "public-room", "publish `room` as a feed (repeatable)")
+	fs.BoolVar(&config.Announce, "mdns", config.Announce, "advertise the server with mDNS")
+	fs.BoolVar(&config.Beacon, "beacon", config.Beacon, "multicast the server's name and port")
+	fs.StringVar(&config.ServerName, "name", config.ServerName, "server `name` for discovery")
+	fs.StringVar(&config.AdminAddr, "admin-http", config.AdminAddr, "serve the admin HTTP API on `addr`")
+	fs.StringVar(&config.AdminToken, "admin-token", config.AdminToken, "bearer `token` of the admin API")
+	fs.StringVar(&config.ConsoleAddr, "console", config.ConsoleAddr, "serve the admin console on a loopback `addr` or unix:<path>")
+	fs.BoolVar(&config.EnablePprof, "pprof", config.EnablePprof, "expose pprof on the admin HTTP port")
+
+	positional := parseInterspersed(fs, expandTLS(args))
+	if len(positional) > 1 || (len(positional) == 1 && portFlag != "") {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if config.MaxClients < 1 {
+		fmt.Fprintln(os.Stderr, "-max-clients must be at least 1")
+		os.Exit(2)
+	}
+	if config.FlushInterval < 0 {
+		fmt.Fprintln(os.Stderr, "-flush-interval must not be negative")
+		os.Exit(2)
+	}
+	if len(positional) == 1 {
+		portFlag = positional[0]
+	}
+	if portFlag != "" {
+		port = portFlag
+	}
+
+	// Without a port on the command line or in the config file the first
+	// -listen address replaces the default; every other one is served
+	// alongside it
+	if len(listen) > 0 && portFlag == "" && filePort == "" {
+		port, listen = listen[0], listen[1:]
+	}
+	config.Listen = listen
+	config.Overrides = flagOverrides(fs, config)
+
+	if passwdUser != "" {
+		setPassword(config, passwdUser)
+		return
+	}
+
+	server := chat.New(chat.WithConfig(config), chat.WithSignalHandling())
 	defer server.Logfile.Close()
 
 	if useUI {
-		if err := internal.RunWithUI(server); err != nil {
+		if err := chat.RunWithUI(server, port); err != nil {
 			log.Fatal(err)
 		}
 	} else {
@@ -49,3 +443,143 @@ func main() {
 		}
 	}
 }
+
+// setPassword sets the password of name in the accounts of config, read
+// from stdin
+func setPassword(config *chat.Config, name string) {
+	fmt.Printf("New password for %s: ", name)
+	password, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err := chat.SetPassword(config, name, strings.TrimSpace(password)); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("Password updated")
+}
+
+// runConnect runs client mode
+func runConnect(args []string) {
+	client := chat.DefaultClientConfig("")
+	useTUI := false
+	discover := false
+	transcriptMB := int(client.TranscriptMaxSize >> 20)
+
+	fs := newFlagSet("connect", "TCPChat connect [flags] <host:port>")
+	fs.BoolVar(&useTUI, "tui", false, "use the terminal UI with room and user panels")
+	fs.BoolVar(&discover, "discover", false, "pick a server announced on the local network")
+	fs.StringVar(&client.Addr, "connect", "", "server `host:port`, the same as the argument")
+	fs.StringVar(&client.Proxy, "proxy", "", "connect through `socks5://host:port`")
+	fs.StringVar(&client.Transcript, "transcript", "", "append the session to `file`")
+	fs.IntVar(&transcriptMB, "transcript-size", transcriptMB, "rotate the transcript at `megabytes`")
+	fs.IntVar(&client.TranscriptKeep, "transcript-keep", client.TranscriptKeep, "keep this many rotated transcript `files`")
+
+	positional := parseInterspersed(fs, args)
+	if len(positional) > 1 || (len(positional) == 1 && client.Addr != "") {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if len(positional) == 1 {
+		client.Addr = positional[0]
+	}
+	if transcriptMB < 1 || client.TranscriptKeep < 0 {
+		fmt.Fprintln(os.Stderr, "-transcript-size must be at least 1 and -transcript-keep not negative")
+		os.Exit(2)
+	}
+	client.TranscriptMaxSize = int64(transcriptMB) << 20
+
+	if discover {
+		addr, err := chat.RunDiscoveryUI()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if addr == "" {
+			return
+		}
+		client.Addr = addr
+	}
+	if client.Addr == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	run := chat.RunClient
+	if useTUI {
+		run = chat.RunClientUI
+	}
+	if err := run(client); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// runAdmin runs one command on a running server through its admin
+// console, or sets a password in its accounts
+func runAdmin(args []string) {
+	config := chat.DefaultConfig()
+	loadConfigFile(args, config)
+
+	fs := newFlagSet("admin", "TCPChat admin [flags] <command> [args]\n\n"+
+		"Commands are those of the admin console (TCPChat admin help lists them),\n"+
+		"and passwd <name> sets a password, read from stdin, in the accounts.")
+	fs.String("config", "", "read the console address and the stores from a TOML `file`")
+	fs.StringVar(&config.ConsoleAddr, "console", config.ConsoleAddr, "admin console `addr` of the server, a loopback host:port or unix:<path>")
+	fs.StringVar(&config.AccountsFile, "accounts", config.AccountsFile, "accounts `file` for passwd")
+	fs.StringVar(&config.Store, "store", config.Store, "`store` of the accounts for passwd: memory, file or sqlite")
+	fs.Func("db", "SQLite database `file` for passwd (implies -store sqlite)", func(path string) error {
+		config.Store = chat.StoreSQLite
+		config.DatabasePath = path
+		return nil
+	})
+
+	command := parseInterspersed(fs, args)
+	if len(command) == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if command[0] == "passwd" {
+		if len(command) != 2 {
+			fs.Usage()
+			os.Exit(2)
+		}
+		setPassword(config, command[1])
+		return
+	}
+
+	if config.ConsoleAddr == "" {
+		fmt.Fprintln(os.Stderr, "admin needs the server's console address: -console <addr>, or console in the -config file")
+		os.Exit(2)
+	}
+	answer, err := chat.RunConsoleCommand(config.ConsoleAddr, strings.Join(command, " "))
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Print(answer)
+}
+
+// runBench parses the bench subcommand's flags, runs the simulation and
+// prints its report
+func runBench(args []string) {
+	cfg := chat.DefaultBenchConfig()
+	fs := newFlagSet("bench", "TCPChat bench [flags] [host:port]")
+	fs.IntVar(&cfg.Clients, "clients", cfg.Clients, "simulated `count` of clients")
+	fs.Float64Var(&cfg.Rate, "rate", cfg.Rate, "`messages per second` over all clients")
+	fs.DurationVar(&cfg.Duration, "duration", cfg.Duration, "how long to run, e.g. 30s")
+
+	positional := parseInterspersed(fs, args)
+	if len(positional) > 1 || cfg.Clients < 1 || cfg.Rate <= 0 || cfg.Duration <= 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if len(positional) == 1 {
+		cfg.Addr = positional[0]
+	}
+	if !strings.Contains(cfg.Addr, ":") {
+		cfg.Addr = "localhost:" + cfg.Addr
+	}
+
+	fmt.Printf("Connecting %d clients to %s...\n", cfg.Clients, cfg.Addr)
+	report, err := chat.RunBench(cfg)
+	if report != nil {
+		fmt.Print(report)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,94 @@
+package chat
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Account holds the persistent profile attached to a nickname
+type Account struct {
+	Name          string    `json:"name"`
+	PasswordHash  string    `json:"password_hash,omitempty"`
+	Email         string    `json:"email,omitempty"`
+	EmailVerified bool      `json:"email_verified,omitempty"`
+	EmailCode     string    `json:"email_code,omitempty"`   // Pending verification code
+	EmailNotify   string    `json:"email_notify,omitempty"` // "", "immediate" or "digest"
+	BotTokenHash  string    `json:"bot_token_hash,omitempty"`
+	Inbox         []Message `json:"inbox,omitempty"`    // Private messages received while offline
+	SSHKeys       []string  `json:"ssh_keys,omitempty"` // authorized_keys lines for the SSH gateway
+	MentionsOff   bool      `json:"mentions_off,omitempty"`
+	Receipts      bool      `json:"receipts,omitempty"` // Delivery and read receipts for sent private messages
+	Keywords      []string  `json:"keywords,omitempty"` // Lowercase words that trigger alerts, managed by /notify
+	Seen          *SeenInfo `json:"seen,omitempty"`
+}
+
+// accountStore keeps accounts in memory and mirrors them to a Store
+type accountStore struct {
+	store    Store
+	mutex    sync.Mutex
+	accounts map[string]*Account // Keyed by lowercase name
+}
+
+func loadAccountStore(store Store) (*accountStore, error) {
+	st := &accountStore{store: store, accounts: make(map[string]*Account)}
+	accounts, err := store.LoadAccounts()
+	for _, a := range accounts {
+		st.accounts[strings.ToLower(a.Name)] = a
+	}
+	return st, err
+}
+
+// reload replaces the accounts with those in the store and returns how
+// many there are, e.g. after passwords were set with -passwd
+func (st *accountStore) reload() (int, error) {
+	accounts, err := st.store.LoadAccounts()
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+	if err != nil {
+		return len(st.accounts), err
+	}
+	st.accounts = make(map[string]*Account, len(accounts))
+	for _, a := range accounts {
+		st.accounts[strings.ToLower(a.Name)] = a
+	}
+	return len(st.accounts), nil
+}
+
+// get returns a copy of the named account
+func (st *accountStore) get(name string) (Account, bool) {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+	a, ok := st.accounts[strings.ToLower(name)]
+	if !ok {
+		return Account{}, false
+	}
+	return *a, true
+}
+
+// update applies fn to the named account, creating it if needed, and saves
+func (st *accountStore) update(name string, fn func(a *Account) error) error {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+
+	key := strings.ToLower(name)
+	a, ok := st.accounts[key]
+	if !ok {
+		a = &Account{Name: name}
+	}
+	updated := *a
+	if err := fn(&updated); err != nil {
+		return err
+	}
+	st.accounts[key] = &updated
+	return st.save()
+}
+
+func (st *accountStore) save() error {
+	accounts := make([]*Account, 0, len(st.accounts))
+	for _, a := range st.accounts {
+		accounts = append(accounts, a)
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].Name < accounts[j].Name })
+	return st.store.SaveAccounts(accounts)
+}
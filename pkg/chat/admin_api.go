@@ -0,0 +1,261 @@
+package chat
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// apiClient is a connected client as reported by GET /api/clients
+type apiClient struct {
+	Name      string    `json:"name"`
+	Room      string    `json:"room"`
+	Role      string    `json:"role"`
+	Address   string    `json:"address"`
+	Bot       bool      `json:"bot,omitempty"`
+	Connected time.Time `json:"connected"`
+	Idle      string    `json:"idle"`
+}
+
+// apiRoom is a room as reported by GET /api/rooms
+type apiRoom struct {
+	Name        string `json:"name"`
+	Users       int    `json:"users"`
+	Description string `json:"description,omitempty"`
+	Topic       string `json:"topic,omitempty"`
+	Creator     string `json:"creator,omitempty"`
+	Password    bool   `json:"password,omitempty"`
+	Hidden      bool   `json:"hidden,omitempty"`
+}
+
+// registerAdminAPI adds the REST routes. Every route requires the
+// AdminToken as a bearer token.
+func (s *Server) registerAdminAPI(mux *http.ServeMux) {
+	routes := map[string]http.HandlerFunc{
+		"GET /api/clients":              s.apiClients,
+		"POST /api/clients/{name}/kick": s.apiKick,
+		"GET /api/rooms":                s.apiRooms,
+		"GET /api/rooms/{room}/history": s.apiHistory,
+		"GET /api/bans":                 s.apiBans,
+		"POST /api/bans":                s.apiBan,
+		"DELETE /api/bans/{ip}":         s.apiUnban,
+		"POST /api/broadcast":           s.apiBroadcast,
+		"GET /api/metrics":              s.apiMetrics,
+	}
+	for pattern, handler := range routes {
+		mux.Handle(pattern, s.requireAdminToken(handler))
+	}
+}
+
+func (s *Server) requireAdminToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.config.AdminToken)) != 1 {
+			writeAPIError(w, http.StatusUnauthorized, fmt.Errorf("missing or invalid admin token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// apiOperator is the client moderation commands run as for API requests
+func (s *Server) apiOperator() *Client {
+	return &Client{conn: apiConn{}, name: "api", joinTime: time.Now(), role: RoleOwner}
+}
+
+// runAPICommand runs a chat command handler as the API operator
+func (s *Server) runAPICommand(w http.ResponseWriter, handler CommandFunc, args []string) {
+	if err := handler(s, s.apiOperator(), args); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+}
+
+// decodeBody reads a small JSON request body into v
+func decodeBody(r *http.Request, v any) error {
+	if err := json.NewDecoder(io.LimitReader(r.Body, 64<<10)).Decode(v); err != nil && err != io.EOF {
+		return fmt.Errorf("invalid JSON body: %v", err)
+	}
+	return nil
+}
+
+func (s *Server) apiClients(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	s.mutex.RLock()
+	clients := make([]apiClient, 0, s.clients.len())
+	s.clients.each(func(conn net.Conn, c *Client) bool {
+		clients = append(clients, apiClient{
+			Name:      c.name,
+			Room:      c.room,
+			Role:      c.role.String(),
+			Address:   conn.RemoteAddr().String(),
+			Bot:       c.bot,
+			Connected: c.joinTime,
+			Idle:      now.Sub(c.lastActive).Round(time.Second).String(),
+		})
+		return true
+	})
+	s.mutex.RUnlock()
+	sort.Slice(clients, func(i, j int) bool { return clients[i].Name < clients[j].Name })
+	writeJSON(w, http.StatusOK, clients)
+}
+
+func (s *Server) apiKick(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if err := decodeBody(r, &body); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	s.runAPICommand(w, kickCommand, append([]string{r.PathValue("name")}, strings.Fields(body.Reason)...))
+}
+
+func (s *Server) apiRooms(w http.ResponseWriter, r *http.Request) {
+	s.mutex.RLock()
+	rooms := make([]apiRoom, 0, len(s.rooms))
+	for _, room := range s.rooms {
+		rooms = append(rooms, apiRoom{
+			Name:        room.name,
+			Users:       room.size(),
+			Description: room.description,
+			Topic:       room.topic,
+			Creator:     room.creator,
+			Password:    room.passwordHash != "",
+			Hidden:      room.hidden,
+		})
+	}
+	s.mutex.RUnlock()
+	sort.Slice(rooms, func(i, j int) bool { return rooms[i].Name < rooms[j].Name })
+	writeJSON(w, http.StatusOK, rooms)
+}
+
+// apiHistory returns the newest messages of a room, 50 unless ?limit=N
+// (0 for everything) is given, in the /export JSON format
+func (s *Server) apiHistory(w http.ResponseWriter, r *http.Request) {
+	room := r.PathValue("room")
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeAPIError(w, http.StatusBadRequest, fmt.Errorf("invalid limit %q", v))
+			return
+		}
+		limit = n
+	}
+
+	s.mutex.RLock()
+	_, exists := s.rooms[room]
+	s.mutex.RUnlock()
+	if !exists {
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("room %s does not exist", room))
+		return
+	}
+	messages, err := s.store.RecentMessages(room, limit)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	ExportMessages(w, room, messages, ExportJSON)
+}
+
+func (s *Server) apiBans(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.bans.list())
+}
+
+func (s *Server) apiBan(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Target string `json:"target"` // Nickname or IP
+		Reason string `json:"reason"`
+	}
+	if err := decodeBody(r, &body); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	if body.Target == "" {
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("target is required"))
+		return
+	}
+	s.runAPICommand(w, banCommand, append([]string{body.Target}, strings.Fields(body.Reason)...))
+}
+
+func (s *Server) apiUnban(w http.ResponseWriter, r *http.Request) {
+	s.runAPICommand(w, unbanCommand, []string{r.PathValue("ip")})
+}
+
+// apiBroadcast sends a system message to everyone, or to one room
+func (s *Server) apiBroadcast(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Message string `json:"message"`
+		Room    string `json:"room"`
+	}
+	if err := decodeBody(r, &body); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	if strings.TrimSpace(body.Message) == "" {
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("message is required"))
+		return
+	}
+	msg := Message{
+		Type:      MessageTypeSystem,
+		Content:   "[Announcement] " + body.Message,
+		Timestamp: time.Now(),
+	}
+
+	if body.Room == "" {
+		s.broadcast(msg, nil)
+	} else {
+		s.mutex.Lock()
+		room, exists := s.rooms[body.Room]
+		if exists {
+			s.broadcastToRoom(room, msg, nil)
+		}
+		s.mutex.Unlock()
+		if !exists {
+			writeAPIError(w, http.StatusNotFound, fmt.Errorf("room %s does not exist", body.Room))
+			return
+		}
+	}
+	s.logActivity("API broadcast", "room", stringOr(body.Room, "everyone"), "content", body.Message)
+	writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+}
+
+func stringOr(s, fallback string) string {
+	if s == "" {
+		return fallback
+	}
+	return s
+}
+
+// apiConn stands in for the API operator's connection; replies that
+// commands write to it are dropped because the API answers with JSON
+type apiConn struct{}
+
+func (apiConn) Read([]byte) (int, error)         { return 0, io.EOF }
+func (apiConn) Write(p []byte) (int, error)      { return len(p), nil }
+func (apiConn) Close() error                     { return nil }
+func (apiConn) LocalAddr() net.Addr              { return nil }
+func (apiConn) RemoteAddr() net.Addr             { return nil }
+func (apiConn) SetDeadline(time.Time) error      { return nil }
+func (apiConn) SetReadDeadline(time.Time) error  { return nil }
+func (apiConn) SetWriteDeadline(time.Time) error { return nil }
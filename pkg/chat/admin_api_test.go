@@ -0,0 +1,87 @@
+package chat
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAdminAPI(t *testing.T) {
+	s, _ := newEmailTestServer(t)
+	s.bans, _ = loadBanList("")
+	s.config.AdminToken = "let-me-in"
+	api := httptest.NewServer(s.newAdminHandler())
+	defer api.Close()
+
+	alice := newPipeClient(t, "Alice")
+	s.clients.add(alice.conn, alice)
+	s.joinRoom(alice, "general", "")
+
+	call := func(method, path, token, body string) *http.Response {
+		req, _ := http.NewRequest(method, api.URL+path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", method, path, err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	if resp := call("GET", "/api/clients", "", ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("request without token got %d, want 401", resp.StatusCode)
+	}
+	if resp := call("GET", "/api/clients", "wrong", ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("request with a wrong token got %d, want 401", resp.StatusCode)
+	}
+
+	var clients []apiClient
+	json.NewDecoder(call("GET", "/api/clients", "let-me-in", "").Body).Decode(&clients)
+	if len(clients) != 1 || clients[0].Name != "Alice" || clients[0].Room != "general" {
+		t.Errorf("GET /api/clients = %+v", clients)
+	}
+
+	var rooms []apiRoom
+	json.NewDecoder(call("GET", "/api/rooms", "let-me-in", "").Body).Decode(&rooms)
+	if len(rooms) != 1 || rooms[0].Name != "general" || rooms[0].Users != 1 {
+		t.Errorf("GET /api/rooms = %+v", rooms)
+	}
+
+	if resp := call("POST", "/api/broadcast", "let-me-in", `{"message":"maintenance at noon","room":"general"}`); resp.StatusCode != http.StatusOK {
+		t.Errorf("broadcast got %d", resp.StatusCode)
+	}
+	var history []exportRecord
+	json.NewDecoder(call("GET", "/api/rooms/general/history?limit=1", "let-me-in", "").Body).Decode(&history)
+	if len(history) != 1 || !strings.Contains(history[0].Content, "maintenance at noon") {
+		t.Errorf("history after broadcast = %+v", history)
+	}
+	if resp := call("GET", "/api/rooms/nowhere/history", "let-me-in", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("history of a missing room got %d, want 404", resp.StatusCode)
+	}
+
+	if resp := call("POST", "/api/bans", "let-me-in", `{"target":"203.0.113.9","reason":"spam"}`); resp.StatusCode != http.StatusOK {
+		t.Errorf("ban got %d", resp.StatusCode)
+	}
+	var bans []string
+	json.NewDecoder(call("GET", "/api/bans", "let-me-in", "").Body).Decode(&bans)
+	if len(bans) != 1 || bans[0] != "203.0.113.9" {
+		t.Errorf("GET /api/bans = %v", bans)
+	}
+	if resp := call("DELETE", "/api/bans/203.0.113.9", "let-me-in", ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("unban got %d", resp.StatusCode)
+	}
+	if resp := call("DELETE", "/api/bans/203.0.113.9", "let-me-in", ""); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("second unban got %d, want 400", resp.StatusCode)
+	}
+
+	if resp := call("POST", "/api/clients/Alice/kick", "let-me-in", `{"reason":"testing"}`); resp.StatusCode != http.StatusOK {
+		t.Errorf("kick got %d", resp.StatusCode)
+	}
+	if entries := s.auditLog.recent(1); len(entries) != 1 || entries[0].Action != "kick" || entries[0].Actor != "api" {
+		t.Errorf("kick not audited as api: %+v", entries)
+	}
+}
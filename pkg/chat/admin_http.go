@@ -0,0 +1,40 @@
+package chat
+
+import (
+	"context"
+	"net/http"
+	"net/http/pprof"
+	"time"
+)
+
+// newAdminHandler builds the routes served on the admin port
+func (s *Server) newAdminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", s.handleHealth)
+	if s.config.EnablePprof {
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+	if s.config.AdminToken != "" {
+		s.registerAdminAPI(mux)
+	}
+	return mux
+}
+
+// serveAdminHTTP runs the admin listener until it fails. It should be bound
+// to a private address since it exposes server internals.
+func (s *Server) serveAdminHTTP(ctx context.Context, addr string) {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           s.newAdminHandler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	context.AfterFunc(ctx, func() { srv.Close() })
+	s.logActivity("Admin HTTP listener started", "addr", addr)
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		s.log.Error("Admin HTTP error", "addr", addr, "err", err)
+	}
+}
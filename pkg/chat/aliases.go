@@ -0,0 +1,96 @@
+package chat
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// defaultAliases are the short forms every server starts with
+var defaultAliases = map[string]string{
+	"j": "join",
+	"w": "who",
+	"m": "msg",
+	"q": "quit",
+	"h": "help",
+	"n": "nick",
+	"t": "topic",
+}
+
+// resolveAlias expands an alias into the command it stands for and the
+// arguments to pass it. Real commands always win over aliases.
+func (s *Server) resolveAlias(command string, args []string) (string, []string) {
+	if _, exists := s.commands[command]; exists {
+		return command, args
+	}
+	s.mutex.Lock()
+	expansion, ok := s.aliases[strings.ToLower(command)]
+	s.mutex.Unlock()
+	if !ok {
+		return command, args
+	}
+	fields := strings.Fields(expansion)
+	return fields[0], append(fields[1:], args...)
+}
+
+// aliasCommand lists aliases, or defines one: /alias dev join dev makes
+// /dev run /join dev. The expansion is checked against the roles of
+// whoever uses the alias, not of its author.
+func aliasCommand(s *Server, c *Client, args []string) error {
+	if len(args) == 0 {
+		s.mutex.Lock()
+		names := make([]string, 0, len(s.aliases))
+		for name := range s.aliases {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var b strings.Builder
+		b.WriteString("Aliases:\n")
+		for _, name := range names {
+			fmt.Fprintf(&b, "/%s -> /%s\n", name, s.aliases[name])
+		}
+		s.mutex.Unlock()
+		c.write([]byte(b.String()))
+		return nil
+	}
+	if c.role < RoleAdmin {
+		return fmt.Errorf("permission denied: defining aliases requires %s", RoleAdmin)
+	}
+	if len(args) < 2 {
+		return fmt.Errorf("usage: /alias <name> <command> [args]")
+	}
+
+	name := strings.ToLower(strings.TrimPrefix(args[0], "/"))
+	target := strings.ToLower(strings.TrimPrefix(args[1], "/"))
+	if _, exists := s.commands[name]; exists {
+		return fmt.Errorf("/%s is already a command", name)
+	}
+	if _, exists := s.commands[target]; !exists {
+		return fmt.Errorf("unknown command /%s", target)
+	}
+	expansion := strings.Join(append([]string{target}, args[2:]...), " ")
+
+	s.mutex.Lock()
+	s.aliases[name] = expansion
+	s.mutex.Unlock()
+	s.logActivity("Alias defined", "client", c.name, "alias", name, "command", expansion)
+	c.write([]byte(fmt.Sprintf("/%s now runs /%s\n", name, expansion)))
+	return nil
+}
+
+func unaliasCommand(s *Server, c *Client, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: /unalias <name>")
+	}
+	name := strings.ToLower(strings.TrimPrefix(args[0], "/"))
+	s.mutex.Lock()
+	_, exists := s.aliases[name]
+	delete(s.aliases, name)
+	s.mutex.Unlock()
+	if !exists {
+		return fmt.Errorf("/%s is not an alias", name)
+	}
+	s.logActivity("Alias removed", "client", c.name, "alias", name)
+	c.write([]byte(fmt.Sprintf("Removed alias /%s\n", name)))
+	return nil
+}
@@ -0,0 +1,68 @@
+package chat
+
+import "testing"
+
+func TestAliases(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountsFile = tempAccountsFile(t)
+	config.RoomsFile = ""
+	config.AuthRequired = true
+	config.Roles = map[string]Role{"alice": RoleAdmin}
+	SetPassword(config, "Alice", "secret1")
+	SetPassword(config, "Bob", "secret2")
+	addr := setupTestServerWithConfig(t, config)
+	login := func(name, password string) *TestClient {
+		c, err := newTestClient(t, addr)
+		if err != nil {
+			t.Fatalf("Client connection failed: %v", err)
+		}
+		c.sendMessage(name)
+		c.sendMessage(password)
+		if err := c.expectMessage(t, name+" joined"); err != nil {
+			t.Fatalf("Login as %s failed: %v", name, err)
+		}
+		return c
+	}
+	alice := login("Alice", "secret1")
+	defer alice.close()
+	bob := login("Bob", "secret2")
+	defer bob.close()
+
+	bob.sendMessage("/w")
+	if err := bob.expectMessage(t, "Users in room general"); err != nil {
+		t.Fatalf("built-in alias not resolved: %v", err)
+	}
+	bob.sendMessage("/alias x who")
+	if err := bob.expectMessage(t, "permission denied: defining aliases requires admin"); err != nil {
+		t.Fatalf("user defined an alias: %v", err)
+	}
+
+	alice.sendMessage("/alias hi msg Bob hello")
+	if err := alice.expectMessage(t, "/hi now runs /msg Bob hello"); err != nil {
+		t.Fatalf("alias not defined: %v", err)
+	}
+	alice.sendMessage("/hi there")
+	if err := bob.expectMessage(t, "[PM #1 from Alice]: hello there"); err != nil {
+		t.Fatalf("custom alias not resolved: %v", err)
+	}
+
+	alice.sendMessage("/alias stop shutdown")
+	alice.expectMessage(t, "/stop now runs /shutdown")
+	bob.sendMessage("/stop")
+	if err := bob.expectMessage(t, "permission denied: /shutdown requires admin"); err != nil {
+		t.Fatalf("alias bypassed the role check: %v", err)
+	}
+
+	alice.sendMessage("/alias who list")
+	if err := alice.expectMessage(t, "/who is already a command"); err != nil {
+		t.Fatalf("alias shadowed a command: %v", err)
+	}
+	alice.sendMessage("/unalias hi")
+	if err := alice.expectMessage(t, "Removed alias /hi"); err != nil {
+		t.Fatalf("alias not removed: %v", err)
+	}
+	alice.sendMessage("/hi again")
+	if err := alice.expectMessage(t, "Unknown command"); err != nil {
+		t.Fatalf("removed alias still works: %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package chat
+
+import (
+	"fmt"
+	"time"
+)
+
+// frozenFor reports whether room is archived for c: admins may still post
+// and join. Caller holds s.mutex.
+func (s *Server) frozenFor(room *ChatRoom, c *Client) bool {
+	return room.archived && c.role < RoleAdmin
+}
+
+func errArchived(room string) error {
+	return fmt.Errorf("%s is archived and read-only", room)
+}
+
+func archiveCommand(s *Server, c *Client, args []string) error {
+	return s.setArchived(c, args, true)
+}
+
+func unarchiveCommand(s *Server, c *Client, args []string) error {
+	return s.setArchived(c, args, false)
+}
+
+// setArchived freezes a room or thaws it again. Members of an archived room
+// stay and can read its history, but only admins can post or join.
+func (s *Server) setArchived(c *Client, args []string, archived bool) error {
+	command := "unarchive"
+	if archived {
+		command = "archive"
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("usage: /%s <room>", command)
+	}
+	s.mutex.Lock()
+	room, exists := s.rooms[args[0]]
+	if !exists {
+		s.mutex.Unlock()
+		return fmt.Errorf("room does not exist")
+	}
+	if room.name == s.lobby() {
+		s.mutex.Unlock()
+		return fmt.Errorf("%s cannot be archived", room.name)
+	}
+	if room.archived == archived {
+		s.mutex.Unlock()
+		return fmt.Errorf("%s is already %sd", room.name, command)
+	}
+	room.archived = archived
+	if err := s.saveRooms(); err != nil {
+		s.log.Error("Error saving rooms", "room", room.name, "err", err)
+	}
+	s.mutex.Unlock()
+
+	notice := fmt.Sprintf("%s archived the room; it is now read-only", c.name)
+	if !archived {
+		notice = fmt.Sprintf("%s reopened the room", c.name)
+	}
+	s.logActivity("Room "+command+"d", "client", c.name, "room", room.name)
+	s.audit(command, c, room.name, "")
+	s.broadcastToRoom(room, Message{
+		Type:      MessageTypeSystem,
+		Content:   notice,
+		Timestamp: time.Now(),
+	}, nil)
+	if c.room != room.name {
+		c.write([]byte(fmt.Sprintf("%s is now %sd\n", room.name, command)))
+	}
+	return nil
+}
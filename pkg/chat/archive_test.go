@@ -0,0 +1,67 @@
+package chat
+
+import (
+	"testing"
+	"time"
+)
+
+func TestArchiveRoom(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountsFile = ""
+	config.RoomsFile = ""
+	config.Store = StoreMemory
+	config.RoomIdleTimeout = time.Hour
+	s := NewServerWithConfig(config)
+	defer s.Logfile.Close()
+
+	admin := newPipeClient(t, "Admin")
+	admin.role = RoleAdmin
+	alice := newPipeClient(t, "Alice")
+	bob := newPipeClient(t, "Bob")
+	if err := s.createRoom(alice, "event", "", ""); err != nil {
+		t.Fatalf("createRoom failed: %v", err)
+	}
+	post := func(c *Client, text string) error {
+		return s.postChat(c, Message{Type: MessageTypeChat, From: c.name, Content: text, Timestamp: time.Now()})
+	}
+	post(alice, "thanks for coming")
+
+	if err := archiveCommand(s, admin, []string{s.lobby()}); err == nil {
+		t.Error("the lobby was archived")
+	}
+	if err := archiveCommand(s, admin, []string{"event"}); err != nil {
+		t.Fatalf("archive failed: %v", err)
+	}
+	if err := post(alice, "one more thing"); err == nil {
+		t.Error("message posted in an archived room")
+	}
+	if err := rollCommand(s, alice, nil); err == nil {
+		t.Error("/roll worked in an archived room")
+	}
+	if err := s.joinRoom(bob, "event", ""); err == nil {
+		t.Error("joined an archived room")
+	}
+	if err := historyCommand(s, alice, nil); err != nil {
+		t.Errorf("history of an archived room unavailable: %v", err)
+	}
+	if err := s.joinRoom(admin, "event", ""); err != nil {
+		t.Errorf("admin could not join: %v", err)
+	}
+	if err := post(admin, "closing notes"); err != nil {
+		t.Errorf("admin could not post: %v", err)
+	}
+
+	s.joinRoom(alice, s.lobby(), "")
+	s.joinRoom(admin, s.lobby(), "")
+	s.checkEmptyRooms(time.Now().Add(2 * time.Hour))
+	if _, exists := s.rooms["event"]; !exists {
+		t.Fatal("archived room pruned")
+	}
+
+	if err := unarchiveCommand(s, admin, []string{"event"}); err != nil {
+		t.Fatalf("unarchive failed: %v", err)
+	}
+	if err := s.joinRoom(bob, "event", ""); err != nil {
+		t.Errorf("join after unarchive failed: %v", err)
+	}
+}
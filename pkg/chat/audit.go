@@ -0,0 +1,143 @@
+package chat
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// auditKeep is how many recent entries /auditlog can show
+const auditKeep = 200
+
+// AuditEntry records one moderation action
+type AuditEntry struct {
+	Time   time.Time `json:"time"`
+	Action string    `json:"action"` // kick, ban, unban, mute, unmute, promote, demote, ...
+	Actor  string    `json:"actor"`
+	Target string    `json:"target"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+func (e AuditEntry) String() string {
+	line := fmt.Sprintf("[%s] %s %s -> %s", e.Time.Format("2006-01-02 15:04:05"), e.Action, e.Actor, e.Target)
+	if e.Reason != "" {
+		line += ": " + e.Reason
+	}
+	return line
+}
+
+// auditLog appends moderation actions to a JSON-lines file, separate from
+// chat.log, and keeps the most recent ones in memory
+type auditLog struct {
+	mutex   sync.Mutex
+	file    *os.File
+	entries []AuditEntry
+}
+
+func openAuditLog(path string) (*auditLog, error) {
+	audit := &auditLog{}
+	if path == "" {
+		return audit, nil
+	}
+
+	// Reload the tail of an existing log so /auditlog survives restarts
+	if f, err := os.Open(path); err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var e AuditEntry
+			if json.Unmarshal(scanner.Bytes(), &e) == nil {
+				audit.keep(e)
+			}
+		}
+		f.Close()
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return audit, err
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return audit, err
+	}
+	audit.file = f
+	return audit, nil
+}
+
+func (a *auditLog) keep(e AuditEntry) {
+	a.entries = append(a.entries, e)
+	if len(a.entries) > auditKeep {
+		a.entries = a.entries[len(a.entries)-auditKeep:]
+	}
+}
+
+func (a *auditLog) record(e AuditEntry) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.keep(e)
+	if a.file == nil {
+		return nil
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = a.file.Write(append(data, '\n'))
+	return err
+}
+
+// recent returns up to n of the newest entries, oldest first
+func (a *auditLog) recent(n int) []AuditEntry {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if n > len(a.entries) {
+		n = len(a.entries)
+	}
+	return append([]AuditEntry(nil), a.entries[len(a.entries)-n:]...)
+}
+
+// audit records a moderation action
+func (s *Server) audit(action string, actor *Client, target, reason string) {
+	s.auditAs(action, actor.name, target, reason)
+}
+
+// auditAs records an action whose actor is not a client, such as the
+// server renaming a client that did not identify
+func (s *Server) auditAs(action, actor, target, reason string) {
+	err := s.auditLog.record(AuditEntry{
+		Time:   time.Now(),
+		Action: action,
+		Actor:  actor,
+		Target: target,
+		Reason: reason,
+	})
+	if err != nil {
+		s.log.Error("Error writing audit log", "action", action, "err", err)
+	}
+}
+
+func auditLogCommand(s *Server, c *Client, args []string) error {
+	n := 20
+	if len(args) > 0 {
+		var err error
+		if n, err = strconv.Atoi(args[0]); err != nil || n < 1 {
+			return fmt.Errorf("usage: /auditlog [count]")
+		}
+	}
+
+	entries := s.auditLog.recent(n)
+	if len(entries) == 0 {
+		c.write([]byte("The audit log is empty\n"))
+		return nil
+	}
+	lines := make([]string, len(entries))
+	for i, e := range entries {
+		lines[i] = e.String()
+	}
+	c.write([]byte(fmt.Sprintf("Audit log (%d):\n%s\n", len(entries), strings.Join(lines, "\n"))))
+	return nil
+}
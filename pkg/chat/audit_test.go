@@ -0,0 +1,61 @@
+package chat
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAuditLogPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	audit, err := openAuditLog(path)
+	if err != nil {
+		t.Fatalf("openAuditLog failed: %v", err)
+	}
+	audit.record(AuditEntry{Action: "kick", Actor: "Alice", Target: "Bob", Reason: "spam"})
+	audit.record(AuditEntry{Action: "ban", Actor: "Alice", Target: "Carol (10.0.0.3)"})
+	audit.file.Close()
+
+	reopened, err := openAuditLog(path)
+	if err != nil {
+		t.Fatalf("reopening audit log failed: %v", err)
+	}
+	defer reopened.file.Close()
+
+	entries := reopened.recent(10)
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries after reopen, want 2", len(entries))
+	}
+	if got := entries[0].String(); !strings.Contains(got, "kick Alice -> Bob: spam") {
+		t.Errorf("unexpected entry %q", got)
+	}
+	if got := reopened.recent(1); len(got) != 1 || got[0].Action != "ban" {
+		t.Errorf("recent(1) = %v, want the ban", got)
+	}
+}
+
+func TestAuditedModeration(t *testing.T) {
+	s, _ := newEmailTestServer(t)
+	s.auditLog, _ = openAuditLog("")
+	s.bans, _ = loadBanList("")
+	mod := newPipeClient(t, "Mod")
+	mod.role = RoleModerator
+
+	if err := banCommand(s, mod, []string{"192.0.2.7", "scanning", "ports"}); err != nil {
+		t.Fatalf("ban failed: %v", err)
+	}
+	if err := unbanCommand(s, mod, []string{"192.0.2.7"}); err != nil {
+		t.Fatalf("unban failed: %v", err)
+	}
+
+	entries := s.auditLog.recent(10)
+	if len(entries) != 2 {
+		t.Fatalf("got %d audit entries, want 2", len(entries))
+	}
+	if e := entries[0]; e.Action != "ban" || e.Actor != "Mod" || e.Reason != "scanning ports" {
+		t.Errorf("unexpected ban entry %+v", e)
+	}
+	if entries[1].Action != "unban" {
+		t.Errorf("unexpected unban entry %+v", entries[1])
+	}
+}
@@ -0,0 +1,113 @@
+package chat
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+const passwordIterations = 100000
+
+var errAuthFailed = errors.New("authentication failed")
+
+// pbkdf2SHA256 derives a 32-byte key as described in RFC 8018
+func pbkdf2SHA256(password, salt []byte, iterations int) []byte {
+	mac := hmac.New(sha256.New, password)
+	mac.Write(salt)
+	mac.Write(binary.BigEndian.AppendUint32(nil, 1))
+	u := mac.Sum(nil)
+
+	key := append([]byte(nil), u...)
+	for i := 1; i < iterations; i++ {
+		mac.Reset()
+		mac.Write(u)
+		u = mac.Sum(u[:0])
+		for j := range key {
+			key[j] ^= u[j]
+		}
+	}
+	return key
+}
+
+// hashPassword encodes a salted hash as pbkdf2-sha256$<iterations>$<salt>$<hash>
+func hashPassword(password string) string {
+	salt := make([]byte, 16)
+	rand.Read(salt)
+	key := pbkdf2SHA256([]byte(password), salt, passwordIterations)
+	return fmt.Sprintf("pbkdf2-sha256$%d$%s$%s", passwordIterations,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key))
+}
+
+func checkPassword(encoded, password string) bool {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 4 || parts[0] != "pbkdf2-sha256" {
+		return false
+	}
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations < 1 {
+		return false
+	}
+	salt, err1 := base64.RawStdEncoding.DecodeString(parts[2])
+	want, err2 := base64.RawStdEncoding.DecodeString(parts[3])
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	got := pbkdf2SHA256([]byte(password), salt, iterations)
+	return subtle.ConstantTimeCompare(got, want) == 1
+}
+
+func validatePassword(password string) error {
+	if len(password) < 6 {
+		return fmt.Errorf("password too short (minimum 6 characters)")
+	}
+	return nil
+}
+
+// SetPassword stores credentials for name in the configured store
+func SetPassword(config *Config, name, password string) error {
+	if err := validatePassword(password); err != nil {
+		return err
+	}
+	store, err := OpenStore(config)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	accounts, err := loadAccountStore(store)
+	if err != nil {
+		return err
+	}
+	return accounts.update(name, func(a *Account) error {
+		a.PasswordHash = hashPassword(password)
+		return nil
+	})
+}
+
+// authenticate asks for the password of name and returns the account's
+// canonical name on success
+func (s *Server) authenticate(conn net.Conn, reader *bufio.Reader, name string) (string, error) {
+	if _, err := conn.Write([]byte("[ENTER PASSWORD]:")); err != nil {
+		return "", err
+	}
+	password, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+
+	account, ok := s.accounts.get(name)
+	if !ok || account.PasswordHash == "" ||
+		!checkPassword(account.PasswordHash, strings.TrimRight(password, "\r\n")) {
+		return "", errAuthFailed
+	}
+	return account.Name, nil
+}
@@ -0,0 +1,74 @@
+package chat
+
+import (
+	"testing"
+)
+
+func TestPasswordHashing(t *testing.T) {
+	encoded := hashPassword("correct horse")
+	if !checkPassword(encoded, "correct horse") {
+		t.Error("valid password rejected")
+	}
+	if checkPassword(encoded, "wrong horse") {
+		t.Error("invalid password accepted")
+	}
+	if checkPassword("plaintext", "plaintext") {
+		t.Error("malformed hash accepted")
+	}
+	if hashPassword("same") == hashPassword("same") {
+		t.Error("hashes must be salted")
+	}
+}
+
+func TestAuthMode(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountsFile = tempAccountsFile(t)
+	config.AuthRequired = true
+	if err := SetPassword(config, "Alice", "secret1"); err != nil {
+		t.Fatalf("SetPassword failed: %v", err)
+	}
+	addr := setupTestServerWithConfig(t, config)
+
+	t.Run("Login", func(t *testing.T) {
+		client, err := newTestClient(t, addr)
+		if err != nil {
+			t.Fatalf("Client connection failed: %v", err)
+		}
+		defer client.close()
+
+		client.expectMessage(t, "Welcome")
+		client.sendMessage("alice")
+		client.sendMessage("wrong")
+		if err := client.expectMessage(t, "Authentication failed"); err != nil {
+			t.Fatalf("Wrong password accepted: %v", err)
+		}
+		client.sendMessage("alice")
+		client.sendMessage("secret1")
+		// The account name replaces whatever was typed
+		if err := client.expectMessage(t, "Alice joined"); err != nil {
+			t.Fatalf("Login failed: %v", err)
+		}
+
+		client.sendMessage("/nick Mallory")
+		if err := client.expectMessage(t, "tied to accounts"); err != nil {
+			t.Errorf("Nick change allowed in auth mode: %v", err)
+		}
+	})
+
+	t.Run("TooManyAttempts", func(t *testing.T) {
+		client, err := newTestClient(t, addr)
+		if err != nil {
+			t.Fatalf("Client connection failed: %v", err)
+		}
+		defer client.close()
+
+		client.expectMessage(t, "Welcome")
+		for i := 0; i < config.AuthMaxAttempts; i++ {
+			client.sendMessage("Bob")
+			client.sendMessage("guess")
+		}
+		if err := client.expectMessage(t, "Too many failed login attempts"); err != nil {
+			t.Fatalf("Connection not rejected: %v", err)
+		}
+	})
+}
@@ -0,0 +1,63 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const defaultAwayMessage = "Away"
+
+// awayCommand marks the client away with an optional message, or back if
+// it is already away and no message is given
+func awayCommand(s *Server, c *Client, args []string) error {
+	message := strings.Join(args, " ")
+	s.mutex.Lock()
+	c.autoAway = false
+	if message == "" && c.away != "" {
+		c.away = ""
+		s.mutex.Unlock()
+		c.write([]byte("You are no longer marked as away\n"))
+		return nil
+	}
+	if message == "" {
+		message = defaultAwayMessage
+	}
+	c.away = message
+	c.awaySince = time.Now()
+	s.mutex.Unlock()
+
+	c.write([]byte(fmt.Sprintf("You are now marked as away: %s\n", message)))
+	return nil
+}
+
+// replyAway tells from that to is away. The caller holds s.mutex.
+func (s *Server) replyAway(from, to *Client) {
+	if to.away == "" || from == to {
+		return
+	}
+	from.sendMessage(Message{
+		Type: MessageTypeSystem,
+		Content: fmt.Sprintf("%s is away (for %s): %s", to.name,
+			time.Since(to.awaySince).Round(time.Minute), to.away),
+		Timestamp: time.Now(),
+	})
+}
+
+// replyAwayMentions auto-replies to the sender of msg for every mentioned
+// user who is away
+func (s *Server) replyAwayMentions(msg Message) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	from := s.findClient(msg.From)
+	if from == nil {
+		return
+	}
+	replied := make(map[*Client]bool)
+	for _, match := range mentionPattern.FindAllStringSubmatch(msg.Content, -1) {
+		if to := s.findClient(match[1]); to != nil && !replied[to] {
+			replied[to] = true
+			s.replyAway(from, to)
+		}
+	}
+}
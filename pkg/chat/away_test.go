@@ -0,0 +1,67 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAway(t *testing.T) {
+	addr := setupTestServer(t)
+	join := func(name string) *TestClient {
+		c, err := newTestClient(t, addr)
+		if err != nil {
+			t.Fatalf("Client connection failed: %v", err)
+		}
+		c.sendMessage(name)
+		if err := c.expectMessage(t, name+" joined"); err != nil {
+			t.Fatalf("Join as %s failed: %v", name, err)
+		}
+		return c
+	}
+	alice := join("Alice")
+	defer alice.close()
+	bob := join("Bob")
+	defer bob.close()
+
+	alice.sendMessage("/away lunch, back at 2")
+	if err := alice.expectMessage(t, "You are now marked as away: lunch, back at 2"); err != nil {
+		t.Fatalf("away not confirmed: %v", err)
+	}
+
+	bob.sendMessage("/list")
+	if err := bob.expectMessage(t, "Alice (in general) [away]"); err != nil {
+		t.Fatalf("no away marker in /list: %v", err)
+	}
+	bob.sendMessage("/msg Alice ping")
+	if err := bob.expectMessage(t, "Alice is away (for 0s): lunch, back at 2"); err != nil {
+		t.Fatalf("no auto-reply to PM: %v", err)
+	}
+	bob.sendMessage("hey @Alice")
+	if err := bob.expectMessage(t, "Alice is away"); err != nil {
+		t.Fatalf("no auto-reply to mention: %v", err)
+	}
+
+	alice.sendMessage("/away")
+	if err := alice.expectMessage(t, "no longer marked as away"); err != nil {
+		t.Fatalf("back not confirmed: %v", err)
+	}
+	bob.sendMessage("/msg Alice ping again")
+	bob.sendMessage("/list")
+	bob.conn.SetReadDeadline(time.Now().Add(messageTimeout))
+	for {
+		line, err := bob.reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("list failed: %v", err)
+		}
+		if strings.Contains(line, "is away") {
+			t.Fatalf("auto-reply after coming back: %q", line)
+		}
+		if strings.Contains(line, "Alice (in general)") {
+			if strings.Contains(line, "[away]") {
+				t.Errorf("away marker after coming back: %q", line)
+			}
+			break
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package chat
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// banList is the set of banned IPs, mirrored to a file with one IP per line
+type banList struct {
+	path  string
+	mutex sync.Mutex
+	ips   map[string]bool
+}
+
+func loadBanList(path string) (*banList, error) {
+	bans := &banList{path: path, ips: make(map[string]bool)}
+	if path == "" {
+		return bans, nil
+	}
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return bans, nil
+	}
+	if err != nil {
+		return bans, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			bans.ips[line] = true
+		}
+	}
+	return bans, scanner.Err()
+}
+
+// reload replaces the bans with the file's and returns how many there are
+func (b *banList) reload() (int, error) {
+	fresh, err := loadBanList(b.path)
+	if err != nil {
+		return len(b.list()), err
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.ips = fresh.ips
+	return len(b.ips), nil
+}
+
+func (b *banList) contains(ip string) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.ips[ip]
+}
+
+func (b *banList) add(ip string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.ips[ip] = true
+	return b.save()
+}
+
+func (b *banList) remove(ip string) (bool, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if !b.ips[ip] {
+		return false, nil
+	}
+	delete(b.ips, ip)
+	return true, b.save()
+}
+
+// list returns the banned addresses in order
+func (b *banList) list() []string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	ips := make([]string, 0, len(b.ips))
+	for ip := range b.ips {
+		ips = append(ips, ip)
+	}
+	sort.Strings(ips)
+	return ips
+}
+
+func (b *banList) save() error {
+	if b.path == "" {
+		return nil
+	}
+	ips := make([]string, 0, len(b.ips))
+	for ip := range b.ips {
+		ips = append(ips, ip)
+	}
+	sort.Strings(ips)
+
+	content := "# Banned IPs, one per line\n" + strings.Join(ips, "\n") + "\n"
+	return os.WriteFile(b.path, []byte(content), 0o644)
+}
+
+// remoteIP returns the IP part of a connection's remote address
+func remoteIP(conn net.Conn) string {
+	if conn == nil || conn.RemoteAddr() == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(conn.RemoteAddr().String())
+	if err != nil {
+		return conn.RemoteAddr().String()
+	}
+	return host
+}
+
+func banCommand(s *Server, c *Client, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: /ban <user|ip> [reason]")
+	}
+	reason := strings.Join(args[1:], " ")
+
+	// Resolve a nickname to its address, otherwise expect a literal IP
+	target := args[0]
+	// An account's configured role protects it even while it is offline
+	if s.rolesFor(target) >= c.role {
+		return fmt.Errorf("you can only ban users below your own role (%s)", c.role)
+	}
+	ip := ""
+	s.mutex.Lock()
+	if client := s.findClient(target); client != nil {
+		ip = remoteIP(client.conn)
+	}
+	s.mutex.Unlock()
+	if ip == "" {
+		if parsed := net.ParseIP(target); parsed != nil {
+			ip = parsed.String()
+		} else {
+			return fmt.Errorf("user %s not found", target)
+		}
+	}
+
+	// A ban drops everyone on the address, so nobody there may outrank c
+	s.mutex.Lock()
+	outranked := false
+	s.clients.each(func(conn net.Conn, client *Client) bool {
+		if client != c && remoteIP(conn) == ip && client.role >= c.role {
+			outranked = true
+			return false
+		}
+		return true
+	})
+	s.mutex.Unlock()
+	if outranked {
+		return fmt.Errorf("you can only ban users below your own role (%s)", c.role)
+	}
+
+	if err := s.bans.add(ip); err != nil {
+		return fmt.Errorf("failed to save ban list: %v", err)
+	}
+
+	// Drop every connection from the banned address
+	var dropped []*Client
+	s.mutex.Lock()
+	s.clients.each(func(conn net.Conn, client *Client) bool {
+		if remoteIP(conn) == ip {
+			dropped = append(dropped, client)
+		}
+		return true
+	})
+	s.mutex.Unlock()
+	for _, client := range dropped {
+		client.write([]byte("You have been banned from this server.\n"))
+		client.closeConn()
+	}
+
+	s.logActivity("Banned", "client", c.name, "target", target, "remote", ip)
+	s.audit("ban", c, fmt.Sprintf("%s (%s)", target, ip), reason)
+	notice := fmt.Sprintf("%s was banned by %s", target, c.name)
+	if reason != "" {
+		notice += ": " + reason
+	}
+	s.broadcast(Message{
+		Type:      MessageTypeSystem,
+		Content:   notice,
+		Timestamp: time.Now(),
+	}, nil)
+	return nil
+}
+
+func unbanCommand(s *Server, c *Client, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: /unban <ip>")
+	}
+
+	removed, err := s.bans.remove(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to save ban list: %v", err)
+	}
+	if !removed {
+		return fmt.Errorf("%s is not banned", args[0])
+	}
+	s.logActivity("Unbanned", "client", c.name, "target", args[0])
+	s.audit("unban", c, args[0], "")
+	c.write([]byte(fmt.Sprintf("%s has been unbanned\n", args[0])))
+	return nil
+}
@@ -0,0 +1,108 @@
+package chat
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBanListPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bans.txt")
+	bans, err := loadBanList(path)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	bans.add("10.0.0.1")
+	bans.add("10.0.0.2")
+	bans.remove("10.0.0.2")
+
+	reloaded, err := loadBanList(path)
+	if err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	if !reloaded.contains("10.0.0.1") || reloaded.contains("10.0.0.2") {
+		t.Errorf("unexpected ban list after reload: %v", reloaded.ips)
+	}
+}
+
+func TestBannedConnectionRejected(t *testing.T) {
+	config := DefaultConfig()
+	config.BanFile = filepath.Join(t.TempDir(), "bans.txt")
+	os.WriteFile(config.BanFile, []byte("127.0.0.1\n"), 0o644)
+	addr := setupTestServerWithConfig(t, config)
+
+	client, err := newTestClient(t, addr)
+	if err != nil {
+		t.Fatalf("Client connection failed: %v", err)
+	}
+	defer client.close()
+
+	if err := client.expectMessage(t, "banned"); err != nil {
+		t.Fatalf("Banned client was not rejected: %v", err)
+	}
+}
+
+func TestBanRequiresModerator(t *testing.T) {
+	config := DefaultConfig()
+	config.BanFile = ""
+	addr := setupTestServerWithConfig(t, config)
+
+	client, err := newTestClient(t, addr)
+	if err != nil {
+		t.Fatalf("Client connection failed: %v", err)
+	}
+	defer client.close()
+
+	client.expectMessage(t, "Welcome")
+	client.sendMessage("Regular")
+	client.expectMessage(t, "joined")
+	client.sendMessage("/ban 10.1.2.3")
+	if err := client.expectMessage(t, "permission denied"); err != nil {
+		t.Fatalf("Non-moderator was allowed to ban: %v", err)
+	}
+}
+
+func TestBanRespectsRoles(t *testing.T) {
+	config := DefaultConfig()
+	config.BanFile = filepath.Join(t.TempDir(), "bans.txt")
+	config.AccountsFile = tempAccountsFile(t)
+	config.AuthRequired = true
+	config.Roles = map[string]Role{"alice": RoleAdmin, "bob": RoleModerator, "carol": RoleOwner}
+	SetPassword(config, "Alice", "secret1")
+	SetPassword(config, "Bob", "secret2")
+	addr := setupTestServerWithConfig(t, config)
+
+	login := func(name, password string) *TestClient {
+		c, err := newTestClient(t, addr)
+		if err != nil {
+			t.Fatalf("Client connection failed: %v", err)
+		}
+		c.expectMessage(t, "Welcome")
+		c.sendMessage(name)
+		c.sendMessage(password)
+		if err := c.expectMessageWithin(t, name+" joined", passwordTimeout); err != nil {
+			t.Fatalf("Login as %s failed: %v", name, err)
+		}
+		return c
+	}
+	alice := login("Alice", "secret1")
+	defer alice.close()
+	bob := login("Bob", "secret2")
+	defer bob.close()
+
+	// By name, by the shared address, and against an offline account
+	for _, target := range []string{"Alice", "127.0.0.1", "Carol"} {
+		bob.sendMessage("/ban " + target)
+		if err := bob.expectMessage(t, "you can only ban users below your own role"); err != nil {
+			t.Fatalf("Moderator was allowed to ban %s: %v", target, err)
+		}
+	}
+
+	bans, err := loadBanList(config.BanFile)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if bans.contains("127.0.0.1") {
+		t.Error("refused ban was still recorded")
+	}
+}
@@ -0,0 +1,150 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A lightweight alternative to mDNS: servers started with -beacon
+// periodically multicast "TCPCHAT1 <port> <name>" and clients listen for
+// it. Unlike mDNS nothing has to answer queries, so it also works where
+// port 5353 is already taken by the system resolver.
+
+const (
+	beaconAddr     = "239.255.77.77:8990"
+	beaconMagic    = "TCPCHAT1"
+	beaconInterval = 5 * time.Second
+)
+
+// beaconPacket encodes the announcement for a server
+func beaconPacket(name, port string) []byte {
+	return []byte(fmt.Sprintf("%s %s %s", beaconMagic, port, name))
+}
+
+// parseBeacon decodes an announcement received from src
+func parseBeacon(packet []byte, src net.IP) (DiscoveredServer, bool) {
+	fields := strings.SplitN(string(packet), " ", 3)
+	if len(fields) < 2 || fields[0] != beaconMagic {
+		return DiscoveredServer{}, false
+	}
+	port, err := strconv.Atoi(fields[1])
+	if err != nil || port <= 0 || port > 65535 {
+		return DiscoveredServer{}, false
+	}
+	server := DiscoveredServer{Addr: net.JoinHostPort(src.String(), fields[1])}
+	if len(fields) == 3 {
+		server.Name = strings.TrimSpace(fields[2])
+	}
+	if server.Name == "" {
+		server.Name = server.Addr
+	}
+	return server, true
+}
+
+// sendBeacons announces the server until it shuts down
+func (s *Server) sendBeacons(ctx context.Context, name, port string) {
+	group, err := net.ResolveUDPAddr("udp4", beaconAddr)
+	if err != nil {
+		s.log.Warn("Beacon disabled", "err", err)
+		return
+	}
+	conn, err := net.DialUDP("udp4", nil, group)
+	if err != nil {
+		s.log.Warn("Beacon disabled", "err", err)
+		return
+	}
+	defer conn.Close()
+	s.logActivity("Announcing beacon", "name", name, "addr", beaconAddr)
+
+	packet := beaconPacket(name, port)
+	ticker := time.NewTicker(beaconInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := conn.Write(packet); err != nil {
+			s.log.Warn("Beacon send error", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ListenBeacons reports servers whose beacons arrive before the timeout
+// elapses
+func ListenBeacons(timeout time.Duration, found func(DiscoveredServer)) error {
+	group, err := net.ResolveUDPAddr("udp4", beaconAddr)
+	if err != nil {
+		return err
+	}
+	conn, err := net.ListenMulticastUDP("udp4", nil, group)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	readBeacons(conn, time.Now().Add(timeout), found)
+	return nil
+}
+
+// readBeacons reads announcements from conn until the deadline, reporting
+// each server once
+func readBeacons(conn net.PacketConn, deadline time.Time, found func(DiscoveredServer)) {
+	conn.SetReadDeadline(deadline)
+	seen := make(map[string]bool)
+	buf := make([]byte, 512)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		udp, ok := addr.(*net.UDPAddr)
+		if !ok {
+			continue
+		}
+		if server, ok := parseBeacon(buf[:n], udp.IP); ok && !seen[server.Addr] {
+			seen[server.Addr] = true
+			found(server)
+		}
+	}
+}
+
+// DiscoverServers browses via mDNS and listens for beacons at the same
+// time, reporting each server once. It fails only if both methods do.
+func DiscoverServers(timeout time.Duration, found func(DiscoveredServer)) error {
+	var (
+		mutex sync.Mutex
+		seen  = make(map[string]bool)
+		wg    sync.WaitGroup
+		errs  [2]error
+	)
+	report := func(server DiscoveredServer) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if !seen[server.Addr] {
+			seen[server.Addr] = true
+			found(server)
+		}
+	}
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		errs[0] = BrowseServers(timeout, report)
+	}()
+	go func() {
+		defer wg.Done()
+		errs[1] = ListenBeacons(timeout, report)
+	}()
+	wg.Wait()
+
+	if errs[0] != nil && errs[1] != nil {
+		return fmt.Errorf("mDNS: %v; beacon: %v", errs[0], errs[1])
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package chat
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestParseBeacon(t *testing.T) {
+	src := net.IPv4(192, 168, 1, 20)
+	server, ok := parseBeacon(beaconPacket("Office chat", "8989"), src)
+	if !ok || server.Name != "Office chat" || server.Addr != "192.168.1.20:8989" {
+		t.Errorf("unexpected beacon: %+v, %v", server, ok)
+	}
+
+	for _, packet := range []string{"", "HELLO 8989 x", "TCPCHAT1 abc x", "TCPCHAT1 70000 x"} {
+		if _, ok := parseBeacon([]byte(packet), src); ok {
+			t.Errorf("accepted invalid beacon %q", packet)
+		}
+	}
+}
+
+func TestReadBeacons(t *testing.T) {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket failed: %v", err)
+	}
+	defer conn.Close()
+
+	sender, err := net.Dial("udp4", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer sender.Close()
+	for _, packet := range []string{"noise", "TCPCHAT1 8989 lab", "TCPCHAT1 8989 lab"} {
+		sender.Write([]byte(packet))
+	}
+
+	var found []DiscoveredServer
+	readBeacons(conn, time.Now().Add(200*time.Millisecond), func(s DiscoveredServer) {
+		found = append(found, s)
+	})
+	if len(found) != 1 || found[0].Name != "lab" || found[0].Addr != "127.0.0.1:8989" {
+		t.Errorf("expected one server, got %+v", found)
+	}
+}
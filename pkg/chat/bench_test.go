@@ -0,0 +1,84 @@
+package chat
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+// discardConn is a net.Conn that swallows writes, isolating broadcast cost
+// from the network
+type discardConn struct{ net.Conn }
+
+func (discardConn) Write(b []byte) (int, error) { return len(b), nil }
+func (discardConn) Close() error                { return nil }
+
+func benchmarkRoom(b *testing.B, clients int) (*Server, *ChatRoom) {
+	s := NewServer()
+	b.Cleanup(func() { s.Logfile.Close() })
+	room := s.rooms["general"]
+	for i := 0; i < clients; i++ {
+		conn := &discardConn{}
+		room.clients[conn] = &Client{conn: conn, name: fmt.Sprintf("User%d", i), room: room.name}
+	}
+	return s, room
+}
+
+func BenchmarkBroadcastToRoom(b *testing.B) {
+	for _, clients := range []int{10, 100, 1000} {
+		b.Run(fmt.Sprintf("clients=%d", clients), func(b *testing.B) {
+			s, room := benchmarkRoom(b, clients)
+			msg := Message{Type: MessageTypeChat, From: "bench", Content: "hello everyone", Timestamp: time.Now()}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				s.broadcastToRoom(room, msg, nil)
+			}
+		})
+	}
+}
+
+func BenchmarkFormatMessage(b *testing.B) {
+	msg := Message{Type: MessageTypeChat, From: "bench", Content: "hello everyone", Timestamp: time.Now()}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		formatMessage(msg)
+	}
+}
+
+// BenchmarkBroadcastLatency measures end-to-end delivery over real TCP
+// connections: one sender, several receivers in the same room.
+func BenchmarkBroadcastLatency(b *testing.B) {
+	// The benchmark body runs several times while calibrating b.N, each
+	// time against a server of its own
+	addr := setupTestServer(b)
+
+	var receivers []*TestClient
+	for i := 0; i < 5; i++ {
+		c, err := newTestClient(nil, addr)
+		if err != nil {
+			b.Fatalf("Client connection failed: %v", err)
+		}
+		defer c.close()
+		c.expectMessage(nil, "Welcome")
+		c.sendMessage(fmt.Sprintf("Bench%d-%d", b.N, i))
+		if err := c.expectMessage(nil, "joined"); err != nil {
+			b.Fatalf("Join failed: %v", err)
+		}
+		receivers = append(receivers, c)
+	}
+	sender := receivers[0]
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		line := fmt.Sprintf("bench message %d", i)
+		sender.sendMessage(line)
+		for _, c := range receivers {
+			if err := c.expectMessage(nil, line); err != nil {
+				b.Fatalf("Delivery failed: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package chat
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+// botPrefix starts the handshake line sent by bots instead of a name
+const botPrefix = "BOT "
+
+// newBotToken returns a random token; only its hash is ever stored
+func newBotToken() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// hashBotToken uses a plain SHA-256: tokens are random, so a slow KDF like
+// the one used for passwords adds nothing
+func hashBotToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// findBot returns the name of the account holding token
+func (st *accountStore) findBot(token string) (string, bool) {
+	hash := hashBotToken(token)
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+	for _, a := range st.accounts {
+		if a.BotTokenHash != "" && a.BotTokenHash == hash {
+			return a.Name, true
+		}
+	}
+	return "", false
+}
+
+// bots lists the names of accounts that hold a bot token
+func (st *accountStore) bots() []string {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+	var names []string
+	for _, a := range st.accounts {
+		if a.BotTokenHash != "" {
+			names = append(names, a.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// authenticateBot checks a BOT handshake line and returns the bot's name
+func (s *Server) authenticateBot(conn net.Conn, line string) (string, error) {
+	token := strings.TrimSpace(strings.TrimPrefix(line, botPrefix))
+	name, ok := s.accounts.findBot(token)
+	if !ok {
+		s.log.Warn("Rejected bot token", "remote", remoteIP(conn))
+		return "", errAuthFailed
+	}
+
+	s.mutex.Lock()
+	taken := s.isNameTaken(name)
+	s.mutex.Unlock()
+	if taken {
+		return "", fmt.Errorf("bot %s is already connected", name)
+	}
+	return name, nil
+}
+
+func botTokenCommand(s *Server, c *Client, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: /bottoken add|revoke <name> or /bottoken list")
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "list":
+		bots := s.accounts.bots()
+		if len(bots) == 0 {
+			c.write([]byte("No bot tokens issued\n"))
+			return nil
+		}
+		c.write([]byte(fmt.Sprintf("Bots (%d):\n%s\n", len(bots), strings.Join(bots, "\n"))))
+		return nil
+
+	case "add":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: /bottoken add <name>")
+		}
+		if len(args[1]) < 2 || len(args[1]) > 20 {
+			return fmt.Errorf("bot names must be 2-20 characters")
+		}
+		token := newBotToken()
+		err := s.accounts.update(args[1], func(a *Account) error {
+			a.BotTokenHash = hashBotToken(token)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save accounts: %v", err)
+		}
+		s.logActivity("Bot token issued", "client", c.name, "target", args[1])
+		c.write([]byte(fmt.Sprintf("Token for %s (shown only once): %s\n", args[1], token)))
+		return nil
+
+	case "revoke":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: /bottoken revoke <name>")
+		}
+		err := s.accounts.update(args[1], func(a *Account) error {
+			if a.BotTokenHash == "" {
+				return fmt.Errorf("no bot named %s", args[1])
+			}
+			a.BotTokenHash = ""
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		// A revoked bot loses its current session too
+		s.mutex.Lock()
+		bot := s.findClient(args[1])
+		s.mutex.Unlock()
+		if bot != nil && bot.bot {
+			bot.sendMessage(Message{
+				Type:      MessageTypeError,
+				Content:   "Your bot token has been revoked.",
+				Timestamp: time.Now(),
+			})
+			bot.closeConn()
+		}
+
+		s.logActivity("Bot token revoked", "client", c.name, "target", args[1])
+		c.write([]byte(fmt.Sprintf("Token for %s revoked\n", args[1])))
+		return nil
+	}
+	return fmt.Errorf("usage: /bottoken add|revoke <name> or /bottoken list")
+}
@@ -0,0 +1,76 @@
+package chat
+
+import (
+	"testing"
+)
+
+func TestBotHandshake(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountsFile = tempAccountsFile(t)
+	config.FloodBurst = 2
+	store, _ := loadAccountStore(newFileStore(config.AccountsFile, "", ""))
+	store.update("Helper", func(a *Account) error {
+		a.BotTokenHash = hashBotToken("s3cr3t-token")
+		return nil
+	})
+	addr := setupTestServerWithConfig(t, config)
+
+	t.Run("InvalidToken", func(t *testing.T) {
+		client, err := newTestClient(t, addr)
+		if err != nil {
+			t.Fatalf("Client connection failed: %v", err)
+		}
+		defer client.close()
+
+		client.expectMessage(t, "Welcome")
+		client.sendMessage("BOT wrong-token")
+		if err := client.expectMessage(t, "Bot login failed"); err != nil {
+			t.Fatalf("Invalid token accepted: %v", err)
+		}
+	})
+
+	t.Run("ValidToken", func(t *testing.T) {
+		client, err := newTestClient(t, addr)
+		if err != nil {
+			t.Fatalf("Client connection failed: %v", err)
+		}
+		defer client.close()
+
+		client.expectMessage(t, "Welcome")
+		client.sendMessage("BOT s3cr3t-token")
+		if err := client.expectMessage(t, "Helper joined"); err != nil {
+			t.Fatalf("Bot login failed: %v", err)
+		}
+
+		// Bots are held to BotFloodBurst rather than the user limit
+		for i := 0; i < 5; i++ {
+			client.sendMessage("tick")
+		}
+		client.sendMessage("/list")
+		if err := client.expectMessage(t, "Helper (in general) [bot]"); err != nil {
+			t.Errorf("Bot not flagged in /list: %v", err)
+		}
+	})
+}
+
+func TestBotTokenCommand(t *testing.T) {
+	s, _ := newEmailTestServer(t)
+	admin := newPipeClient(t, "Admin")
+
+	if err := botTokenCommand(s, admin, []string{"add", "Helper"}); err != nil {
+		t.Fatalf("add failed: %v", err)
+	}
+	if bots := s.accounts.bots(); len(bots) != 1 || bots[0] != "Helper" {
+		t.Fatalf("bots() = %v, want [Helper]", bots)
+	}
+
+	if err := botTokenCommand(s, admin, []string{"revoke", "Helper"}); err != nil {
+		t.Fatalf("revoke failed: %v", err)
+	}
+	if bots := s.accounts.bots(); len(bots) != 0 {
+		t.Errorf("token still listed after revoke: %v", bots)
+	}
+	if err := botTokenCommand(s, admin, []string{"revoke", "Nobody"}); err == nil {
+		t.Error("expected error revoking an unknown bot")
+	}
+}
@@ -0,0 +1,277 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	bridgeQueueSize      = 100
+	bridgeMaxRetries     = 3
+	defaultBridgeBackoff = time.Second
+)
+
+// BridgeConfig relays one room to a Slack or Discord channel or an MQTT
+// broker
+type BridgeConfig struct {
+	Room       string `json:"room"`
+	Kind       string `json:"kind"`        // "slack", "discord" or "mqtt"
+	WebhookURL string `json:"webhook_url"` // Outbound delivery
+	Token      string `json:"token"`       // Bot token used to read the channel
+	Channel    string `json:"channel"`     // Channel ID to read from
+	// PollSeconds is how often the channel is checked for new messages
+	PollSeconds int `json:"poll_seconds"`
+
+	// MQTT bridges: Topic is the subscribed filter, PublishTopic receives
+	// the room's chat messages as JSON
+	Broker       string `json:"broker"` // tcp://host:1883 or ssl://host:8883
+	Topic        string `json:"topic"`
+	PublishTopic string `json:"publish_topic"`
+	Username     string `json:"username"`
+	Password     string `json:"password"`
+}
+
+// LoadBridgeConfigs reads a JSON list of bridge definitions
+func LoadBridgeConfigs(path string) ([]BridgeConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var configs []BridgeConfig
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("invalid bridge config %s: %v", path, err)
+	}
+	return configs, nil
+}
+
+// bridgeMessage is a message read from the remote side of a bridge
+type bridgeMessage struct {
+	From    string
+	Content string
+	System  bool // Shown as a system notice rather than a chat line
+}
+
+// bridgeService talks to one external chat service
+type bridgeService interface {
+	// Send posts a chat line on behalf of a local user
+	Send(from, content string) error
+	// Poll returns messages posted remotely since the previous call. The
+	// first call only records the current position.
+	Poll() ([]bridgeMessage, error)
+}
+
+// bridgeSubscriber is implemented by services that push incoming messages
+// instead of being polled. Subscribe returns once ctx is cancelled.
+type bridgeSubscriber interface {
+	Subscribe(ctx context.Context, deliver func(bridgeMessage))
+}
+
+type outboundBridgeMessage struct {
+	from    string
+	content string
+}
+
+// roomBridge couples a room with a remote channel
+type roomBridge struct {
+	config  BridgeConfig
+	service bridgeService
+	queue   chan outboundBridgeMessage
+	log     *slog.Logger
+}
+
+func newBridgeService(config BridgeConfig, logger *slog.Logger) (bridgeService, error) {
+	switch config.Kind {
+	case "slack":
+		return newSlackBridge(config), nil
+	case "discord":
+		return newDiscordBridge(config), nil
+	case "mqtt":
+		if config.Broker == "" {
+			return nil, fmt.Errorf("mqtt bridge needs a broker")
+		}
+		return newMQTTBridge(config, logger), nil
+	default:
+		return nil, fmt.Errorf("unknown bridge kind: %s", config.Kind)
+	}
+}
+
+// openBridges sets up every configured bridge, so that room messages are
+// queued for them from the start; runBridges starts relaying
+func (s *Server) openBridges() {
+	for _, config := range s.config.Bridges {
+		service, err := newBridgeService(config, s.log)
+		if err != nil {
+			s.log.Error("Bridge disabled", "room", config.Room, "kind", config.Kind, "err", err)
+			continue
+		}
+
+		b := &roomBridge{
+			config:  config,
+			service: service,
+			queue:   make(chan outboundBridgeMessage, bridgeQueueSize),
+			log:     s.log,
+		}
+		s.bridges[config.Room] = append(s.bridges[config.Room], b)
+	}
+}
+
+// runBridges launches the relay workers of every bridge; they stop when
+// ctx is cancelled
+func (s *Server) runBridges(ctx context.Context) {
+	for _, bridges := range s.bridges {
+		for _, b := range bridges {
+			s.goWorker(func() { b.sendLoop(ctx) })
+			if sub, ok := b.service.(bridgeSubscriber); ok {
+				s.goWorker(func() { sub.Subscribe(ctx, func(m bridgeMessage) { s.injectBridgeMessage(b, m) }) })
+			} else if b.config.Token != "" && b.config.Channel != "" {
+				s.goWorker(func() { s.pollBridge(ctx, b) })
+			}
+			s.logActivity("Room bridged", "room", b.config.Room, "kind", b.config.Kind)
+		}
+	}
+}
+
+// relayToBridges queues a local chat message for every bridge of the room
+func (s *Server) relayToBridges(room string, msg Message) {
+	if msg.Type != MessageTypeChat {
+		return
+	}
+	for _, b := range s.bridges[room] {
+		select {
+		case b.queue <- outboundBridgeMessage{from: msg.From, content: msg.Content}:
+		default:
+			s.log.Warn("Bridge queue full, dropping message", "room", room, "kind", b.config.Kind)
+		}
+	}
+}
+
+func (b *roomBridge) sendLoop(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case m := <-b.queue:
+			if err := b.service.Send(m.from, m.content); err != nil {
+				b.log.Warn("Bridge send failed", "room", b.config.Room, "kind", b.config.Kind, "err", err)
+			}
+		}
+	}
+}
+
+func (s *Server) pollBridge(ctx context.Context, b *roomBridge) {
+	interval := time.Duration(b.config.PollSeconds) * time.Second
+	if interval <= 0 {
+		interval = 3 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		messages, err := b.service.Poll()
+		if err != nil {
+			s.log.Warn("Bridge poll failed", "room", b.config.Room, "kind", b.config.Kind, "err", err)
+		}
+		for _, m := range messages {
+			s.injectBridgeMessage(b, m)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// injectBridgeMessage delivers a remote message without relaying it back.
+// It is sanitized like client input, and dropped unless the bridged room
+// exists.
+func (s *Server) injectBridgeMessage(b *roomBridge, m bridgeMessage) {
+	from, err := s.applySanitizePolicy(m.From)
+	if err == nil {
+		m.Content, err = s.applySanitizePolicy(m.Content)
+	}
+	if err != nil {
+		b.log.Warn("Bridge message rejected", "room", b.config.Room, "kind", b.config.Kind, "err", err)
+		return
+	}
+	if m.Content = strings.TrimSpace(m.Content); m.Content == "" {
+		return
+	}
+
+	msg := Message{
+		Type:      MessageTypeChat,
+		From:      fmt.Sprintf("%s@%s", from, b.config.Kind),
+		Content:   m.Content,
+		Timestamp: time.Now(),
+	}
+	if m.System {
+		msg = Message{
+			Type:      MessageTypeSystem,
+			Content:   fmt.Sprintf("[%s] %s", from, m.Content),
+			Timestamp: time.Now(),
+		}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	room, exists := s.rooms[b.config.Room]
+	if !exists {
+		b.log.Warn("Bridge message dropped: room does not exist", "room", b.config.Room, "kind", b.config.Kind)
+		return
+	}
+	if room.archived {
+		b.log.Warn("Bridge message dropped: room is archived", "room", b.config.Room, "kind", b.config.Kind)
+		return
+	}
+	s.deliverToRoom(room, msg, nil)
+	// Leaves out ephemeral rooms, like broadcastToRoom
+	s.publishRoomMessage(room, msg)
+}
+
+// doBridgeRequest performs an HTTP request, waiting out rate limits
+func doBridgeRequest(client *http.Client, newRequest func() (*http.Request, error)) ([]byte, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests {
+			if attempt >= bridgeMaxRetries {
+				return nil, fmt.Errorf("rate limited")
+			}
+			time.Sleep(retryAfter(resp.Header.Get("Retry-After")))
+			continue
+		}
+		if resp.StatusCode >= 300 {
+			return nil, fmt.Errorf("unexpected status %s: %s", resp.Status, body)
+		}
+		return body, nil
+	}
+}
+
+// retryAfter parses a Retry-After header given in (possibly fractional) seconds
+func retryAfter(header string) time.Duration {
+	seconds, err := strconv.ParseFloat(header, 64)
+	if err != nil || seconds <= 0 {
+		return defaultBridgeBackoff
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -0,0 +1,116 @@
+package chat
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const discordAPI = "https://discord.com/api/v10"
+
+// discordBridge posts through a channel webhook (or the bot API when only a
+// token is configured) and reads the channel with the bot token
+type discordBridge struct {
+	config  BridgeConfig
+	apiBase string
+	client  *http.Client
+	after   string // ID of the newest message seen
+	primed  bool
+}
+
+func newDiscordBridge(config BridgeConfig) *discordBridge {
+	return &discordBridge{
+		config:  config,
+		apiBase: discordAPI,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type discordMessage struct {
+	ID        string `json:"id"`
+	Content   string `json:"content"`
+	WebhookID string `json:"webhook_id"`
+	Author    struct {
+		Username   string `json:"username"`
+		GlobalName string `json:"global_name"`
+		Bot        bool   `json:"bot"`
+	} `json:"author"`
+}
+
+func (b *discordBridge) Send(from, content string) error {
+	target := b.config.WebhookURL
+	payload := map[string]string{"content": content, "username": from}
+	if target == "" {
+		target = fmt.Sprintf("%s/channels/%s/messages", b.apiBase, b.config.Channel)
+		payload = map[string]string{"content": fmt.Sprintf("**%s**: %s", from, content)}
+	}
+	body, _ := json.Marshal(payload)
+
+	_, err := doBridgeRequest(b.client, func() (*http.Request, error) {
+		req, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if b.config.WebhookURL == "" {
+			req.Header.Set("Authorization", "Bot "+b.config.Token)
+		}
+		return req, nil
+	})
+	return err
+}
+
+func (b *discordBridge) Poll() ([]bridgeMessage, error) {
+	query := url.Values{"limit": {"100"}}
+	if !b.primed {
+		query.Set("limit", "1")
+	} else if b.after != "" {
+		query.Set("after", b.after)
+	}
+
+	body, err := doBridgeRequest(b.client, func() (*http.Request, error) {
+		req, err := http.NewRequest(http.MethodGet,
+			fmt.Sprintf("%s/channels/%s/messages?%s", b.apiBase, b.config.Channel, query.Encode()), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bot "+b.config.Token)
+		return req, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var history []discordMessage
+	if err := json.Unmarshal(body, &history); err != nil {
+		return nil, err
+	}
+
+	// Discord returns newest first; snowflake IDs sort chronologically
+	sort.Slice(history, func(i, j int) bool {
+		a, _ := strconv.ParseUint(history[i].ID, 10, 64)
+		c, _ := strconv.ParseUint(history[j].ID, 10, 64)
+		return a < c
+	})
+
+	var messages []bridgeMessage
+	for _, m := range history {
+		b.after = m.ID
+		// Skip webhook posts (including our own relays) and other bots
+		if !b.primed || m.WebhookID != "" || m.Author.Bot || m.Content == "" {
+			continue
+		}
+		name := m.Author.GlobalName
+		if name == "" {
+			name = m.Author.Username
+		}
+		messages = append(messages, bridgeMessage{From: name, Content: m.Content})
+	}
+	b.primed = true
+	return messages, nil
+}
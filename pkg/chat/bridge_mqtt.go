@@ -0,0 +1,317 @@
+package chat
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MQTT 3.1.1 control packet types
+const (
+	mqttConnect    = 1
+	mqttConnack    = 2
+	mqttPublish    = 3
+	mqttSubscribe  = 8
+	mqttSuback     = 9
+	mqttPingreq    = 12
+	mqttPingresp   = 13
+	mqttDisconnect = 14
+)
+
+const (
+	mqttKeepAlive  = 60 * time.Second
+	mqttMaxBackoff = time.Minute
+	mqttMaxPacket  = 256 << 10
+)
+
+// mqttBridge subscribes to a topic filter and turns each message into a
+// system message in the room; chat messages from the room are published
+// to PublishTopic when it is set. It speaks just enough MQTT 3.1.1 at
+// QoS 0 for that, so no client library is needed.
+type mqttBridge struct {
+	config   BridgeConfig
+	clientID string
+	log      *slog.Logger
+
+	mutex sync.Mutex // Guards conn and writes to it
+	conn  net.Conn
+}
+
+func newMQTTBridge(config BridgeConfig, logger *slog.Logger) *mqttBridge {
+	id := make([]byte, 4)
+	rand.Read(id)
+	return &mqttBridge{config: config, clientID: "netcat-" + hex.EncodeToString(id), log: logger}
+}
+
+// mqttPayload is the JSON published for each chat message
+type mqttPayload struct {
+	Room    string    `json:"room"`
+	From    string    `json:"from"`
+	Content string    `json:"content"`
+	Time    time.Time `json:"time"`
+}
+
+func (b *mqttBridge) Send(from, content string) error {
+	if b.config.PublishTopic == "" {
+		return nil
+	}
+	payload, _ := json.Marshal(mqttPayload{Room: b.config.Room, From: from, Content: content, Time: time.Now()})
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.conn == nil {
+		return errors.New("not connected to broker")
+	}
+	return writeMQTTPacket(b.conn, mqttPublish<<4, mqttString(b.config.PublishTopic), payload)
+}
+
+// Poll is unused; incoming messages are pushed through Subscribe
+func (b *mqttBridge) Poll() ([]bridgeMessage, error) {
+	return nil, nil
+}
+
+// Subscribe keeps a broker connection open, reconnecting with backoff,
+// and passes every received message to deliver until ctx is cancelled
+func (b *mqttBridge) Subscribe(ctx context.Context, deliver func(bridgeMessage)) {
+	backoff := defaultBridgeBackoff
+	for {
+		started := time.Now()
+		err := b.session(ctx, deliver)
+		if ctx.Err() != nil {
+			return
+		}
+		b.log.Warn("Bridge disconnected", "room", b.config.Room, "kind", "mqtt", "err", err)
+		if time.Since(started) > mqttKeepAlive {
+			backoff = defaultBridgeBackoff
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, mqttMaxBackoff)
+	}
+}
+
+// dialBroker connects to tcp://, mqtt://, ssl://, tls:// or mqtts:// URLs
+// and to plain host:port addresses
+func dialBroker(broker string) (net.Conn, error) {
+	scheme, addr, found := strings.Cut(broker, "://")
+	if !found {
+		scheme, addr = "tcp", broker
+	}
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	switch scheme {
+	case "tcp", "mqtt":
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			addr = net.JoinHostPort(addr, "1883")
+		}
+		return dialer.Dial("tcp", addr)
+	case "ssl", "tls", "mqtts":
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			addr = net.JoinHostPort(addr, "8883")
+		}
+		return tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{MinVersion: tls.VersionTLS12})
+	default:
+		return nil, fmt.Errorf("unsupported broker scheme %q", scheme)
+	}
+}
+
+// session runs one broker connection until it fails or ctx is cancelled
+func (b *mqttBridge) session(ctx context.Context, deliver func(bridgeMessage)) error {
+	conn, err := dialBroker(b.config.Broker)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	stopClose := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stopClose()
+	reader := bufio.NewReader(conn)
+
+	// CONNECT with a clean session
+	flags := byte(0x02)
+	payload := mqttString(b.clientID)
+	if b.config.Username != "" {
+		flags |= 0x80
+		payload = append(payload, mqttString(b.config.Username)...)
+		if b.config.Password != "" {
+			flags |= 0x40
+			payload = append(payload, mqttString(b.config.Password)...)
+		}
+	}
+	header := append(mqttString("MQTT"), 4, flags)
+	header = binary.BigEndian.AppendUint16(header, uint16(mqttKeepAlive/time.Second))
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	if err := writeMQTTPacket(conn, mqttConnect<<4, header, payload); err != nil {
+		return err
+	}
+	packetType, body, err := readMQTTPacket(reader)
+	if err != nil {
+		return err
+	}
+	if packetType>>4 != mqttConnack || len(body) < 2 {
+		return fmt.Errorf("expected CONNACK, got packet type %d", packetType>>4)
+	}
+	if body[1] != 0 {
+		return fmt.Errorf("broker refused connection (code %d)", body[1])
+	}
+
+	if b.config.Topic != "" {
+		subscribe := binary.BigEndian.AppendUint16(nil, 1) // Packet identifier
+		subscribe = append(append(subscribe, mqttString(b.config.Topic)...), 0)
+		if err := writeMQTTPacket(conn, mqttSubscribe<<4|0x02, subscribe); err != nil {
+			return err
+		}
+	}
+	conn.SetDeadline(time.Time{})
+
+	b.mutex.Lock()
+	b.conn = conn
+	b.mutex.Unlock()
+	defer func() {
+		b.mutex.Lock()
+		b.conn = nil
+		b.mutex.Unlock()
+	}()
+
+	// Ping at half the keep-alive; a broker that stops answering trips the
+	// read deadline below
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		ticker := time.NewTicker(mqttKeepAlive / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				b.mutex.Lock()
+				writeMQTTPacket(conn, mqttPingreq<<4)
+				b.mutex.Unlock()
+			}
+		}
+	}()
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(mqttKeepAlive))
+		packetType, body, err := readMQTTPacket(reader)
+		if err != nil {
+			return err
+		}
+		switch packetType >> 4 {
+		case mqttPublish:
+			topic, payload, err := parseMQTTPublish(packetType, body)
+			if err != nil {
+				return err
+			}
+			// Our own chat messages echoed back by a matching filter
+			if topic == b.config.PublishTopic {
+				continue
+			}
+			deliver(bridgeMessage{From: topic, Content: string(payload), System: true})
+		case mqttSuback:
+			if len(body) >= 3 && body[2] == 0x80 {
+				return fmt.Errorf("broker refused subscription to %s", b.config.Topic)
+			}
+			b.log.Info("Bridge subscribed", "room", b.config.Room, "kind", "mqtt", "topic", b.config.Topic)
+		case mqttPingresp:
+		case mqttDisconnect:
+			return errors.New("broker closed the session")
+		}
+	}
+}
+
+// parseMQTTPublish extracts the topic and payload of a PUBLISH packet
+func parseMQTTPublish(packetType byte, body []byte) (string, []byte, error) {
+	if len(body) < 2 {
+		return "", nil, errors.New("short PUBLISH packet")
+	}
+	n := int(binary.BigEndian.Uint16(body))
+	if len(body) < 2+n {
+		return "", nil, errors.New("short PUBLISH packet")
+	}
+	topic, rest := string(body[2:2+n]), body[2+n:]
+	if qos := packetType >> 1 & 0x03; qos > 0 {
+		// We subscribe at QoS 0, but skip the packet identifier if a broker
+		// sends a higher QoS anyway
+		if len(rest) < 2 {
+			return "", nil, errors.New("short PUBLISH packet")
+		}
+		rest = rest[2:]
+	}
+	return topic, rest, nil
+}
+
+// mqttString encodes a length-prefixed UTF-8 string
+func mqttString(s string) []byte {
+	return append(binary.BigEndian.AppendUint16(nil, uint16(len(s))), s...)
+}
+
+// writeMQTTPacket writes a packet with the given first byte and body parts
+func writeMQTTPacket(w io.Writer, first byte, parts ...[]byte) error {
+	length := 0
+	for _, part := range parts {
+		length += len(part)
+	}
+	packet := []byte{first}
+	for {
+		digit := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			digit |= 0x80
+		}
+		packet = append(packet, digit)
+		if length == 0 {
+			break
+		}
+	}
+	for _, part := range parts {
+		packet = append(packet, part...)
+	}
+	_, err := w.Write(packet)
+	return err
+}
+
+// readMQTTPacket reads one packet and returns its first byte and body
+func readMQTTPacket(r *bufio.Reader) (byte, []byte, error) {
+	first, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		digit, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length += int(digit&0x7F) * multiplier
+		if digit&0x80 == 0 {
+			break
+		}
+		if i == 3 {
+			return 0, nil, errors.New("malformed MQTT remaining length")
+		}
+		multiplier *= 128
+	}
+	if length > mqttMaxPacket {
+		return 0, nil, fmt.Errorf("MQTT packet of %d bytes exceeds limit", length)
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return first, body, nil
+}
@@ -0,0 +1,149 @@
+package chat
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const slackAPI = "https://slack.com/api"
+
+// slackBridge posts through an incoming webhook (or chat.postMessage when
+// only a bot token is configured) and reads via conversations.history
+type slackBridge struct {
+	config  BridgeConfig
+	apiBase string
+	client  *http.Client
+	oldest  string            // Timestamp of the newest message seen
+	users   map[string]string // Slack user ID -> display name
+}
+
+func newSlackBridge(config BridgeConfig) *slackBridge {
+	return &slackBridge{
+		config:  config,
+		apiBase: slackAPI,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		users:   make(map[string]string),
+	}
+}
+
+type slackResponse struct {
+	OK       bool   `json:"ok"`
+	Error    string `json:"error"`
+	Messages []struct {
+		User    string `json:"user"`
+		Text    string `json:"text"`
+		TS      string `json:"ts"`
+		BotID   string `json:"bot_id"`
+		Subtype string `json:"subtype"`
+	} `json:"messages"`
+	User struct {
+		Name    string `json:"name"`
+		Profile struct {
+			DisplayName string `json:"display_name"`
+		} `json:"profile"`
+	} `json:"user"`
+}
+
+func (b *slackBridge) Send(from, content string) error {
+	target := b.config.WebhookURL
+	payload := map[string]string{"text": content, "username": from}
+	if target == "" {
+		target = b.apiBase + "/chat.postMessage"
+		payload["channel"] = b.config.Channel
+	}
+	body, _ := json.Marshal(payload)
+
+	resp, err := doBridgeRequest(b.client, func() (*http.Request, error) {
+		req, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+		if b.config.WebhookURL == "" {
+			req.Header.Set("Authorization", "Bearer "+b.config.Token)
+		}
+		return req, nil
+	})
+	if err != nil || b.config.WebhookURL != "" {
+		// Webhooks answer with a plain "ok" body
+		return err
+	}
+	_, err = parseSlackResponse(resp)
+	return err
+}
+
+func (b *slackBridge) Poll() ([]bridgeMessage, error) {
+	if b.oldest == "" {
+		b.oldest = strconv.FormatInt(time.Now().Unix(), 10) + ".000000"
+		return nil, nil
+	}
+
+	query := url.Values{"channel": {b.config.Channel}, "oldest": {b.oldest}, "limit": {"100"}}
+	result, err := b.call("conversations.history", query)
+	if err != nil {
+		return nil, err
+	}
+
+	// Slack returns newest first
+	history := result.Messages
+	sort.Slice(history, func(i, j int) bool { return history[i].TS < history[j].TS })
+
+	var messages []bridgeMessage
+	for _, m := range history {
+		b.oldest = m.TS
+		// Skip bot posts (including our own relays) and join/leave events
+		if m.BotID != "" || m.Subtype != "" || m.Text == "" {
+			continue
+		}
+		messages = append(messages, bridgeMessage{From: b.userName(m.User), Content: m.Text})
+	}
+	return messages, nil
+}
+
+func (b *slackBridge) userName(id string) string {
+	if name, ok := b.users[id]; ok {
+		return name
+	}
+	result, err := b.call("users.info", url.Values{"user": {id}})
+	if err != nil {
+		return id
+	}
+	name := result.User.Profile.DisplayName
+	if name == "" {
+		name = result.User.Name
+	}
+	b.users[id] = name
+	return name
+}
+
+func (b *slackBridge) call(method string, query url.Values) (*slackResponse, error) {
+	body, err := doBridgeRequest(b.client, func() (*http.Request, error) {
+		req, err := http.NewRequest(http.MethodGet, b.apiBase+"/"+method+"?"+query.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+b.config.Token)
+		return req, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parseSlackResponse(body)
+}
+
+func parseSlackResponse(body []byte) (*slackResponse, error) {
+	var result slackResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+	if !result.OK {
+		return nil, fmt.Errorf("slack error: %s", result.Error)
+	}
+	return &result, nil
+}
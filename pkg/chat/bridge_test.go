@@ -0,0 +1,217 @@
+package chat
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestBridgeRateLimitRetry(t *testing.T) {
+	attempts := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.Header().Set("Retry-After", "0.01")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprint(w, "ok")
+	}))
+	defer ts.Close()
+
+	b := newDiscordBridge(BridgeConfig{Kind: "discord", WebhookURL: ts.URL})
+	if err := b.Send("alice", "hello"); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("expected a retry after the rate limit, got %d attempts", attempts)
+	}
+}
+
+func TestDiscordBridgePoll(t *testing.T) {
+	polls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		polls++
+		if polls == 1 {
+			fmt.Fprint(w, `[{"id":"100","content":"old","author":{"username":"bob"}}]`)
+			return
+		}
+		if got := r.URL.Query().Get("after"); got != "100" {
+			t.Errorf("expected after=100, got %q", got)
+		}
+		fmt.Fprint(w, `[
+			{"id":"102","content":"relayed","webhook_id":"1","author":{"username":"hook"}},
+			{"id":"101","content":"hi there","author":{"username":"bob","global_name":"Bobby"}}
+		]`)
+	}))
+	defer ts.Close()
+
+	b := newDiscordBridge(BridgeConfig{Kind: "discord", Token: "t", Channel: "42"})
+	b.apiBase = ts.URL
+
+	if messages, err := b.Poll(); err != nil || len(messages) != 0 {
+		t.Fatalf("priming poll returned %v, %v", messages, err)
+	}
+	messages, err := b.Poll()
+	if err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	if len(messages) != 1 || messages[0].From != "Bobby" || messages[0].Content != "hi there" {
+		t.Errorf("unexpected messages: %+v", messages)
+	}
+	if b.after != "102" {
+		t.Errorf("expected cursor 102, got %s", b.after)
+	}
+}
+
+func TestMQTTBridge(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer listener.Close()
+
+	published := make(chan []byte, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		for {
+			first, body, err := readMQTTPacket(reader)
+			if err != nil {
+				return
+			}
+			switch first >> 4 {
+			case mqttConnect:
+				writeMQTTPacket(conn, mqttConnack<<4, []byte{0, 0})
+			case mqttSubscribe:
+				writeMQTTPacket(conn, mqttSuback<<4, body[:2], []byte{0})
+				writeMQTTPacket(conn, mqttPublish<<4, mqttString("chat/out"), []byte("echo"))
+				writeMQTTPacket(conn, mqttPublish<<4, mqttString("ops/deploy"), []byte("v1.2 rolled out"))
+			case mqttPublish:
+				topic, payload, _ := parseMQTTPublish(first, body)
+				if topic == "chat/out" {
+					published <- payload
+				}
+			}
+		}
+	}()
+
+	b := newMQTTBridge(BridgeConfig{
+		Room: "ops", Kind: "mqtt", Broker: "tcp://" + listener.Addr().String(),
+		Topic: "#", PublishTopic: "chat/out",
+	}, slog.Default())
+	delivered := make(chan bridgeMessage, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		b.Subscribe(ctx, func(m bridgeMessage) { delivered <- m })
+	}()
+
+	select {
+	case m := <-delivered:
+		if !m.System || m.From != "ops/deploy" || m.Content != "v1.2 rolled out" {
+			t.Errorf("unexpected message %+v", m)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no message delivered from broker")
+	}
+
+	if err := b.Send("alice", "ack"); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	select {
+	case payload := <-published:
+		var p mqttPayload
+		if err := json.Unmarshal(payload, &p); err != nil || p.Room != "ops" || p.From != "alice" || p.Content != "ack" {
+			t.Errorf("unexpected publish %s (%v)", payload, err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("chat message not published")
+	}
+
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Subscribe did not return after cancel")
+	}
+}
+
+func TestInjectBridgeMessage(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountsFile = ""
+	config.RoomsFile = ""
+	config.Store = StoreMemory
+	s := NewServerWithConfig(config)
+	defer s.Logfile.Close()
+	bus := &recordingBus{}
+	s.cluster = bus
+
+	// Escape sequences from the remote side are stripped
+	general := &roomBridge{config: BridgeConfig{Room: s.lobby(), Kind: "slack"}, log: s.log}
+	s.injectBridgeMessage(general, bridgeMessage{From: "bob\x1b[2J", Content: "hi \x1b]0;pwned\x07there"})
+	history, _ := s.store.RecentMessages(s.lobby(), 0)
+	if len(history) != 1 || history[0].From != "bob@slack" || history[0].Content != "hi there" {
+		t.Errorf("bridged message not sanitized: %+v", history)
+	}
+	if len(bus.events) != 1 {
+		t.Errorf("bridged message not shared with the cluster: %+v", bus.events)
+	}
+
+	// So are those in MQTT payloads, shown as system messages
+	mqtt := &roomBridge{config: BridgeConfig{Room: s.lobby(), Kind: "mqtt"}, log: s.log}
+	s.injectBridgeMessage(mqtt, bridgeMessage{From: "ops/\x1b[31mdeploy", Content: "\x1b[1Adone\x07", System: true})
+	history, _ = s.store.RecentMessages(s.lobby(), 0)
+	if last := history[len(history)-1]; last.Content != "[ops/deploy] done" {
+		t.Errorf("MQTT message not sanitized: %q", last.Content)
+	}
+
+	// Rooms are not created for bridges
+	missing := &roomBridge{config: BridgeConfig{Room: "nowhere", Kind: "slack"}, log: s.log}
+	s.injectBridgeMessage(missing, bridgeMessage{From: "bob", Content: "hello?"})
+	if _, exists := s.rooms["nowhere"]; exists {
+		t.Error("bridge created its room")
+	}
+
+	// Ephemeral rooms stay off the cluster
+	alice := newPipeClient(t, "Alice")
+	if err := s.createRoom(alice, "secret", "", ""); err != nil {
+		t.Fatalf("createRoom failed: %v", err)
+	}
+	if err := ephemeralCommand(s, alice, []string{"on"}); err != nil {
+		t.Fatalf("ephemeral failed: %v", err)
+	}
+	bus.events = bus.events[:0]
+	secret := &roomBridge{config: BridgeConfig{Room: "secret", Kind: "discord"}, log: s.log}
+	s.injectBridgeMessage(secret, bridgeMessage{From: "bob", Content: "off the record"})
+	for _, ev := range bus.events {
+		if ev.Message.Content == "off the record" {
+			t.Errorf("bridged message of an ephemeral room published to the cluster: %+v", ev)
+		}
+	}
+
+	// Archived rooms take no bridged messages
+	if err := ephemeralCommand(s, alice, []string{"off"}); err != nil {
+		t.Fatalf("ephemeral failed: %v", err)
+	}
+	s.rooms["secret"].archived = true
+	s.injectBridgeMessage(secret, bridgeMessage{From: "bob", Content: "too late"})
+	history, _ = s.store.RecentMessages("secret", 0)
+	for _, msg := range history {
+		if msg.Content == "too late" {
+			t.Error("bridged message posted to an archived room")
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package chat
+
+import "sync"
+
+// Buffers up to maxPooledBuffer bytes are returned to bufferPool; larger
+// ones, grown by an unusually big batch, are left to the garbage collector
+const (
+	pooledBufferSize = 512
+	maxPooledBuffer  = 2 * maxBatchSize
+)
+
+// bufferPool holds the byte slices client writers format their batches in
+var bufferPool = sync.Pool{
+	New: func() any {
+		b := make([]byte, 0, pooledBufferSize)
+		return &b
+	},
+}
+
+// getBuffer returns an empty buffer from the pool
+func getBuffer() *[]byte {
+	return bufferPool.Get().(*[]byte)
+}
+
+// putBuffer returns buf to the pool holding b, which was grown from it.
+// The connection must be done with b: net.Conn writes never keep it.
+func putBuffer(buf *[]byte, b []byte) {
+	if cap(b) > maxPooledBuffer {
+		return
+	}
+	*buf = b[:0]
+	bufferPool.Put(buf)
+}
+
+// sharedText formats a broadcast once for all of its plain text
+// recipients instead of once per recipient. The text is read-only.
+type sharedText struct {
+	plain func() []byte
+	color func() []byte
+}
+
+func newSharedText(msg Message) *sharedText {
+	return &sharedText{
+		plain: sync.OnceValue(func() []byte { return append(appendMessage(nil, msg), '\n') }),
+		color: sync.OnceValue(func() []byte { return append(appendColorMessage(nil, msg), '\n') }),
+	}
+}
+
+// get returns the text with or without colors, formatting it on first use
+func (t *sharedText) get(color bool) []byte {
+	if color {
+		return t.color()
+	}
+	return t.plain()
+}
@@ -0,0 +1,77 @@
+package chat
+
+import (
+	"bufio"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAppendMessage(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	ts := "[2024-05-01 12:30:00]"
+	cases := []struct {
+		msg  Message
+		want string
+	}{
+		{Message{Type: MessageTypeChat, From: "Alice", Content: "hi", Timestamp: now}, ts + "[Alice]: hi"},
+		{Message{Type: MessageTypeChat, ID: 7, From: "Alice", Content: "hi", Timestamp: now}, ts + "[#7][Alice]: hi"},
+		{Message{Type: MessageTypePrivate, From: "Bob", Content: "psst", Timestamp: now}, ts + "[PM from Bob]: psst"},
+		{Message{Type: MessageTypePrivate, ID: 3, From: "Bob", Content: "psst", Timestamp: now}, ts + "[PM #3 from Bob]: psst"},
+		{Message{Type: MessageTypeSystem, Content: "Bob joined", Timestamp: now}, ts + " Bob joined"},
+		{Message{Type: MessageTypeError, Content: "nope", Timestamp: now}, ts + "[ERROR] nope"},
+		{Message{Type: MessageTypeMention, From: "Bob", To: "general", Content: "hey", Timestamp: now}, "\a" + ts + "[Bob in general mentioned you]: hey"},
+		{Message{Type: MessageTypeMention, ID: 9, From: "Bob", To: "general", Content: "hey", Timestamp: now}, "\a" + ts + "[#9][Bob in general mentioned you]: hey"},
+		{Message{Type: MessageTypeKeyword, ID: 4, From: "Bob", To: "dev", Content: "deploy", Timestamp: now}, "\a" + ts + "[#4][Bob in dev]: deploy"},
+		{Message{Type: MessageTypeReaction, From: "Bob", Content: "+1", Ref: 12, Timestamp: now}, ts + " Bob reacted +1 to #12"},
+		{Message{Type: MessageTypeReceipt, From: "Bob", Content: "read", Ref: 5, Timestamp: now}, ts + " PM #5 to Bob: read"},
+	}
+	for _, tc := range cases {
+		if got := string(appendMessage([]byte("> "), tc.msg)); got != "> "+tc.want {
+			t.Errorf("appendMessage = %q, want %q", got, "> "+tc.want)
+		}
+	}
+
+	// Formatting into a buffer with room to spare does not allocate
+	msg := cases[1].msg
+	buf := make([]byte, 0, 256)
+	if allocs := testing.AllocsPerRun(100, func() { appendMessage(buf, msg) }); allocs != 0 {
+		t.Errorf("appendMessage made %v allocations, want 0", allocs)
+	}
+}
+
+func TestSharedText(t *testing.T) {
+	msg := Message{Type: MessageTypeChat, From: "Alice", Content: "hello", Timestamp: time.Now()}
+	text := newSharedText(msg)
+	if got, want := string(text.get(false)), formatMessage(msg)+"\n"; got != want {
+		t.Errorf("plain text = %q, want %q", got, want)
+	}
+	if got, want := string(text.get(true)), colorMessage(msg)+"\n"; got != want {
+		t.Errorf("color text = %q, want %q", got, want)
+	}
+	if &text.get(false)[0] != &text.get(false)[0] {
+		t.Error("shared text formatted more than once")
+	}
+
+	// Each recipient gets the variant it asked for
+	for _, color := range []bool{false, true} {
+		server, client := net.Pipe()
+		c := &Client{conn: server, name: "Bob", color: color}
+		c.startWriter(0, slog.Default())
+		c.sendShared(msg, text)
+		line, err := bufio.NewReader(client).ReadString('\n')
+		if err != nil {
+			t.Fatalf("read failed: %v", err)
+		}
+		if want := string(text.get(color)); line != want {
+			t.Errorf("color=%v: got %q, want %q", color, line, want)
+		}
+		if color != strings.Contains(line, ansiReset) {
+			t.Errorf("color=%v: got %q", color, line)
+		}
+		go c.closeConn()
+		client.Close()
+	}
+}
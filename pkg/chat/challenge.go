@@ -0,0 +1,39 @@
+package chat
+
+import (
+	"bufio"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// joinChallenge asks a fresh connection to solve a small sum before the
+// name prompt, which slows down scripted connection floods. It reports
+// whether the client answered correctly in time.
+func (s *Server) joinChallenge(conn net.Conn, reader *bufio.Reader) bool {
+	a, b := rand.IntN(9)+1, rand.IntN(9)+1
+	if _, err := conn.Write([]byte(fmt.Sprintf("Anti-bot check: what is %d + %d? ", a, b))); err != nil {
+		return false
+	}
+
+	conn.SetReadDeadline(time.Now().Add(s.config.ChallengeTimeout))
+	answer, err := reader.ReadString('\n')
+	conn.SetReadDeadline(time.Time{})
+	if err != nil {
+		if ne, ok := err.(net.Error); ok && ne.Timeout() {
+			conn.Write([]byte("\nToo slow. Goodbye.\n"))
+			s.log.Warn("Join challenge timed out", "remote", remoteIP(conn))
+		}
+		return false
+	}
+
+	if n, err := strconv.Atoi(strings.TrimSpace(answer)); err != nil || n != a+b {
+		conn.Write([]byte("Wrong answer. Goodbye.\n"))
+		s.log.Warn("Join challenge failed", "remote", remoteIP(conn))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,63 @@
+package chat
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// solveChallenge reads the sum prompt and returns its answer
+func (c *TestClient) solveChallenge(t *testing.T) int {
+	c.conn.SetReadDeadline(time.Now().Add(messageTimeout))
+	prompt, err := c.reader.ReadString('?')
+	if err != nil {
+		t.Fatalf("no challenge received: %v", err)
+	}
+	var a, b int
+	if _, err := fmt.Sscanf(prompt, "Anti-bot check: what is %d + %d?", &a, &b); err != nil {
+		t.Fatalf("unexpected challenge %q: %v", prompt, err)
+	}
+	return a + b
+}
+
+func TestJoinChallenge(t *testing.T) {
+	config := DefaultConfig()
+	config.JoinChallenge = true
+	config.ChallengeTimeout = 300 * time.Millisecond
+	addr := setupTestServerWithConfig(t, config)
+
+	connect := func() *TestClient {
+		c, err := newTestClient(t, addr)
+		if err != nil {
+			t.Fatalf("Client connection failed: %v", err)
+		}
+		return c
+	}
+
+	t.Run("Correct", func(t *testing.T) {
+		client := connect()
+		defer client.close()
+		client.sendMessage(fmt.Sprint(client.solveChallenge(t)))
+		if err := client.expectMessage(t, "Welcome"); err != nil {
+			t.Fatalf("Correct answer not accepted: %v", err)
+		}
+	})
+
+	t.Run("Wrong", func(t *testing.T) {
+		client := connect()
+		defer client.close()
+		client.sendMessage(fmt.Sprint(client.solveChallenge(t) + 1))
+		if err := client.expectMessage(t, "Wrong answer"); err != nil {
+			t.Fatalf("Wrong answer not rejected: %v", err)
+		}
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		client := connect()
+		defer client.close()
+		client.solveChallenge(t)
+		if err := client.expectMessage(t, "Too slow"); err != nil {
+			t.Fatalf("Slow answer not rejected: %v", err)
+		}
+	})
+}
@@ -0,0 +1,168 @@
+package chat
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// namePrompt ends the server's welcome
+const namePrompt = "[ENTER YOUR NAME]:"
+
+// loginTimeout bounds how long a client waits for the server's welcome
+const loginTimeout = 10 * time.Second
+
+// ClientConfig describes a client mode session
+type ClientConfig struct {
+	Addr              string // Server to connect to, host:port
+	Proxy             string // socks5:// proxy to connect through, if any
+	Transcript        string // File to append the session to, if any
+	TranscriptMaxSize int64  // Size at which the transcript is rotated
+	TranscriptKeep    int    // Rotated transcripts to keep
+}
+
+// DefaultClientConfig returns the settings of ./TCPChat -connect addr
+// without other flags
+func DefaultClientConfig(addr string) ClientConfig {
+	return ClientConfig{
+		Addr:              addr,
+		TranscriptMaxSize: DefaultTranscriptMaxSize,
+		TranscriptKeep:    DefaultTranscriptKeep,
+	}
+}
+
+// RunClient connects to a chat server and relays the terminal to it,
+// with line editing and history when stdin is a terminal. A dropped
+// connection is picked up again where it left off.
+func RunClient(cfg ClientConfig) error {
+	fd := int(os.Stdin.Fd())
+	restore, rawErr := makeRaw(fd)
+	var editor *lineEditor
+	show := func(text string) { fmt.Println(text) }
+	if rawErr == nil {
+		defer restore()
+		editor = newLineEditor(os.Stdin, os.Stdout, "> ", func() int { return terminalWidth(fd) })
+		show = editor.print
+	}
+
+	link, err := newClientLink(cfg, show, func() {})
+	if err != nil {
+		return err
+	}
+	welcome, reader, err := link.connect()
+	if err != nil {
+		link.close()
+		return fmt.Errorf("failed to connect to %s: %v", cfg.Addr, err)
+	}
+	defer link.close()
+	show(strings.TrimRight(welcome, "\n"))
+	link.transcript.record(transcriptReceived, welcome)
+
+	if editor != nil {
+		go editLines(link, editor)
+	} else {
+		go scanLines(link)
+	}
+
+	link.run(reader)
+	if editor != nil {
+		editor.close()
+	}
+	fmt.Println("Connection closed")
+	return nil
+}
+
+// editLines sends what the user types in the line editor until Ctrl-C or
+// Ctrl-D, which hang up
+func editLines(link *clientLink, editor *lineEditor) {
+	for {
+		line, err := editor.readLine()
+		if err != nil {
+			link.close()
+			return
+		}
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		// Lines typed while logging in may be passwords
+		if cs, _ := link.state(); cs.loggedIn {
+			editor.addHistory(line)
+		}
+		if err := link.sendLine(line); err != nil {
+			editor.print(fmt.Sprintf("Send failed: %v", err))
+		}
+	}
+}
+
+// scanLines sends the lines of stdin until it ends, then waits for the
+// server to answer the last of them
+func scanLines(link *clientLink) {
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			if err := link.sendLine(line); err != nil {
+				fmt.Printf("Send failed: %v\n", err)
+			}
+		}
+	}
+	link.closeWrite()
+}
+
+func dialServer(addr, proxy string) (net.Conn, error) {
+	if proxy == "" {
+		return net.DialTimeout("tcp", addr, 10*time.Second)
+	}
+	u, err := parseProxyURL(proxy)
+	if err != nil {
+		return nil, err
+	}
+	// Tor can take a while to build a circuit
+	return dialSOCKS5(u, addr, 30*time.Second)
+}
+
+// dialJSON connects to addr like dialServer and switches the connection to
+// the JSON protocol, returning the welcome the server sent before that
+func dialJSON(addr, proxy string) (net.Conn, *bufio.Reader, string, error) {
+	conn, err := dialServer(addr, proxy)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	conn.SetDeadline(time.Now().Add(loginTimeout))
+	reader := bufio.NewReader(conn)
+	welcome, err := readUntil(reader, namePrompt)
+	if err == nil {
+		_, err = conn.Write([]byte(protoJSON + "\n"))
+	}
+	if err != nil {
+		conn.Close()
+		return nil, nil, "", err
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, reader, strings.TrimSuffix(welcome, namePrompt), nil
+}
+
+// readUntil reads from r up to and including marker and returns what it
+// read. If the server hangs up first, what it said is the error.
+func readUntil(r *bufio.Reader, marker string) (string, error) {
+	var seen []byte
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			if text := strings.TrimSpace(string(seen)); text != "" {
+				return "", fmt.Errorf("%s", text)
+			}
+			return "", err
+		}
+		seen = append(seen, b)
+		if bytes.HasSuffix(seen, []byte(marker)) {
+			return string(seen), nil
+		}
+		if len(seen) > 64*1024 {
+			return "", fmt.Errorf("%q not found", marker)
+		}
+	}
+}
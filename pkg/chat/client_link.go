@@ -0,0 +1,256 @@
+package chat
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// Client mode waits reconnectMinDelay before its first reconnect attempt
+// and doubles the wait after every failure, up to reconnectMaxDelay
+const (
+	reconnectMinDelay = time.Second
+	reconnectMaxDelay = 30 * time.Second
+)
+
+// rosterDelay coalesces the roster requests of a burst of joins and leaves
+const rosterDelay = 200 * time.Millisecond
+
+// clientLink is client mode's JSON protocol connection to the server. It
+// keeps the session up to date and, when the connection drops, reconnects
+// with exponential backoff and resumes the session: same name, same room
+// and a replay of the messages missed in between.
+type clientLink struct {
+	addr  string
+	proxy string
+
+	show       func(text string) // Shows a line to the user
+	changed    func()            // Called when the session or status may have changed
+	transcript *transcript       // Nil unless the session is logged to a file
+
+	writeMutex sync.Mutex // Serializes requests to the server
+
+	mutex         sync.Mutex // Guards the fields below
+	conn          net.Conn
+	session       clientSession
+	status        string
+	rosterPending bool
+	closed        bool
+	done          chan struct{} // Closed by close
+}
+
+func newClientLink(cfg ClientConfig, show func(string), changed func()) (*clientLink, error) {
+	l := &clientLink{addr: cfg.Addr, proxy: cfg.Proxy, show: show, changed: changed, done: make(chan struct{})}
+	if cfg.Transcript != "" {
+		t, err := openTranscript(cfg.Transcript, cfg.TranscriptMaxSize, cfg.TranscriptKeep)
+		if err != nil {
+			return nil, err
+		}
+		l.transcript = t
+	}
+	return l, nil
+}
+
+// connect dials the server and returns the welcome it sent and the reader
+// for its events
+func (l *clientLink) connect() (string, *bufio.Reader, error) {
+	conn, reader, welcome, err := dialJSON(l.addr, l.proxy)
+	if err != nil {
+		return "", nil, err
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.closed {
+		conn.Close()
+		return "", nil, fmt.Errorf("connection closed")
+	}
+	l.conn, l.status = conn, "connected"
+	return welcome, reader, nil
+}
+
+// run applies the server's events, reconnecting whenever the connection
+// drops unless the session ended, and returns when it is over
+func (l *clientLink) run(reader *bufio.Reader) {
+	for reader != nil {
+		l.receive(reader)
+
+		l.mutex.Lock()
+		l.conn.Close()
+		resume := l.session.canResume() && !l.closed
+		l.status = "disconnected"
+		l.mutex.Unlock()
+		if !resume {
+			l.changed()
+			return
+		}
+		reader = l.reconnect()
+	}
+}
+
+// receive applies the events read from one connection until it closes
+func (l *clientLink) receive(reader *bufio.Reader) {
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			return
+		}
+		e, ok := parseEvent(line)
+		if !ok {
+			continue
+		}
+
+		l.mutex.Lock()
+		text, visible := l.session.apply(e)
+		wantsRoster := l.session.wantsRoster(e)
+		rejoin := l.session.rejoined(e)
+		l.mutex.Unlock()
+
+		if visible {
+			l.show(text)
+			l.transcript.record(transcriptReceived, text)
+		}
+		for _, line := range rejoin {
+			l.sendLine(line)
+		}
+		if wantsRoster {
+			l.requestRoster()
+		}
+		l.changed()
+	}
+}
+
+// reconnect dials the server until it answers, waiting longer after every
+// failure, and logs in again. It returns nil if the link is closed first.
+func (l *clientLink) reconnect() *bufio.Reader {
+	l.notice("Connection lost.")
+	delay := reconnectMinDelay
+	for {
+		l.setStatus(fmt.Sprintf("reconnecting in %s", delay))
+		l.notice(fmt.Sprintf("Reconnecting in %s...", delay))
+		select {
+		case <-l.done:
+			return nil
+		case <-time.After(delay):
+		}
+		delay = min(2*delay, reconnectMaxDelay)
+
+		_, reader, err := l.connect()
+		if err != nil {
+			l.notice(fmt.Sprintf("Reconnect failed: %v", err))
+			continue
+		}
+		l.mutex.Lock()
+		lines := l.session.resume()
+		l.mutex.Unlock()
+		for _, line := range lines {
+			if err = l.write(jsonRequest{Content: line}); err != nil {
+				break
+			}
+		}
+		if err != nil {
+			l.mutex.Lock()
+			l.conn.Close()
+			l.mutex.Unlock()
+			l.notice(fmt.Sprintf("Reconnect failed: %v", err))
+			continue
+		}
+		l.notice("Reconnected to " + l.addr)
+		l.changed()
+		return reader
+	}
+}
+
+// notice shows a line of the client's own
+func (l *clientLink) notice(text string) {
+	l.show(text)
+	l.transcript.record(transcriptNotice, text)
+}
+
+// requestRoster asks the server for the room and user lists, once for
+// all requests made within rosterDelay
+func (l *clientLink) requestRoster() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.rosterPending {
+		return
+	}
+	l.rosterPending = true
+	time.AfterFunc(rosterDelay, func() {
+		l.mutex.Lock()
+		l.rosterPending = false
+		l.mutex.Unlock()
+		l.write(jsonRequest{Type: "command", Command: "roster"})
+	})
+}
+
+// sendLine sends a line the user typed: the server reads names,
+// passwords, chat and commands alike from them
+func (l *clientLink) sendLine(line string) error {
+	l.mutex.Lock()
+	loggedIn, first := l.session.loggedIn, len(l.session.login) == 0
+	l.session.sent(line)
+	l.mutex.Unlock()
+	l.transcript.sent(line, loggedIn, first)
+	return l.write(jsonRequest{Content: line})
+}
+
+// write sends one request to the server
+func (l *clientLink) write(req jsonRequest) error {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	l.mutex.Lock()
+	conn := l.conn
+	l.mutex.Unlock()
+	if conn == nil {
+		return fmt.Errorf("not connected")
+	}
+	l.writeMutex.Lock()
+	defer l.writeMutex.Unlock()
+	_, err = conn.Write(append(data, '\n'))
+	return err
+}
+
+// state returns a copy of the session and the connection status
+func (l *clientLink) state() (clientSession, string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.session, l.status
+}
+
+func (l *clientLink) setStatus(status string) {
+	l.mutex.Lock()
+	l.status = status
+	l.mutex.Unlock()
+	l.changed()
+}
+
+// closeWrite tells the server there is nothing more to send and ends the
+// session once it has answered
+func (l *clientLink) closeWrite() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.session.final = true
+	if tcp, ok := l.conn.(*net.TCPConn); ok {
+		tcp.CloseWrite()
+	}
+}
+
+// close hangs up for good
+func (l *clientLink) close() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.closed {
+		return
+	}
+	l.closed = true
+	close(l.done)
+	if l.conn != nil {
+		l.conn.Close()
+	}
+	l.transcript.close()
+}
@@ -0,0 +1,119 @@
+package chat
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// waitShown waits for a line containing want among the lines a clientLink
+// shows
+func waitShown(t *testing.T, shown <-chan string, want string) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case line := <-shown:
+			if strings.Contains(line, want) {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("never shown: %q", want)
+		}
+	}
+}
+
+// waitRoom waits for the roster to put the link's session in room
+func waitRoom(t *testing.T, link *clientLink, room string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		cs, _ := link.state()
+		if cs.room == room && !cs.resuming {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("room is %q, want %q", cs.room, room)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestClientLinkReconnect(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountsFile = ""
+	config.RoomsFile = ""
+	config.Store = StoreMemory
+	s := NewServerWithConfig(config)
+	defer s.Logfile.Close()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	go s.Serve(l)
+	defer s.Shutdown()
+	addr := l.Addr().String()
+
+	shown := make(chan string, 256)
+	link, err := newClientLink(DefaultClientConfig(addr), func(text string) { shown <- text }, func() {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer link.close()
+	_, reader, err := link.connect()
+	if err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		link.run(reader)
+		close(done)
+	}()
+
+	link.sendLine("Alice")
+	waitShown(t, shown, "Alice joined the room")
+	link.sendLine("/create dev")
+	waitShown(t, shown, "Alice joined the room")
+	link.sendLine("before the drop")
+	waitShown(t, shown, "before the drop")
+	waitRoom(t, link, "dev")
+
+	bob, err := newTestClient(t, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bob.close()
+	bob.expectMessage(t, "Welcome")
+	bob.sendMessage("Bob")
+	bob.expectMessage(t, "Bob joined the room")
+	bob.sendMessage("/join dev")
+	bob.expectMessage(t, "Bob joined the room")
+
+	// Drop Alice without a goodbye; Bob talks while she is away
+	for _, c := range s.clients.all() {
+		if c.name == "Alice" {
+			c.conn.Close()
+		}
+	}
+	waitShown(t, shown, "Connection lost")
+	bob.expectMessage(t, "Alice left")
+	bob.sendMessage("while you were away")
+
+	waitShown(t, shown, "Reconnected to "+addr)
+	waitShown(t, shown, "Resuming dev after")
+	waitShown(t, shown, "while you were away")
+
+	waitRoom(t, link, "dev")
+	if cs, status := link.state(); cs.name != "Alice" || status != "connected" {
+		t.Errorf("back as %q, status %q", cs.name, status)
+	}
+
+	// Quitting ends the session for good
+	link.sendLine("/quit")
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("link reconnected after /quit")
+	}
+}
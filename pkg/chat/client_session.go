@@ -0,0 +1,229 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// joinedSuffix ends the notice a room gets when someone joins it, and
+// renamedInfix is in the one it gets when someone changes name
+const (
+	joinedSuffix = " joined the room"
+	renamedInfix = " changed name to "
+)
+
+// farewells start the notices after which the server hangs up on purpose,
+// so client mode does not reconnect
+var farewells = []string{"Goodbye!", "You have been kicked", "You have been banned", "You are banned"}
+
+// messageTypesByName maps the type names of JSON events back to message
+// types
+var messageTypesByName = func() map[string]int {
+	types := make(map[string]int, len(messageTypeNames))
+	for t, name := range messageTypeNames {
+		types[name] = t
+	}
+	return types
+}()
+
+// clientSession is what client mode knows about its connection, built
+// from the server's JSON events and kept apart from the terminal
+type clientSession struct {
+	name     string // Known once logged in
+	loggedIn bool
+	room     string
+	rooms    []string
+	users    []string
+
+	// What it takes to pick up where the connection left off
+	login    []string          // Lines sent since the last name prompt
+	identify string            // Last /identify line sent
+	joins    map[string]string // Last /join line sent per room
+	lastIDs  map[string]int64  // Newest message ID seen per room
+	resuming bool              // Logging in again after a reconnect
+	rejoin   string            // Room to go back to once logged in again
+	final    bool              // The server said goodbye
+}
+
+// apply updates the session with an event from the server and returns the
+// line to show for it, if any
+func (cs *clientSession) apply(e jsonEvent) (string, bool) {
+	if e.ID > 0 && e.Room != "" && e.ID > cs.lastIDs[e.Room] {
+		if cs.lastIDs == nil {
+			cs.lastIDs = make(map[string]int64)
+		}
+		cs.lastIDs[e.Room] = e.ID
+	}
+
+	// Only clients in a room get numbered messages or join notices, so
+	// the first of them, replayed history included, ends the login
+	if !cs.loggedIn && (e.ID > 0 || e.Type == "system" && strings.HasSuffix(e.Content, joinedSuffix)) {
+		cs.loggedIn = true
+		if len(cs.login) > 0 {
+			cs.name = cs.login[0]
+		}
+	}
+
+	switch e.Type {
+	case "proto":
+		return "", false
+	case "roster":
+		cs.room, cs.rooms, cs.users = e.Room, e.Rooms, e.Users
+		return "", false
+	case "system":
+		for _, farewell := range farewells {
+			if strings.HasPrefix(e.Content, farewell) {
+				cs.final = true
+			}
+		}
+		if cs.loggedIn {
+			// Join notices spell the name the way the server does
+			if joined, ok := strings.CutSuffix(e.Content, joinedSuffix); ok && (cs.name == "" || strings.EqualFold(joined, cs.name)) {
+				cs.name = joined
+			}
+			if old, renamed, ok := strings.Cut(e.Content, renamedInfix); ok && old == cs.name {
+				if fields := strings.Fields(renamed); len(fields) > 0 {
+					cs.name = fields[0]
+				}
+			}
+			break
+		}
+
+		if cs.resuming {
+			// The prompts are answered already unless the login
+			// failed, which leaves the rest to the user
+			if !strings.HasPrefix(e.Content, "Invalid name") && !strings.HasPrefix(e.Content, "Authentication failed") {
+				return "", false
+			}
+			cs.resuming, cs.rejoin = false, ""
+		}
+		if isNamePrompt(e.Content) {
+			cs.login = nil
+		}
+		// The login dialogue is plain text, without timestamps
+		return e.Content, true
+	}
+	return eventLine(e), true
+}
+
+// sent records a line the user sent to the server
+func (cs *clientSession) sent(line string) {
+	if !cs.loggedIn {
+		cs.login = append(cs.login, line)
+		return
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return
+	}
+	switch fields[0] {
+	case "/identify":
+		cs.identify = line
+	case "/join":
+		if len(fields) > 1 {
+			if cs.joins == nil {
+				cs.joins = make(map[string]string)
+			}
+			cs.joins[fields[1]] = line
+		}
+	case "/quit":
+		cs.final = true
+	}
+}
+
+// wantsRoster reports whether e may have changed the room or its users,
+// so the roster should be asked for again
+func (cs *clientSession) wantsRoster(e jsonEvent) bool {
+	return cs.loggedIn && e.Type == "system"
+}
+
+// canResume reports whether a dropped connection should be picked up
+// again: the client logged in and nobody meant to end the session
+func (cs *clientSession) canResume() bool {
+	return cs.name != "" && !cs.final
+}
+
+// resume starts logging in again on a new connection and returns the
+// lines to send for it: RESUME lines for the rooms with messages seen, so
+// the server replays only what was missed, then the login
+func (cs *clientSession) resume() []string {
+	rooms := make([]string, 0, len(cs.lastIDs))
+	for room := range cs.lastIDs {
+		rooms = append(rooms, room)
+	}
+	sort.Strings(rooms)
+	var lines []string
+	for _, room := range rooms {
+		lines = append(lines, fmt.Sprintf("%s%s %d", resumePrefix, room, cs.lastIDs[room]))
+	}
+
+	// A password only goes with the name it was typed for
+	if len(cs.login) == 0 || cs.login[0] != cs.name {
+		cs.login = []string{cs.name}
+	}
+	lines = append(lines, cs.login...)
+
+	cs.loggedIn, cs.resuming, cs.rejoin = false, true, cs.room
+	return lines
+}
+
+// rejoined returns the lines that take a client that just logged in again
+// back to its room and identity, once e shows it in the lobby
+func (cs *clientSession) rejoined(e jsonEvent) []string {
+	if !cs.resuming || !cs.loggedIn || e.Type != "roster" {
+		return nil
+	}
+	var lines []string
+	if cs.identify != "" {
+		lines = append(lines, cs.identify)
+	}
+	if cs.rejoin != "" && cs.rejoin != e.Room {
+		join, ok := cs.joins[cs.rejoin]
+		if !ok {
+			join = "/join " + cs.rejoin
+		}
+		lines = append(lines, join)
+	}
+	cs.resuming, cs.rejoin = false, ""
+	return lines
+}
+
+// isNamePrompt reports whether the server is asking for a name
+func isNamePrompt(content string) bool {
+	return strings.HasSuffix(strings.ToLower(strings.TrimRight(content, ":] ")), "name")
+}
+
+// eventLine formats a JSON event the way the server formats messages for
+// text clients, with markup rendered
+func eventLine(e jsonEvent) string {
+	msgType, ok := messageTypesByName[e.Type]
+	if !ok {
+		msgType = MessageTypeSystem
+	}
+	msg := Message{
+		Type:      msgType,
+		From:      e.From,
+		To:        e.To,
+		Content:   e.Content,
+		Timestamp: e.Timestamp,
+		ID:        e.ID,
+		Ref:       e.Ref,
+	}
+	if msgType == MessageTypeMention || msgType == MessageTypeKeyword {
+		msg.To = e.Room
+	}
+	// A terminal UI has no use for the bell
+	return strings.TrimPrefix(renderMessage(msg), "\a")
+}
+
+// parseEvent decodes one line from the server, which is not an event if
+// it was written before the switch to JSON
+func parseEvent(line []byte) (jsonEvent, bool) {
+	var e jsonEvent
+	if json.Unmarshal(line, &e) != nil || e.Type == "" {
+		return jsonEvent{}, false
+	}
+	return e, true
+}
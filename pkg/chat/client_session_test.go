@@ -0,0 +1,151 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClientSession(t *testing.T) {
+	var cs clientSession
+	now := time.Now()
+
+	if _, visible := cs.apply(jsonEvent{Type: "proto", Content: "json"}); visible {
+		t.Error("proto event shown")
+	}
+	if line, visible := cs.apply(jsonEvent{Type: "system", Content: namePrompt, Timestamp: now}); !visible || !strings.Contains(line, namePrompt) {
+		t.Errorf("name prompt shown as %q", line)
+	}
+	login := jsonEvent{Type: "system", Content: "alice joined the room", Timestamp: now}
+	if cs.wantsRoster(login) {
+		t.Error("roster wanted before login")
+	}
+	cs.sent("Alice")
+	// History replayed as the client joins the lobby comes first
+	cs.apply(jsonEvent{Type: "system", Content: "Bob joined the room", Timestamp: now, ID: 1})
+	if !cs.loggedIn || cs.name != "Alice" {
+		t.Errorf("logged in = %v as %q, want Alice", cs.loggedIn, cs.name)
+	}
+	cs.apply(login)
+	if cs.name != "alice" {
+		t.Errorf("name = %q, want the server's spelling", cs.name)
+	}
+	if !cs.wantsRoster(login) {
+		t.Error("roster not wanted after a join")
+	}
+
+	// Later join notices are other people's
+	cs.apply(jsonEvent{Type: "system", Content: "Bob joined the room", Timestamp: now})
+	if cs.name != "alice" {
+		t.Errorf("name changed to %q", cs.name)
+	}
+
+	if _, visible := cs.apply(jsonEvent{Type: "roster", Room: "dev", Rooms: []string{"dev", "general"}, Users: []string{"@Alice", "Bob"}}); visible {
+		t.Error("roster event shown")
+	}
+	if cs.room != "dev" || len(cs.rooms) != 2 || len(cs.users) != 2 {
+		t.Errorf("roster not applied: %+v", cs)
+	}
+	if cs.wantsRoster(jsonEvent{Type: "chat"}) {
+		t.Error("roster wanted after chat")
+	}
+}
+
+func TestEventLine(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	tests := []struct {
+		e    jsonEvent
+		want string
+	}{
+		{jsonEvent{Type: "chat", Room: "dev", From: "Bob", Content: "hi", ID: 4, Timestamp: now}, "[2024-05-01 12:30:00][#4][Bob]: hi"},
+		{jsonEvent{Type: "error", Content: "nope", Timestamp: now}, "[2024-05-01 12:30:00][ERROR] nope"},
+		{jsonEvent{Type: "mention", Room: "dev", From: "Bob", Content: "@Alice hi", Timestamp: now}, "[2024-05-01 12:30:00][Bob in dev mentioned you]: @Alice hi"},
+		{jsonEvent{Type: "something new", Content: "hello", Timestamp: now}, "[2024-05-01 12:30:00] hello"},
+	}
+	for _, tc := range tests {
+		if got := eventLine(tc.e); got != tc.want {
+			t.Errorf("eventLine(%s) = %q, want %q", tc.e.Type, got, tc.want)
+		}
+	}
+}
+
+func TestDialJSON(t *testing.T) {
+	conn, reader, welcome, err := dialJSON(setupTestServer(t), "")
+	if err != nil {
+		t.Fatalf("dialJSON failed: %v", err)
+	}
+	defer conn.Close()
+	if !strings.Contains(welcome, "Welcome") || strings.Contains(welcome, namePrompt) {
+		t.Errorf("welcome = %q", welcome)
+	}
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if e, ok := parseEvent(line); !ok || e.Type != "proto" {
+		t.Errorf("first event = %q, want the protocol switch", line)
+	}
+	if _, ok := parseEvent([]byte("Welcome to TCP-Chat!\n")); ok {
+		t.Error("plain text parsed as an event")
+	}
+}
+
+func TestClientSessionResume(t *testing.T) {
+	var cs clientSession
+	cs.apply(jsonEvent{Type: "system", Content: namePrompt})
+	cs.sent("Taken")
+	cs.apply(jsonEvent{Type: "system", Content: "Invalid name: name already taken"})
+	cs.apply(jsonEvent{Type: "system", Content: "Please enter another name:"})
+	cs.sent("Alice")
+	cs.sent("secret")
+	cs.apply(jsonEvent{Type: "system", Content: "Alice joined the room"})
+	cs.sent("/identify hunter2")
+	cs.sent("/join dev letmein")
+	cs.apply(jsonEvent{Type: "roster", Room: "dev"})
+	cs.apply(jsonEvent{Type: "chat", Room: "dev", From: "Bob", Content: "hi", ID: 7})
+	cs.apply(jsonEvent{Type: "chat", Room: "dev", From: "Bob", Content: "hi", ID: 5})
+	cs.apply(jsonEvent{Type: "mention", Room: "general", From: "Bob", Content: "@Alice", ID: 3})
+	if !cs.canResume() {
+		t.Fatal("logged in session cannot resume")
+	}
+
+	want := []string{"RESUME dev 7", "RESUME general 3", "Alice", "secret"}
+	if got := cs.resume(); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("resume = %q, want %q", got, want)
+	}
+
+	// The login dialogue is answered already
+	if _, visible := cs.apply(jsonEvent{Type: "system", Content: "Resuming dev after #7"}); visible {
+		t.Error("resume reply shown")
+	}
+	if _, visible := cs.apply(jsonEvent{Type: "system", Content: namePrompt}); visible {
+		t.Error("name prompt shown while resuming")
+	}
+	cs.apply(jsonEvent{Type: "system", Content: "Alice joined the room"})
+	want = []string{"/identify hunter2", "/join dev letmein"}
+	if got := cs.rejoined(jsonEvent{Type: "roster", Room: "general"}); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("rejoined = %q, want %q", got, want)
+	}
+	if got := cs.rejoined(jsonEvent{Type: "roster", Room: "general"}); got != nil {
+		t.Errorf("rejoined twice: %q", got)
+	}
+
+	// A new name logs in without the old password
+	cs.apply(jsonEvent{Type: "system", Content: "Alice changed name to Alicia"})
+	if got := cs.resume(); got[len(got)-1] != "Alicia" {
+		t.Errorf("resume logs in as %q, want Alicia", got[len(got)-1])
+	}
+
+	// A failed login hands the prompts back to the user
+	if line, visible := cs.apply(jsonEvent{Type: "system", Content: "Invalid name: name already taken"}); !visible || line != "Invalid name: name already taken" {
+		t.Errorf("login failure shown as %q", line)
+	}
+	if _, visible := cs.apply(jsonEvent{Type: "system", Content: "Please enter another name:"}); !visible {
+		t.Error("prompt hidden after a failed resume")
+	}
+
+	cs.apply(jsonEvent{Type: "system", Content: "You have been kicked by Bob."})
+	if cs.canResume() {
+		t.Error("kicked session resumes")
+	}
+}
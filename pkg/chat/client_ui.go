@@ -0,0 +1,178 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jroimartin/gocui"
+)
+
+// Views of the client TUI
+const (
+	clientMessagesView = "messages"
+	clientRoomsView    = "rooms"
+	clientUsersView    = "users"
+	clientInputView    = "input"
+)
+
+// clientUI is the terminal interface of client mode. Everything it shows
+// comes from its JSON protocol connection to the server.
+type clientUI struct {
+	gui  *gocui.Gui
+	link *clientLink
+}
+
+// RunClientUI connects to a chat server and shows it in a terminal UI
+// with the messages, an input box and the rooms and users of the client's
+// room
+func RunClientUI(cfg ClientConfig) error {
+	ui := &clientUI{}
+	link, err := newClientLink(cfg, ui.show, func() { ui.gui.Update(ui.render) })
+	if err != nil {
+		return err
+	}
+	ui.link = link
+	welcome, reader, err := link.connect()
+	if err != nil {
+		link.close()
+		return fmt.Errorf("failed to connect to %s: %v", cfg.Addr, err)
+	}
+	defer ui.link.close()
+
+	g, err := gocui.NewGui(gocui.OutputNormal)
+	if err != nil {
+		return err
+	}
+	defer g.Close()
+	g.Cursor = true
+	ui.gui = g
+
+	g.SetManagerFunc(ui.layout)
+	if err := ui.keybindings(); err != nil {
+		return err
+	}
+
+	ui.show(strings.TrimRight(welcome, "\n"))
+	link.transcript.record(transcriptReceived, welcome)
+	go func() {
+		ui.link.run(reader)
+		ui.show("Connection closed. Press Ctrl-C to quit.")
+	}()
+
+	if err := g.MainLoop(); err != nil && err != gocui.ErrQuit {
+		return err
+	}
+	return nil
+}
+
+func (ui *clientUI) layout(g *gocui.Gui) error {
+	maxX, maxY := g.Size()
+	sidebarWidth := 24
+	msgWidth := maxX - sidebarWidth - 1
+	msgHeight := maxY - 4
+	roomHeight := msgHeight / 3
+
+	if v, err := g.SetView(clientMessagesView, 0, 0, msgWidth, msgHeight); err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+		v.Wrap = true
+		v.Autoscroll = true
+	}
+	if v, err := g.SetView(clientRoomsView, msgWidth+1, 0, maxX-1, roomHeight); err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+		v.Title = "Rooms"
+	}
+	if v, err := g.SetView(clientUsersView, msgWidth+1, roomHeight+1, maxX-1, msgHeight); err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+		v.Title = "Users"
+	}
+	if v, err := g.SetView(clientInputView, 0, msgHeight+1, maxX-1, maxY-1); err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+		v.Title = "Enter: send | PgUp/PgDn/Home/End: scroll | Ctrl-C: quit"
+		v.Editable = true
+		if _, err := g.SetCurrentView(clientInputView); err != nil {
+			return err
+		}
+	}
+	return ui.render(g)
+}
+
+// render draws the sidebar and the title from the session
+func (ui *clientUI) render(g *gocui.Gui) error {
+	cs, status := ui.link.state()
+
+	if v, err := g.View(clientMessagesView); err == nil {
+		title := ui.link.addr + " | " + status
+		if cs.room != "" {
+			title = "#" + cs.room + " | " + title
+		}
+		if below := linesBelow(v); below > 0 {
+			title += fmt.Sprintf(" | scrolled back %d lines, End for latest", below)
+		}
+		v.Title = title
+	}
+	if v, err := g.View(clientRoomsView); err == nil {
+		v.Clear()
+		for _, room := range cs.rooms {
+			marker := "  "
+			if room == cs.room {
+				marker = "> "
+			}
+			fmt.Fprintln(v, marker+room)
+		}
+	}
+	if v, err := g.View(clientUsersView); err == nil {
+		v.Title = fmt.Sprintf("Users (%d)", len(cs.users))
+		v.Clear()
+		for _, user := range cs.users {
+			fmt.Fprintln(v, user)
+		}
+	}
+	return nil
+}
+
+// show appends text to the messages view
+func (ui *clientUI) show(text string) {
+	ui.gui.Update(func(g *gocui.Gui) error {
+		v, err := g.View(clientMessagesView)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(v, text)
+		return nil
+	})
+}
+
+func (ui *clientUI) keybindings() error {
+	if err := ui.gui.SetKeybinding("", gocui.KeyCtrlC, gocui.ModNone,
+		func(_ *gocui.Gui, _ *gocui.View) error {
+			return gocui.ErrQuit
+		}); err != nil {
+		return err
+	}
+	if err := bindScrollKeys(ui.gui, func() string { return clientMessagesView }, ui.render); err != nil {
+		return err
+	}
+
+	return ui.gui.SetKeybinding(clientInputView, gocui.KeyEnter, gocui.ModNone,
+		func(_ *gocui.Gui, v *gocui.View) error {
+			line := strings.TrimSpace(v.Buffer())
+			v.Clear()
+			v.SetCursor(0, 0)
+			v.SetOrigin(0, 0)
+			if line == "" {
+				return nil
+			}
+			if err := ui.link.sendLine(line); err != nil {
+				ui.show(fmt.Sprintf("Send failed: %v", err))
+			}
+			return nil
+		})
+}
@@ -0,0 +1,240 @@
+package chat
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+)
+
+// clusterPresenceInterval is how often an instance republishes who is
+// connected to it; rosters not refreshed for three intervals are dropped
+const clusterPresenceInterval = 10 * time.Second
+
+// Cluster event types
+const (
+	clusterMessage  = ""         // A message for a room or everyone
+	clusterPresence = "presence" // The origin's full list of clients
+	clusterRoomMode = "room"     // A room was made ephemeral or recording again
+)
+
+// ClusterEvent is the envelope exchanged between server instances
+type ClusterEvent struct {
+	Type     string          `json:"type,omitempty"`
+	Origin   string          `json:"origin"`
+	Room     string          `json:"room,omitempty"` // Empty for server-wide broadcasts
+	Message  Message         `json:"message"`
+	Presence []ClusterMember `json:"presence,omitempty"`
+
+	Ephemeral bool `json:"ephemeral,omitempty"` // Of clusterRoomMode events
+}
+
+// ClusterMember is a client connected to one of the instances
+type ClusterMember struct {
+	Name string `json:"name"`
+	Room string `json:"room"`
+	Away bool   `json:"away,omitempty"`
+}
+
+// clusterRoster is the last presence event of another instance
+type clusterRoster struct {
+	members []ClusterMember
+	expires time.Time
+}
+
+// ClusterBus relays room traffic between server instances sharing a backend
+type ClusterBus interface {
+	Publish(ev ClusterEvent) error
+	Subscribe(handler func(ev ClusterEvent)) error
+	Close() error
+}
+
+// newClusterBus returns the bus selected by the config, or nil when the
+// server runs standalone
+func newClusterBus(cfg *Config, logger *slog.Logger) (ClusterBus, error) {
+	switch cfg.ClusterBackend {
+	case "":
+		return nil, nil
+	case "nats":
+		return newNATSBus(cfg.ClusterURL, cfg.ClusterPrefix, logger)
+	case "redis":
+		return newRedisBus(cfg.ClusterURL, cfg.ClusterPrefix, logger)
+	default:
+		return nil, fmt.Errorf("unknown cluster backend: %s", cfg.ClusterBackend)
+	}
+}
+
+func newInstanceID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func (s *Server) publishCluster(room string, msg Message) {
+	if s.cluster == nil {
+		return
+	}
+	ev := ClusterEvent{Origin: s.instanceID, Room: room, Message: msg}
+	if err := s.cluster.Publish(ev); err != nil {
+		s.log.Error("Cluster publish failed", "room", room, "err", err)
+	}
+}
+
+// publishRoomMessage shares msg of room with the other instances. They
+// know nothing of a room's password, invites or operators and would let
+// anyone read along, so only public rooms are shared.
+func (s *Server) publishRoomMessage(room *ChatRoom, msg Message) {
+	if room.public() {
+		s.publishCluster(room.name, msg)
+	}
+}
+
+// publishRoomMode tells the other instances whether the room is ephemeral,
+// since its messages are no longer relayed to them while it is. Rooms with
+// a password, invites or hidden are never shared, so they are not named.
+func (s *Server) publishRoomMode(room *ChatRoom, ephemeral bool) {
+	if s.cluster == nil {
+		return
+	}
+	s.mutex.RLock()
+	private := room.passwordHash != "" || room.inviteOnly || room.hidden
+	s.mutex.RUnlock()
+	if private {
+		return
+	}
+	ev := ClusterEvent{Type: clusterRoomMode, Origin: s.instanceID, Room: room.name, Ephemeral: ephemeral}
+	if err := s.cluster.Publish(ev); err != nil {
+		s.log.Error("Cluster publish failed", "room", room.name, "err", err)
+	}
+}
+
+// handleClusterEvent delivers traffic from other instances to local clients
+func (s *Server) handleClusterEvent(ev ClusterEvent) {
+	if ev.Origin == s.instanceID {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if ev.Type == clusterPresence {
+		s.updateRoster(ev.Origin, ev.Presence, time.Now())
+		return
+	}
+
+	if ev.Room == "" {
+		s.deliverToAll(ev.Message, nil)
+		return
+	}
+
+	// Only public rooms are shared, so a plain room is a faithful copy
+	room, exists := s.rooms[ev.Room]
+	if !exists {
+		room = newChatRoom(ev.Room)
+		s.rooms[ev.Room] = room
+	}
+	if ev.Type == clusterRoomMode {
+		room.ephemeral = ev.Ephemeral
+		if err := s.saveRooms(); err != nil {
+			s.log.Error("Error saving rooms", "room", room.name, "err", err)
+		}
+		return
+	}
+	if room.archived {
+		s.log.Warn("Cluster message dropped: room is archived", "room", room.name, "origin", ev.Origin)
+		return
+	}
+	s.deliverToRoom(room, ev.Message, nil)
+}
+
+// presenceChanged asks for the local roster to be republished soon, after
+// a client joined, left, renamed itself or changed rooms
+func (s *Server) presenceChanged() {
+	if s.cluster == nil {
+		return
+	}
+	select {
+	case s.presence <- struct{}{}:
+	default:
+	}
+}
+
+// sharePresence publishes the local roster whenever it changes and every
+// clusterPresenceInterval, and an empty one when the server stops
+func (s *Server) sharePresence(ctx context.Context) {
+	ticker := time.NewTicker(clusterPresenceInterval)
+	defer ticker.Stop()
+	for {
+		s.publishPresence(true)
+		select {
+		case <-ctx.Done():
+			s.publishPresence(false)
+			return
+		case <-ticker.C:
+		case <-s.presence:
+		}
+	}
+}
+
+// publishPresence sends the other instances the local clients, or an
+// empty roster when online is false. Clients in hidden rooms are listed
+// without their room, which other instances could not hide.
+func (s *Server) publishPresence(online bool) {
+	ev := ClusterEvent{Type: clusterPresence, Origin: s.instanceID}
+	if online {
+		s.mutex.RLock()
+		for _, c := range s.clients.all() {
+			room := c.room
+			if r, exists := s.rooms[room]; exists && r.hidden {
+				room = ""
+			}
+			ev.Presence = append(ev.Presence, ClusterMember{Name: c.name, Room: room, Away: c.away != ""})
+		}
+		s.mutex.RUnlock()
+	}
+	if err := s.cluster.Publish(ev); err != nil {
+		s.log.Error("Cluster publish failed", "kind", clusterPresence, "err", err)
+	}
+}
+
+// updateRoster records the clients of another instance and forgets
+// instances that went quiet. Caller holds s.mutex.
+func (s *Server) updateRoster(origin string, members []ClusterMember, now time.Time) {
+	if s.rosters == nil {
+		s.rosters = make(map[string]clusterRoster)
+	}
+	for id, roster := range s.rosters {
+		if now.After(roster.expires) {
+			delete(s.rosters, id)
+		}
+	}
+	if len(members) == 0 {
+		delete(s.rosters, origin)
+		return
+	}
+	s.rosters[origin] = clusterRoster{members: members, expires: now.Add(3 * clusterPresenceInterval)}
+}
+
+// remoteMembers returns the clients connected to other instances, in room
+// or in any room when it is empty, sorted by name. Caller holds s.mutex.
+func (s *Server) remoteMembers(room string, now time.Time) []ClusterMember {
+	var members []ClusterMember
+	for _, roster := range s.rosters {
+		if now.After(roster.expires) {
+			continue
+		}
+		for _, m := range roster.members {
+			if room == "" || m.Room == room {
+				members = append(members, m)
+			}
+		}
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return strings.ToLower(members[i].Name) < strings.ToLower(members[j].Name)
+	})
+	return members
+}
@@ -0,0 +1,294 @@
+package chat
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	natsReconnectDelay = 2 * time.Second
+	natsWriteTimeout   = 5 * time.Second
+	natsQueueSize      = 1024 // Events waiting for writeLoop before Publish drops them
+)
+
+// natsBus is a minimal NATS client speaking the plain text protocol.
+// Every room maps to its own subject (<prefix>.room.<name>), server-wide
+// broadcasts go to <prefix>.all. Publish only queues the event, so a slow
+// or unreachable broker never holds up the server.
+type natsBus struct {
+	addr   string
+	user   *url.Userinfo
+	prefix string
+	log    *slog.Logger
+
+	mutex   sync.Mutex
+	conn    net.Conn
+	handler func(ev ClusterEvent)
+	closed  bool
+
+	out     chan []byte   // PUB frames for writeLoop
+	done    chan struct{} // Closed by Close
+	flushed chan struct{} // Closed by writeLoop once it has stopped
+}
+
+func newNATSBus(rawURL, prefix string, logger *slog.Logger) (*natsBus, error) {
+	if rawURL == "" {
+		rawURL = "nats://localhost:4222"
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid NATS url: %v", err)
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "4222")
+	}
+
+	b := &natsBus{
+		addr:    addr,
+		user:    u.User,
+		prefix:  prefix,
+		log:     logger,
+		out:     make(chan []byte, natsQueueSize),
+		done:    make(chan struct{}),
+		flushed: make(chan struct{}),
+	}
+	if err := b.connect(); err != nil {
+		return nil, err
+	}
+	go b.writeLoop()
+	return b, nil
+}
+
+func (b *natsBus) connect() error {
+	conn, err := net.DialTimeout("tcp", b.addr, 5*time.Second)
+	if err != nil {
+		return fmt.Errorf("failed to connect to NATS: %v", err)
+	}
+
+	reader := bufio.NewReader(conn)
+	info, err := reader.ReadString('\n')
+	if err != nil || !strings.HasPrefix(info, "INFO") {
+		conn.Close()
+		return fmt.Errorf("unexpected NATS greeting: %q", info)
+	}
+
+	opts := map[string]interface{}{
+		"verbose":  false,
+		"pedantic": false,
+		"name":     "tcpchat",
+		"lang":     "go",
+		"protocol": 1,
+	}
+	if b.user != nil {
+		opts["user"] = b.user.Username()
+		if pass, ok := b.user.Password(); ok {
+			opts["pass"] = pass
+		}
+	}
+	connectOpts, _ := json.Marshal(opts)
+
+	// Subscribe to every subject under the prefix in one go
+	if _, err := fmt.Fprintf(conn, "CONNECT %s\r\nSUB %s.> 1\r\n", connectOpts, b.prefix); err != nil {
+		conn.Close()
+		return fmt.Errorf("NATS handshake failed: %v", err)
+	}
+
+	b.mutex.Lock()
+	b.conn = conn
+	b.mutex.Unlock()
+
+	go b.readLoop(conn, reader)
+	return nil
+}
+
+func (b *natsBus) subject(room string) string {
+	if room == "" {
+		return b.prefix + ".all"
+	}
+	// Subject tokens cannot contain separators or wildcards
+	token := strings.Map(func(r rune) rune {
+		switch r {
+		case '.', '*', '>', ' ', '\t', '\r', '\n':
+			return '_'
+		}
+		return r
+	}, room)
+	return b.prefix + ".room." + token
+}
+
+// Publish queues ev for writeLoop, dropping it when the queue is full
+func (b *natsBus) Publish(ev ClusterEvent) error {
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	frame := fmt.Appendf(nil, "PUB %s %d\r\n%s\r\n", b.subject(ev.Room), len(payload), payload)
+
+	select {
+	case <-b.done:
+		return fmt.Errorf("NATS connection closed")
+	default:
+	}
+	select {
+	case b.out <- frame:
+		return nil
+	default:
+		return fmt.Errorf("NATS publish queue full, dropping event")
+	}
+}
+
+// writeLoop writes queued frames until the bus is closed, and then what
+// is still queued, such as the empty roster of a stopping server
+func (b *natsBus) writeLoop() {
+	defer close(b.flushed)
+	for {
+		select {
+		case <-b.done:
+			for {
+				select {
+				case frame := <-b.out:
+					b.write(frame)
+				default:
+					return
+				}
+			}
+		case frame := <-b.out:
+			b.write(frame)
+		}
+	}
+}
+
+// write sends frame to the broker. Frames queued while the connection is
+// down are dropped.
+func (b *natsBus) write(frame []byte) {
+	b.mutex.Lock()
+	conn := b.conn
+	b.mutex.Unlock()
+	if conn == nil {
+		b.log.Debug("Dropping cluster event while disconnected", "addr", b.addr)
+		return
+	}
+	conn.SetWriteDeadline(time.Now().Add(natsWriteTimeout))
+	if _, err := conn.Write(frame); err != nil {
+		// readLoop sees the closed connection and reconnects
+		b.log.Warn("NATS publish failed", "addr", b.addr, "err", err)
+		conn.Close()
+	}
+}
+
+func (b *natsBus) Subscribe(handler func(ev ClusterEvent)) error {
+	b.mutex.Lock()
+	b.handler = handler
+	b.mutex.Unlock()
+	return nil
+}
+
+func (b *natsBus) Close() error {
+	b.mutex.Lock()
+	if b.closed {
+		b.mutex.Unlock()
+		return nil
+	}
+	b.closed = true
+	close(b.done)
+	b.mutex.Unlock()
+
+	// Give writeLoop the chance to send what is still queued
+	select {
+	case <-b.flushed:
+	case <-time.After(natsWriteTimeout):
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.conn != nil {
+		return b.conn.Close()
+	}
+	return nil
+}
+
+func (b *natsBus) readLoop(conn net.Conn, reader *bufio.Reader) {
+	err := b.readMessages(conn, reader)
+
+	b.mutex.Lock()
+	closed := b.closed
+	b.conn = nil
+	b.mutex.Unlock()
+	conn.Close()
+	if closed {
+		return
+	}
+
+	b.log.Warn("NATS connection lost", "addr", b.addr, "err", err)
+	for {
+		time.Sleep(natsReconnectDelay)
+		b.mutex.Lock()
+		closed := b.closed
+		b.mutex.Unlock()
+		if closed {
+			return
+		}
+		if err := b.connect(); err != nil {
+			b.log.Warn("NATS reconnect failed", "addr", b.addr, "err", err)
+			continue
+		}
+		return
+	}
+}
+
+func (b *natsBus) readMessages(conn net.Conn, reader *bufio.Reader) error {
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		switch {
+		case line == "PING":
+			conn.SetWriteDeadline(time.Now().Add(natsWriteTimeout))
+			_, err = conn.Write([]byte("PONG\r\n"))
+			if err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			b.log.Error("NATS error", "addr", b.addr, "reply", line)
+		case strings.HasPrefix(line, "MSG "):
+			// MSG <subject> <sid> [reply-to] <#bytes>
+			fields := strings.Fields(line)
+			size, err := strconv.Atoi(fields[len(fields)-1])
+			if err != nil {
+				return fmt.Errorf("malformed MSG line: %q", line)
+			}
+			payload := make([]byte, size+2) // Payload plus trailing CRLF
+			if _, err := io.ReadFull(reader, payload); err != nil {
+				return err
+			}
+			b.dispatch(payload[:size])
+		}
+	}
+}
+
+func (b *natsBus) dispatch(payload []byte) {
+	var ev ClusterEvent
+	if err := json.Unmarshal(payload, &ev); err != nil {
+		b.log.Warn("Dropping malformed cluster event", "err", err)
+		return
+	}
+
+	b.mutex.Lock()
+	handler := b.handler
+	b.mutex.Unlock()
+	if handler != nil {
+		handler(ev)
+	}
+}
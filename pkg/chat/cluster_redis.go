@@ -0,0 +1,421 @@
+package chat
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	redisReconnectDelay = 2 * time.Second
+	redisWriteTimeout   = 5 * time.Second
+	redisQueueSize      = 1024 // Events waiting for writeLoop before Publish drops them
+)
+
+// redisBus is a minimal Redis pub/sub client speaking RESP. Every room
+// maps to its own channel (<prefix>:room:<name>), server-wide broadcasts
+// and presence go to <prefix>:all. Redis does not allow publishing on a
+// subscribed connection, so the bus keeps one connection for each.
+// Publish only queues the event; the publisher connection is dialed and
+// written to in the background, so an outage never holds up the server.
+type redisBus struct {
+	addr   string
+	user   *url.Userinfo
+	prefix string
+	log    *slog.Logger
+
+	mutex   sync.Mutex
+	pub     net.Conn // Nil until the first publish and after an error
+	sub     net.Conn
+	handler func(ev ClusterEvent)
+	closed  bool
+
+	out     chan []byte   // PUBLISH commands for writeLoop
+	done    chan struct{} // Closed by Close
+	flushed chan struct{} // Closed by writeLoop once it has stopped
+}
+
+func newRedisBus(rawURL, prefix string, logger *slog.Logger) (*redisBus, error) {
+	if rawURL == "" {
+		rawURL = "redis://localhost:6379"
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis url: %v", err)
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+
+	b := &redisBus{
+		addr:    addr,
+		user:    u.User,
+		prefix:  prefix,
+		log:     logger,
+		out:     make(chan []byte, redisQueueSize),
+		done:    make(chan struct{}),
+		flushed: make(chan struct{}),
+	}
+	if err := b.subscribe(); err != nil {
+		return nil, err
+	}
+	go b.writeLoop()
+	return b, nil
+}
+
+// dial opens an authenticated connection to Redis
+func (b *redisBus) dial() (net.Conn, *bufio.Reader, error) {
+	conn, err := net.DialTimeout("tcp", b.addr, 5*time.Second)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to Redis: %v", err)
+	}
+	reader := bufio.NewReader(conn)
+	if b.user == nil {
+		return conn, reader, nil
+	}
+
+	// redis://:pass@host authenticates with the password alone, as
+	// servers before ACLs (Redis 6) expect
+	auth := []string{"AUTH"}
+	if pass, ok := b.user.Password(); ok {
+		if b.user.Username() != "" {
+			auth = append(auth, b.user.Username())
+		}
+		auth = append(auth, pass)
+	} else {
+		auth = append(auth, b.user.Username())
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Write(redisCommand(auth...)); err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("handshake with Redis failed: %v", err)
+	}
+	reply, err := readRESP(reader)
+	if e, ok := reply.(redisError); ok {
+		err = e
+	}
+	if err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("authentication with Redis failed: %v", err)
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, reader, nil
+}
+
+// subscribe connects the subscriber to every channel under the prefix
+func (b *redisBus) subscribe() error {
+	conn, reader, err := b.dial()
+	if err != nil {
+		return err
+	}
+	if _, err := conn.Write(redisCommand("PSUBSCRIBE", b.prefix+":*")); err != nil {
+		conn.Close()
+		return fmt.Errorf("subscribing to Redis failed: %v", err)
+	}
+
+	b.mutex.Lock()
+	b.sub = conn
+	b.mutex.Unlock()
+
+	go b.readLoop(conn, reader)
+	return nil
+}
+
+func (b *redisBus) channel(room string) string {
+	if room == "" {
+		return b.prefix + ":all"
+	}
+	return b.prefix + ":room:" + room
+}
+
+// Publish queues ev for writeLoop, dropping it when the queue is full
+func (b *redisBus) Publish(ev ClusterEvent) error {
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	command := redisCommand("PUBLISH", b.channel(ev.Room), string(payload))
+
+	select {
+	case <-b.done:
+		return fmt.Errorf("Redis connection closed")
+	default:
+	}
+	select {
+	case b.out <- command:
+		return nil
+	default:
+		return fmt.Errorf("Redis publish queue full, dropping event")
+	}
+}
+
+// writeLoop writes queued commands until the bus is closed, and then what
+// is still queued on an open connection, such as the empty roster of a
+// stopping server
+func (b *redisBus) writeLoop() {
+	defer close(b.flushed)
+	var retry time.Time
+	for {
+		select {
+		case <-b.done:
+			for {
+				select {
+				case command := <-b.out:
+					b.mutex.Lock()
+					conn := b.pub
+					b.mutex.Unlock()
+					if conn != nil {
+						b.write(conn, command)
+					}
+				default:
+					return
+				}
+			}
+		case command := <-b.out:
+			b.mutex.Lock()
+			conn := b.pub
+			b.mutex.Unlock()
+			if conn == nil {
+				if time.Now().Before(retry) {
+					b.log.Debug("Dropping cluster event while disconnected", "addr", b.addr)
+					continue
+				}
+				var err error
+				if conn, err = b.connectPublisher(); err != nil {
+					b.log.Warn("Redis publisher connect failed", "addr", b.addr, "err", err)
+					retry = time.Now().Add(redisReconnectDelay)
+					continue
+				}
+			}
+			b.write(conn, command)
+		}
+	}
+}
+
+// connectPublisher opens the publisher connection and starts reading its
+// replies; they are not waited for, so readReplies reports errors
+func (b *redisBus) connectPublisher() (net.Conn, error) {
+	conn, reader, err := b.dial()
+	if err != nil {
+		return nil, err
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.closed {
+		conn.Close()
+		return nil, fmt.Errorf("Redis connection closed")
+	}
+	b.pub = conn
+	go b.readReplies(conn, reader)
+	return conn, nil
+}
+
+// write sends command on the publisher connection, dropping the
+// connection if that fails so the next command reconnects
+func (b *redisBus) write(conn net.Conn, command []byte) {
+	conn.SetWriteDeadline(time.Now().Add(redisWriteTimeout))
+	if _, err := conn.Write(command); err != nil {
+		b.log.Warn("Redis publish failed", "addr", b.addr, "err", err)
+		b.mutex.Lock()
+		if b.pub == conn {
+			b.pub = nil
+		}
+		b.mutex.Unlock()
+		conn.Close()
+	}
+}
+
+func (b *redisBus) Subscribe(handler func(ev ClusterEvent)) error {
+	b.mutex.Lock()
+	b.handler = handler
+	b.mutex.Unlock()
+	return nil
+}
+
+func (b *redisBus) Close() error {
+	b.mutex.Lock()
+	if b.closed {
+		b.mutex.Unlock()
+		return nil
+	}
+	b.closed = true
+	close(b.done)
+	b.mutex.Unlock()
+
+	// Give writeLoop the chance to send what is still queued
+	select {
+	case <-b.flushed:
+	case <-time.After(redisWriteTimeout):
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.pub != nil {
+		b.pub.Close()
+	}
+	if b.sub != nil {
+		return b.sub.Close()
+	}
+	return nil
+}
+
+// readReplies consumes the replies to PUBLISH, reporting errors, until
+// the publisher connection fails; writeLoop then reconnects
+func (b *redisBus) readReplies(conn net.Conn, reader *bufio.Reader) {
+	for {
+		reply, err := readRESP(reader)
+		if err != nil {
+			break
+		}
+		if e, ok := reply.(redisError); ok {
+			b.log.Error("Redis error", "addr", b.addr, "reply", string(e))
+		}
+	}
+	b.mutex.Lock()
+	if b.pub == conn {
+		b.pub = nil
+	}
+	b.mutex.Unlock()
+	conn.Close()
+}
+
+func (b *redisBus) readLoop(conn net.Conn, reader *bufio.Reader) {
+	err := b.readMessages(reader)
+
+	b.mutex.Lock()
+	closed := b.closed
+	b.sub = nil
+	b.mutex.Unlock()
+	conn.Close()
+	if closed {
+		return
+	}
+
+	b.log.Warn("Redis connection lost", "addr", b.addr, "err", err)
+	for {
+		time.Sleep(redisReconnectDelay)
+		b.mutex.Lock()
+		closed := b.closed
+		b.mutex.Unlock()
+		if closed {
+			return
+		}
+		if err := b.subscribe(); err != nil {
+			b.log.Warn("Redis reconnect failed", "addr", b.addr, "err", err)
+			continue
+		}
+		return
+	}
+}
+
+func (b *redisBus) readMessages(reader *bufio.Reader) error {
+	for {
+		reply, err := readRESP(reader)
+		if err != nil {
+			return err
+		}
+		// pmessage <pattern> <channel> <payload>; subscription
+		// confirmations are ignored
+		switch msg := reply.(type) {
+		case redisError:
+			return msg
+		case []any:
+			if len(msg) == 4 && msg[0] == "pmessage" {
+				if payload, ok := msg[3].(string); ok {
+					b.dispatch([]byte(payload))
+				}
+			}
+		}
+	}
+}
+
+func (b *redisBus) dispatch(payload []byte) {
+	var ev ClusterEvent
+	if err := json.Unmarshal(payload, &ev); err != nil {
+		b.log.Warn("Dropping malformed cluster event", "err", err)
+		return
+	}
+
+	b.mutex.Lock()
+	handler := b.handler
+	b.mutex.Unlock()
+	if handler != nil {
+		handler(ev)
+	}
+}
+
+// redisError is an error reply from Redis
+type redisError string
+
+func (e redisError) Error() string { return string(e) }
+
+// redisCommand encodes a command as a RESP array of bulk strings
+func redisCommand(args ...string) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&buf, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	return buf.Bytes()
+}
+
+// readRESP reads one reply: a string for simple and bulk strings, a
+// redisError, an int64, nil for null replies or a []any for arrays
+func readRESP(r *bufio.Reader) (any, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("empty RESP line")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return redisError(line[1:]), nil
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("malformed bulk length: %q", line)
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		data := make([]byte, size+2) // Data plus trailing CRLF
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		return string(data[:size]), nil
+	case '*':
+		count, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("malformed array length: %q", line)
+		}
+		if count < 0 {
+			return nil, nil
+		}
+		items := make([]any, count)
+		for i := range items {
+			if items[i], err = readRESP(r); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("unexpected RESP reply: %q", line)
+	}
+}
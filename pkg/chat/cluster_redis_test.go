@@ -0,0 +1,221 @@
+package chat
+
+import (
+	"bufio"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis is a tiny Redis that only knows AUTH, PSUBSCRIBE and PUBLISH,
+// and sends every message to every subscriber
+type fakeRedis struct {
+	listener    net.Listener
+	password    string
+	mutex       sync.Mutex
+	conns       []net.Conn
+	subscribers map[net.Conn]string // Connection to pattern
+}
+
+func startFakeRedis(t *testing.T, password string) *fakeRedis {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("fake Redis listen failed: %v", err)
+	}
+	f := &fakeRedis{listener: l, password: password, subscribers: make(map[net.Conn]string)}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			f.mutex.Lock()
+			f.conns = append(f.conns, conn)
+			f.mutex.Unlock()
+			go f.serve(conn)
+		}
+	}()
+	t.Cleanup(f.close)
+	return f
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	reader := bufio.NewReader(conn)
+	authed := f.password == ""
+	for {
+		reply, err := readRESP(reader)
+		if err != nil {
+			return
+		}
+		args, _ := reply.([]any)
+		if len(args) == 0 {
+			continue
+		}
+		f.mutex.Lock()
+		switch cmd := args[0].(string); {
+		case cmd == "AUTH":
+			if args[len(args)-1] == f.password {
+				authed = true
+				conn.Write([]byte("+OK\r\n"))
+			} else {
+				conn.Write([]byte("-WRONGPASS invalid password\r\n"))
+			}
+		case !authed:
+			conn.Write([]byte("-NOAUTH Authentication required.\r\n"))
+		case cmd == "PSUBSCRIBE":
+			f.subscribers[conn] = args[1].(string)
+			conn.Write(redisCommand("psubscribe", args[1].(string)))
+		case cmd == "PUBLISH":
+			for sub, pattern := range f.subscribers {
+				sub.Write(redisCommand("pmessage", pattern, args[1].(string), args[2].(string)))
+			}
+			fmt.Fprintf(conn, ":%d\r\n", len(f.subscribers))
+		}
+		f.mutex.Unlock()
+	}
+}
+
+func (f *fakeRedis) close() {
+	f.listener.Close()
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	for _, c := range f.conns {
+		c.Close()
+	}
+}
+
+func TestRESP(t *testing.T) {
+	if got, want := string(redisCommand("PUBLISH", "chat:all", "hi")), "*3\r\n$7\r\nPUBLISH\r\n$8\r\nchat:all\r\n$2\r\nhi\r\n"; got != want {
+		t.Errorf("redisCommand = %q, want %q", got, want)
+	}
+
+	input := "*5\r\n+OK\r\n-ERR nope\r\n:42\r\n$-1\r\n*2\r\n$5\r\nhello\r\n$0\r\n\r\n"
+	reply, err := readRESP(bufio.NewReader(strings.NewReader(input)))
+	if err != nil {
+		t.Fatalf("readRESP failed: %v", err)
+	}
+	want := []any{"OK", redisError("ERR nope"), int64(42), nil, []any{"hello", ""}}
+	if !reflect.DeepEqual(reply, want) {
+		t.Errorf("readRESP = %#v, want %#v", reply, want)
+	}
+
+	for _, bad := range []string{"", "?what\r\n", "$abc\r\n", "$5\r\nhi\r\n"} {
+		if _, err := readRESP(bufio.NewReader(strings.NewReader(bad))); err == nil {
+			t.Errorf("readRESP(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestRedisAuth(t *testing.T) {
+	f := startFakeRedis(t, "secret")
+	addr := f.listener.Addr().String()
+
+	if _, err := newRedisBus("redis://:wrong@"+addr, "tcpchat", slog.Default()); err == nil {
+		t.Error("connected with the wrong password")
+	}
+	b, err := newRedisBus("redis://:secret@"+addr, "tcpchat", slog.Default())
+	if err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+	defer b.Close()
+
+	events := make(chan ClusterEvent, 1)
+	b.Subscribe(func(ev ClusterEvent) { events <- ev })
+	if err := b.Publish(ClusterEvent{Origin: "a", Room: "dev", Message: Message{Content: "hi"}}); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	select {
+	case ev := <-events:
+		if ev.Room != "dev" || ev.Message.Content != "hi" {
+			t.Errorf("got %+v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("published event not received")
+	}
+}
+
+func TestRedisClusterRelay(t *testing.T) {
+	f := startFakeRedis(t, "")
+
+	config := DefaultConfig()
+	config.ClusterBackend = "redis"
+	config.ClusterURL = "redis://" + f.listener.Addr().String()
+
+	var addrs []string
+	for i := 0; i < 2; i++ {
+		instance := *config
+		addrs = append(addrs, setupTestServerWithConfig(t, &instance))
+	}
+
+	var clients []*TestClient
+	for i, addr := range addrs {
+		c, err := newTestClient(t, addr)
+		if err != nil {
+			t.Fatalf("Client%d connection failed: %v", i+1, err)
+		}
+		defer c.close()
+		if err := c.expectMessage(t, "Welcome"); err != nil {
+			t.Fatalf("Client%d welcome failed: %v", i+1, err)
+		}
+		c.sendMessage(fmt.Sprintf("Redis%d", i+1))
+		if err := c.expectMessage(t, "joined"); err != nil {
+			t.Fatalf("Client%d join failed: %v", i+1, err)
+		}
+		clients = append(clients, c)
+	}
+
+	clients[0].sendMessage("hello through redis")
+	if err := clients[1].expectMessage(t, "hello through redis"); err != nil {
+		t.Fatalf("Cluster relay failed: %v", err)
+	}
+
+	// Rosters are shared shortly after every join
+	for attempt := 0; ; attempt++ {
+		clients[1].sendMessage("/who")
+		if err := clients[1].expectMessage(t, "Users in room"); err != nil {
+			t.Fatalf("/who failed: %v", err)
+		}
+		line, _ := clients[1].reader.ReadString('\n')
+		if strings.Contains(line, "Redis1 [remote]") {
+			break
+		}
+		if attempt == 20 {
+			t.Fatalf("/who does not list the other instance's client: %q", line)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestRedisPublishDoesNotBlock(t *testing.T) {
+	// A Redis that accepts connections but never answers AUTH
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	defer l.Close()
+	b := &redisBus{
+		addr:    l.Addr().String(),
+		user:    url.UserPassword("", "secret"),
+		prefix:  "tcpchat",
+		log:     slog.Default(),
+		out:     make(chan []byte, redisQueueSize),
+		done:    make(chan struct{}),
+		flushed: make(chan struct{}),
+	}
+	go b.writeLoop()
+	defer b.Close()
+
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		b.Publish(ClusterEvent{Origin: "a", Room: "dev", Message: Message{Content: "hi"}})
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Publish waited %s for an unresponsive Redis", elapsed)
+	}
+}
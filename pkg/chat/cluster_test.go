@@ -0,0 +1,251 @@
+package chat
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// relayTimeout bounds how long a message takes to reach another instance
+// through the broker, which is slow under -race
+const relayTimeout = 5 * time.Second
+
+// fakeNATS is a tiny broker that fans every PUB out to all connections
+type fakeNATS struct {
+	listener net.Listener
+	mutex    sync.Mutex
+	conns    []net.Conn
+}
+
+func startFakeNATS(t *testing.T) *fakeNATS {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("fake NATS listen failed: %v", err)
+	}
+	f := &fakeNATS{listener: l}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			// Like NATS, greet the connection before it gets any messages
+			f.mutex.Lock()
+			fmt.Fprint(conn, "INFO {}\r\n")
+			f.conns = append(f.conns, conn)
+			f.mutex.Unlock()
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeNATS) serve(conn net.Conn) {
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 3 || fields[0] != "PUB" {
+			continue
+		}
+		size, _ := strconv.Atoi(fields[2])
+		payload := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			return
+		}
+
+		f.mutex.Lock()
+		for _, c := range f.conns {
+			fmt.Fprintf(c, "MSG %s 1 %d\r\n%s", fields[1], size, payload)
+		}
+		f.mutex.Unlock()
+	}
+}
+
+func (f *fakeNATS) close() {
+	f.listener.Close()
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	for _, c := range f.conns {
+		c.Close()
+	}
+}
+
+func TestNATSSubjectMapping(t *testing.T) {
+	b := &natsBus{prefix: "tcpchat"}
+	tests := map[string]string{
+		"":         "tcpchat.all",
+		"general":  "tcpchat.room.general",
+		"a.b*c>":   "tcpchat.room.a_b_c_",
+		"dev-chat": "tcpchat.room.dev-chat",
+	}
+	for room, want := range tests {
+		if got := b.subject(room); got != want {
+			t.Errorf("subject(%q) = %q, want %q", room, got, want)
+		}
+	}
+}
+
+func TestClusterRelay(t *testing.T) {
+	broker := startFakeNATS(t)
+	defer broker.close()
+
+	config := DefaultConfig()
+	config.ClusterBackend = "nats"
+	config.ClusterURL = "nats://" + broker.listener.Addr().String()
+
+	// Each instance gets a copy of the config, and files of its own
+	var addrs []string
+	for i := 0; i < 2; i++ {
+		instance := *config
+		addrs = append(addrs, setupTestServerWithConfig(t, &instance))
+	}
+
+	client1, err := newTestClient(t, addrs[0])
+	if err != nil {
+		t.Fatalf("Client1 connection failed: %v", err)
+	}
+	defer client1.close()
+
+	client2, err := newTestClient(t, addrs[1])
+	if err != nil {
+		t.Fatalf("Client2 connection failed: %v", err)
+	}
+	defer client2.close()
+
+	for i, c := range []*TestClient{client1, client2} {
+		if err := c.expectMessage(t, "Welcome"); err != nil {
+			t.Fatalf("Client%d welcome failed: %v", i+1, err)
+		}
+		c.sendMessage(fmt.Sprintf("Node%d", i+1))
+		if err := c.expectMessage(t, "joined"); err != nil {
+			t.Fatalf("Client%d join failed: %v", i+1, err)
+		}
+	}
+
+	// Node2's roster reaching node one shows the relay is up
+	deadline := time.Now().Add(relayTimeout)
+	for {
+		client1.sendMessage("/who")
+		if client1.expectMessage(t, "Node2 [remote]") == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Node2 never showed up on node one")
+		}
+	}
+
+	if err := client1.sendMessage("hello from node one"); err != nil {
+		t.Fatalf("Send message failed: %v", err)
+	}
+	if err := client2.expectMessageWithin(t, "hello from node one", relayTimeout); err != nil {
+		t.Fatalf("Cluster relay failed: %v", err)
+	}
+
+	// Password rooms stay on their instance
+	client1.sendMessage("/create vault hunter22")
+	if err := client1.expectMessageWithin(t, "Node1 joined", passwordTimeout); err != nil {
+		t.Fatalf("Room not created: %v", err)
+	}
+	client1.sendMessage("the vault code is 1234")
+	client1.sendMessage("/leave")
+	client1.sendMessage("back in general")
+	client2.conn.SetReadDeadline(time.Now().Add(relayTimeout))
+	for {
+		line, err := client2.reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Cluster relay failed: %v", err)
+		}
+		if strings.Contains(line, "vault") {
+			t.Fatalf("private room relayed: %q", line)
+		}
+		if strings.Contains(line, "back in general") {
+			break
+		}
+	}
+	client2.sendMessage("/join vault")
+	if err := client2.expectMessage(t, "room does not exist"); err != nil {
+		t.Errorf("private room copied to another instance: %v", err)
+	}
+}
+
+func TestClusterRosters(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountsFile = ""
+	config.RoomsFile = ""
+	config.Store = StoreMemory
+	s := NewServerWithConfig(config)
+	defer s.Logfile.Close()
+
+	now := time.Now()
+	s.updateRoster("a", []ClusterMember{{Name: "bob", Room: "dev"}, {Name: "Alice", Room: "general", Away: true}}, now)
+	s.updateRoster("b", []ClusterMember{{Name: "Carol", Room: "dev"}}, now)
+
+	names := func(members []ClusterMember) []string {
+		var out []string
+		for _, m := range members {
+			out = append(out, m.Name)
+		}
+		return out
+	}
+	if got := names(s.remoteMembers("", now)); strings.Join(got, ",") != "Alice,bob,Carol" {
+		t.Errorf("remoteMembers = %v", got)
+	}
+	if got := names(s.remoteMembers("dev", now)); strings.Join(got, ",") != "bob,Carol" {
+		t.Errorf("remoteMembers(dev) = %v", got)
+	}
+
+	// An empty roster means the instance stopped
+	s.updateRoster("b", nil, now)
+	if got := names(s.remoteMembers("dev", now)); strings.Join(got, ",") != "bob" {
+		t.Errorf("after b left: %v", got)
+	}
+
+	// Rosters that are not refreshed expire
+	later := now.Add(3*clusterPresenceInterval + time.Second)
+	if got := s.remoteMembers("", later); len(got) != 0 {
+		t.Errorf("expired roster still listed: %v", names(got))
+	}
+	s.updateRoster("c", []ClusterMember{{Name: "Dave", Room: "general"}}, later)
+	if _, ok := s.rosters["a"]; ok {
+		t.Error("expired roster not removed")
+	}
+}
+
+func TestPresenceHidesHiddenRooms(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountsFile = ""
+	config.RoomsFile = ""
+	config.Store = StoreMemory
+	s := NewServerWithConfig(config)
+	defer s.Logfile.Close()
+	bus := &recordingBus{}
+	s.cluster = bus
+
+	alice := newPipeClient(t, "Alice")
+	s.clients.add(alice.conn, alice)
+	if err := s.createRoom(alice, "hideout", "", ""); err != nil {
+		t.Fatalf("createRoom failed: %v", err)
+	}
+	if err := hiddenCommand(s, alice, []string{"on"}); err != nil {
+		t.Fatalf("hidden failed: %v", err)
+	}
+
+	bus.events = bus.events[:0]
+	s.publishPresence(true)
+	if len(bus.events) != 1 || len(bus.events[0].Presence) != 1 {
+		t.Fatalf("unexpected presence events: %+v", bus.events)
+	}
+	if m := bus.events[0].Presence[0]; m.Name != "Alice" || m.Room != "" {
+		t.Errorf("hidden room shared with other instances: %+v", m)
+	}
+}
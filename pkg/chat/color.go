@@ -0,0 +1,91 @@
+package chat
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+)
+
+// ANSI SGR sequences used by colorMessage
+const (
+	ansiReset   = "\x1b[0m"
+	ansiBold    = "\x1b[1m"
+	ansiRed     = "\x1b[31m"
+	ansiYellow  = "\x1b[33m"
+	ansiMagenta = "\x1b[35m"
+)
+
+// nickColors are the foreground colors nicknames are spread over; black,
+// white and red are left out so names stay readable and distinct from errors
+var nickColors = []string{"32", "33", "34", "35", "36", "92", "93", "94", "95", "96"}
+
+// nickColor returns the SGR sequence for name, the same for every client
+// and every run
+func nickColor(name string) string {
+	return "\x1b[" + nickColors[nickHash(name)%uint32(len(nickColors))] + "m"
+}
+
+// nickHash spreads nicknames over colors, ignoring case
+func nickHash(name string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(strings.ToLower(name)))
+	return h.Sum32()
+}
+
+// paint wraps text in the SGR sequence code
+func paint(code, text string) string {
+	return code + text + ansiReset
+}
+
+// colorMessage is formatMessage with ANSI colors: nicknames in their own
+// color, private messages magenta, errors bold red and everything the
+// server says yellow. Mentions and keyword alerts are bold.
+func colorMessage(msg Message) string {
+	return string(appendColorMessage(nil, msg))
+}
+
+// appendColorMessage appends msg as colorMessage would format it to dst
+func appendColorMessage(dst []byte, msg Message) []byte {
+	var code string
+	switch msg.Type {
+	case MessageTypeChat:
+		msg.From = paint(nickColor(msg.From), msg.From)
+		return appendMessage(dst, msg)
+	case MessageTypeMention, MessageTypeKeyword:
+		msg.From = paint(nickColor(msg.From), msg.From) + ansiBold
+		code = ansiBold
+	case MessageTypePrivate:
+		code = ansiMagenta
+	case MessageTypeError:
+		code = ansiBold + ansiRed
+	default:
+		code = ansiYellow
+	}
+	dst = append(dst, code...)
+	dst = appendMessage(dst, msg)
+	return append(dst, ansiReset...)
+}
+
+func colorCommand(s *Server, c *Client, args []string) error {
+	switch c.conn.(type) {
+	case *jsonConn, *ircConn:
+		return fmt.Errorf("colors are only available to text clients")
+	}
+	if len(args) == 0 {
+		mode := "off"
+		if c.color {
+			mode = "on"
+		}
+		c.write([]byte(fmt.Sprintf("Colors: %s\n", mode)))
+		return nil
+	}
+	mode := strings.ToLower(args[0])
+	if mode != "on" && mode != "off" {
+		return fmt.Errorf("usage: /color on|off")
+	}
+	s.mutex.Lock()
+	c.color = mode == "on"
+	s.mutex.Unlock()
+	c.write([]byte(fmt.Sprintf("Colors: %s\n", mode)))
+	return nil
+}
@@ -0,0 +1,58 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNickColor(t *testing.T) {
+	if nickColor("Alice") != nickColor("alice") {
+		t.Error("nick colors differ by case")
+	}
+	seen := make(map[string]bool)
+	for _, name := range []string{"Alice", "Bob", "Carol", "Dave", "Eve", "Mallory"} {
+		seen[nickColor(name)] = true
+	}
+	if len(seen) < 2 {
+		t.Error("every nickname got the same color")
+	}
+}
+
+func TestColorMessage(t *testing.T) {
+	now := time.Now()
+	chat := colorMessage(Message{Type: MessageTypeChat, From: "Alice", Content: "hi", Timestamp: now})
+	if !strings.Contains(chat, "["+nickColor("Alice")+"Alice"+ansiReset+"]: hi") {
+		t.Errorf("chat nickname not colored: %q", chat)
+	}
+	errLine := colorMessage(Message{Type: MessageTypeError, Content: "nope", Timestamp: now})
+	if !strings.HasPrefix(errLine, ansiBold+ansiRed) || !strings.HasSuffix(errLine, ansiReset) {
+		t.Errorf("error not bold red: %q", errLine)
+	}
+}
+
+func TestColorToggle(t *testing.T) {
+	addr := setupTestServer(t)
+	client, err := newTestClient(t, addr)
+	if err != nil {
+		t.Fatalf("Client connection failed: %v", err)
+	}
+	defer client.close()
+	client.sendMessage("Alice")
+	if err := client.expectMessage(t, "Alice joined"); err != nil {
+		t.Fatalf("Join failed: %v", err)
+	}
+
+	client.sendMessage("plain")
+	if err := client.expectMessage(t, "[Alice]: plain"); err != nil {
+		t.Fatalf("colors on by default: %v", err)
+	}
+	client.sendMessage("/color on")
+	if err := client.expectMessage(t, "Colors: on"); err != nil {
+		t.Fatalf("toggle failed: %v", err)
+	}
+	client.sendMessage("colored")
+	if err := client.expectMessage(t, nickColor("Alice")+"Alice"+ansiReset+"]: colored"); err != nil {
+		t.Fatalf("message not colored: %v", err)
+	}
+}
@@ -0,0 +1,243 @@
+package chat
+
+import "time"
+
+// Config holds the tunable settings of a Server
+type Config struct {
+	// ConfigFile is the TOML file the settings came from, if any; reload
+	// re-reads it
+	ConfigFile string
+	// Overrides re-applies the settings given on the command line after a
+	// reload of ConfigFile, so they keep winning over the file
+	Overrides func(*Config)
+
+	MaxClients int
+	// MaxConnsPerIP caps simultaneous connections from one address (0 = no limit)
+	MaxConnsPerIP int
+
+	// AllowNetworks and DenyNetworks filter connections by remote address
+	// (CIDR ranges or single IPs). Deny wins; a non-empty allow list
+	// rejects everything it does not match.
+	AllowNetworks []string
+	DenyNetworks  []string
+
+	// TLSCert and TLSKey switch the chat listener to TLS-only when set.
+	// TLSOptional keeps accepting plaintext clients on the same port,
+	// upgrading connections that open with a TLS handshake.
+	TLSCert     string
+	TLSKey      string
+	TLSOptional bool
+
+	// Clustering: relays room traffic between several server instances.
+	// An empty ClusterBackend runs the server standalone.
+	ClusterBackend string // "nats" or "redis"
+	ClusterURL     string // e.g. nats://localhost:4222 or redis://:password@localhost:6379
+	ClusterPrefix  string // subject/channel prefix shared by all instances
+
+	// Bridges relay rooms to Slack or Discord channels
+	Bridges []BridgeConfig
+
+	// AuthRequired makes clients log in with a password after the name
+	// prompt; the account name then becomes their fixed nickname
+	AuthRequired    bool
+	AuthMaxAttempts int
+	// IdentifyTimeout is how long a client using a registered nickname
+	// has to /identify before being renamed (outside auth mode)
+	IdentifyTimeout time.Duration
+	// Roles grants roles to account names at login. Only honoured in auth
+	// mode, where names cannot be impersonated.
+	Roles map[string]Role
+
+	// JoinChallenge makes new connections solve a small sum within
+	// ChallengeTimeout before they get the name prompt
+	JoinChallenge    bool
+	ChallengeTimeout time.Duration
+
+	// Store selects where messages, rooms and accounts are kept:
+	// StoreFile (default), StoreMemory or StoreSQLite
+	Store string
+	// The file store keeps per-nickname profiles (email settings,
+	// credentials, offline messages) in AccountsFile and rooms created
+	// with /create in RoomsFile. HistoryFile adds a JSON-lines message
+	// log; without it history stays in memory.
+	AccountsFile string
+	RoomsFile    string
+	HistoryFile  string
+	// DatabasePath is the SQLite file used by the sqlite store
+	DatabasePath string
+	// HistoryLimit bounds the messages per room kept by in-memory history
+	// (0 = unbounded); RoomHistoryLimits overrides it for single rooms
+	HistoryLimit      int
+	RoomHistoryLimits map[string]int
+	// HistoryReplay caps the messages replayed when joining a room
+	// (0 replays everything)
+	HistoryReplay int
+
+	// Retention prunes stored history every RetentionInterval;
+	// RoomRetention overrides the global rule for single rooms
+	Retention         RetentionRule
+	RoomRetention     map[string]RetentionRule
+	RetentionInterval time.Duration
+
+	// ExportDir receives the files written by /export
+	ExportDir string
+
+	// UnfurlLinks posts the titles of pages linked in rooms. Only hosts in
+	// UnfurlAllow ("example.com", "*.example.com") are fetched; internal
+	// addresses additionally have to be listed as IP literals.
+	UnfurlLinks bool
+	UnfurlAllow []string
+
+	// NoFunCommands leaves out /roll, /flip and /8ball
+	NoFunCommands bool
+
+	// MOTDFile holds the message of the day shown after login and by /motd;
+	// edits are picked up without a restart
+	MOTDFile string
+
+	// BanFile persists banned IPs across restarts
+	BanFile string
+	// AuditFile receives a JSON line per moderation action
+	AuditFile string
+	// LogFile receives the activity log; empty sends it to stderr
+	LogFile string
+	// LogLevel drops activity log records below it: debug, info (the
+	// default), warn or error. LogFormat is LogFormatText or LogFormatJSON.
+	LogLevel  string
+	LogFormat string
+
+	// Flood protection: more than FloodBurst lines within FloodWindow earns
+	// a warning; exceeding FloodWarnings warnings disconnects the client.
+	// A zero FloodBurst disables the check.
+	FloodBurst    int
+	FloodWindow   time.Duration
+	FloodWarnings int
+	// Bots authenticated with a token use their own limit; a zero
+	// BotFloodBurst exempts them entirely
+	BotFloodBurst  int
+	BotFloodWindow time.Duration
+
+	// IdleTimeout disconnects clients that send nothing for this long,
+	// warning them IdleWarning beforehand. Zero disables the reaper.
+	IdleTimeout time.Duration
+	IdleWarning time.Duration
+	// AutoAway marks clients away after this long without input, until
+	// they send something again. Zero disables it.
+	AutoAway time.Duration
+	// RoomIdleTimeout deletes rooms that stay empty this long, except
+	// the lobby, DefaultRooms and PersistentRooms. Zero keeps every room.
+	RoomIdleTimeout time.Duration
+	PersistentRooms []string
+
+	// DefaultRooms are created at startup alongside Lobby, the room new
+	// clients are put in ("general" when empty)
+	DefaultRooms []string
+	Lobby        string
+
+	// FlushInterval holds each client's outbound batch open this long for
+	// more messages before writing it. Zero writes as soon as the queue is
+	// empty, coalescing only what has already piled up.
+	FlushInterval time.Duration
+
+	// SanitizePolicy decides what happens to input containing escape
+	// sequences or control characters: SanitizeStrip or SanitizeReject
+	SanitizePolicy string
+
+	// Email notifications for offline activity. An empty SMTPAddr disables them.
+	SMTPAddr       string // host:port
+	SMTPFrom       string
+	SMTPUser       string
+	SMTPPassword   string
+	DigestInterval time.Duration
+
+	// Listen holds extra chat addresses served alongside the one passed to
+	// Start, e.g. "[::1]:8989"
+	Listen []string
+
+	// ProxyProtocol expects a HAProxy PROXY v1/v2 header on every chat
+	// connection; ProxyTrusted limits which peers may send one
+	ProxyProtocol bool
+	ProxyTrusted  []string
+
+	// HTTPAddr enables the HTTP gateway (feeds) when set, e.g. ":8080"
+	HTTPAddr string
+
+	// WSAddr enables the WebSocket gateway for browser clients, e.g. ":8081"
+	WSAddr string
+
+	// IRCAddr enables the IRC gateway, e.g. ":6667"
+	IRCAddr string
+
+	// SSHAddr enables the SSH gateway, e.g. ":2222" (needs -tags ssh);
+	// SSHHostKey is created on first start if missing
+	SSHAddr    string
+	SSHHostKey string
+
+	// WebhookToken enables POST /hooks/<room> on the HTTP gateway; posted
+	// messages appear from WebhookName
+	WebhookToken string
+	WebhookName  string
+
+	// PublicRooms are exposed read-only as RSS/Atom feeds
+	PublicRooms []string
+
+	// Announce advertises the server on the local network via mDNS
+	Announce bool
+	// Beacon multicasts the server's name and port every few seconds
+	Beacon     bool
+	ServerName string
+
+	// UITheme picks the colors of the server's terminal UI (-ui): a preset
+	// (default, light or mono) or one of UIThemes
+	UITheme  string
+	UIThemes map[string]Theme
+
+	// AdminAddr enables the private admin HTTP listener, e.g. 127.0.0.1:6060
+	AdminAddr string
+	// AdminToken enables the REST API under /api/ on the admin port;
+	// requests must send it as a bearer token
+	AdminToken string
+	// ConsoleAddr enables the unauthenticated admin console on a loopback
+	// host:port or a unix socket given as unix:/path/to/socket
+	ConsoleAddr string
+	// EnablePprof exposes net/http/pprof under /debug/pprof/ on the admin port
+	EnablePprof bool
+}
+
+// DefaultConfig returns the settings used by NewServer
+func DefaultConfig() *Config {
+	return &Config{
+		MaxClients:        10,
+		ClusterPrefix:     "tcpchat",
+		AccountsFile:      "accounts.json",
+		AuthMaxAttempts:   3,
+		IdentifyTimeout:   time.Minute,
+		ChallengeTimeout:  30 * time.Second,
+		HistoryLimit:      defaultHistoryLimit,
+		HistoryReplay:     50,
+		RoomsFile:         "rooms.json",
+		ExportDir:         "exports",
+		RetentionInterval: time.Hour,
+		SSHHostKey:        "ssh_host_key",
+		WebhookName:       "Webhook",
+		BanFile:           "bans.txt",
+		LogFile:           "chat.log",
+		AuditFile:         "audit.log",
+		FloodBurst:        10,
+		FloodWindow:       2 * time.Second,
+		FloodWarnings:     1,
+		BotFloodBurst:     50,
+		BotFloodWindow:    2 * time.Second,
+		IdleWarning:       time.Minute,
+		SanitizePolicy:    SanitizeStrip,
+		DigestInterval:    time.Hour,
+	}
+}
+
+// lobby returns the room new clients join
+func (s *Server) lobby() string {
+	if s.config.Lobby == "" {
+		return "general"
+	}
+	return s.config.Lobby
+}
@@ -0,0 +1,427 @@
+package chat
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A config file holds the server's settings in TOML, so deployments do not
+// have to spell everything out on the command line:
+//
+//	port = 8989
+//	listen = ["[::1]:8989"]
+//	max_clients = 50
+//	default_rooms = ["dev", "random"]
+//	motd = "motd.txt"
+//
+//	[log]
+//	file = "/var/log/tcpchat/chat.log"
+//	level = "warn"
+//
+//	[tls]
+//	cert = "cert.pem"
+//	key = "key.pem"
+//
+//	[limits]
+//	flood_burst = 20
+//	idle_timeout = "30m"
+//
+// Only the part of TOML settings need is understood: tables, and strings,
+// integers, booleans and arrays of them as values. Durations are strings
+// like "90s" or "1h30m".
+
+// configSetting applies one value of the config file to a Config
+type configSetting func(c *Config, v any) error
+
+// configSettings maps the keys of the config file, prefixed with their
+// table, to the settings they set. The port is not among them, since it
+// is passed to Start rather than kept in the Config.
+var configSettings = map[string]configSetting{
+	"listen":           stringsSetting(func(c *Config) *[]string { return &c.Listen }),
+	"max_clients":      intSetting(func(c *Config) *int { return &c.MaxClients }),
+	"max_conns_per_ip": intSetting(func(c *Config) *int { return &c.MaxConnsPerIP }),
+	"lobby":            stringSetting(func(c *Config) *string { return &c.Lobby }),
+	"default_rooms":    stringsSetting(func(c *Config) *[]string { return &c.DefaultRooms }),
+	"persistent_rooms": stringsSetting(func(c *Config) *[]string { return &c.PersistentRooms }),
+	"motd":             stringSetting(func(c *Config) *string { return &c.MOTDFile }),
+	"server_name":      stringSetting(func(c *Config) *string { return &c.ServerName }),
+	"console":          stringSetting(func(c *Config) *string { return &c.ConsoleAddr }),
+
+	"log.file":    stringSetting(func(c *Config) *string { return &c.LogFile }),
+	"log.audit":   stringSetting(func(c *Config) *string { return &c.AuditFile }),
+	"log.history": stringSetting(func(c *Config) *string { return &c.HistoryFile }),
+	"log.level":   stringSetting(func(c *Config) *string { return &c.LogLevel }),
+	"log.format":  stringSetting(func(c *Config) *string { return &c.LogFormat }),
+
+	"tls.cert":     stringSetting(func(c *Config) *string { return &c.TLSCert }),
+	"tls.key":      stringSetting(func(c *Config) *string { return &c.TLSKey }),
+	"tls.optional": boolSetting(func(c *Config) *bool { return &c.TLSOptional }),
+
+	"limits.flood_burst":       intSetting(func(c *Config) *int { return &c.FloodBurst }),
+	"limits.flood_window":      durationSetting(func(c *Config) *time.Duration { return &c.FloodWindow }),
+	"limits.flood_warnings":    intSetting(func(c *Config) *int { return &c.FloodWarnings }),
+	"limits.bot_flood_burst":   intSetting(func(c *Config) *int { return &c.BotFloodBurst }),
+	"limits.bot_flood_window":  durationSetting(func(c *Config) *time.Duration { return &c.BotFloodWindow }),
+	"limits.idle_timeout":      durationSetting(func(c *Config) *time.Duration { return &c.IdleTimeout }),
+	"limits.idle_warning":      durationSetting(func(c *Config) *time.Duration { return &c.IdleWarning }),
+	"limits.auto_away":         durationSetting(func(c *Config) *time.Duration { return &c.AutoAway }),
+	"limits.room_idle_timeout": durationSetting(func(c *Config) *time.Duration { return &c.RoomIdleTimeout }),
+	"limits.history_limit":     intSetting(func(c *Config) *int { return &c.HistoryLimit }),
+	"limits.history_replay":    intSetting(func(c *Config) *int { return &c.HistoryReplay }),
+
+	"ui.theme": stringSetting(func(c *Config) *string { return &c.UITheme }),
+	"ui.themes": func(c *Config, v any) error {
+		path, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("expected a string")
+		}
+		themes, err := LoadThemes(path)
+		if err != nil {
+			return err
+		}
+		c.UIThemes = themes
+		return nil
+	},
+}
+
+func stringSetting(field func(*Config) *string) configSetting {
+	return func(c *Config, v any) error {
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("expected a string")
+		}
+		*field(c) = s
+		return nil
+	}
+}
+
+func stringsSetting(field func(*Config) *[]string) configSetting {
+	return func(c *Config, v any) error {
+		values, ok := v.([]any)
+		if !ok {
+			return fmt.Errorf("expected an array of strings")
+		}
+		strs := make([]string, len(values))
+		for i, value := range values {
+			if strs[i], ok = value.(string); !ok {
+				return fmt.Errorf("expected an array of strings")
+			}
+		}
+		*field(c) = strs
+		return nil
+	}
+}
+
+func intSetting(field func(*Config) *int) configSetting {
+	return func(c *Config, v any) error {
+		n, ok := v.(int64)
+		if !ok {
+			return fmt.Errorf("expected an integer")
+		}
+		*field(c) = int(n)
+		return nil
+	}
+}
+
+func boolSetting(field func(*Config) *bool) configSetting {
+	return func(c *Config, v any) error {
+		b, ok := v.(bool)
+		if !ok {
+			return fmt.Errorf("expected true or false")
+		}
+		*field(c) = b
+		return nil
+	}
+}
+
+func durationSetting(field func(*Config) *time.Duration) configSetting {
+	return func(c *Config, v any) error {
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("expected a duration like \"90s\"")
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		*field(c) = d
+		return nil
+	}
+}
+
+// LoadConfigFile applies the settings of a config file to config and
+// returns the port it names, "" if none
+func LoadConfigFile(path string, config *Config) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	values, err := parseTOML(string(data))
+	if err != nil {
+		return "", fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+
+	port := ""
+	for _, kv := range values {
+		if kv.key == "port" {
+			switch p := kv.value.(type) {
+			case int64:
+				port = strconv.FormatInt(p, 10)
+			case string:
+				port = p
+			default:
+				return "", fmt.Errorf("invalid config file %s: line %d: port: expected a number", path, kv.line)
+			}
+			continue
+		}
+		set, ok := configSettings[kv.key]
+		if !ok {
+			return "", fmt.Errorf("invalid config file %s: line %d: unknown setting %s", path, kv.line, kv.key)
+		}
+		if err := set(config, kv.value); err != nil {
+			return "", fmt.Errorf("invalid config file %s: line %d: %s: %v", path, kv.line, kv.key, err)
+		}
+	}
+	if err := checkLimits(config); err != nil {
+		return "", fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+	return port, nil
+}
+
+// checkLimits rejects limits the server cannot run with, such as a client
+// limit below 1 that turns everyone away
+func checkLimits(c *Config) error {
+	switch {
+	case c.MaxClients < 1:
+		return fmt.Errorf("max_clients must be at least 1")
+	case c.MaxConnsPerIP < 0:
+		return fmt.Errorf("max_conns_per_ip must not be negative")
+	case c.FloodBurst < 0 || c.FloodWarnings < 0 || c.BotFloodBurst < 0:
+		return fmt.Errorf("flood limits must not be negative")
+	case c.FloodBurst > 0 && c.FloodWindow <= 0, c.BotFloodBurst > 0 && c.BotFloodWindow <= 0:
+		return fmt.Errorf("flood windows must be positive")
+	}
+	return nil
+}
+
+// reloadConfigFile re-reads the config file and applies the settings that
+// can change while the server runs: the client limits, the flood limits
+// and the room lists. Config.Overrides puts the command line back on top,
+// and a file with invalid limits changes nothing. Listeners, TLS, timers
+// and the rest keep their startup values until a restart.
+func (s *Server) reloadConfigFile() error {
+	s.mutex.RLock()
+	fresh := *s.config
+	s.mutex.RUnlock()
+	if _, err := LoadConfigFile(fresh.ConfigFile, &fresh); err != nil {
+		return err
+	}
+	if fresh.Overrides != nil {
+		fresh.Overrides(&fresh)
+		if err := checkLimits(&fresh); err != nil {
+			return err
+		}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	// A limit set with /setlimit stays unless the file changes it
+	if fresh.MaxClients != s.config.MaxClients {
+		s.config.MaxClients = fresh.MaxClients
+		s.maxClients = fresh.MaxClients
+	}
+	s.config.MaxConnsPerIP = fresh.MaxConnsPerIP
+	s.config.FloodBurst = fresh.FloodBurst
+	s.config.FloodWindow = fresh.FloodWindow
+	s.config.FloodWarnings = fresh.FloodWarnings
+	s.config.BotFloodBurst = fresh.BotFloodBurst
+	s.config.BotFloodWindow = fresh.BotFloodWindow
+	s.config.DefaultRooms = fresh.DefaultRooms
+	s.config.PersistentRooms = fresh.PersistentRooms
+	return nil
+}
+
+// tomlValue is a key of a TOML document, prefixed with its table, and its
+// value: a string, an int64, a bool or a []any of those
+type tomlValue struct {
+	key   string
+	value any
+	line  int
+}
+
+// parseTOML reads the keys of a TOML document in order
+func parseTOML(doc string) ([]tomlValue, error) {
+	var values []tomlValue
+	seen := make(map[string]bool)
+	table := ""
+	lines := strings.Split(doc, "\n")
+	for i := 0; i < len(lines); i++ {
+		lineNo := i + 1
+		line := strings.TrimSpace(stripTOMLComment(lines[i]))
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			name, ok := strings.CutSuffix(line, "]")
+			name = strings.TrimSpace(strings.TrimPrefix(name, "["))
+			if !ok || name == "" || strings.ContainsAny(name, "[]\"' ") {
+				return nil, fmt.Errorf("line %d: invalid table %s", lineNo, line)
+			}
+			table = name + "."
+			continue
+		}
+
+		key, raw, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t\"'") {
+			return nil, fmt.Errorf("line %d: expected key = value", lineNo)
+		}
+		raw = strings.TrimSpace(raw)
+		// Arrays may go on over several lines
+		for strings.HasPrefix(raw, "[") && !closesTOMLArray(raw) && i+1 < len(lines) {
+			i++
+			raw += " " + strings.TrimSpace(stripTOMLComment(lines[i]))
+		}
+		value, rest, err := parseTOMLValue(raw)
+		if err == nil && strings.TrimSpace(rest) != "" {
+			err = fmt.Errorf("unexpected %s", strings.TrimSpace(rest))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %v", lineNo, key, err)
+		}
+		key = table + key
+		if seen[key] {
+			return nil, fmt.Errorf("line %d: %s is set twice", lineNo, key)
+		}
+		seen[key] = true
+		values = append(values, tomlValue{key: key, value: value, line: lineNo})
+	}
+	return values, nil
+}
+
+// parseTOMLValue reads the value at the start of s and returns what
+// follows it
+func parseTOMLValue(s string) (any, string, error) {
+	switch {
+	case s == "":
+		return nil, "", fmt.Errorf("missing value")
+	case s[0] == '"':
+		return parseTOMLString(s)
+	case s[0] == '\'':
+		end := strings.IndexByte(s[1:], '\'')
+		if end < 0 {
+			return nil, "", fmt.Errorf("unterminated string")
+		}
+		return s[1 : end+1], s[end+2:], nil
+	case s[0] == '[':
+		values := []any{}
+		rest := strings.TrimSpace(s[1:])
+		for !strings.HasPrefix(rest, "]") {
+			value, after, err := parseTOMLValue(rest)
+			if err != nil {
+				return nil, "", err
+			}
+			values = append(values, value)
+			rest = strings.TrimSpace(after)
+			if strings.HasPrefix(rest, ",") {
+				rest = strings.TrimSpace(rest[1:])
+			} else if !strings.HasPrefix(rest, "]") {
+				return nil, "", fmt.Errorf("expected , or ] in array")
+			}
+		}
+		return values, rest[1:], nil
+	}
+
+	end := strings.IndexAny(s, ",] \t")
+	if end < 0 {
+		end = len(s)
+	}
+	word, rest := s[:end], s[end:]
+	switch word {
+	case "true":
+		return true, rest, nil
+	case "false":
+		return false, rest, nil
+	}
+	n, err := strconv.ParseInt(strings.ReplaceAll(word, "_", ""), 10, 64)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid value %s", word)
+	}
+	return n, rest, nil
+}
+
+// parseTOMLString reads a basic string, resolving its escapes
+func parseTOMLString(s string) (any, string, error) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"':
+			return b.String(), s[i+1:], nil
+		case '\\':
+			i++
+			if i == len(s) {
+				return nil, "", fmt.Errorf("unterminated string")
+			}
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case '"', '\\':
+				b.WriteByte(s[i])
+			default:
+				return nil, "", fmt.Errorf("invalid escape \\%c", s[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return nil, "", fmt.Errorf("unterminated string")
+}
+
+// stripTOMLComment cuts a # comment off line, leaving # within strings
+func stripTOMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// closesTOMLArray reports whether the brackets opened in s outside strings
+// are all closed
+func closesTOMLArray(s string) bool {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		}
+	}
+	return depth <= 0
+}
@@ -0,0 +1,123 @@
+package chat
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTOML(t *testing.T) {
+	doc := `# Comments and blank lines are skipped
+name = "a \"quoted\" # not a comment" # a comment
+path = 'C:\logs'
+count = 1_000
+on = true
+rooms = [
+  "dev",   # trailing comments in arrays
+  "ops",
+]
+
+[limits]
+window = "2s"
+empty = []
+`
+	values, err := parseTOML(doc)
+	if err != nil {
+		t.Fatalf("parseTOML failed: %v", err)
+	}
+	want := []tomlValue{
+		{"name", `a "quoted" # not a comment`, 2},
+		{"path", `C:\logs`, 3},
+		{"count", int64(1000), 4},
+		{"on", true, 5},
+		{"rooms", []any{"dev", "ops"}, 6},
+		{"limits.window", "2s", 12},
+		{"limits.empty", []any{}, 13},
+	}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("Expected %v, got %v", want, values)
+	}
+
+	for _, bad := range []string{
+		"name",
+		"name = ",
+		`name = "open`,
+		"count = 12 13",
+		"rooms = [\"a\" \"b\"]",
+		"[limits",
+		"a = 1\na = 2",
+	} {
+		if _, err := parseTOML(bad); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chat.toml")
+	os.WriteFile(path, []byte(`port = 9999
+listen = ["[::1]:9999"]
+max_clients = 50
+default_rooms = ["dev", "random"]
+motd = "motd.txt"
+
+[log]
+file = "server.log"
+level = "warn"
+
+[tls]
+cert = "cert.pem"
+key = "key.pem"
+optional = true
+
+[limits]
+flood_burst = 20
+idle_timeout = "30m"
+
+[ui]
+theme = "mono"
+`), 0o600)
+
+	config := DefaultConfig()
+	port, err := LoadConfigFile(path, config)
+	if err != nil {
+		t.Fatalf("LoadConfigFile failed: %v", err)
+	}
+	if port != "9999" {
+		t.Errorf("Expected port 9999, got %q", port)
+	}
+	if config.MaxClients != 50 || config.MOTDFile != "motd.txt" || config.LogFile != "server.log" || config.LogLevel != "warn" {
+		t.Errorf("Settings not applied: %+v", config)
+	}
+	if !reflect.DeepEqual(config.Listen, []string{"[::1]:9999"}) || !reflect.DeepEqual(config.DefaultRooms, []string{"dev", "random"}) {
+		t.Errorf("Lists not applied: %v, %v", config.Listen, config.DefaultRooms)
+	}
+	if config.TLSCert != "cert.pem" || config.TLSKey != "key.pem" || !config.TLSOptional {
+		t.Errorf("TLS settings not applied: %+v", config)
+	}
+	if config.FloodBurst != 20 || config.IdleTimeout != 30*time.Minute || config.UITheme != "mono" {
+		t.Errorf("Limits not applied: %+v", config)
+	}
+	if config.FloodWindow != DefaultConfig().FloodWindow {
+		t.Errorf("Expected settings left out to keep their defaults, got %v", config.FloodWindow)
+	}
+
+	for doc, want := range map[string]string{
+		"max_clients = \"ten\"":           "line 1: max_clients: expected an integer",
+		"\n[limits]\nflood = 3":           "line 3: unknown setting limits.flood",
+		"[limits]\nidle_timeout = 5":      "line 2: limits.idle_timeout: expected a duration",
+		"port = true":                     "line 1: port: expected a number",
+		"max_clients = 0":                 "max_clients must be at least 1",
+		"max_conns_per_ip = -1":           "max_conns_per_ip must not be negative",
+		"[limits]\nflood_burst = -5":      "flood limits must not be negative",
+		"[limits]\nflood_window = \"0s\"": "flood windows must be positive",
+	} {
+		os.WriteFile(path, []byte(doc), 0o600)
+		if _, err := LoadConfigFile(path, DefaultConfig()); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected an error containing %q for %q, got %v", want, doc, err)
+		}
+	}
+}
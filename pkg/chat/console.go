@@ -0,0 +1,169 @@
+package chat
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// consoleCommands are the chat commands available on the admin console
+var consoleCommands = map[string]bool{
+	"kick":     true,
+	"ban":      true,
+	"unban":    true,
+	"mute":     true,
+	"unmute":   true,
+	"promote":  true,
+	"demote":   true,
+	"stats":    true,
+	"auditlog": true,
+	"export":   true,
+	"bottoken": true,
+	"shutdown": true,
+	"setlimit": true,
+	"reload":   true,
+}
+
+const consoleHelp = `Admin console commands:
+conns                    - List open chat connections
+kick <user> [reason]     - Disconnect a user
+ban <user|ip> [reason]   - Ban an address
+unban <ip>               - Lift a ban
+mute <user> <duration> [room], unmute <user>
+promote <user> <role>, demote <user> [role]
+stats, auditlog [count], bottoken add|revoke|list
+setlimit [clients]       - Show or change the client limit
+reload                   - Re-read bans, accounts, rooms and MOTD
+export <room> [json|csv] - Write a room's history to a file
+shutdown                 - Stop the server
+quit                     - Close the console
+`
+
+// listenConsole opens the admin console listener. addr is either
+// "unix:<path>" or a loopback host:port; other addresses are refused
+// because the console skips authentication.
+func listenConsole(addr string) (net.Listener, error) {
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+		os.Remove(path) // Stale socket from an unclean exit
+		l, err := net.Listen("unix", path)
+		if err != nil {
+			return nil, err
+		}
+		os.Chmod(path, 0o600)
+		return l, nil
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return nil, fmt.Errorf("console address %s is not a loopback address", addr)
+	}
+	return net.Listen("tcp", addr)
+}
+
+// serveConsole accepts admin console sessions until the listener closes
+func (s *Server) serveConsole(ctx context.Context, addr string) {
+	listener, err := listenConsole(addr)
+	if err != nil {
+		s.log.Error("Admin console disabled", "addr", addr, "err", err)
+		return
+	}
+	defer listener.Close()
+	context.AfterFunc(ctx, func() { listener.Close() })
+	s.logActivity("Admin console listening", "addr", addr)
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() == nil {
+				s.log.Error("Admin console stopped", "addr", addr, "err", err)
+			}
+			return
+		}
+		go s.handleConsole(conn)
+	}
+}
+
+// handleConsole runs one console session. The operator acts as an owner
+// but never joins the chat, so they are not listed or sent chat traffic.
+func (s *Server) handleConsole(conn net.Conn) {
+	defer conn.Close()
+	operator := &Client{conn: conn, name: "console", joinTime: time.Now(), role: RoleOwner}
+	s.logActivity("Admin console session opened")
+
+	conn.Write([]byte("TCP-Chat admin console. Type help for commands.\n> "))
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		line := strings.TrimPrefix(strings.TrimSpace(scanner.Text()), "/")
+		fields := strings.Fields(line)
+		if len(fields) > 0 {
+			switch command := strings.ToLower(fields[0]); {
+			case command == "quit" || command == "exit":
+				s.logActivity("Admin console session closed")
+				return
+			case command == "help":
+				conn.Write([]byte(consoleHelp))
+			case command == "conns":
+				conn.Write([]byte(s.describeConnections()))
+			case consoleCommands[command]:
+				s.handleCommand(operator, "/"+command+" "+strings.Join(fields[1:], " "))
+			default:
+				conn.Write([]byte("Unknown command. Type help for commands.\n"))
+			}
+		}
+		conn.Write([]byte("> "))
+	}
+	s.logActivity("Admin console session closed")
+}
+
+// describeConnections lists every chat connection for the console
+func (s *Server) describeConnections() string {
+	now := time.Now()
+	s.mutex.RLock()
+	lines := make([]string, 0, s.clients.len())
+	s.clients.each(func(conn net.Conn, c *Client) bool {
+		lines = append(lines, fmt.Sprintf("%-20s %-22s room=%s role=%s connected=%s idle=%s",
+			c.name, conn.RemoteAddr(), c.room, c.role,
+			now.Sub(c.joinTime).Round(time.Second), now.Sub(c.lastActive).Round(time.Second)))
+		return true
+	})
+	s.mutex.RUnlock()
+
+	sort.Strings(lines)
+	return fmt.Sprintf("Connections (%d):\n%s\n", len(lines), strings.Join(lines, "\n"))
+}
+
+// RunConsoleCommand runs one command on the admin console listening on
+// addr, "unix:<path>" or host:port, and returns the console's answer
+func RunConsoleCommand(addr, command string) (string, error) {
+	network, address := "tcp", addr
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+		network, address = "unix", path
+	}
+	conn, err := net.DialTimeout(network, address, 5*time.Second)
+	if err != nil {
+		return "", fmt.Errorf("failed to reach the admin console: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(30 * time.Second))
+
+	if _, err := fmt.Fprintf(conn, "%s\nquit\n", command); err != nil {
+		return "", fmt.Errorf("failed to send to the admin console: %v", err)
+	}
+	data, err := io.ReadAll(conn)
+	if err != nil {
+		return "", fmt.Errorf("failed to read from the admin console: %v", err)
+	}
+	// The answer is between the prompt after the banner and the one
+	// before quit
+	_, answer, _ := strings.Cut(string(data), "> ")
+	return strings.TrimSuffix(answer, "> "), nil
+}
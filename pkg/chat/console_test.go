@@ -0,0 +1,90 @@
+package chat
+
+import (
+	"bufio"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConsoleRequiresLoopback(t *testing.T) {
+	if _, err := listenConsole("0.0.0.0:0"); err == nil {
+		t.Error("console accepted a non-loopback address")
+	}
+	l, err := listenConsole("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("loopback console failed: %v", err)
+	}
+	l.Close()
+}
+
+func TestAdminConsole(t *testing.T) {
+	config := DefaultConfig()
+	config.ConsoleAddr = "unix:" + filepath.Join(t.TempDir(), "admin.sock")
+	addr := setupTestServerWithConfig(t, config)
+
+	alice, err := newTestClient(t, addr)
+	if err != nil {
+		t.Fatalf("Client connection failed: %v", err)
+	}
+	defer alice.close()
+	alice.expectMessage(t, "Welcome")
+	alice.sendMessage("Alice")
+	alice.expectMessage(t, "joined")
+
+	var conn net.Conn
+	for i := 0; i < 20; i++ {
+		if conn, err = net.Dial("unix", config.ConsoleAddr[len("unix:"):]); err == nil {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("Console connection failed: %v", err)
+	}
+	console := &TestClient{conn: conn, reader: bufio.NewReader(conn)}
+	defer console.close()
+
+	console.sendMessage("conns")
+	if err := console.expectMessage(t, "Alice"); err != nil {
+		t.Fatalf("conns did not list the user: %v", err)
+	}
+
+	console.sendMessage("join general")
+	if err := console.expectMessage(t, "Unknown command"); err != nil {
+		t.Errorf("Chat command allowed on the console: %v", err)
+	}
+
+	console.sendMessage("kick Alice maintenance")
+	if err := alice.expectMessage(t, "kicked by console: maintenance"); err != nil {
+		t.Fatalf("Console kick failed: %v", err)
+	}
+}
+
+func TestRunConsoleCommand(t *testing.T) {
+	config := DefaultConfig()
+	config.ConsoleAddr = "unix:" + filepath.Join(t.TempDir(), "admin.sock")
+	setupTestServerWithConfig(t, config)
+
+	var answer string
+	var err error
+	for i := 0; i < 20; i++ {
+		if answer, err = RunConsoleCommand(config.ConsoleAddr, "conns"); err == nil {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("RunConsoleCommand failed: %v", err)
+	}
+	if !strings.Contains(answer, "Connections (") || strings.Contains(answer, "> ") {
+		t.Errorf("Expected only the connection list, got %q", answer)
+	}
+
+	answer, err = RunConsoleCommand(config.ConsoleAddr, "join general")
+	if err != nil || !strings.Contains(answer, "Unknown command") {
+		t.Errorf("Expected Unknown command, got %q, %v", answer, err)
+	}
+}
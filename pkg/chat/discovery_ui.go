@@ -0,0 +1,155 @@
+package chat
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jroimartin/gocui"
+)
+
+const discoveryView = "servers"
+
+// discoveryUI lists chat servers announced on the local network
+type discoveryUI struct {
+	gui      *gocui.Gui
+	mutex    sync.Mutex
+	servers  []DiscoveredServer
+	selected string
+	status   string
+}
+
+// RunDiscoveryUI browses the local network for servers and returns the
+// address picked by the user, or "" if they quit without choosing
+func RunDiscoveryUI() (string, error) {
+	g, err := gocui.NewGui(gocui.OutputNormal)
+	if err != nil {
+		return "", err
+	}
+	defer g.Close()
+
+	d := &discoveryUI{gui: g, status: "Searching..."}
+	g.SetManagerFunc(d.layout)
+	if err := d.keybindings(); err != nil {
+		return "", err
+	}
+
+	go d.browse()
+
+	if err := g.MainLoop(); err != nil && err != gocui.ErrQuit {
+		return "", err
+	}
+	return d.selected, nil
+}
+
+func (d *discoveryUI) browse() {
+	for {
+		err := DiscoverServers(5*time.Second, func(server DiscoveredServer) {
+			d.mutex.Lock()
+			known := false
+			for _, s := range d.servers {
+				known = known || s.Addr == server.Addr
+			}
+			if !known {
+				d.servers = append(d.servers, server)
+			}
+			d.mutex.Unlock()
+			d.gui.Update(d.render)
+		})
+
+		d.mutex.Lock()
+		if err != nil {
+			d.status = fmt.Sprintf("Discovery failed: %v", err)
+		} else {
+			d.status = fmt.Sprintf("%d server(s) found, still listening...", len(d.servers))
+		}
+		d.mutex.Unlock()
+		d.gui.Update(d.render)
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (d *discoveryUI) layout(g *gocui.Gui) error {
+	maxX, maxY := g.Size()
+	if v, err := g.SetView(discoveryView, 0, 0, maxX-1, maxY-1); err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+		v.Title = "TCPChat servers on this network | Enter: connect | Ctrl-C: quit"
+		v.Highlight = true
+		v.SelBgColor = gocui.ColorGreen
+		v.SelFgColor = gocui.ColorBlack
+		if _, err := g.SetCurrentView(discoveryView); err != nil {
+			return err
+		}
+		return d.render(g)
+	}
+	return nil
+}
+
+func (d *discoveryUI) render(g *gocui.Gui) error {
+	v, err := g.View(discoveryView)
+	if err != nil {
+		return err
+	}
+	v.Clear()
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	for _, server := range d.servers {
+		fmt.Fprintf(v, "%-30s %s\n", server.Name, server.Addr)
+	}
+	fmt.Fprintf(v, "\n%s\n", d.status)
+	return nil
+}
+
+func (d *discoveryUI) keybindings() error {
+	if err := d.gui.SetKeybinding("", gocui.KeyCtrlC, gocui.ModNone,
+		func(_ *gocui.Gui, _ *gocui.View) error {
+			return gocui.ErrQuit
+		}); err != nil {
+		return err
+	}
+
+	if err := d.gui.SetKeybinding(discoveryView, gocui.KeyArrowDown, gocui.ModNone,
+		func(_ *gocui.Gui, v *gocui.View) error {
+			return d.moveCursor(v, 1)
+		}); err != nil {
+		return err
+	}
+
+	if err := d.gui.SetKeybinding(discoveryView, gocui.KeyArrowUp, gocui.ModNone,
+		func(_ *gocui.Gui, v *gocui.View) error {
+			return d.moveCursor(v, -1)
+		}); err != nil {
+		return err
+	}
+
+	return d.gui.SetKeybinding(discoveryView, gocui.KeyEnter, gocui.ModNone,
+		func(_ *gocui.Gui, v *gocui.View) error {
+			_, y := v.Cursor()
+			d.mutex.Lock()
+			defer d.mutex.Unlock()
+			if y < len(d.servers) {
+				d.selected = d.servers[y].Addr
+				return gocui.ErrQuit
+			}
+			return nil
+		})
+}
+
+func (d *discoveryUI) moveCursor(v *gocui.View, delta int) error {
+	_, y := v.Cursor()
+	d.mutex.Lock()
+	count := len(d.servers)
+	d.mutex.Unlock()
+
+	y += delta
+	if y < 0 || y >= count {
+		return nil
+	}
+	return v.SetCursor(0, y)
+}
@@ -0,0 +1,17 @@
+// Package chat is the TCP-Chat server. The netcat binary is a thin command
+// line around it; other Go programs can embed the server the same way:
+//
+//	server := chat.New(
+//		chat.WithMaxClients(50),
+//		chat.WithStore(chat.NewMemoryStore()),
+//		chat.WithLogger(slog.New(slog.NewJSONHandler(os.Stderr, nil))),
+//	)
+//	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+//	defer stop()
+//	if err := server.StartContext(ctx, "127.0.0.1:8989"); err != nil {
+//		log.Fatal(err)
+//	}
+//
+// Settings without an option of their own are set on a Config passed with
+// WithConfig.
+package chat
@@ -0,0 +1,282 @@
+package chat
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	emailNotifyImmediate = "immediate"
+	emailNotifyDigest    = "digest"
+)
+
+// emailCooldown is the least time between two immediate notifications to
+// one address, and between two verification emails for one account
+const emailCooldown = time.Minute
+
+var mentionPattern = regexp.MustCompile(`@([^\s@,.:;!?]+)`)
+
+// emailNotifier sends offline activity notifications over SMTP
+type emailNotifier struct {
+	config *Config
+	send   func(to, subject, body string) error
+	log    *slog.Logger
+
+	cooldown time.Duration
+
+	mutex     sync.Mutex
+	digests   map[string][]string  // Address -> pending lines
+	immediate map[string][]string  // Address -> lines waiting out the cooldown
+	lastSent  map[string]time.Time // Address -> last immediate notification
+	verifies  map[string]time.Time // Lowercase account -> last verification email
+}
+
+func newEmailNotifier(config *Config, logger *slog.Logger) *emailNotifier {
+	n := &emailNotifier{
+		config:    config,
+		log:       logger,
+		cooldown:  emailCooldown,
+		digests:   make(map[string][]string),
+		immediate: make(map[string][]string),
+		lastSent:  make(map[string]time.Time),
+		verifies:  make(map[string]time.Time),
+	}
+	n.send = n.sendSMTP
+	return n
+}
+
+func (n *emailNotifier) enabled() bool {
+	return n.config.SMTPAddr != ""
+}
+
+func (n *emailNotifier) sendSMTP(to, subject, body string) error {
+	var auth smtp.Auth
+	if n.config.SMTPUser != "" {
+		host, _, _ := net.SplitHostPort(n.config.SMTPAddr)
+		auth = smtp.PlainAuth("", n.config.SMTPUser, n.config.SMTPPassword, host)
+	}
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s\r\n",
+		n.config.SMTPFrom, to, subject, body)
+	return smtp.SendMail(n.config.SMTPAddr, auth, n.config.SMTPFrom, []string{to}, []byte(msg))
+}
+
+// notify emails the account owner or queues the line for their digest
+func (n *emailNotifier) notify(a Account, line string) {
+	if !n.enabled() || !a.EmailVerified {
+		return
+	}
+	switch a.EmailNotify {
+	case emailNotifyImmediate:
+		n.mutex.Lock()
+		defer n.mutex.Unlock()
+		pending := n.immediate[a.Email]
+		n.immediate[a.Email] = append(pending, line)
+		if len(pending) == 0 {
+			// The first line schedules the mail; later ones join it
+			wait := time.Until(n.lastSent[a.Email].Add(n.cooldown))
+			time.AfterFunc(max(wait, 0), func() { n.sendImmediate(a.Name, a.Email) })
+		}
+	case emailNotifyDigest:
+		n.mutex.Lock()
+		n.digests[a.Email] = append(n.digests[a.Email], line)
+		n.mutex.Unlock()
+	}
+}
+
+// sendImmediate mails the lines queued for to in one message
+func (n *emailNotifier) sendImmediate(name, to string) {
+	n.mutex.Lock()
+	lines := n.immediate[to]
+	delete(n.immediate, to)
+	n.lastSent[to] = time.Now()
+	n.mutex.Unlock()
+
+	subject := "New activity on TCP-Chat"
+	if len(lines) > 1 {
+		subject = fmt.Sprintf("TCP-Chat: %d new notifications", len(lines))
+	}
+	if err := n.send(to, subject, strings.Join(lines, "\r\n")); err != nil {
+		n.log.Warn("Email failed", "client", name, "to", to, "err", err)
+	}
+}
+
+// allowVerification reports how long name must wait before another
+// verification email, recording the send when it may go now
+func (n *emailNotifier) allowVerification(name string) time.Duration {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	key := strings.ToLower(name)
+	if wait := time.Until(n.verifies[key].Add(n.cooldown)); wait > 0 {
+		// Round up so the wait never reads as zero
+		return (wait + time.Second - 1).Truncate(time.Second)
+	}
+	n.verifies[key] = time.Now()
+	return 0
+}
+
+// digestLoop periodically flushes the queued digest emails until ctx is
+// cancelled
+func (n *emailNotifier) digestLoop(ctx context.Context) {
+	ticker := time.NewTicker(n.config.DigestInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		n.mutex.Lock()
+		digests := n.digests
+		n.digests = make(map[string][]string)
+		n.mutex.Unlock()
+
+		for to, lines := range digests {
+			subject := fmt.Sprintf("TCP-Chat digest: %d new notifications", len(lines))
+			if err := n.send(to, subject, strings.Join(lines, "\r\n")); err != nil {
+				n.log.Warn("Digest email failed", "to", to, "err", err)
+			}
+		}
+	}
+}
+
+func newVerificationCode() string {
+	n, _ := rand.Int(rand.Reader, big.NewInt(1000000))
+	return fmt.Sprintf("%06d", n.Int64())
+}
+
+// notifyOffline emails an offline user with a verified, opted-in address.
+// It reports whether a notification was sent or queued.
+func (s *Server) notifyOffline(name, line string) bool {
+	if !s.emails.enabled() {
+		return false
+	}
+	a, ok := s.accounts.get(name)
+	if !ok || !a.EmailVerified || a.EmailNotify == "" {
+		return false
+	}
+	s.emails.notify(a, line)
+	return true
+}
+
+// notifyMentions emails offline users mentioned as @name in a room message.
+// Private rooms only notify their members, so that their messages are not
+// mailed to anyone who could not read them.
+func (s *Server) notifyMentions(room string, msg Message) {
+	for _, match := range mentionPattern.FindAllStringSubmatch(msg.Content, -1) {
+		name := match[1]
+		s.mutex.Lock()
+		online := s.isNameTaken(name)
+		r, exists := s.rooms[room]
+		allowed := exists && (r.public() || r.isMember(name))
+		s.mutex.Unlock()
+		if !online && allowed {
+			s.notifyOffline(name, fmt.Sprintf("%s mentioned you in %s: %s", msg.From, room, msg.Content))
+		}
+	}
+}
+
+// errEmailNotOwner refuses email settings to clients that have not proved
+// they own their nickname, which would otherwise receive its mail
+var errEmailNotOwner = errors.New("register your nickname and /identify before setting up email")
+
+func emailCommand(s *Server, c *Client, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: /email <address>")
+	}
+	if !s.ownsAccount(c) {
+		return errEmailNotOwner
+	}
+	if !s.emails.enabled() {
+		return fmt.Errorf("email notifications are not configured on this server")
+	}
+	addr, err := mail.ParseAddress(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid email address")
+	}
+	if wait := s.emails.allowVerification(c.name); wait > 0 {
+		return fmt.Errorf("wait %s before requesting another verification email", wait)
+	}
+
+	code := newVerificationCode()
+	err = s.accounts.update(c.name, func(a *Account) error {
+		a.Email = addr.Address
+		a.EmailVerified = false
+		a.EmailCode = code
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		body := fmt.Sprintf("Type /verify %s in the chat to confirm this address for %s.", code, c.name)
+		if err := s.emails.send(addr.Address, "Verify your TCP-Chat email", body); err != nil {
+			s.log.Warn("Verification email failed", "client", c.name, "to", addr.Address, "err", err)
+		}
+	}()
+	c.write([]byte(fmt.Sprintf("Verification code sent to %s\n", addr.Address)))
+	return nil
+}
+
+func verifyCommand(s *Server, c *Client, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: /verify <code>")
+	}
+	if !s.ownsAccount(c) {
+		return errEmailNotOwner
+	}
+	err := s.accounts.update(c.name, func(a *Account) error {
+		if a.EmailCode == "" || a.EmailCode != args[0] {
+			return fmt.Errorf("invalid verification code")
+		}
+		a.EmailVerified = true
+		a.EmailCode = ""
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	c.write([]byte("Email address verified\n"))
+	return nil
+}
+
+func emailNotifyCommand(s *Server, c *Client, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: /emailnotify off|immediate|digest")
+	}
+	if !s.ownsAccount(c) {
+		return errEmailNotOwner
+	}
+	mode := strings.ToLower(args[0])
+	switch mode {
+	case "off":
+		mode = ""
+	case emailNotifyImmediate, emailNotifyDigest:
+	default:
+		return fmt.Errorf("usage: /emailnotify off|immediate|digest")
+	}
+
+	err := s.accounts.update(c.name, func(a *Account) error {
+		if mode != "" && !a.EmailVerified {
+			return fmt.Errorf("verify an email address with /email first")
+		}
+		a.EmailNotify = mode
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	c.write([]byte(fmt.Sprintf("Email notifications: %s\n", args[0])))
+	return nil
+}
@@ -0,0 +1,203 @@
+package chat
+
+import (
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newEmailTestServer(t *testing.T) (*Server, chan string) {
+	config := DefaultConfig()
+	config.AccountsFile = ""
+	config.RoomsFile = ""
+	config.SMTPAddr = "localhost:25"
+	s := NewServerWithConfig(config)
+	t.Cleanup(func() { s.Logfile.Close() })
+
+	sent := make(chan string, 1)
+	s.emails.send = func(to, subject, body string) error {
+		sent <- to + ": " + body
+		return nil
+	}
+	return s, sent
+}
+
+func newPipeClient(t *testing.T, name string) *Client {
+	server, client := net.Pipe()
+	go io.Copy(io.Discard, client)
+	t.Cleanup(func() { server.Close(); client.Close() })
+	return &Client{conn: server, name: name, joinTime: time.Now()}
+}
+
+func TestOfflinePrivateMessageEmail(t *testing.T) {
+	s, sent := newEmailTestServer(t)
+	s.accounts.update("Alice", func(a *Account) error {
+		a.Email = "alice@example.com"
+		a.EmailVerified = true
+		a.EmailNotify = emailNotifyImmediate
+		return nil
+	})
+
+	if err := s.sendPrivateMessage(newPipeClient(t, "Bob"), "alice", "are you there?"); err != nil {
+		t.Fatalf("PM to offline opted-in user failed: %v", err)
+	}
+
+	select {
+	case got := <-sent:
+		if !strings.HasPrefix(got, "alice@example.com") || !strings.Contains(got, "are you there?") {
+			t.Errorf("unexpected email: %q", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no email sent")
+	}
+}
+
+func TestEmailRequiresVerification(t *testing.T) {
+	s, _ := newEmailTestServer(t)
+	s.accounts.update("Carol", func(a *Account) error {
+		a.PasswordHash = "registered"
+		a.Email = "carol@example.com"
+		a.EmailCode = "123456"
+		return nil
+	})
+
+	c := newPipeClient(t, "Carol")
+	c.identified = true
+	if err := emailNotifyCommand(s, c, []string{"immediate"}); err == nil {
+		t.Error("expected opt-in to fail before verification")
+	}
+	if err := verifyCommand(s, c, []string{"000000"}); err == nil {
+		t.Error("expected wrong code to be rejected")
+	}
+	if err := verifyCommand(s, c, []string{"123456"}); err != nil {
+		t.Fatalf("verify failed: %v", err)
+	}
+	if err := emailNotifyCommand(s, c, []string{"digest"}); err != nil {
+		t.Fatalf("opt-in failed: %v", err)
+	}
+
+	s.notifyMentions("general", Message{From: "Dave", Content: "ping @Carol"})
+	if lines := s.emails.digests["carol@example.com"]; len(lines) != 1 {
+		t.Errorf("expected one queued digest line, got %v", lines)
+	}
+}
+
+func TestEmailRequiresIdentifiedOwner(t *testing.T) {
+	s, sent := newEmailTestServer(t)
+	s.accounts.update("Erin", func(a *Account) error {
+		a.PasswordHash = "registered"
+		return nil
+	})
+
+	// Holding a registered nickname during the identify window, or any
+	// unregistered one, must not let a client take its mail
+	for _, c := range []*Client{newPipeClient(t, "Erin"), newPipeClient(t, "Frank")} {
+		if err := emailCommand(s, c, []string{"mallory@example.com"}); err != errEmailNotOwner {
+			t.Errorf("%s: expected /email to be refused, got %v", c.name, err)
+		}
+		if err := verifyCommand(s, c, []string{"123456"}); err != errEmailNotOwner {
+			t.Errorf("%s: expected /verify to be refused, got %v", c.name, err)
+		}
+		if err := emailNotifyCommand(s, c, []string{"immediate"}); err != errEmailNotOwner {
+			t.Errorf("%s: expected /emailnotify to be refused, got %v", c.name, err)
+		}
+	}
+	if account, _ := s.accounts.get("Erin"); account.Email != "" {
+		t.Errorf("address set without identifying: %q", account.Email)
+	}
+
+	erin := newPipeClient(t, "Erin")
+	erin.identified = true
+	if err := emailCommand(s, erin, []string{"erin@example.com"}); err != nil {
+		t.Fatalf("/email failed for the identified owner: %v", err)
+	}
+	select {
+	case got := <-sent:
+		if !strings.HasPrefix(got, "erin@example.com") {
+			t.Errorf("verification sent to the wrong address: %q", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no verification email sent")
+	}
+}
+
+func TestEmailCooldown(t *testing.T) {
+	s, sent := newEmailTestServer(t)
+	s.emails.cooldown = 200 * time.Millisecond
+	s.accounts.update("Erin", func(a *Account) error {
+		a.PasswordHash = "registered"
+		return nil
+	})
+	erin := newPipeClient(t, "Erin")
+	erin.identified = true
+	if err := emailCommand(s, erin, []string{"erin@example.com"}); err != nil {
+		t.Fatalf("/email failed: %v", err)
+	}
+	<-sent
+	if err := emailCommand(s, erin, []string{"victim@example.com"}); err == nil || !strings.Contains(err.Error(), "wait") {
+		t.Errorf("expected a repeated /email to wait, got %v", err)
+	}
+
+	// Immediate notifications within the cooldown go out as one mail
+	s.accounts.update("Alice", func(a *Account) error {
+		a.Email = "alice@example.com"
+		a.EmailVerified = true
+		a.EmailNotify = emailNotifyImmediate
+		return nil
+	})
+	mention := func(text string) {
+		s.notifyMentions("general", Message{From: "Bob", Content: "@Alice " + text})
+	}
+	expect := func(want ...string) {
+		t.Helper()
+		select {
+		case got := <-sent:
+			for _, w := range want {
+				if !strings.Contains(got, w) {
+					t.Errorf("email %q lacks %q", got, w)
+				}
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("no email with %v", want)
+		}
+	}
+	mention("one")
+	expect("@Alice one")
+	mention("two")
+	mention("three")
+	expect("@Alice two", "@Alice three")
+	select {
+	case got := <-sent:
+		t.Errorf("unexpected extra email: %q", got)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
+
+func TestMentionEmailPrivateRoom(t *testing.T) {
+	s, _ := newEmailTestServer(t)
+	s.accounts.update("Carol", func(a *Account) error {
+		a.Email = "carol@example.com"
+		a.EmailVerified = true
+		a.EmailNotify = emailNotifyDigest
+		return nil
+	})
+	room := newChatRoom("secret")
+	room.inviteOnly = true
+	s.mutex.Lock()
+	s.rooms["secret"] = room
+	s.mutex.Unlock()
+
+	s.notifyMentions("secret", Message{From: "Dave", Content: "ping @Carol"})
+	if lines := s.emails.digests["carol@example.com"]; len(lines) != 0 {
+		t.Errorf("private room mailed to a non-member: %v", lines)
+	}
+	s.mutex.Lock()
+	room.ops["carol"] = true
+	s.mutex.Unlock()
+	s.notifyMentions("secret", Message{From: "Dave", Content: "ping @Carol"})
+	if lines := s.emails.digests["carol@example.com"]; len(lines) != 1 {
+		t.Errorf("expected one queued digest line for a member, got %v", lines)
+	}
+}
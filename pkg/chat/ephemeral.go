@@ -0,0 +1,59 @@
+package chat
+
+import (
+	"fmt"
+	"time"
+)
+
+// errNoHistory is returned by the history commands of ephemeral rooms
+func errNoHistory(room string) error {
+	return fmt.Errorf("%s is ephemeral and keeps no history", room)
+}
+
+// isEphemeral reports whether messages of the named room are kept out of
+// the history. Caller holds s.mutex.
+func (s *Server) isEphemeral(name string) bool {
+	room, exists := s.rooms[name]
+	return exists && room.ephemeral
+}
+
+// ephemeralCommand turns history recording of the current room off or on.
+// Messages of an ephemeral room only ever reach the clients present on
+// this instance; bridges and other instances never see them.
+func ephemeralCommand(s *Server, c *Client, args []string) error {
+	if len(args) != 1 || (args[0] != "on" && args[0] != "off") {
+		return fmt.Errorf("usage: /ephemeral on|off")
+	}
+	s.mutex.Lock()
+	room, exists := s.rooms[c.room]
+	if !exists {
+		s.mutex.Unlock()
+		return fmt.Errorf("you are not in any room")
+	}
+	if !s.isRoomOperator(room, c) {
+		s.mutex.Unlock()
+		return fmt.Errorf("only room operators can change whether the room keeps history")
+	}
+	if room.name == s.lobby() {
+		s.mutex.Unlock()
+		return fmt.Errorf("%s always keeps history", room.name)
+	}
+	room.ephemeral = args[0] == "on"
+	if err := s.saveRooms(); err != nil {
+		s.log.Error("Error saving rooms", "room", room.name, "err", err)
+	}
+	s.mutex.Unlock()
+	s.publishRoomMode(room, room.ephemeral)
+
+	state := "ephemeral: new messages are not recorded or replayed"
+	if !room.ephemeral {
+		state = "record its history again"
+	}
+	s.logActivity("Room setting changed", "client", c.name, "room", room.name, "setting", state)
+	s.broadcastToRoom(room, Message{
+		Type:      MessageTypeSystem,
+		Content:   fmt.Sprintf("%s made the room %s", c.name, state),
+		Timestamp: time.Now(),
+	}, nil)
+	return nil
+}
@@ -0,0 +1,131 @@
+package chat
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEphemeralRoom(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountsFile = ""
+	config.RoomsFile = ""
+	config.Store = StoreMemory
+	s := NewServerWithConfig(config)
+	defer s.Logfile.Close()
+
+	var shown []Message
+	s.onRecord = func(room string, msg Message) { shown = append(shown, msg) }
+
+	alice := newPipeClient(t, "Alice")
+	if err := s.createRoom(alice, "secret", "", ""); err != nil {
+		t.Fatalf("createRoom failed: %v", err)
+	}
+	if err := ephemeralCommand(s, alice, []string{"on"}); err != nil {
+		t.Fatalf("ephemeral failed: %v", err)
+	}
+	before := len(shown)
+	if err := s.postChat(alice, Message{Type: MessageTypeChat, From: "Alice", Content: "off the record @Bob", Timestamp: time.Now()}); err != nil {
+		t.Fatalf("postChat failed: %v", err)
+	}
+
+	// Only Alice's join notice, recorded before the room went ephemeral
+	if history, _ := s.store.RecentMessages("secret", 0); len(history) != 1 {
+		t.Errorf("ephemeral room recorded %d messages: %v", len(history), history)
+	}
+	if len(shown) != before {
+		t.Errorf("ephemeral message passed to the UI: %v", shown[before:])
+	}
+	if err := historyCommand(s, alice, nil); err == nil {
+		t.Error("/history worked in an ephemeral room")
+	}
+	if err := searchCommand(s, alice, []string{"record"}); err == nil {
+		t.Error("/search worked in an ephemeral room")
+	}
+
+	bob := newPipeClient(t, "Bob")
+	s.mutex.Lock()
+	s.clients.add(bob.conn, bob)
+	s.mutex.Unlock()
+	s.deliverMentions("secret", Message{Type: MessageTypeChat, From: "Alice", Content: "@Bob psst", Timestamp: time.Now()})
+	if len(bob.mentions) != 0 {
+		t.Errorf("mention of an ephemeral room kept: %v", bob.mentions)
+	}
+
+	if err := ephemeralCommand(s, alice, []string{"off"}); err != nil {
+		t.Fatalf("ephemeral off failed: %v", err)
+	}
+	s.postChat(alice, Message{Type: MessageTypeChat, From: "Alice", Content: "on the record", Timestamp: time.Now()})
+	history, _ := s.store.RecentMessages("secret", 0)
+	if len(history) == 0 || history[len(history)-1].Content != "on the record" {
+		t.Errorf("history not recorded after ephemeral off: %v", history)
+	}
+}
+
+// recordingBus is a ClusterBus that keeps what is published to it
+type recordingBus struct {
+	events []ClusterEvent
+}
+
+func (b *recordingBus) Publish(ev ClusterEvent) error {
+	b.events = append(b.events, ev)
+	return nil
+}
+
+func (b *recordingBus) Subscribe(handler func(ev ClusterEvent)) error { return nil }
+
+func (b *recordingBus) Close() error { return nil }
+
+func TestEphemeralRoomStaysLocal(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountsFile = ""
+	config.RoomsFile = ""
+	config.Store = StoreMemory
+	s := NewServerWithConfig(config)
+	defer s.Logfile.Close()
+	bus := &recordingBus{}
+	s.cluster = bus
+	bridge := &roomBridge{queue: make(chan outboundBridgeMessage, 10)}
+	s.bridges = map[string][]*roomBridge{"secret": {bridge}}
+
+	alice := newPipeClient(t, "Alice")
+	if err := s.createRoom(alice, "secret", "", ""); err != nil {
+		t.Fatalf("createRoom failed: %v", err)
+	}
+	if err := ephemeralCommand(s, alice, []string{"on"}); err != nil {
+		t.Fatalf("ephemeral failed: %v", err)
+	}
+	bus.events = bus.events[:0]
+	if err := s.postChat(alice, Message{Type: MessageTypeChat, From: "Alice", Content: "off the record", Timestamp: time.Now()}); err != nil {
+		t.Fatalf("postChat failed: %v", err)
+	}
+
+	for _, ev := range bus.events {
+		if ev.Message.Content == "off the record" {
+			t.Errorf("ephemeral message published to the cluster: %+v", ev)
+		}
+	}
+	if len(bridge.queue) != 0 {
+		t.Errorf("ephemeral message relayed to a bridge: %+v", <-bridge.queue)
+	}
+	if alice.lastText != "" {
+		t.Errorf("ephemeral message kept for /seen: %q", alice.lastText)
+	}
+}
+
+func TestClusterRoomMode(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountsFile = ""
+	config.RoomsFile = ""
+	config.Store = StoreMemory
+	s := NewServerWithConfig(config)
+	defer s.Logfile.Close()
+
+	s.handleClusterEvent(ClusterEvent{Type: clusterRoomMode, Origin: "peer", Room: "secret", Ephemeral: true})
+	s.handleClusterEvent(ClusterEvent{Origin: "peer", Room: "secret", Message: Message{Type: MessageTypeChat, From: "Bob", Content: "psst"}})
+	if !s.isEphemeral("secret") {
+		t.Error("room not made ephemeral by a peer")
+	}
+	if history, _ := s.store.RecentMessages("secret", 0); len(history) != 0 {
+		t.Errorf("message of a peer's ephemeral room recorded: %v", history)
+	}
+}
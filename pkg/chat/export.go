@@ -0,0 +1,118 @@
+package chat
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// Export formats accepted by ExportMessages and /export
+const (
+	ExportJSON = "json"
+	ExportCSV  = "csv"
+)
+
+var messageTypeNames = map[int]string{
+	MessageTypeChat:     "chat",
+	MessageTypeSystem:   "system",
+	MessageTypePrivate:  "private",
+	MessageTypeError:    "error",
+	MessageTypeMention:  "mention",
+	MessageTypeReaction: "reaction",
+	MessageTypeReceipt:  "receipt",
+	MessageTypeKeyword:  "keyword",
+}
+
+// exportRecord is the structured form of a Message in exports
+type exportRecord struct {
+	Room      string    `json:"room"`
+	Type      string    `json:"type"`
+	From      string    `json:"from,omitempty"`
+	To        string    `json:"to,omitempty"`
+	Content   string    `json:"content"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// ExportMessages writes the messages of room to w as a JSON array or as
+// CSV with a header row
+func ExportMessages(w io.Writer, room string, messages []Message, format string) error {
+	records := make([]exportRecord, len(messages))
+	for i, msg := range messages {
+		records[i] = exportRecord{
+			Room:      room,
+			Type:      messageTypeNames[msg.Type],
+			From:      msg.From,
+			To:        msg.To,
+			Content:   msg.Content,
+			Timestamp: msg.Timestamp,
+		}
+	}
+
+	switch format {
+	case ExportJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	case ExportCSV:
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"room", "type", "from", "to", "content", "timestamp"})
+		for _, r := range records {
+			cw.Write([]string{r.Room, r.Type, r.From, r.To, r.Content, r.Timestamp.Format(time.RFC3339)})
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+	return fmt.Errorf("unknown export format %q (json, csv)", format)
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+func exportCommand(s *Server, c *Client, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: /export <room> [json|csv]")
+	}
+	room, format := args[0], ExportJSON
+	if len(args) > 1 {
+		format = args[1]
+	}
+	if format != ExportJSON && format != ExportCSV {
+		return fmt.Errorf("unknown export format %q (json, csv)", format)
+	}
+
+	s.mutex.Lock()
+	_, exists := s.rooms[room]
+	s.mutex.Unlock()
+	if !exists {
+		return fmt.Errorf("room %s does not exist", room)
+	}
+	messages, err := s.store.RecentMessages(room, 0)
+	if err != nil {
+		return fmt.Errorf("failed to load history: %v", err)
+	}
+
+	if err := os.MkdirAll(s.config.ExportDir, 0o700); err != nil {
+		return fmt.Errorf("failed to create export directory: %v", err)
+	}
+	name := fmt.Sprintf("%s-%s.%s", unsafeFileChars.ReplaceAllString(room, "_"), time.Now().Format("20060102-150405"), format)
+	path := filepath.Join(s.config.ExportDir, name)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create export: %v", err)
+	}
+	if err := ExportMessages(f, room, messages, format); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write export: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write export: %v", err)
+	}
+
+	s.logActivity("History exported", "client", c.name, "room", room, "messages", len(messages), "path", path)
+	c.write([]byte(fmt.Sprintf("Exported %d messages to %s\n", len(messages), path)))
+	return nil
+}
@@ -0,0 +1,70 @@
+package chat
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestExportMessages(t *testing.T) {
+	when := time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)
+	messages := []Message{
+		{Type: MessageTypeSystem, Content: "Alice joined the room", Timestamp: when},
+		{Type: MessageTypeChat, From: "Alice", Content: `hello, "world"`, Timestamp: when},
+	}
+
+	var buf bytes.Buffer
+	if err := ExportMessages(&buf, "general", messages, ExportJSON); err != nil {
+		t.Fatalf("JSON export failed: %v", err)
+	}
+	var records []exportRecord
+	if err := json.Unmarshal(buf.Bytes(), &records); err != nil {
+		t.Fatalf("export is not valid JSON: %v", err)
+	}
+	if len(records) != 2 || records[1].Type != "chat" || records[1].From != "Alice" || records[0].Room != "general" {
+		t.Errorf("unexpected JSON records %+v", records)
+	}
+
+	buf.Reset()
+	if err := ExportMessages(&buf, "general", messages, ExportCSV); err != nil {
+		t.Fatalf("CSV export failed: %v", err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("export is not valid CSV: %v", err)
+	}
+	if len(rows) != 3 || rows[0][0] != "room" || rows[2][4] != `hello, "world"` || rows[2][5] != "2024-05-01T09:30:00Z" {
+		t.Errorf("unexpected CSV rows %q", rows)
+	}
+
+	if err := ExportMessages(&buf, "general", messages, "xml"); err == nil {
+		t.Error("expected error for an unknown format")
+	}
+}
+
+func TestExportCommand(t *testing.T) {
+	s, _ := newEmailTestServer(t)
+	s.config.ExportDir = t.TempDir()
+	admin := newPipeClient(t, "Admin")
+	s.record("general", Message{Type: MessageTypeChat, From: "Alice", Content: "archived", Timestamp: time.Now()})
+
+	if err := exportCommand(s, admin, []string{"general", "csv"}); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(s.config.ExportDir, "general-*.csv"))
+	if len(files) != 1 {
+		t.Fatalf("expected one export file, found %v", files)
+	}
+	data, _ := os.ReadFile(files[0])
+	if !bytes.Contains(data, []byte("archived")) {
+		t.Errorf("export file missing message: %s", data)
+	}
+
+	if err := exportCommand(s, admin, []string{"nowhere"}); err == nil {
+		t.Error("expected error for an unknown room")
+	}
+}
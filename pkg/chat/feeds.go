@@ -0,0 +1,176 @@
+package chat
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+const feedItemLimit = 50
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title       string    `xml:"title"`
+	Link        string    `xml:"link"`
+	Description string    `xml:"description"`
+	Items       []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title   string `xml:"title"`
+	GUID    string `xml:"guid"`
+	PubDate string `xml:"pubDate"`
+	Author  string `xml:"author,omitempty"`
+	Body    string `xml:"description"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	Title   string     `xml:"title"`
+	ID      string     `xml:"id"`
+	Updated string     `xml:"updated"`
+	Author  atomAuthor `xml:"author"`
+	Content string     `xml:"content"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+func (s *Server) isPublicRoom(name string) bool {
+	for _, room := range s.config.PublicRooms {
+		if room == name {
+			return true
+		}
+	}
+	return false
+}
+
+// feedMessages returns the newest chat messages of a room, newest first
+func (s *Server) feedMessages(name string) ([]Message, bool) {
+	s.mutex.RLock()
+	_, exists := s.rooms[name]
+	s.mutex.RUnlock()
+	if !exists {
+		return nil, false
+	}
+
+	// System notices are skipped, so a feed may show fewer than
+	// feedItemLimit entries
+	history, err := s.store.RecentMessages(name, feedItemLimit)
+	if err != nil {
+		s.log.Error("Error loading history", "room", name, "err", err)
+	}
+	var messages []Message
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Type == MessageTypeChat {
+			messages = append(messages, history[i])
+		}
+	}
+	return messages, true
+}
+
+// handleFeed serves /feeds/<room>.rss and /feeds/<room>.atom
+func (s *Server) handleFeed(w http.ResponseWriter, r *http.Request) {
+	feed := r.PathValue("feed")
+	format := path.Ext(feed)
+	name := strings.TrimSuffix(feed, format)
+
+	if !s.isPublicRoom(name) {
+		http.NotFound(w, r)
+		return
+	}
+	messages, ok := s.feedMessages(name)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	link := fmt.Sprintf("http://%s/feeds/%s", r.Host, feed)
+	var doc interface{}
+	switch format {
+	case ".rss":
+		w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+		doc = buildRSS(name, link, messages)
+	case ".atom":
+		w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+		doc = buildAtom(name, link, messages)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Write([]byte(xml.Header))
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	enc.Encode(doc)
+}
+
+func feedItemID(room string, msg Message) string {
+	return fmt.Sprintf("tcpchat:%s:%d", room, msg.Timestamp.UnixNano())
+}
+
+func feedItemTitle(msg Message) string {
+	title := msg.Content
+	if r := []rune(title); len(r) > 80 {
+		title = string(r[:77]) + "..."
+	}
+	return fmt.Sprintf("%s: %s", msg.From, title)
+}
+
+func buildRSS(room, link string, messages []Message) rssFeed {
+	feed := rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:       "TCP-Chat #" + room,
+			Link:        link,
+			Description: "Messages posted in " + room,
+		},
+	}
+	for _, msg := range messages {
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:   feedItemTitle(msg),
+			GUID:    feedItemID(room, msg),
+			PubDate: msg.Timestamp.Format(time.RFC1123Z),
+			Author:  msg.From,
+			Body:    msg.Content,
+		})
+	}
+	return feed
+}
+
+func buildAtom(room, link string, messages []Message) atomFeed {
+	feed := atomFeed{
+		Title:   "TCP-Chat #" + room,
+		ID:      link,
+		Updated: time.Now().Format(time.RFC3339),
+	}
+	if len(messages) > 0 {
+		feed.Updated = messages[0].Timestamp.Format(time.RFC3339)
+	}
+	for _, msg := range messages {
+		feed.Entries = append(feed.Entries, atomEntry{
+			Title:   feedItemTitle(msg),
+			ID:      feedItemID(room, msg),
+			Updated: msg.Timestamp.Format(time.RFC3339),
+			Author:  atomAuthor{Name: msg.From},
+			Content: msg.Content,
+		})
+	}
+	return feed
+}
@@ -0,0 +1,55 @@
+package chat
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRoomFeeds(t *testing.T) {
+	config := DefaultConfig()
+	config.PublicRooms = []string{"general"}
+	s := NewServerWithConfig(config)
+	defer s.Logfile.Close()
+
+	s.rooms["secret"] = newChatRoom("secret")
+	s.deliverToRoom(s.rooms["general"], Message{
+		Type:      MessageTypeChat,
+		From:      "Alice",
+		Content:   "Release 1.2 is out",
+		Timestamp: time.Now(),
+	}, nil)
+
+	ts := httptest.NewServer(s.newHTTPHandler())
+	defer ts.Close()
+
+	tests := []struct {
+		path     string
+		status   int
+		contains string
+	}{
+		{"/feeds/general.rss", http.StatusOK, "<title>Alice: Release 1.2 is out</title>"},
+		{"/feeds/general.atom", http.StatusOK, "<name>Alice</name>"},
+		{"/feeds/secret.rss", http.StatusNotFound, ""},
+		{"/feeds/general.txt", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		resp, err := http.Get(ts.URL + tt.path)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", tt.path, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != tt.status {
+			t.Errorf("GET %s: status %d, want %d", tt.path, resp.StatusCode, tt.status)
+		}
+		if !strings.Contains(string(body), tt.contains) {
+			t.Errorf("GET %s: body missing %q:\n%s", tt.path, tt.contains, body)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package chat
+
+import (
+	"fmt"
+	"time"
+)
+
+// rateLimiter counts events in a sliding time window
+type rateLimiter struct {
+	limit  int
+	window time.Duration
+	events []time.Time
+}
+
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{limit: limit, window: window}
+}
+
+// allow records an event and reports whether it stays within the limit.
+// A limit of zero disables limiting.
+func (r *rateLimiter) allow(now time.Time) bool {
+	if r == nil || r.limit <= 0 {
+		return true
+	}
+
+	cutoff := now.Add(-r.window)
+	kept := r.events[:0]
+	for _, t := range r.events {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	r.events = append(kept, now)
+	return len(r.events) <= r.limit
+}
+
+// checkFlood applies the flood policy to an incoming line. allowed reports
+// whether the line may be processed; keep turns false once the client has
+// been warned too often and must be disconnected.
+func (s *Server) checkFlood(c *Client) (allowed, keep bool) {
+	if c.flood.allow(time.Now()) {
+		return true, true
+	}
+
+	s.mutex.RLock()
+	warnings := s.config.FloodWarnings
+	s.mutex.RUnlock()
+	c.floodWarnings++
+	if c.floodWarnings > warnings {
+		s.stats.floodKicks.Add(1)
+		c.sendMessage(Message{
+			Type:      MessageTypeError,
+			Content:   "Disconnected for flooding.",
+			Timestamp: time.Now(),
+		})
+		s.log.Warn("Kicked for flooding", clientAttrs(c)...)
+		return false, false
+	}
+
+	s.stats.floodWarnings.Add(1)
+	c.sendMessage(Message{
+		Type: MessageTypeError,
+		Content: fmt.Sprintf("You are sending messages too fast (max %d per %s). Slow down or you will be disconnected.",
+			c.flood.limit, c.flood.window),
+		Timestamp: time.Now(),
+	})
+	return false, true
+}
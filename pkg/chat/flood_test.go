@@ -0,0 +1,73 @@
+package chat
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	r := newRateLimiter(3, time.Second)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if !r.allow(start) {
+			t.Fatalf("event %d rejected within burst", i)
+		}
+	}
+	if r.allow(start) {
+		t.Error("event over the burst allowed")
+	}
+	if !r.allow(start.Add(2 * time.Second)) {
+		t.Error("event after the window rejected")
+	}
+
+	var disabled *rateLimiter
+	if !disabled.allow(start) {
+		t.Error("nil limiter must allow everything")
+	}
+}
+
+func TestFloodKick(t *testing.T) {
+	config := DefaultConfig()
+	config.FloodBurst = 3
+	config.FloodWindow = time.Minute
+	config.FloodWarnings = 1
+	addr := setupTestServerWithConfig(t, config)
+
+	observer, err := newTestClient(t, addr)
+	if err != nil {
+		t.Fatalf("Client connection failed: %v", err)
+	}
+	defer observer.close()
+	observer.expectMessage(t, "Welcome")
+	observer.sendMessage("Observer")
+	observer.expectMessage(t, "joined")
+
+	spammer, err := newTestClient(t, addr)
+	if err != nil {
+		t.Fatalf("Client connection failed: %v", err)
+	}
+	defer spammer.close()
+	spammer.expectMessage(t, "Welcome")
+	spammer.sendMessage("Spammer")
+	spammer.expectMessage(t, "joined")
+
+	for i := 0; i < 4; i++ {
+		spammer.sendMessage(fmt.Sprintf("spam %d", i))
+	}
+	if err := spammer.expectMessage(t, "too fast"); err != nil {
+		t.Fatalf("No flood warning: %v", err)
+	}
+	spammer.sendMessage("spam again")
+	if err := spammer.expectMessage(t, "Disconnected for flooding"); err != nil {
+		t.Fatalf("Flooder not disconnected: %v", err)
+	}
+	if err := observer.expectMessage(t, "Spammer has left"); err != nil {
+		t.Fatalf("No leave broadcast: %v", err)
+	}
+
+	observer.sendMessage("/stats")
+	if err := observer.expectMessage(t, "Flood kicks:     1"); err != nil {
+		t.Errorf("Stats missing flood kick: %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+package chat
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// funCommands are registered unless Config.NoFunCommands is set
+var funCommands = map[string]CommandFunc{
+	"roll":  rollCommand,
+	"flip":  flipCommand,
+	"8ball": eightBallCommand,
+}
+
+const funHelp = `/roll [NdM]     - Roll dice for the room, e.g. /roll 2d6
+/flip           - Flip a coin for the room
+/8ball <question> - Ask the magic 8-ball
+`
+
+// Dice limits for /roll
+const (
+	maxDice  = 20
+	maxSides = 1000
+)
+
+var eightBallAnswers = []string{
+	"It is certain.", "Without a doubt.", "Yes, definitely.", "You may rely on it.",
+	"Most likely.", "Outlook good.", "Signs point to yes.",
+	"Reply hazy, try again.", "Ask again later.", "Cannot predict now.",
+	"Don't count on it.", "My reply is no.", "Outlook not so good.", "Very doubtful.",
+}
+
+// parseDice reads "NdM", "dM" or "N" (six-sided) dice notation
+func parseDice(spec string) (count, sides int, err error) {
+	n, m, found := strings.Cut(strings.ToLower(spec), "d")
+	if !found {
+		n, m = spec, "6"
+	}
+	if n == "" {
+		n = "1"
+	}
+	count, err1 := strconv.Atoi(n)
+	sides, err2 := strconv.Atoi(m)
+	if err1 != nil || err2 != nil || count < 1 || count > maxDice || sides < 2 || sides > maxSides {
+		return 0, 0, fmt.Errorf("usage: /roll [NdM] with up to %d dice of 2 to %d sides", maxDice, maxSides)
+	}
+	return count, sides, nil
+}
+
+// announceFun posts the outcome of a fun command to c's room
+func (s *Server) announceFun(c *Client, text string) error {
+	s.mutex.Lock()
+	muted := s.muteRemaining(c, c.room)
+	room := s.rooms[c.room]
+	frozen := room != nil && s.frozenFor(room, c)
+	s.mutex.Unlock()
+	if muted > 0 {
+		return fmt.Errorf("you are muted for another %s", muted)
+	}
+	if room == nil {
+		return fmt.Errorf("you are not in any room")
+	}
+	if frozen {
+		return errArchived(room.name)
+	}
+	s.broadcastToRoom(room, Message{
+		Type:      MessageTypeSystem,
+		Content:   text,
+		Timestamp: time.Now(),
+	}, nil)
+	return nil
+}
+
+func rollCommand(s *Server, c *Client, args []string) error {
+	spec := "1d6"
+	if len(args) > 0 {
+		spec = args[0]
+	}
+	count, sides, err := parseDice(spec)
+	if err != nil {
+		return err
+	}
+	rolls := make([]string, count)
+	total := 0
+	for i := range rolls {
+		roll := rand.IntN(sides) + 1
+		total += roll
+		rolls[i] = strconv.Itoa(roll)
+	}
+	result := strconv.Itoa(total)
+	if count > 1 {
+		result = strings.Join(rolls, " + ") + " = " + result
+	}
+	return s.announceFun(c, fmt.Sprintf("%s rolled %dd%d: %s", c.name, count, sides, result))
+}
+
+func flipCommand(s *Server, c *Client, args []string) error {
+	side := "heads"
+	if rand.IntN(2) == 1 {
+		side = "tails"
+	}
+	return s.announceFun(c, fmt.Sprintf("%s flipped a coin: %s", c.name, side))
+}
+
+func eightBallCommand(s *Server, c *Client, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: /8ball <question>")
+	}
+	answer := eightBallAnswers[rand.IntN(len(eightBallAnswers))]
+	return s.announceFun(c, fmt.Sprintf("%s asked the magic 8-ball %q: %s", c.name, strings.Join(args, " "), answer))
+}
@@ -0,0 +1,76 @@
+package chat
+
+import "testing"
+
+func TestParseDice(t *testing.T) {
+	tests := []struct {
+		spec         string
+		count, sides int
+		wantErr      bool
+	}{
+		{"2d6", 2, 6, false},
+		{"d20", 1, 20, false},
+		{"3", 3, 6, false},
+		{"0d6", 0, 0, true},
+		{"21d6", 0, 0, true},
+		{"1d1", 0, 0, true},
+		{"1d1001", 0, 0, true},
+		{"xdy", 0, 0, true},
+	}
+	for _, tt := range tests {
+		count, sides, err := parseDice(tt.spec)
+		if (err != nil) != tt.wantErr || count != tt.count || sides != tt.sides {
+			t.Errorf("parseDice(%q) = %d, %d, %v", tt.spec, count, sides, err)
+		}
+	}
+}
+
+func TestFunCommands(t *testing.T) {
+	config := DefaultConfig()
+	config.RoomsFile = ""
+	addr := setupTestServerWithConfig(t, config)
+	join := func(name string) *TestClient {
+		c, err := newTestClient(t, addr)
+		if err != nil {
+			t.Fatalf("Client connection failed: %v", err)
+		}
+		c.sendMessage(name)
+		if err := c.expectMessage(t, name+" joined"); err != nil {
+			t.Fatalf("Join as %s failed: %v", name, err)
+		}
+		return c
+	}
+	alice := join("Alice")
+	defer alice.close()
+	bob := join("Bob")
+	defer bob.close()
+
+	alice.sendMessage("/roll 3d1")
+	if err := alice.expectMessage(t, "usage: /roll [NdM]"); err != nil {
+		t.Fatalf("bad dice not rejected: %v", err)
+	}
+	alice.sendMessage("/roll 2d6")
+	if err := bob.expectMessage(t, "Alice rolled 2d6: "); err != nil {
+		t.Fatalf("roll not broadcast: %v", err)
+	}
+	alice.sendMessage("/flip")
+	if err := bob.expectMessage(t, "Alice flipped a coin: "); err != nil {
+		t.Fatalf("flip not broadcast: %v", err)
+	}
+	alice.sendMessage("/8ball will it ship?")
+	if err := bob.expectMessage(t, `Alice asked the magic 8-ball "will it ship?": `); err != nil {
+		t.Fatalf("8ball not broadcast: %v", err)
+	}
+}
+
+func TestFunCommandsDisabled(t *testing.T) {
+	config := DefaultConfig()
+	config.NoFunCommands = true
+	s := NewServerWithConfig(config)
+	defer s.Logfile.Close()
+	for name := range funCommands {
+		if _, ok := s.commands[name]; ok {
+			t.Errorf("/%s registered although fun commands are disabled", name)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package chat
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// healthProbe is the line a load balancer may send instead of a name to
+// get a one-line status reply on the chat port
+const healthProbe = "HEALTH"
+
+// healthStatus is the JSON body of GET /healthz
+type healthStatus struct {
+	Status     string    `json:"status"` // "ok" or "shutting down"
+	Listeners  []string  `json:"listeners"`
+	Clients    int       `json:"clients"`
+	MaxClients int       `json:"max_clients"`
+	Rooms      int       `json:"rooms"`
+	Started    time.Time `json:"started"`
+	Uptime     float64   `json:"uptime_seconds"`
+}
+
+// health reports the current state of the server
+func (s *Server) health() healthStatus {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	status := healthStatus{
+		Status:     "ok",
+		Listeners:  make([]string, 0, len(s.listeners)),
+		Clients:    s.clients.len(),
+		MaxClients: s.maxClients,
+		Rooms:      len(s.rooms),
+		Started:    s.startTime,
+		Uptime:     time.Since(s.startTime).Seconds(),
+	}
+	for _, l := range s.listeners {
+		status.Listeners = append(status.Listeners, l.Addr().String())
+	}
+	if s.closing || len(s.listeners) == 0 {
+		status.Status = "shutting down"
+	}
+	return status
+}
+
+// handleHealth serves GET /healthz, answering 503 once the server stops
+// accepting connections
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	status := s.health()
+	code := http.StatusOK
+	if status.Status != "ok" {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, status)
+}
+
+// healthLine is the reply to a HEALTH probe on the chat port
+func (s *Server) healthLine() string {
+	status := s.health()
+	state := "OK"
+	if status.Status != "ok" {
+		state = "FAIL"
+	}
+	return fmt.Sprintf("%s listeners=%s clients=%d rooms=%d uptime=%s\n", state,
+		strings.Join(status.Listeners, ","), status.Clients, status.Rooms,
+		time.Duration(status.Uptime*float64(time.Second)).Round(time.Second))
+}
@@ -0,0 +1,72 @@
+package chat
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealthProbe(t *testing.T) {
+	addr := setupTestServer(t)
+
+	client, err := newTestClient(t, addr)
+	if err != nil {
+		t.Fatalf("Client connection failed: %v", err)
+	}
+	defer client.close()
+	client.sendMessage(healthProbe)
+	if err := client.expectMessage(t, "OK listeners="+addr+" clients=0"); err != nil {
+		t.Fatalf("no health reply: %v", err)
+	}
+
+	// Probes are not counted as clients
+	user, err := newTestClient(t, addr)
+	if err != nil {
+		t.Fatalf("Client connection failed: %v", err)
+	}
+	defer user.close()
+	user.sendMessage("Alice")
+	if err := user.expectMessage(t, "Alice joined"); err != nil {
+		t.Fatalf("join failed: %v", err)
+	}
+
+	probe, err := newTestClient(t, addr)
+	if err != nil {
+		t.Fatalf("Client connection failed: %v", err)
+	}
+	defer probe.close()
+	probe.sendMessage(healthProbe)
+	if err := probe.expectMessage(t, "clients=1 "); err != nil {
+		t.Fatalf("health reply should count only Alice: %v", err)
+	}
+}
+
+func TestHealthEndpoint(t *testing.T) {
+	s := NewServerWithConfig(DefaultConfig())
+	defer s.Logfile.Close()
+
+	// Not started yet: no listeners
+	rec := httptest.NewRecorder()
+	s.newHTTPHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/healthz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 before the server listens, got %d", rec.Code)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer listener.Close()
+	s.listeners = []net.Listener{listener}
+	rec = httptest.NewRecorder()
+	s.newAdminHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/healthz", nil))
+	var status healthStatus
+	if err := json.NewDecoder(rec.Body).Decode(&status); err != nil {
+		t.Fatalf("bad body: %v", err)
+	}
+	if rec.Code != http.StatusOK || status.Status != "ok" || len(status.Listeners) != 1 || status.Rooms < 1 {
+		t.Errorf("unexpected health %d %+v", rec.Code, status)
+	}
+}
@@ -0,0 +1,60 @@
+package chat
+
+import (
+	"fmt"
+	"time"
+)
+
+// visibleTo reports whether room is listed for c. Hidden rooms are only
+// listed to their members and to moderators; anyone else has to know the
+// exact name or be invited.
+func (s *Server) visibleTo(room *ChatRoom, c *Client) bool {
+	return !room.hidden || room.name == c.room || c.role >= RoleModerator
+}
+
+// roomNameFor returns the name of the room to show c where another user
+// is, or "a hidden room" when c could not list it. Caller holds s.mutex.
+func (s *Server) roomNameFor(name string, c *Client) string {
+	if room, exists := s.rooms[name]; exists && !s.visibleTo(room, c) {
+		return "a hidden room"
+	}
+	return name
+}
+
+// hiddenCommand hides the current room from /rooms or lists it again
+func hiddenCommand(s *Server, c *Client, args []string) error {
+	if len(args) != 1 || (args[0] != "on" && args[0] != "off") {
+		return fmt.Errorf("usage: /hidden on|off")
+	}
+	s.mutex.Lock()
+	room, exists := s.rooms[c.room]
+	if !exists {
+		s.mutex.Unlock()
+		return fmt.Errorf("you are not in any room")
+	}
+	if !s.isRoomOperator(room, c) {
+		s.mutex.Unlock()
+		return fmt.Errorf("only room operators can hide the room")
+	}
+	if room.name == s.lobby() {
+		s.mutex.Unlock()
+		return fmt.Errorf("%s is always listed", room.name)
+	}
+	room.hidden = args[0] == "on"
+	if err := s.saveRooms(); err != nil {
+		s.log.Error("Error saving rooms", "room", room.name, "err", err)
+	}
+	s.mutex.Unlock()
+
+	state := "hidden from /rooms"
+	if !room.hidden {
+		state = "listed in /rooms again"
+	}
+	s.logActivity("Room setting changed", "client", c.name, "room", room.name, "setting", state)
+	s.broadcastToRoom(room, Message{
+		Type:      MessageTypeSystem,
+		Content:   fmt.Sprintf("%s made the room %s", c.name, state),
+		Timestamp: time.Now(),
+	}, nil)
+	return nil
+}
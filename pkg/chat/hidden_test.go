@@ -0,0 +1,89 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHiddenRoom(t *testing.T) {
+	config := DefaultConfig()
+	config.RoomsFile = ""
+	addr := setupTestServerWithConfig(t, config)
+	join := func(name string) *TestClient {
+		c, err := newTestClient(t, addr)
+		if err != nil {
+			t.Fatalf("Client connection failed: %v", err)
+		}
+		c.sendMessage(name)
+		if err := c.expectMessage(t, name+" joined"); err != nil {
+			t.Fatalf("Join as %s failed: %v", name, err)
+		}
+		return c
+	}
+	alice := join("Alice")
+	defer alice.close()
+	bob := join("Bob")
+	defer bob.close()
+
+	alice.sendMessage("/create huddle")
+	bob.expectMessage(t, "Alice left the room")
+	alice.sendMessage("/hidden on")
+	if err := alice.expectMessage(t, "Alice made the room hidden from /rooms"); err != nil {
+		t.Fatalf("room not hidden: %v", err)
+	}
+	alice.sendMessage("/rooms")
+	if err := alice.expectMessage(t, "huddle (1 users) [hidden]"); err != nil {
+		t.Fatalf("hidden room not listed to its member: %v", err)
+	}
+
+	// Where Alice is and what she said there stay hidden from Bob
+	alice.sendMessage("secret plans")
+	alice.expectMessage(t, "secret plans")
+	for command, want := range map[string]string{
+		"/list":        "Alice (in a hidden room)",
+		"/whois Alice": "Alice is in a hidden room",
+		"/seen Alice":  "Alice is online in a hidden room",
+	} {
+		bob.sendMessage(command)
+		if err := bob.expectMessage(t, want); err != nil {
+			t.Errorf("%s leaked the hidden room: %v", command, err)
+		}
+	}
+	bob.sendMessage("/whois Nobody")
+	for {
+		bob.conn.SetReadDeadline(time.Now().Add(messageTimeout))
+		line, err := bob.reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading /seen failed: %v", err)
+		}
+		if strings.Contains(line, "secret plans") {
+			t.Errorf("/seen showed what was said in a hidden room: %q", line)
+		}
+		if strings.Contains(line, "Nobody not found") {
+			break
+		}
+	}
+
+	bob.sendMessage("/join huddle")
+	if err := alice.expectMessage(t, "Bob joined the room"); err != nil {
+		t.Fatalf("join by exact name failed: %v", err)
+	}
+}
+
+func TestHiddenRoomListing(t *testing.T) {
+	s := NewServerWithConfig(DefaultConfig())
+	defer s.Logfile.Close()
+	room := newChatRoom("huddle")
+	room.hidden = true
+
+	if s.visibleTo(room, &Client{name: "Bob", room: "general"}) {
+		t.Error("hidden room visible to a non-member")
+	}
+	if !s.visibleTo(room, &Client{name: "Alice", room: "huddle"}) {
+		t.Error("hidden room invisible to its member")
+	}
+	if !s.visibleTo(room, &Client{name: "Mod", room: "general", role: RoleModerator}) {
+		t.Error("hidden room invisible to a moderator")
+	}
+}
@@ -0,0 +1,106 @@
+package chat
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// record numbers msg within its room and appends it to the room's
+// history, logging failures
+func (s *Server) record(room string, msg Message) Message {
+	if room != "" {
+		msg.ID = s.messageIDs.next(room, s.store)
+	}
+	if err := s.store.AppendMessage(room, msg); err != nil {
+		s.log.Error("Error saving message history", "room", room, "err", err)
+	}
+	if s.onRecord != nil {
+		s.onRecord(room, msg)
+	}
+	return msg
+}
+
+// messageIDs hands out increasing message IDs per room, continuing from
+// the newest stored message after a restart
+type messageIDs struct {
+	mutex sync.Mutex
+	last  map[string]int64
+}
+
+func (m *messageIDs) next(room string, store Store) int64 {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.last == nil {
+		m.last = make(map[string]int64)
+	}
+	id, ok := m.last[room]
+	if !ok {
+		if recent, err := store.RecentMessages(room, 1); err == nil && len(recent) > 0 {
+			id = recent[0].ID
+		}
+	}
+	id++
+	m.last[room] = id
+	return id
+}
+
+const (
+	historyDefaultPage = 20
+	historyMaxPage     = 200
+)
+
+// historyCommand pages backwards through the current room's history:
+// /history [N] shows the newest N messages, /history more the N before them
+func historyCommand(s *Server, c *Client, args []string) error {
+	if c.room == "" {
+		return fmt.Errorf("you are not in any room")
+	}
+	s.mutex.Lock()
+	ephemeral := s.isEphemeral(c.room)
+	s.mutex.Unlock()
+	if ephemeral {
+		return errNoHistory(c.room)
+	}
+
+	if len(args) > 0 && strings.EqualFold(args[0], "more") {
+		if c.historyPage == 0 || c.historyRoom != c.room {
+			return fmt.Errorf("use /history [N] first")
+		}
+	} else {
+		page := historyDefaultPage
+		if len(args) > 0 {
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n < 1 {
+				return fmt.Errorf("usage: /history [N] or /history more")
+			}
+			page = min(n, historyMaxPage)
+		}
+		c.historyRoom, c.historyPage, c.historySeen = c.room, page, 0
+	}
+
+	messages, err := s.store.RecentMessages(c.room, c.historySeen+c.historyPage)
+	if err != nil {
+		return fmt.Errorf("failed to load history: %v", err)
+	}
+	older := len(messages) - c.historySeen
+	if older <= 0 {
+		c.write([]byte("No older messages\n"))
+		return nil
+	}
+	page := messages[max(0, older-c.historyPage):older]
+	c.historySeen += len(page)
+
+	c.write([]byte(fmt.Sprintf("--- History of %s (%d messages) ---\n", c.room, len(page))))
+	for _, msg := range page {
+		c.sendMessage(msg)
+	}
+	if len(messages) == c.historySeen {
+		// The store returned as much as was asked for, so more may exist
+		c.write([]byte("--- /history more for older messages ---\n"))
+	} else {
+		c.write([]byte("--- Start of history ---\n"))
+	}
+	return nil
+}
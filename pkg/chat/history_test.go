@@ -0,0 +1,87 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistoryReplayLimit(t *testing.T) {
+	config := DefaultConfig()
+	config.HistoryReplay = 2
+	addr := setupTestServerWithConfig(t, config)
+
+	alice, err := newTestClient(t, addr)
+	if err != nil {
+		t.Fatalf("Client connection failed: %v", err)
+	}
+	defer alice.close()
+	alice.expectMessage(t, "Welcome")
+	alice.sendMessage("Alice")
+	alice.expectMessage(t, "joined")
+	for _, line := range []string{"first", "second", "third"} {
+		alice.sendMessage(line)
+		alice.expectMessage(t, line)
+	}
+
+	bob, err := newTestClient(t, addr)
+	if err != nil {
+		t.Fatalf("Client connection failed: %v", err)
+	}
+	defer bob.close()
+	bob.expectMessage(t, "Welcome")
+	bob.sendMessage("Bob")
+
+	// Collect the replay, which ends with Bob's own join notice
+	var replay []string
+	bob.conn.SetReadDeadline(time.Now().Add(messageTimeout))
+	for {
+		line, err := bob.reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Replay incomplete: %v", err)
+		}
+		if strings.Contains(line, "Bob joined") {
+			break
+		}
+		replay = append(replay, line)
+	}
+	joined := strings.Join(replay, "")
+	if strings.Contains(joined, "first") || !strings.Contains(joined, "second") || !strings.Contains(joined, "third") {
+		t.Errorf("replay should hold only the last 2 messages, got %q", joined)
+	}
+}
+
+func TestHistoryCommand(t *testing.T) {
+	addr := setupTestServer(t)
+	client, err := newTestClient(t, addr)
+	if err != nil {
+		t.Fatalf("Client connection failed: %v", err)
+	}
+	defer client.close()
+	client.expectMessage(t, "Welcome")
+	client.sendMessage("Pager")
+	client.expectMessage(t, "joined")
+	for i := 1; i <= 5; i++ {
+		client.sendMessage(fmt.Sprintf("line %d", i))
+		client.expectMessage(t, fmt.Sprintf("line %d", i))
+	}
+
+	client.sendMessage("/history 2")
+	for _, want := range []string{"History of general (2 messages)", "line 4", "line 5", "/history more"} {
+		if err := client.expectMessage(t, want); err != nil {
+			t.Fatalf("first page missing %q: %v", want, err)
+		}
+	}
+	client.sendMessage("/history more")
+	for _, want := range []string{"line 2", "line 3"} {
+		if err := client.expectMessage(t, want); err != nil {
+			t.Fatalf("second page missing %q: %v", want, err)
+		}
+	}
+	client.sendMessage("/history more")
+	client.sendMessage("/history more")
+	if err := client.expectMessage(t, "No older messages"); err != nil {
+		t.Errorf("paging past the start not reported: %v", err)
+	}
+}
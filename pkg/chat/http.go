@@ -0,0 +1,32 @@
+package chat
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// newHTTPHandler builds the routes served by the HTTP gateway
+func (s *Server) newHTTPHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /feeds/{feed}", s.handleFeed)
+	mux.HandleFunc("GET /healthz", s.handleHealth)
+	if s.config.WebhookToken != "" {
+		mux.HandleFunc("POST /hooks/{room}", s.handleWebhook)
+	}
+	return mux
+}
+
+// serveHTTP runs the HTTP gateway until it fails
+func (s *Server) serveHTTP(ctx context.Context, addr string) {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           s.newHTTPHandler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	context.AfterFunc(ctx, func() { srv.Close() })
+	s.logActivity("HTTP gateway started", "addr", addr)
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		s.log.Error("HTTP gateway error", "addr", addr, "err", err)
+	}
+}
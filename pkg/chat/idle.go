@@ -0,0 +1,150 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// autoAwayMessage is the away message set by the auto-away watcher
+const autoAwayMessage = "Idle"
+
+// minCheckInterval keeps a tiny timeout from giving its watcher a zero
+// interval, which time.NewTicker refuses, or one that spins
+const minCheckInterval = 10 * time.Millisecond
+
+// checkInterval returns how often to look for a timeout of d: a tenth of
+// it, at least minCheckInterval and at most most
+func checkInterval(d, most time.Duration) time.Duration {
+	return min(max(d/10, minCheckInterval), most)
+}
+
+// touch records activity from a client, resetting its idle timer and
+// clearing an automatic away status
+func (s *Server) touch(c *Client) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	c.lastActive = time.Now()
+	c.idleWarned = false
+	if c.autoAway {
+		c.autoAway = false
+		c.away = ""
+		s.announcePresence(c, fmt.Sprintf("%s is back", c.name))
+	}
+}
+
+// announcePresence tells the other clients in c's room about a presence
+// change. Such notices are not kept in the history. The caller holds
+// s.mutex.
+func (s *Server) announcePresence(c *Client, text string) {
+	room, exists := s.rooms[c.room]
+	if !exists {
+		return
+	}
+	notice := Message{Type: MessageTypeSystem, Content: text, Timestamp: time.Now()}
+	for conn, client := range room.clients {
+		if conn != c.conn && !client.ignores(c.name) {
+			client.sendMessage(notice)
+		}
+	}
+}
+
+// watchAutoAway periodically marks clients away after Config.AutoAway
+// without input
+func (s *Server) watchAutoAway(ctx context.Context) {
+	ticker := time.NewTicker(checkInterval(s.config.AutoAway, 30*time.Second))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !s.checkAutoAway(time.Now()) {
+			return
+		}
+	}
+}
+
+// checkAutoAway handles one auto-away pass. Clients that set /away
+// themselves are left alone. It returns false once the server is shutting
+// down.
+func (s *Server) checkAutoAway(now time.Time) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closing {
+		return false
+	}
+	for _, c := range s.clients.all() {
+		if c.away != "" || c.bot || now.Sub(c.lastActive) < s.config.AutoAway {
+			continue
+		}
+		c.away, c.awaySince, c.autoAway = autoAwayMessage, c.lastActive, true
+		c.sendMessage(Message{
+			Type:      MessageTypeSystem,
+			Content:   fmt.Sprintf("You have been marked as away after %s without activity", s.config.AutoAway),
+			Timestamp: now,
+		})
+		s.announcePresence(c, fmt.Sprintf("%s is away (idle)", c.name))
+	}
+	return true
+}
+
+// reapIdleClients periodically warns and then disconnects idle clients
+func (s *Server) reapIdleClients(ctx context.Context) {
+	ticker := time.NewTicker(checkInterval(s.config.IdleTimeout, 30*time.Second))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !s.checkIdleClients(time.Now()) {
+			return
+		}
+	}
+}
+
+// checkIdleClients handles one reaper pass. It returns false once the
+// server is shutting down.
+func (s *Server) checkIdleClients(now time.Time) bool {
+	timeout := s.config.IdleTimeout
+	warnAt := timeout - s.config.IdleWarning
+
+	var warn []*Client
+	var expired []*Client
+
+	s.mutex.Lock()
+	if s.closing {
+		s.mutex.Unlock()
+		return false
+	}
+	for _, c := range s.clients.all() {
+		idle := now.Sub(c.lastActive)
+		switch {
+		case idle >= timeout:
+			c.leaveReason = fmt.Sprintf("idle for %s", timeout)
+			expired = append(expired, c)
+		case idle >= warnAt && !c.idleWarned:
+			c.idleWarned = true
+			warn = append(warn, c)
+		}
+	}
+	s.mutex.Unlock()
+
+	for _, c := range warn {
+		c.sendMessage(Message{
+			Type:      MessageTypeSystem,
+			Content:   fmt.Sprintf("You will be disconnected in %s unless you send something.", timeout-now.Sub(c.lastActive).Round(time.Second)),
+			Timestamp: now,
+		})
+	}
+	for _, c := range expired {
+		c.write([]byte("Disconnected due to inactivity.\n"))
+		c.closeConn()
+	}
+	return true
+}
@@ -0,0 +1,131 @@
+package chat
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestIdleTimeout(t *testing.T) {
+	config := DefaultConfig()
+	config.IdleTimeout = 600 * time.Millisecond
+	config.IdleWarning = 300 * time.Millisecond
+	addr := setupTestServerWithConfig(t, config)
+
+	client, err := newTestClient(t, addr)
+	if err != nil {
+		t.Fatalf("Client connection failed: %v", err)
+	}
+	defer client.close()
+
+	client.expectMessage(t, "Welcome")
+	client.sendMessage("Sleepy")
+	client.expectMessage(t, "joined")
+
+	if err := client.expectMessage(t, "will be disconnected"); err != nil {
+		t.Fatalf("No idle warning: %v", err)
+	}
+	if err := client.expectMessage(t, "Disconnected due to inactivity"); err != nil {
+		t.Fatalf("Idle client not disconnected: %v", err)
+	}
+}
+
+func TestIdleActivityResetsTimer(t *testing.T) {
+	s := NewServerWithConfig(DefaultConfig())
+	defer s.Logfile.Close()
+	s.config.IdleTimeout = time.Minute
+	s.config.IdleWarning = 10 * time.Second
+
+	c := newPipeClient(t, "Busy")
+	c.lastActive = time.Now().Add(-55 * time.Second)
+	s.clients.add(c.conn, c)
+
+	s.checkIdleClients(time.Now())
+	if !c.idleWarned {
+		t.Fatal("client close to the timeout was not warned")
+	}
+	s.touch(c)
+	if c.idleWarned {
+		t.Error("activity did not reset the warning")
+	}
+	s.checkIdleClients(time.Now())
+	if c.idleWarned || c.leaveReason != "" {
+		t.Error("active client treated as idle")
+	}
+}
+
+func TestAutoAway(t *testing.T) {
+	config := DefaultConfig()
+	config.AutoAway = 300 * time.Millisecond
+	addr := setupTestServerWithConfig(t, config)
+	join := func(name string) *TestClient {
+		c, err := newTestClient(t, addr)
+		if err != nil {
+			t.Fatalf("Client connection failed: %v", err)
+		}
+		c.sendMessage(name)
+		if err := c.expectMessage(t, name+" joined"); err != nil {
+			t.Fatalf("Join as %s failed: %v", name, err)
+		}
+		return c
+	}
+	alice := join("Alice")
+	defer alice.close()
+	bob := join("Bob")
+	defer bob.close()
+
+	if err := alice.expectMessage(t, "You have been marked as away"); err != nil {
+		t.Fatalf("idle client not marked away: %v", err)
+	}
+	if err := bob.expectMessage(t, "Alice is away (idle)"); err != nil {
+		t.Fatalf("auto-away not announced: %v", err)
+	}
+	alice.sendMessage("back again")
+	if err := bob.expectMessage(t, "Alice is back"); err != nil {
+		t.Fatalf("return not announced: %v", err)
+	}
+}
+
+func TestAutoAwayKeepsManualAway(t *testing.T) {
+	s := NewServerWithConfig(DefaultConfig())
+	defer s.Logfile.Close()
+	s.config.AutoAway = time.Minute
+
+	c := newPipeClient(t, "Lunch")
+	c.away = "Lunch"
+	c.lastActive = time.Now().Add(-time.Hour)
+	s.clients.add(c.conn, c)
+
+	s.checkAutoAway(time.Now())
+	if c.autoAway || c.away != "Lunch" {
+		t.Fatalf("manual away replaced: autoAway=%v away=%q", c.autoAway, c.away)
+	}
+	s.touch(c)
+	if c.away != "Lunch" {
+		t.Error("activity cleared a manual away status")
+	}
+}
+
+func TestCheckInterval(t *testing.T) {
+	for _, tt := range []struct{ d, most, want time.Duration }{
+		{5 * time.Nanosecond, 30 * time.Second, minCheckInterval},
+		{600 * time.Millisecond, 30 * time.Second, 60 * time.Millisecond},
+		{time.Hour, 30 * time.Second, 30 * time.Second},
+	} {
+		if got := checkInterval(tt.d, tt.most); got != tt.want {
+			t.Errorf("checkInterval(%s, %s) = %s, want %s", tt.d, tt.most, got, tt.want)
+		}
+	}
+
+	// A timeout below 10ns used to give the watchers a zero interval
+	s := NewServerWithConfig(DefaultConfig())
+	defer s.Logfile.Close()
+	s.config.IdleTimeout = 5 * time.Nanosecond
+	s.config.AutoAway = 5 * time.Nanosecond
+	s.config.RoomIdleTimeout = 5 * time.Nanosecond
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	s.reapIdleClients(ctx)
+	s.watchAutoAway(ctx)
+	s.pruneRooms(ctx)
+}
@@ -0,0 +1,62 @@
+package chat
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ignores reports whether c has chosen not to receive messages from name
+func (c *Client) ignores(name string) bool {
+	return name != "" && c.ignored[strings.ToLower(name)]
+}
+
+func ignoreCommand(s *Server, c *Client, args []string) error {
+	if len(args) < 1 {
+		s.mutex.Lock()
+		names := make([]string, 0, len(c.ignored))
+		for name := range c.ignored {
+			names = append(names, name)
+		}
+		s.mutex.Unlock()
+		if len(names) == 0 {
+			c.write([]byte("You are not ignoring anyone\n"))
+			return nil
+		}
+		sort.Strings(names)
+		c.write([]byte(fmt.Sprintf("Ignoring: %s\n", strings.Join(names, ", "))))
+		return nil
+	}
+
+	name := args[0]
+	if strings.EqualFold(name, c.name) {
+		return fmt.Errorf("you cannot ignore yourself")
+	}
+	s.mutex.Lock()
+	if c.ignored == nil {
+		c.ignored = make(map[string]bool)
+	}
+	c.ignored[strings.ToLower(name)] = true
+	s.mutex.Unlock()
+
+	c.write([]byte(fmt.Sprintf("You will no longer see messages from %s\n", name)))
+	return nil
+}
+
+func unignoreCommand(s *Server, c *Client, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: /unignore <user>")
+	}
+
+	key := strings.ToLower(args[0])
+	s.mutex.Lock()
+	ignored := c.ignored[key]
+	delete(c.ignored, key)
+	s.mutex.Unlock()
+	if !ignored {
+		return fmt.Errorf("you are not ignoring %s", args[0])
+	}
+
+	c.write([]byte(fmt.Sprintf("You will see messages from %s again\n", args[0])))
+	return nil
+}
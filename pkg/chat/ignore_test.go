@@ -0,0 +1,45 @@
+package chat
+
+import "testing"
+
+func TestIgnoreCommand(t *testing.T) {
+	addr := setupTestServer(t)
+
+	join := func(name string) *TestClient {
+		c, err := newTestClient(t, addr)
+		if err != nil {
+			t.Fatalf("Client connection failed: %v", err)
+		}
+		c.expectMessage(t, "Welcome")
+		c.sendMessage(name)
+		if err := c.expectMessage(t, name+" joined"); err != nil {
+			t.Fatalf("Join as %s failed: %v", name, err)
+		}
+		return c
+	}
+	alice := join("Alice")
+	defer alice.close()
+	bob := join("Bob")
+	defer bob.close()
+
+	bob.sendMessage("/ignore alice")
+	if err := bob.expectMessage(t, "no longer see messages from alice"); err != nil {
+		t.Fatalf("Ignore failed: %v", err)
+	}
+
+	alice.sendMessage("hidden line")
+	alice.sendMessage("/msg Bob hidden whisper")
+	if err := alice.expectMessage(t, "hidden whisper"); err != nil {
+		t.Errorf("Sender lost the private message echo: %v", err)
+	}
+	if err := bob.expectMessage(t, "hidden"); err == nil {
+		t.Fatal("Ignored user's messages were delivered")
+	}
+
+	bob.sendMessage("/unignore Alice")
+	bob.expectMessage(t, "messages from Alice again")
+	alice.sendMessage("visible line")
+	if err := bob.expectMessage(t, "visible line"); err != nil {
+		t.Errorf("Messages not delivered after unignore: %v", err)
+	}
+}
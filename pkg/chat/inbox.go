@@ -0,0 +1,63 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// inboxLimit caps the private messages queued for one offline user
+const inboxLimit = 100
+
+// errNoInbox leaves the accounts file untouched when nothing is queued
+var errNoInbox = errors.New("no offline messages")
+
+// queueOffline stores a private message for a registered user who is not
+// connected. It reports whether the message was queued.
+func (s *Server) queueOffline(name string, msg Message) (bool, error) {
+	account, ok := s.accounts.get(name)
+	if !ok || !account.registered() {
+		return false, nil
+	}
+	err := s.accounts.update(account.Name, func(a *Account) error {
+		if len(a.Inbox) >= inboxLimit {
+			return fmt.Errorf("%s's inbox is full", a.Name)
+		}
+		msg.To = a.Name
+		a.Inbox = append(a.Inbox, msg)
+		return nil
+	})
+	return err == nil, err
+}
+
+// deliverInbox sends the messages queued while c was away. It must only be
+// called once c has proved it owns its nickname.
+func (s *Server) deliverInbox(c *Client) {
+	var inbox []Message
+	err := s.accounts.update(c.name, func(a *Account) error {
+		if len(a.Inbox) == 0 {
+			return errNoInbox
+		}
+		inbox, a.Inbox = a.Inbox, nil
+		return nil
+	})
+	if err == errNoInbox {
+		return
+	}
+	if err != nil {
+		s.log.Error("Error delivering offline messages", "client", c.name, "err", err)
+		return
+	}
+
+	summary := fmt.Sprintf("You have %d messages while you were away:", len(inbox))
+	if len(inbox) == 1 {
+		summary = "You have 1 message while you were away:"
+	}
+	c.sendMessage(Message{Type: MessageTypeSystem, Content: summary, Timestamp: time.Now()})
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, msg := range inbox {
+		c.sendMessage(msg)
+		s.privateDelivered(c, msg)
+	}
+}
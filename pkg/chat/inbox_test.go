@@ -0,0 +1,54 @@
+package chat
+
+import (
+	"testing"
+)
+
+func TestOfflineMessages(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountsFile = tempAccountsFile(t)
+	config.AuthRequired = true
+	SetPassword(config, "Alice", "secret1")
+	SetPassword(config, "Bob", "secret2")
+	addr := setupTestServerWithConfig(t, config)
+
+	login := func(name, password string) *TestClient {
+		c, err := newTestClient(t, addr)
+		if err != nil {
+			t.Fatalf("Client connection failed: %v", err)
+		}
+		c.expectMessage(t, "Welcome")
+		c.sendMessage(name)
+		c.sendMessage(password)
+		if err := c.expectMessage(t, name+" joined"); err != nil {
+			t.Fatalf("Login as %s failed: %v", name, err)
+		}
+		return c
+	}
+
+	alice := login("Alice", "secret1")
+	defer alice.close()
+	alice.sendMessage("/msg Bob are you around?")
+	if err := alice.expectMessage(t, "delivered when they reconnect"); err != nil {
+		t.Fatalf("Message to offline user not queued: %v", err)
+	}
+	alice.sendMessage("/msg Bob call me")
+	alice.expectMessage(t, "delivered when they reconnect")
+	alice.sendMessage("/msg Nobody hello")
+	if err := alice.expectMessage(t, "user Nobody not found"); err != nil {
+		t.Errorf("Message to unknown user accepted: %v", err)
+	}
+
+	bob := login("Bob", "secret2")
+	defer bob.close()
+	for _, want := range []string{"You have 2 messages while you were away", "are you around?", "call me"} {
+		if err := bob.expectMessage(t, want); err != nil {
+			t.Fatalf("Offline delivery missing %q: %v", want, err)
+		}
+	}
+
+	// Delivered messages are removed from the inbox
+	if store, _ := loadAccountStore(newFileStore(config.AccountsFile, "", "")); len(store.accounts["bob"].Inbox) != 0 {
+		t.Error("inbox not cleared after delivery")
+	}
+}
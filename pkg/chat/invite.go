@@ -0,0 +1,83 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// inviteOnlyCommand turns the invite-only flag of the current room on or off
+func inviteOnlyCommand(s *Server, c *Client, args []string) error {
+	if len(args) != 1 || (args[0] != "on" && args[0] != "off") {
+		return fmt.Errorf("usage: /inviteonly on|off")
+	}
+	s.mutex.Lock()
+	room, exists := s.rooms[c.room]
+	if !exists {
+		s.mutex.Unlock()
+		return fmt.Errorf("you are not in any room")
+	}
+	if !s.isRoomOperator(room, c) {
+		s.mutex.Unlock()
+		return fmt.Errorf("only room operators can change who may join")
+	}
+	if room.name == s.lobby() {
+		s.mutex.Unlock()
+		return fmt.Errorf("%s is always open", room.name)
+	}
+	room.inviteOnly = args[0] == "on"
+	if err := s.saveRooms(); err != nil {
+		s.log.Error("Error saving rooms", "room", room.name, "err", err)
+	}
+	s.mutex.Unlock()
+
+	state := "now invite-only"
+	if !room.inviteOnly {
+		state = "open to everyone again"
+	}
+	s.logActivity("Room setting changed", "client", c.name, "room", room.name, "setting", state)
+	s.broadcastToRoom(room, Message{
+		Type:      MessageTypeSystem,
+		Content:   fmt.Sprintf("%s made the room %s", c.name, state),
+		Timestamp: time.Now(),
+	}, nil)
+	return nil
+}
+
+// inviteCommand lets a room operator admit a user to the current room
+// once, bypassing the invite-only flag
+func inviteCommand(s *Server, c *Client, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: /invite <user>")
+	}
+	s.mutex.Lock()
+	room, exists := s.rooms[c.room]
+	if !exists {
+		s.mutex.Unlock()
+		return fmt.Errorf("you are not in any room")
+	}
+	if !s.isRoomOperator(room, c) {
+		s.mutex.Unlock()
+		return fmt.Errorf("only room operators can invite users")
+	}
+	target := s.findClient(args[0])
+	if target == nil {
+		s.mutex.Unlock()
+		return fmt.Errorf("user %s not found", args[0])
+	}
+	if target.room == room.name {
+		s.mutex.Unlock()
+		return fmt.Errorf("%s is already in %s", target.name, room.name)
+	}
+	room.invited[strings.ToLower(target.name)] = true
+	s.mutex.Unlock()
+
+	s.logActivity("Invited", "client", c.name, "target", target.name, "room", room.name)
+	target.sendMessage(Message{
+		Type:      MessageTypeSystem,
+		Content:   fmt.Sprintf("%s invited you to %s. Type /join %s to enter.", c.name, room.name, room.name),
+		Timestamp: time.Now(),
+	})
+	c.write([]byte(fmt.Sprintf("Invited %s to %s\n", target.name, room.name)))
+	return nil
+}
@@ -0,0 +1,112 @@
+package chat
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Invite code lifetimes: single-use codes expire after a day unless
+// redeemed, time-limited ones may last up to a month
+const (
+	inviteCodeLifetime    = 24 * time.Hour
+	maxInviteCodeLifetime = 30 * 24 * time.Hour
+)
+
+// inviteCode admits whoever redeems it to a room, past its password and
+// invite-only flag
+type inviteCode struct {
+	room      string
+	creator   string
+	expires   time.Time
+	singleUse bool
+}
+
+// newInviteCode returns a random code such as 7KQ2MZ4D
+func newInviteCode() string {
+	b := make([]byte, 5)
+	rand.Read(b)
+	return base32.StdEncoding.EncodeToString(b)
+}
+
+// sweepInviteCodes forgets expired codes. Caller holds s.mutex.
+func (s *Server) sweepInviteCodes(now time.Time) {
+	for code, invite := range s.inviteCodes {
+		if now.After(invite.expires) {
+			delete(s.inviteCodes, code)
+		}
+	}
+}
+
+// inviteCodeCommand creates a code for a room: single-use by default, or
+// usable by anyone until it expires when a duration is given
+func inviteCodeCommand(s *Server, c *Client, args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("usage: /invitecode <room> [duration]")
+	}
+	invite := &inviteCode{
+		room:      args[0],
+		creator:   c.name,
+		expires:   time.Now().Add(inviteCodeLifetime),
+		singleUse: true,
+	}
+	if len(args) == 2 {
+		d, err := time.ParseDuration(args[1])
+		if err != nil || d <= 0 || d > maxInviteCodeLifetime {
+			return fmt.Errorf("usage: /invitecode <room> [duration], e.g. 2h, up to %s", maxInviteCodeLifetime)
+		}
+		invite.expires, invite.singleUse = time.Now().Add(d), false
+	}
+
+	s.mutex.Lock()
+	room, exists := s.rooms[invite.room]
+	if !exists {
+		s.mutex.Unlock()
+		return fmt.Errorf("room does not exist")
+	}
+	if !s.isRoomOperator(room, c) {
+		s.mutex.Unlock()
+		return fmt.Errorf("only room operators can create invite codes")
+	}
+	s.sweepInviteCodes(time.Now())
+	code := newInviteCode()
+	s.inviteCodes[code] = invite
+	s.mutex.Unlock()
+
+	s.logActivity("Invite code created", "client", c.name, "room", room.name)
+	use := "once"
+	if !invite.singleUse {
+		use = "by anyone"
+	}
+	c.write([]byte(fmt.Sprintf("Invite code for %s: #%s (can be used %s until %s). Share it; others type /join #%s\n",
+		room.name, code, use, invite.expires.Format("2006-01-02 15:04"), code)))
+	return nil
+}
+
+// redeemInviteCode admits c to the room of code and joins it
+func (s *Server) redeemInviteCode(c *Client, code string) error {
+	code = strings.ToUpper(strings.TrimPrefix(code, "#"))
+	s.mutex.Lock()
+	s.sweepInviteCodes(time.Now())
+	invite, exists := s.inviteCodes[code]
+	if !exists {
+		s.mutex.Unlock()
+		return fmt.Errorf("invalid or expired invite code")
+	}
+	room, exists := s.rooms[invite.room]
+	if !exists {
+		delete(s.inviteCodes, code)
+		s.mutex.Unlock()
+		return fmt.Errorf("room %s no longer exists", invite.room)
+	}
+	if invite.singleUse {
+		delete(s.inviteCodes, code)
+	}
+	room.admitted[strings.ToLower(c.name)] = true
+	s.mutex.Unlock()
+
+	s.logActivity("Invite code redeemed", "client", c.name, "creator", invite.creator, "room", room.name)
+	return s.joinRoom(c, room.name, "")
+}
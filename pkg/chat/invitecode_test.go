@@ -0,0 +1,85 @@
+package chat
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+var inviteCodePattern = regexp.MustCompile(`#([A-Z2-7]{8}) `)
+
+// readInviteCode reads lines until the reply to /invitecode
+func (c *TestClient) readInviteCode(t *testing.T) string {
+	c.conn.SetReadDeadline(time.Now().Add(messageTimeout))
+	for {
+		line, err := c.reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("no invite code: %v", err)
+		}
+		if strings.Contains(line, "Invite code for") {
+			return inviteCodePattern.FindStringSubmatch(line)[1]
+		}
+	}
+}
+
+func TestInviteCodes(t *testing.T) {
+	config := DefaultConfig()
+	config.RoomsFile = ""
+	addr := setupTestServerWithConfig(t, config)
+	join := func(name string) *TestClient {
+		c, err := newTestClient(t, addr)
+		if err != nil {
+			t.Fatalf("Client connection failed: %v", err)
+		}
+		c.sendMessage(name)
+		if err := c.expectMessage(t, name+" joined"); err != nil {
+			t.Fatalf("Join as %s failed: %v", name, err)
+		}
+		return c
+	}
+	alice := join("Alice")
+	defer alice.close()
+	bob := join("Bob")
+	defer bob.close()
+	carol := join("Carol")
+	defer carol.close()
+
+	alice.sendMessage("/create vault hunter22")
+	bob.expectMessage(t, "Alice left the room")
+	alice.sendMessage("/inviteonly on")
+	alice.expectMessage(t, "now invite-only")
+
+	bob.sendMessage("/invitecode vault")
+	if err := bob.expectMessage(t, "only room operators can create invite codes"); err != nil {
+		t.Fatalf("non-operator created a code: %v", err)
+	}
+
+	alice.sendMessage("/invitecode vault")
+	code := alice.readInviteCode(t)
+	bob.sendMessage("/join #" + strings.ToLower(code))
+	if err := alice.expectMessage(t, "Bob joined the room"); err != nil {
+		t.Fatalf("code did not admit Bob: %v", err)
+	}
+	carol.sendMessage("/join #" + code)
+	if err := carol.expectMessage(t, "invalid or expired invite code"); err != nil {
+		t.Fatalf("single-use code used twice: %v", err)
+	}
+
+	alice.sendMessage("/invitecode vault 1h")
+	code = alice.readInviteCode(t)
+	carol.sendMessage("/join #" + code)
+	if err := alice.expectMessage(t, "Carol joined the room"); err != nil {
+		t.Fatalf("time-limited code did not admit Carol: %v", err)
+	}
+	carol.sendMessage("/leave")
+	carol.sendMessage("/join #" + code)
+	if err := alice.expectMessage(t, "Carol joined the room"); err != nil {
+		t.Fatalf("time-limited code not reusable: %v", err)
+	}
+	carol.sendMessage("/leave")
+	carol.sendMessage("/join vault")
+	if err := carol.expectMessage(t, "room vault requires a password"); err != nil {
+		t.Fatalf("code admitted Carol beyond its redemption: %v", err)
+	}
+}
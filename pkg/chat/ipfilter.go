@@ -0,0 +1,73 @@
+package chat
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ipFilter decides which remote addresses may connect. Deny rules win;
+// when allow rules exist, an address must match one of them.
+type ipFilter struct {
+	allow []*net.IPNet
+	deny  []*net.IPNet
+}
+
+func newIPFilter(allow, deny []string) (*ipFilter, error) {
+	f := &ipFilter{}
+	var err error
+	if f.allow, err = parseNetworks(allow); err != nil {
+		return nil, err
+	}
+	if f.deny, err = parseNetworks(deny); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// parseNetworks accepts CIDR ranges as well as single addresses
+func parseNetworks(specs []string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		if !strings.Contains(spec, "/") {
+			ip := net.ParseIP(spec)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid address %q", spec)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network %q", spec)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+func (f *ipFilter) permits(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return len(f.allow) == 0 && len(f.deny) == 0
+	}
+	for _, network := range f.deny {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	if len(f.allow) == 0 {
+		return true
+	}
+	for _, network := range f.allow {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package chat
+
+import "testing"
+
+func TestIPFilter(t *testing.T) {
+	f, err := newIPFilter([]string{"10.0.0.0/8", "192.168.1.5", "fd00::/8"}, []string{"10.6.0.0/16"})
+	if err != nil {
+		t.Fatalf("newIPFilter failed: %v", err)
+	}
+	tests := map[string]bool{
+		"10.1.2.3":    true,
+		"10.6.0.9":    false, // Denied inside an allowed range
+		"192.168.1.5": true,
+		"192.168.1.6": false,
+		"fd00::1":     true,
+		"8.8.8.8":     false,
+	}
+	for ip, want := range tests {
+		if got := f.permits(ip); got != want {
+			t.Errorf("permits(%s) = %v, want %v", ip, got, want)
+		}
+	}
+
+	open, _ := newIPFilter(nil, []string{"203.0.113.0/24"})
+	if !open.permits("8.8.8.8") || open.permits("203.0.113.50") {
+		t.Error("deny-only filter should allow everything outside the denied range")
+	}
+
+	if _, err := newIPFilter([]string{"10.0.0.0/33"}, nil); err == nil {
+		t.Error("expected error for an invalid network")
+	}
+}
+
+func TestDeniedConnection(t *testing.T) {
+	config := DefaultConfig()
+	config.DenyNetworks = []string{"127.0.0.0/8", "::1"}
+	addr := setupTestServerWithConfig(t, config)
+
+	client, err := newTestClient(t, addr)
+	if err != nil {
+		t.Fatalf("Client connection failed: %v", err)
+	}
+	defer client.close()
+	if err := client.expectMessage(t, "Welcome"); err == nil {
+		t.Error("denied address received the welcome")
+	}
+}
@@ -0,0 +1,57 @@
+package chat
+
+import (
+	"testing"
+)
+
+func TestPerIPConnectionLimit(t *testing.T) {
+	config := DefaultConfig()
+	config.MaxConnsPerIP = 2
+	addr := setupTestServerWithConfig(t, config)
+
+	var clients []*TestClient
+	defer func() {
+		for _, c := range clients {
+			c.close()
+		}
+	}()
+	for i := 0; i < 2; i++ {
+		c, err := newTestClient(t, addr)
+		if err != nil {
+			t.Fatalf("Client %d connection failed: %v", i, err)
+		}
+		clients = append(clients, c)
+		if err := c.expectMessage(t, "Welcome"); err != nil {
+			t.Fatalf("Client %d within the limit was rejected: %v", i, err)
+		}
+	}
+
+	extra, err := newTestClient(t, addr)
+	if err != nil {
+		t.Fatalf("Extra connection failed: %v", err)
+	}
+	defer extra.close()
+	if err := extra.expectMessage(t, "Too many connections from your address"); err != nil {
+		t.Fatalf("Connection over the limit was accepted: %v", err)
+	}
+
+	// Closing a session frees its slot
+	clients[0].close()
+	clients = clients[1:]
+	var again *TestClient
+	for attempt := 0; attempt < 5; attempt++ {
+		c, err := newTestClient(t, addr)
+		if err != nil {
+			t.Fatalf("Reconnect failed: %v", err)
+		}
+		if c.expectMessage(t, "Welcome") == nil {
+			again = c
+			break
+		}
+		c.close()
+	}
+	if again == nil {
+		t.Fatal("slot was not released after disconnect")
+	}
+	again.close()
+}
@@ -0,0 +1,409 @@
+package chat
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ircServerName prefixes server replies on the IRC port
+const ircServerName = "netcat"
+
+// ircConn lets an IRC client (irssi, weechat, ...) use the chat through
+// the same handleConnection path as TCP clients. Rooms are channels
+// (#general) and private messages are PRIVMSGs to a nick. Read turns IRC
+// commands into the lines a text client would type; Write turns plain
+// server text into NOTICEs, and Client.sendMessage and joinRoom call
+// writeMessage and joined for structured output.
+type ircConn struct {
+	net.Conn
+	server  *Server
+	reader  *bufio.Reader
+	pending []byte
+
+	mutex      sync.Mutex
+	nick       string // Confirmed chat name once registered
+	registered bool
+	room       string
+	password   string // From PASS, sent after the name when auth is required
+}
+
+func newIRCConn(conn net.Conn, s *Server) *ircConn {
+	return &ircConn{Conn: conn, server: s, reader: bufio.NewReader(conn)}
+}
+
+// ircChannel maps a room name to its channel name and back
+func ircChannel(room string) string { return "#" + room }
+
+func ircRoom(channel string) string { return strings.TrimLeft(channel, "#&") }
+
+// parseIRC splits a line into its command and parameters, keeping a
+// trailing ":" parameter whole
+func parseIRC(line string) (string, []string) {
+	if strings.HasPrefix(line, ":") {
+		// Clients may send a prefix; it carries nothing we need
+		if _, rest, ok := strings.Cut(line, " "); ok {
+			line = rest
+		}
+	}
+	var trailing string
+	hasTrailing := false
+	if before, after, ok := strings.Cut(line, " :"); ok {
+		line, trailing, hasTrailing = before, after, true
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return "", nil
+	}
+	params := fields[1:]
+	if hasTrailing {
+		params = append(params, trailing)
+	}
+	return strings.ToUpper(fields[0]), params
+}
+
+func (i *ircConn) Read(p []byte) (int, error) {
+	for len(i.pending) == 0 {
+		line, err := i.reader.ReadString('\n')
+		if trimmed := strings.TrimRight(line, "\r\n"); trimmed != "" {
+			text, quit := i.translate(trimmed)
+			if quit {
+				return 0, io.EOF
+			}
+			i.pending = []byte(text)
+		}
+		if err != nil && len(i.pending) == 0 {
+			return 0, err
+		}
+	}
+	n := copy(p, i.pending)
+	i.pending = i.pending[n:]
+	return n, nil
+}
+
+// translate handles one IRC line and returns the chat input it stands for,
+// which may be empty if it was answered directly
+func (i *ircConn) translate(line string) (string, bool) {
+	command, params := parseIRC(line)
+	param := func(n int) string {
+		if n < len(params) {
+			return params[n]
+		}
+		return ""
+	}
+
+	i.mutex.Lock()
+	registered, nick, room := i.registered, i.nick, i.room
+	i.mutex.Unlock()
+
+	switch command {
+	case "PING":
+		i.send(fmt.Sprintf(":%s PONG %s :%s", ircServerName, ircServerName, param(0)))
+		return "", false
+	case "PONG":
+		return "", false
+	case "QUIT":
+		return "", true
+	case "CAP":
+		if strings.EqualFold(param(0), "LS") {
+			i.send(fmt.Sprintf(":%s CAP * LS :", ircServerName))
+		}
+		return "", false
+	}
+
+	if !registered {
+		switch command {
+		case "PASS":
+			i.mutex.Lock()
+			i.password = param(0)
+			i.mutex.Unlock()
+			return "", false
+		case "NICK":
+			if param(0) == "" {
+				i.reply(431, ":No nickname given")
+				return "", false
+			}
+			i.mutex.Lock()
+			i.nick = param(0)
+			password := i.password
+			i.mutex.Unlock()
+			if i.server.config.AuthRequired && password != "" {
+				return param(0) + "\n" + password + "\n", false
+			}
+			return param(0) + "\n", false
+		case "USER":
+			return "", false
+		}
+		// Anything else, e.g. the join challenge answer sent with /quote
+		return line + "\n", false
+	}
+
+	switch command {
+	case "NICK":
+		if param(0) == "" {
+			i.reply(431, ":No nickname given")
+			return "", false
+		}
+		return "/nick " + param(0) + "\n", false
+	case "USER", "PASS":
+		i.reply(462, ":You may not reregister")
+	case "JOIN":
+		if param(0) == "" {
+			i.reply(461, "JOIN :Not enough parameters")
+			return "", false
+		}
+		// One room at a time: join the first channel listed
+		target := ircRoom(strings.Split(param(0), ",")[0])
+		key := strings.Split(param(1), ",")[0]
+		if target == room {
+			return "", false
+		}
+		i.server.mutex.Lock()
+		_, exists := i.server.rooms[target]
+		i.server.mutex.Unlock()
+		if !exists {
+			return strings.TrimSpace("/create "+target+" "+key) + "\n", false
+		}
+		return strings.TrimSpace("/join "+target+" "+key) + "\n", false
+	case "PART":
+		// Everyone is always in some room, so parting returns to the lobby
+		if ircRoom(param(0)) != room || room == i.server.lobby() {
+			i.reply(442, ircChannel(ircRoom(param(0)))+" :You're not on that channel")
+			return "", false
+		}
+		return "/leave\n", false
+	case "PRIVMSG", "NOTICE":
+		target, text := param(0), param(1)
+		if target == "" || text == "" {
+			if command == "PRIVMSG" {
+				i.reply(412, ":No text to send")
+			}
+			return "", false
+		}
+		text = strings.ReplaceAll(text, "\x01", "")
+		if action, ok := strings.CutPrefix(text, "ACTION "); ok {
+			text = "* " + nick + " " + action
+		}
+		if !strings.HasPrefix(target, "#") && !strings.HasPrefix(target, "&") {
+			return "/msg " + target + " " + text + "\n", false
+		}
+		if ircRoom(target) != room {
+			i.reply(404, target+" :You are in "+ircChannel(room)+"; JOIN "+target+" first")
+			return "", false
+		}
+		return text + "\n", false
+	case "MOTD":
+		i.motd()
+	case "NAMES":
+		channel := param(0)
+		if channel == "" {
+			channel = ircChannel(room)
+		}
+		i.server.mutex.Lock()
+		if r, exists := i.server.rooms[ircRoom(channel)]; exists {
+			i.sendNames(r)
+		} else {
+			i.reply(366, channel+" :End of /NAMES list")
+		}
+		i.server.mutex.Unlock()
+	case "TOPIC":
+		if len(params) > 1 {
+			return "/topic " + param(1) + "\n", false
+		}
+		return "/topic\n", false
+	case "MODE":
+		if strings.HasPrefix(param(0), "#") {
+			i.reply(324, param(0)+" +")
+		} else {
+			i.reply(221, "+")
+		}
+	case "WHO":
+		i.reply(315, param(0)+" :End of /WHO list")
+	case "LIST":
+		return "/rooms\n", false
+	default:
+		i.reply(421, command+" :Unknown command")
+	}
+	return "", false
+}
+
+// Write sends plain server text as NOTICEs. Before registration the
+// login errors are mapped to their numeric replies.
+func (i *ircConn) Write(p []byte) (int, error) {
+	i.mutex.Lock()
+	registered, nick := i.registered, i.nick
+	i.mutex.Unlock()
+
+	var lines []string
+	for _, line := range strings.Split(string(p), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" || line == "Please enter another name:" || line == "Please enter your name:":
+		case registered:
+			lines = append(lines, fmt.Sprintf(":%s NOTICE %s :%s", ircServerName, nick, line))
+		case strings.HasPrefix(line, "Invalid name: name already taken"):
+			lines = append(lines, fmt.Sprintf(":%s 433 * %s :Nickname is already in use", ircServerName, nick))
+		case strings.HasPrefix(line, "Invalid name: "):
+			lines = append(lines, fmt.Sprintf(":%s 432 * %s :%s", ircServerName, nick, strings.TrimPrefix(line, "Invalid name: ")))
+		case strings.HasPrefix(line, "Authentication failed"):
+			lines = append(lines, fmt.Sprintf(":%s 464 * :Password incorrect", ircServerName))
+		default:
+			lines = append(lines, fmt.Sprintf(":%s NOTICE * :%s", ircServerName, line))
+		}
+	}
+	if len(lines) > 0 {
+		if err := i.send(strings.Join(lines, "\r\n")); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// send writes one or more CRLF-separated lines
+func (i *ircConn) send(lines string) error {
+	_, err := i.Conn.Write([]byte(lines + "\r\n"))
+	return err
+}
+
+// reply sends a numeric reply addressed to the client
+func (i *ircConn) reply(code int, params string) {
+	i.mutex.Lock()
+	nick := i.nick
+	i.mutex.Unlock()
+	if nick == "" {
+		nick = "*"
+	}
+	i.send(fmt.Sprintf(":%s %03d %s %s", ircServerName, code, nick, params))
+}
+
+// welcome completes registration once the chat accepted name
+func (i *ircConn) welcome(name string) {
+	i.mutex.Lock()
+	i.nick = name
+	i.registered = true
+	i.mutex.Unlock()
+	i.reply(1, ":Welcome to TCP-Chat, "+name)
+	i.reply(2, ":Your host is "+ircServerName)
+	i.reply(3, ":This server speaks a subset of RFC 1459; rooms are channels")
+	i.reply(4, ircServerName+" netcat o o")
+	i.motd()
+}
+
+// motd sends the message of the day as RPL_MOTD lines
+func (i *ircConn) motd() {
+	text := i.server.motd.get()
+	if text == "" {
+		i.reply(422, ":MOTD File is missing")
+		return
+	}
+	i.reply(375, ":- "+ircServerName+" Message of the day -")
+	for _, line := range strings.Split(text, "\n") {
+		i.reply(372, ":- "+line)
+	}
+	i.reply(376, ":End of /MOTD command")
+}
+
+// renamed tells the client its chat name changed to name
+func (i *ircConn) renamed(name string) {
+	i.mutex.Lock()
+	old := i.nick
+	i.nick = name
+	i.mutex.Unlock()
+	i.send(":" + old + "!" + old + "@" + ircServerName + " NICK " + name)
+}
+
+// joined tells the client it moved from oldRoom into room. Caller holds
+// s.mutex.
+func (i *ircConn) joined(oldRoom string, room *ChatRoom) {
+	i.mutex.Lock()
+	i.room = room.name
+	prefix := i.nick + "!" + i.nick + "@" + ircServerName
+	i.mutex.Unlock()
+
+	if oldRoom != "" {
+		i.send(":" + prefix + " PART " + ircChannel(oldRoom))
+	}
+	i.send(":" + prefix + " JOIN " + ircChannel(room.name))
+	if room.topic != "" {
+		i.reply(332, ircChannel(room.name)+" :"+room.topic)
+	}
+	i.sendNames(room)
+}
+
+// sendNames lists the members of room, marking moderators with @. Caller
+// holds s.mutex.
+func (i *ircConn) sendNames(room *ChatRoom) {
+	names := make([]string, 0, len(room.clients))
+	for _, c := range room.clients {
+		if c.role >= RoleModerator {
+			names = append(names, "@"+c.name)
+		} else {
+			names = append(names, c.name)
+		}
+	}
+	sort.Strings(names)
+	channel := ircChannel(room.name)
+	i.reply(353, "= "+channel+" :"+strings.Join(names, " "))
+	i.reply(366, channel+" :End of /NAMES list")
+}
+
+// writeMessage renders msg for a client currently in room
+func (i *ircConn) writeMessage(msg Message, room string) {
+	i.mutex.Lock()
+	nick := i.nick
+	i.mutex.Unlock()
+
+	switch msg.Type {
+	case MessageTypeChat:
+		i.send(fmt.Sprintf(":%s!%s@%s PRIVMSG %s :%s", msg.From, msg.From, ircServerName, ircChannel(room), msg.Content))
+	case MessageTypePrivate:
+		i.send(fmt.Sprintf(":%s!%s@%s PRIVMSG %s :%s", msg.From, msg.From, ircServerName, nick, msg.Content))
+	case MessageTypeReaction:
+		i.send(fmt.Sprintf(":%s NOTICE %s :%s reacted %s to #%d", ircServerName, nick, msg.From, msg.Content, msg.Ref))
+	case MessageTypeReceipt:
+		i.send(fmt.Sprintf(":%s NOTICE %s :Private message #%d to %s: %s", ircServerName, nick, msg.Ref, msg.From, msg.Content))
+	case MessageTypeMention, MessageTypeKeyword:
+		// IRC clients highlight their nick themselves
+		switch {
+		case msg.To == room:
+			i.send(fmt.Sprintf(":%s!%s@%s PRIVMSG %s :%s", msg.From, msg.From, ircServerName, ircChannel(room), msg.Content))
+		case msg.Type == MessageTypeMention:
+			i.send(fmt.Sprintf(":%s NOTICE %s :%s mentioned you in %s: %s", ircServerName, nick, msg.From, ircChannel(msg.To), msg.Content))
+		default:
+			i.send(fmt.Sprintf(":%s NOTICE %s :%s in %s: %s", ircServerName, nick, msg.From, ircChannel(msg.To), msg.Content))
+		}
+	default:
+		i.send(fmt.Sprintf(":%s NOTICE %s :%s", ircServerName, nick, msg.Content))
+	}
+}
+
+// serveIRC accepts IRC clients until the listener fails
+func (s *Server) serveIRC(ctx context.Context, addr string, filter *ipFilter) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		s.log.Error("IRC gateway disabled", "addr", addr, "err", err)
+		return
+	}
+	defer listener.Close()
+	context.AfterFunc(ctx, func() { listener.Close() })
+	s.logActivity("IRC gateway listening", "addr", addr)
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() == nil {
+				s.log.Error("IRC gateway stopped", "addr", addr, "err", err)
+			}
+			return
+		}
+		irc := newIRCConn(s.meter(conn), s)
+		if s.admit(irc, filter) {
+			go s.serveConn(ctx, irc)
+		}
+	}
+}
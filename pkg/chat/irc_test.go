@@ -0,0 +1,97 @@
+package chat
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseIRC(t *testing.T) {
+	tests := []struct {
+		line    string
+		command string
+		params  []string
+	}{
+		{"NICK alice", "NICK", []string{"alice"}},
+		{"PRIVMSG #general :hello there", "PRIVMSG", []string{"#general", "hello there"}},
+		{":alice!a@host join #dev key", "JOIN", []string{"#dev", "key"}},
+		{"USER alice 0 * :Alice Liddell", "USER", []string{"alice", "0", "*", "Alice Liddell"}},
+	}
+	for _, tt := range tests {
+		command, params := parseIRC(tt.line)
+		if command != tt.command || !reflect.DeepEqual(params, tt.params) {
+			t.Errorf("parseIRC(%q) = %q %q", tt.line, command, params)
+		}
+	}
+}
+
+func TestIRCGateway(t *testing.T) {
+	config := DefaultConfig()
+	config.IRCAddr = freeAddr(t)
+	config.RoomsFile = "" // JOIN #dev creates a room
+	addr := setupTestServerWithConfig(t, config)
+
+	// The gateway's listener opens once the server runs
+	var irc *TestClient
+	var err error
+	for i := 0; i < 20; i++ {
+		if irc, err = newTestClient(t, config.IRCAddr); err == nil {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("IRC connection failed: %v", err)
+	}
+	defer irc.close()
+	irc.sendMessage("NICK alice")
+	irc.sendMessage("USER alice 0 * :Alice")
+	if err := irc.expectMessage(t, " 001 alice :Welcome"); err != nil {
+		t.Fatalf("Registration failed: %v", err)
+	}
+	if err := irc.expectMessage(t, ":alice!alice@netcat JOIN #general"); err != nil {
+		t.Fatalf("Not joined to #general: %v", err)
+	}
+	if err := irc.expectMessage(t, " 353 alice = #general :alice"); err != nil {
+		t.Errorf("NAMES not sent on join: %v", err)
+	}
+
+	tcp, err := newTestClient(t, addr)
+	if err != nil {
+		t.Fatalf("Client connection failed: %v", err)
+	}
+	defer tcp.close()
+	tcp.expectMessage(t, "ENTER YOUR NAME")
+	tcp.sendMessage("Bob")
+	tcp.expectMessage(t, "Bob joined")
+
+	irc.sendMessage("PRIVMSG #general :hi from irc")
+	if err := tcp.expectMessage(t, "[alice]: hi from irc"); err != nil {
+		t.Errorf("Channel message not delivered: %v", err)
+	}
+	tcp.sendMessage("hi from tcp")
+	if err := irc.expectMessage(t, ":Bob!Bob@netcat PRIVMSG #general :hi from tcp"); err != nil {
+		t.Errorf("Room message not relayed as PRIVMSG: %v", err)
+	}
+	tcp.sendMessage("/msg alice psst")
+	if err := irc.expectMessage(t, ":Bob!Bob@netcat PRIVMSG alice :psst"); err != nil {
+		t.Errorf("Private message not relayed as PRIVMSG: %v", err)
+	}
+
+	irc.sendMessage("JOIN #dev")
+	if err := irc.expectMessage(t, ":alice!alice@netcat JOIN #dev"); err != nil {
+		t.Fatalf("JOIN of a new channel failed: %v", err)
+	}
+	irc.sendMessage("PRIVMSG #general :wrong room")
+	if err := irc.expectMessage(t, " 404 alice #general"); err != nil {
+		t.Errorf("Message to a channel the client is not in accepted: %v", err)
+	}
+	irc.sendMessage("NICK alicia")
+	if err := irc.expectMessage(t, ":alice!alice@netcat NICK alicia"); err != nil {
+		t.Errorf("Nick change not confirmed: %v", err)
+	}
+	irc.sendMessage("PING :token")
+	if err := irc.expectMessage(t, "PONG netcat :token"); err != nil {
+		t.Errorf("PING not answered: %v", err)
+	}
+}
@@ -0,0 +1,147 @@
+package chat
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Limits on the keywords a user may subscribe to with /notify
+const (
+	maxKeywords      = 20
+	maxKeywordLength = 32
+)
+
+// messageWords returns the lowercase words of content, split at anything
+// that is not a letter, digit, '-' or '_'
+func messageWords(content string) map[string]bool {
+	words := make(map[string]bool)
+	for _, w := range strings.FieldsFunc(content, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_'
+	}) {
+		words[strings.ToLower(w)] = true
+	}
+	return words
+}
+
+// cacheKeywords copies the /notify keywords of the account c owns onto c,
+// so broadcasts need not look them up
+func (s *Server) cacheKeywords(c *Client) {
+	account, _ := s.accounts.get(c.name)
+	c.keywords.Store(&account.Keywords)
+}
+
+// keywordHit returns the first of c's keywords among words, the words of
+// msg, or "" if none is or c should not be alerted about msg
+func keywordHit(c *Client, msg Message, words map[string]bool) string {
+	keywords := c.keywords.Load()
+	if keywords == nil || msg.Type != MessageTypeChat || strings.EqualFold(c.name, msg.From) || c.ignores(msg.From) {
+		return ""
+	}
+	for _, keyword := range *keywords {
+		if words[keyword] {
+			return keyword
+		}
+	}
+	return ""
+}
+
+// keywordAlert is the notification for msg in room matching a keyword
+func keywordAlert(msg Message, room string) Message {
+	return Message{
+		Type:      MessageTypeKeyword,
+		From:      msg.From,
+		To:        room,
+		Content:   msg.Content,
+		Timestamp: msg.Timestamp,
+		ID:        msg.ID,
+	}
+}
+
+// deliverKeywords alerts users in other rooms whose keywords msg contains.
+// Those in the room itself get the highlighted message from deliverToRoom,
+// and mentioned users already got a mention. Only public rooms alert
+// outsiders, who could not read along otherwise.
+func (s *Server) deliverKeywords(room string, msg Message) {
+	mentioned := mentionedNames(msg.Content)
+	words := messageWords(msg.Content)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if r, exists := s.rooms[room]; !exists || !r.public() {
+		return
+	}
+	for _, c := range s.clients.all() {
+		if c.room == room || (mentioned[strings.ToLower(c.name)] && s.wantsMention(c, msg.From)) {
+			continue
+		}
+		if keywordHit(c, msg, words) != "" {
+			c.sendMessage(keywordAlert(msg, room))
+		}
+	}
+}
+
+// notifyCommand manages the keywords that trigger alerts for the client
+func notifyCommand(s *Server, c *Client, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: /notify add|remove <word>, /notify list")
+	}
+	if !s.ownsAccount(c) {
+		return errNotOwner
+	}
+	switch strings.ToLower(args[0]) {
+	case "list":
+		account, _ := s.accounts.get(c.name)
+		if len(account.Keywords) == 0 {
+			c.write([]byte("You have no notification keywords\n"))
+			return nil
+		}
+		c.write([]byte("Notification keywords: " + strings.Join(account.Keywords, ", ") + "\n"))
+		return nil
+	case "add", "remove":
+	default:
+		return fmt.Errorf("usage: /notify add|remove <word>, /notify list")
+	}
+	if len(args) != 2 {
+		return fmt.Errorf("usage: /notify %s <word>", strings.ToLower(args[0]))
+	}
+
+	keyword := strings.ToLower(args[1])
+	if strings.ToLower(args[0]) == "remove" {
+		err := s.accounts.update(c.name, func(a *Account) error {
+			i := slices.Index(a.Keywords, keyword)
+			if i < 0 {
+				return fmt.Errorf("%s is not one of your keywords", keyword)
+			}
+			a.Keywords = slices.Delete(slices.Clone(a.Keywords), i, i+1)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		s.cacheKeywords(c)
+		c.write([]byte(fmt.Sprintf("No longer notifying you about %s\n", keyword)))
+		return nil
+	}
+
+	if words := messageWords(keyword); len(words) != 1 || !words[keyword] || utf8.RuneCountInString(keyword) > maxKeywordLength {
+		return fmt.Errorf("keywords are single words of up to %d characters", maxKeywordLength)
+	}
+	err := s.accounts.update(c.name, func(a *Account) error {
+		if slices.Contains(a.Keywords, keyword) {
+			return fmt.Errorf("%s is already one of your keywords", keyword)
+		}
+		if len(a.Keywords) >= maxKeywords {
+			return fmt.Errorf("you can have at most %d keywords", maxKeywords)
+		}
+		a.Keywords = append(a.Keywords, keyword)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.cacheKeywords(c)
+	c.write([]byte(fmt.Sprintf("You will be notified when someone says %s\n", keyword)))
+	return nil
+}
@@ -0,0 +1,123 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMessageWords(t *testing.T) {
+	words := messageWords("Deploy: v2-beta, re_deploy redeploy!")
+	for _, want := range []string{"deploy", "v2-beta", "re_deploy", "redeploy"} {
+		if !words[want] {
+			t.Errorf("missing word %q in %v", want, words)
+		}
+	}
+	if len(words) != 4 {
+		t.Errorf("words = %v, want 4", words)
+	}
+}
+
+func TestKeywordHit(t *testing.T) {
+	msg := Message{Type: MessageTypeChat, From: "Alice", Content: "time to DEPLOY"}
+	words := messageWords(msg.Content)
+	owner := &Client{name: "Bob"}
+	owner.keywords.Store(&[]string{"release", "deploy"})
+	if hit := keywordHit(owner, msg, words); hit != "deploy" {
+		t.Errorf("keywordHit = %q, want deploy", hit)
+	}
+	// Keywords are only cached for clients that own their account
+	if hit := keywordHit(&Client{name: "Bob"}, msg, words); hit != "" {
+		t.Errorf("client without cached keywords alerted about %q", hit)
+	}
+	msg.From = "bob"
+	if hit := keywordHit(owner, msg, words); hit != "" {
+		t.Errorf("alerted about its own message: %q", hit)
+	}
+}
+
+func TestKeywordNotify(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountsFile = ""
+	config.RoomsFile = ""
+	addr := setupTestServerWithConfig(t, config)
+	join := func(name string) *TestClient {
+		c, err := newTestClient(t, addr)
+		if err != nil {
+			t.Fatalf("Client connection failed: %v", err)
+		}
+		c.sendMessage(name)
+		if err := c.expectMessage(t, name+" joined"); err != nil {
+			t.Fatalf("Join as %s failed: %v", name, err)
+		}
+		return c
+	}
+	alice := join("Alice")
+	defer alice.close()
+	bob := join("Bob")
+	defer bob.close()
+
+	bob.sendMessage("/notify add Deploy")
+	if err := bob.expectMessage(t, errNotOwner.Error()); err != nil {
+		t.Fatalf("keyword added for a guest: %v", err)
+	}
+	bob.sendMessage("/register secret1")
+	if err := bob.expectMessageWithin(t, "now registered", passwordTimeout); err != nil {
+		t.Fatalf("Registration failed: %v", err)
+	}
+	bob.sendMessage("/notify add Deploy")
+	if err := bob.expectMessage(t, "You will be notified when someone says deploy"); err != nil {
+		t.Fatalf("keyword not added: %v", err)
+	}
+	bob.sendMessage("/notify add two words")
+	if err := bob.expectMessage(t, "usage: /notify add <word>"); err != nil {
+		t.Fatalf("bad keyword not rejected: %v", err)
+	}
+	bob.sendMessage("/create dev")
+	if err := bob.expectMessage(t, "Bob joined"); err != nil {
+		t.Fatalf("room not created: %v", err)
+	}
+
+	alice.sendMessage("redeploy please")
+	alice.sendMessage("ok, DEPLOY now")
+	bob.conn.SetReadDeadline(time.Now().Add(messageTimeout))
+	for {
+		line, err := bob.reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("keyword alert not delivered: %v", err)
+		}
+		if strings.Contains(line, "redeploy") {
+			t.Fatalf("alerted about a partial word: %q", line)
+		}
+		if strings.Contains(line, "[Alice in general]: ok, DEPLOY now") {
+			break
+		}
+	}
+
+	// Rooms outsiders cannot read along in do not alert them
+	alice.sendMessage("/create ops")
+	if err := alice.expectMessage(t, "Alice joined"); err != nil {
+		t.Fatalf("room not created: %v", err)
+	}
+	alice.sendMessage("/inviteonly on")
+	if err := alice.expectMessage(t, "invite-only"); err != nil {
+		t.Fatalf("room not made invite-only: %v", err)
+	}
+	alice.sendMessage("deploy the secret build")
+	if err := bob.expectMessage(t, "secret build"); err == nil {
+		t.Fatal("alerted about a message in an invite-only room")
+	}
+
+	bob.sendMessage("/notify list")
+	if err := bob.expectMessage(t, "Notification keywords: deploy"); err != nil {
+		t.Fatalf("keywords not listed: %v", err)
+	}
+	bob.sendMessage("/notify remove deploy")
+	if err := bob.expectMessage(t, "No longer notifying you about deploy"); err != nil {
+		t.Fatalf("keyword not removed: %v", err)
+	}
+	bob.sendMessage("/notify list")
+	if err := bob.expectMessage(t, "You have no notification keywords"); err != nil {
+		t.Fatalf("keyword still listed: %v", err)
+	}
+}
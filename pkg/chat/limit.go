@@ -0,0 +1,36 @@
+package chat
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// setLimitCommand shows or changes how many clients the server admits.
+// Lowering it below the current count disconnects nobody; new clients are
+// turned away until enough have left.
+func setLimitCommand(s *Server, c *Client, args []string) error {
+	s.mutex.Lock()
+	clients, old := s.clients.len(), s.maxClients
+	if len(args) == 0 {
+		s.mutex.Unlock()
+		c.write([]byte(fmt.Sprintf("Client limit: %d (%d connected)\n", old, clients)))
+		return nil
+	}
+	limit, err := strconv.Atoi(args[0])
+	if err != nil || len(args) != 1 || limit < 1 {
+		s.mutex.Unlock()
+		return fmt.Errorf("usage: /setlimit <clients>, at least 1")
+	}
+	s.maxClients = limit
+	s.mutex.Unlock()
+
+	s.logActivity("Client limit changed", "client", c.name, "old", old, "new", limit)
+	s.audit("setlimit", c, strconv.Itoa(limit), "")
+	reply := fmt.Sprintf("Client limit changed from %d to %d\n", old, limit)
+	if clients > limit {
+		reply = fmt.Sprintf("Client limit changed from %d to %d; %d clients stay connected, new ones are refused until enough leave\n",
+			old, limit, clients)
+	}
+	c.write([]byte(reply))
+	return nil
+}
@@ -0,0 +1,54 @@
+package chat
+
+import (
+	"net"
+	"testing"
+)
+
+func TestSetLimit(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountsFile = ""
+	config.RoomsFile = ""
+	config.Store = StoreMemory
+	config.MaxClients = 5
+	s := NewServerWithConfig(config)
+	defer s.Logfile.Close()
+
+	admin := newPipeClient(t, "Alice")
+	admin.role = RoleAdmin
+	bob := newPipeClient(t, "Bob")
+	s.clients.add(admin.conn, admin)
+	s.clients.add(bob.conn, bob)
+
+	for _, args := range [][]string{{"0"}, {"many"}, {"3", "4"}} {
+		if err := setLimitCommand(s, admin, args); err == nil {
+			t.Errorf("/setlimit %v accepted", args)
+		}
+	}
+	if err := setLimitCommand(s, admin, nil); err != nil {
+		t.Errorf("/setlimit without arguments failed: %v", err)
+	}
+	if err := setLimitCommand(s, admin, []string{"2"}); err != nil {
+		t.Fatalf("/setlimit 2 failed: %v", err)
+	}
+	if s.maxClients != 2 {
+		t.Errorf("limit is %d, want 2", s.maxClients)
+	}
+
+	// Nobody is disconnected, but the server is now full
+	if s.clients.len() != 2 {
+		t.Errorf("%d clients left, want 2", s.clients.len())
+	}
+	filter, _ := newIPFilter(nil, nil)
+	server, client := net.Pipe()
+	defer client.Close()
+	go client.Read(make([]byte, 64))
+	if s.admit(server, filter) {
+		t.Error("admitted a client past the new limit")
+	}
+
+	s.handleCommand(bob, "/setlimit 10")
+	if s.maxClients != 2 {
+		t.Error("a user changed the client limit")
+	}
+}
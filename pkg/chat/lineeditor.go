@@ -0,0 +1,331 @@
+package chat
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// lineHistorySize caps how many sent lines the line editor remembers
+const lineHistorySize = 500
+
+// editorKey is a key press decoded from the terminal
+type editorKey int
+
+const (
+	keyIgnored editorKey = iota
+	keyRune
+	keyEnter
+	keyBackspace
+	keyDelete
+	keyLeft
+	keyRight
+	keyWordLeft
+	keyWordRight
+	keyHome
+	keyEnd
+	keyUp
+	keyDown
+	keyDeleteWord    // Ctrl-W
+	keyDeleteToStart // Ctrl-U
+	keyDeleteToEnd   // Ctrl-K
+	keyRedraw        // Ctrl-L
+	keyEOF           // Ctrl-D on an empty line
+	keyInterrupt     // Ctrl-C
+)
+
+// controlKeys maps the control characters the editor understands, mostly
+// as readline binds them
+var controlKeys = map[rune]editorKey{
+	0x01: keyHome,          // Ctrl-A
+	0x02: keyLeft,          // Ctrl-B
+	0x03: keyInterrupt,     // Ctrl-C
+	0x04: keyDelete,        // Ctrl-D
+	0x05: keyEnd,           // Ctrl-E
+	0x06: keyRight,         // Ctrl-F
+	0x08: keyBackspace,     // Ctrl-H
+	0x0a: keyEnter,         // Ctrl-J
+	0x0b: keyDeleteToEnd,   // Ctrl-K
+	0x0c: keyRedraw,        // Ctrl-L
+	0x0d: keyEnter,         // Ctrl-M
+	0x0e: keyDown,          // Ctrl-N
+	0x10: keyUp,            // Ctrl-P
+	0x15: keyDeleteToStart, // Ctrl-U
+	0x17: keyDeleteWord,    // Ctrl-W
+	0x7f: keyBackspace,
+}
+
+// escapeKeys maps the final byte of CSI and SS3 sequences, and
+// tildeKeys the parameter of CSI ... ~ sequences
+var (
+	escapeKeys = map[byte]editorKey{'A': keyUp, 'B': keyDown, 'C': keyRight, 'D': keyLeft, 'H': keyHome, 'F': keyEnd}
+	tildeKeys  = map[string]editorKey{"1": keyHome, "7": keyHome, "4": keyEnd, "8": keyEnd, "3": keyDelete}
+)
+
+// lineEditor reads lines from a terminal in raw mode with readline-style
+// editing and history, and prints what arrives meanwhile above the line
+// being typed
+type lineEditor struct {
+	in     *bufio.Reader
+	out    io.Writer
+	prompt string
+	width  func() int // Columns of the terminal, 0 if unknown
+
+	mutex   sync.Mutex // Guards the fields below and writes to out
+	line    []rune
+	cursor  int // Position in line
+	offset  int // First rune of line on screen, for lines wider than it
+	history []string
+	browse  int    // Position in history while browsing it with up and down
+	draft   []rune // The line being typed before browsing history
+	closed  bool   // No more lines are read, so the line is not drawn
+}
+
+func newLineEditor(in io.Reader, out io.Writer, prompt string, width func() int) *lineEditor {
+	return &lineEditor{in: bufio.NewReader(in), out: out, prompt: prompt, width: width}
+}
+
+// readLine returns the next line the user enters. Ctrl-C, and Ctrl-D on an
+// empty line, return io.EOF.
+func (e *lineEditor) readLine() (string, error) {
+	e.mutex.Lock()
+	e.refresh()
+	e.mutex.Unlock()
+	for {
+		key, r, err := e.readKey()
+		if err != nil {
+			return "", err
+		}
+
+		e.mutex.Lock()
+		if r == 0x04 && len(e.line) == 0 {
+			key = keyEOF
+		}
+		switch key {
+		case keyEnter:
+			line := string(e.line)
+			e.line, e.cursor, e.offset, e.draft = nil, 0, 0, nil
+			e.browse = len(e.history)
+			e.refresh()
+			e.mutex.Unlock()
+			return line, nil
+		case keyEOF, keyInterrupt:
+			fmt.Fprint(e.out, "\r\x1b[K")
+			e.mutex.Unlock()
+			return "", io.EOF
+		}
+		e.edit(key, r)
+		e.refresh()
+		e.mutex.Unlock()
+	}
+}
+
+// edit applies a key to the line. Caller holds e.mutex.
+func (e *lineEditor) edit(key editorKey, r rune) {
+	switch key {
+	case keyRune:
+		e.line = append(e.line[:e.cursor], append([]rune{r}, e.line[e.cursor:]...)...)
+		e.cursor++
+	case keyBackspace:
+		if e.cursor > 0 {
+			e.line = append(e.line[:e.cursor-1], e.line[e.cursor:]...)
+			e.cursor--
+		}
+	case keyDelete:
+		if e.cursor < len(e.line) {
+			e.line = append(e.line[:e.cursor], e.line[e.cursor+1:]...)
+		}
+	case keyLeft:
+		e.cursor = max(e.cursor-1, 0)
+	case keyRight:
+		e.cursor = min(e.cursor+1, len(e.line))
+	case keyWordLeft:
+		e.cursor = e.wordStart()
+	case keyWordRight:
+		for e.cursor < len(e.line) && unicode.IsSpace(e.line[e.cursor]) {
+			e.cursor++
+		}
+		for e.cursor < len(e.line) && !unicode.IsSpace(e.line[e.cursor]) {
+			e.cursor++
+		}
+	case keyHome:
+		e.cursor = 0
+	case keyEnd:
+		e.cursor = len(e.line)
+	case keyDeleteWord:
+		start := e.wordStart()
+		e.line = append(e.line[:start], e.line[e.cursor:]...)
+		e.cursor = start
+	case keyDeleteToStart:
+		e.line = append([]rune(nil), e.line[e.cursor:]...)
+		e.cursor = 0
+	case keyDeleteToEnd:
+		e.line = e.line[:e.cursor]
+	case keyRedraw:
+		fmt.Fprint(e.out, "\x1b[H\x1b[2J")
+	case keyUp:
+		if e.browse > 0 {
+			if e.browse == len(e.history) {
+				e.draft = e.line
+			}
+			e.browse--
+			e.setLine([]rune(e.history[e.browse]))
+		}
+	case keyDown:
+		if e.browse < len(e.history) {
+			e.browse++
+			if e.browse == len(e.history) {
+				e.setLine(e.draft)
+			} else {
+				e.setLine([]rune(e.history[e.browse]))
+			}
+		}
+	}
+}
+
+func (e *lineEditor) setLine(line []rune) {
+	e.line = append([]rune(nil), line...)
+	e.cursor = len(e.line)
+}
+
+// wordStart returns where the word before the cursor starts, skipping
+// the spaces after it
+func (e *lineEditor) wordStart() int {
+	i := e.cursor
+	for i > 0 && unicode.IsSpace(e.line[i-1]) {
+		i--
+	}
+	for i > 0 && !unicode.IsSpace(e.line[i-1]) {
+		i--
+	}
+	return i
+}
+
+// addHistory remembers a sent line for up and down
+func (e *lineEditor) addHistory(line string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if line == "" || (len(e.history) > 0 && e.history[len(e.history)-1] == line) {
+		return
+	}
+	e.history = append(e.history, line)
+	if len(e.history) > lineHistorySize {
+		e.history = e.history[len(e.history)-lineHistorySize:]
+	}
+	e.browse = len(e.history)
+}
+
+// print shows text above the line being typed
+func (e *lineEditor) print(text string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	fmt.Fprint(e.out, "\r\x1b[K")
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		fmt.Fprintln(e.out, line)
+	}
+	e.refresh()
+}
+
+// close stops drawing the line being typed
+func (e *lineEditor) close() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.closed = true
+	fmt.Fprint(e.out, "\r\x1b[K")
+}
+
+// refresh redraws the line, scrolled sideways so the cursor stays on
+// screen. Caller holds e.mutex.
+func (e *lineEditor) refresh() {
+	if e.closed {
+		return
+	}
+	width := 80
+	if e.width != nil {
+		if w := e.width(); w > 0 {
+			width = w
+		}
+	}
+	// One column stays free for the cursor at the end of the line
+	visible := max(width-len([]rune(e.prompt))-1, 10)
+	if e.cursor < e.offset {
+		e.offset = e.cursor
+	}
+	if e.cursor > e.offset+visible {
+		e.offset = e.cursor - visible
+	}
+	end := min(len(e.line), e.offset+visible)
+
+	var b strings.Builder
+	b.WriteString("\r\x1b[K")
+	b.WriteString(e.prompt)
+	b.WriteString(string(e.line[e.offset:end]))
+	if back := end - e.cursor; back > 0 {
+		fmt.Fprintf(&b, "\x1b[%dD", back)
+	}
+	io.WriteString(e.out, b.String())
+}
+
+// readKey decodes the next key press
+func (e *lineEditor) readKey() (editorKey, rune, error) {
+	r, _, err := e.in.ReadRune()
+	if err != nil {
+		return keyIgnored, 0, err
+	}
+	if r != 0x1b {
+		if key, ok := controlKeys[r]; ok {
+			return key, r, nil
+		}
+		if unicode.IsPrint(r) {
+			return keyRune, r, nil
+		}
+		return keyIgnored, r, nil
+	}
+
+	// Escape sequences: ESC [ <parameters> <final>, ESC O <final>, or
+	// ESC <key> for Alt-<key>
+	b, err := e.in.ReadByte()
+	if err != nil {
+		return keyIgnored, 0, err
+	}
+	switch b {
+	case 'b':
+		return keyWordLeft, 0, nil
+	case 'f':
+		return keyWordRight, 0, nil
+	case 0x7f, 0x08:
+		return keyDeleteWord, 0, nil
+	case '[', 'O':
+	default:
+		return keyIgnored, 0, nil
+	}
+	var params []byte
+	for {
+		c, err := e.in.ReadByte()
+		if err != nil {
+			return keyIgnored, 0, err
+		}
+		if c >= 0x30 && c <= 0x3f {
+			params = append(params, c)
+			continue
+		}
+		if c == '~' {
+			param, _, _ := strings.Cut(string(params), ";")
+			return tildeKeys[param], 0, nil
+		}
+		key := escapeKeys[c]
+		// Ctrl or Alt with left and right move by words: ESC [ 1 ; 5 D
+		if _, mod, ok := strings.Cut(string(params), ";"); ok && mod != "1" {
+			switch key {
+			case keyLeft:
+				key = keyWordLeft
+			case keyRight:
+				key = keyWordRight
+			}
+		}
+		return key, 0, nil
+	}
+}
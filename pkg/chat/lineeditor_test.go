@@ -0,0 +1,104 @@
+package chat
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLineEditor(t *testing.T) {
+	tests := []struct {
+		name, input, want string
+	}{
+		{"plain", "hello\r", "hello"},
+		{"newline", "hello\n", "hello"},
+		{"backspace", "héllo\x7f\r", "héll"},
+		{"ctrl-w", "say abc  \x17def\r", "say def"},
+		{"ctrl-u", "foo bar\x1b[D\x1b[D\x15baz\r", "bazar"},
+		{"ctrl-k", "abcdef\x1b[D\x1b[D\x0b\r", "abcd"},
+		{"left", "ac\x1b[Db\r", "abc"},
+		{"home and end", "bc\x01a\x05d\r", "abcd"},
+		{"home and end keys", "bc\x1b[Ha\x1b[Fd\x1b[1~\x1b[3~\r", "bcd"},
+		{"alt-b", "foo bar\x1bbX\r", "foo Xbar"},
+		{"ctrl-left", "foo bar\x1b[1;5DX\x1b[1;5CY\r", "foo XbarY"},
+		{"ctrl-d deletes", "ab\x1b[D\x04\r", "a"},
+		{"controls ignored", "a\x00\x07b\r", "ab"},
+	}
+	for _, tc := range tests {
+		var out bytes.Buffer
+		e := newLineEditor(strings.NewReader(tc.input), &out, "> ", nil)
+		got, err := e.readLine()
+		if err != nil || got != tc.want {
+			t.Errorf("%s: readLine = %q, %v, want %q", tc.name, got, err, tc.want)
+		}
+	}
+
+	for _, input := range []string{"\x03", "\x04", "abc\x03"} {
+		e := newLineEditor(strings.NewReader(input), io.Discard, "> ", nil)
+		if _, err := e.readLine(); err != io.EOF {
+			t.Errorf("readLine(%q) error = %v, want io.EOF", input, err)
+		}
+	}
+}
+
+func TestLineEditorHistory(t *testing.T) {
+	input := "\x1b[A\x1b[A\r" + // Two back
+		"draft\x1b[A\x1b[B\r" + // Back and forth to the draft
+		"\x10\x10\x10\x10\x0e\r" // Past the oldest with Ctrl-P, then Ctrl-N
+	e := newLineEditor(strings.NewReader(input), io.Discard, "> ", nil)
+	e.addHistory("one")
+	e.addHistory("two")
+	e.addHistory("two")
+	e.addHistory("")
+
+	for _, want := range []string{"one", "draft", "two"} {
+		got, err := e.readLine()
+		if err != nil || got != want {
+			t.Errorf("readLine = %q, %v, want %q", got, err, want)
+		}
+	}
+	if len(e.history) != 2 {
+		t.Errorf("history = %q, want duplicates and empty lines left out", e.history)
+	}
+}
+
+func TestLineEditorDisplay(t *testing.T) {
+	var out bytes.Buffer
+	long := strings.Repeat("abcdefghij", 3)
+	e := newLineEditor(strings.NewReader(long), &out, "> ", func() int { return 20 })
+	e.readLine() // Runs out of input mid-line
+
+	// The end of the line scrolls into view: 17 columns after the prompt
+	frames := strings.Split(out.String(), "\r\x1b[K")
+	if last := frames[len(frames)-1]; last != "> "+long[13:] {
+		t.Errorf("last frame = %q, want the tail of the line", last)
+	}
+
+	out.Reset()
+	e.print("[Bob]: hi\nsecond line\n")
+	if got, want := out.String(), "\r\x1b[K[Bob]: hi\nsecond line\n\r\x1b[K> "+long[13:]; got != want {
+		t.Errorf("print wrote %q, want %q", got, want)
+	}
+
+	e.close()
+	out.Reset()
+	e.print("Connection closed")
+	if got := out.String(); got != "\r\x1b[KConnection closed\n" {
+		t.Errorf("print after close wrote %q", got)
+	}
+}
+
+func TestMakeRawOnPipe(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	if restore, err := makeRaw(int(r.Fd())); err == nil {
+		restore()
+		t.Error("makeRaw succeeded on a pipe")
+	}
+}
@@ -0,0 +1,158 @@
+package chat
+
+import (
+	"bufio"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// pipeListener is an in-memory listener whose connections are net.Pipes
+type pipeListener struct {
+	conns     chan net.Conn
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newPipeListener() *pipeListener {
+	return &pipeListener{conns: make(chan net.Conn), closed: make(chan struct{})}
+}
+
+func (l *pipeListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *pipeListener) Close() error {
+	l.closeOnce.Do(func() { close(l.closed) })
+	return nil
+}
+
+func (l *pipeListener) Addr() net.Addr { return pipeAddr{} }
+
+// dial connects a test client through the listener
+func (l *pipeListener) dial() *TestClient {
+	server, client := net.Pipe()
+	l.conns <- server
+	return &TestClient{conn: client, reader: bufio.NewReader(client)}
+}
+
+type pipeAddr struct{}
+
+func (pipeAddr) Network() string { return "pipe" }
+func (pipeAddr) String() string  { return "pipe" }
+
+func TestListenAddress(t *testing.T) {
+	tests := []struct {
+		in, network, addr string
+		ok                bool
+	}{
+		{"8989", "tcp", ":8989", true},
+		{":8989", "tcp", ":8989", true},
+		{"127.0.0.1:8989", "tcp4", "127.0.0.1:8989", true},
+		{"[::1]:8989", "tcp6", "[::1]:8989", true},
+		{"localhost:8989", "tcp", "localhost:8989", true},
+		{"::1", "", "", false},
+		{"99999", "", "", false},
+	}
+	for _, tt := range tests {
+		network, addr, err := listenAddress(tt.in)
+		if (err == nil) != tt.ok || network != tt.network || addr != tt.addr {
+			t.Errorf("listenAddress(%q) = %q, %q, %v", tt.in, network, addr, err)
+		}
+	}
+}
+
+func TestMultipleListeners(t *testing.T) {
+	addrs := []string{freeAddr(t), freeAddr(t)}
+	if l, err := net.Listen("tcp6", "[::1]:0"); err == nil {
+		addrs = append(addrs, l.Addr().String())
+		l.Close()
+	}
+
+	config := DefaultConfig()
+	config.RoomsFile = ""
+	config.Listen = addrs[1:]
+	s := NewServerWithConfig(config)
+	defer s.Logfile.Close()
+	done := make(chan error, 1)
+	go func() { done <- s.Start(addrs[0]) }()
+	time.Sleep(serverStartDelay)
+
+	for _, addr := range addrs {
+		client, err := newTestClient(t, addr)
+		if err != nil {
+			t.Fatalf("Dial %s failed: %v", addr, err)
+		}
+		if err := client.expectMessage(t, "Welcome"); err != nil {
+			t.Errorf("No welcome on %s: %v", addr, err)
+		}
+		client.close()
+	}
+
+	s.Shutdown()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Start returned %v after shutdown", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Start did not return after shutdown")
+	}
+	for _, addr := range addrs {
+		if conn, err := net.DialTimeout("tcp", addr, dialTimeout); err == nil {
+			conn.Close()
+			t.Errorf("%s still accepting after shutdown", addr)
+		}
+	}
+}
+
+func TestServeListener(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountsFile = ""
+	config.RoomsFile = ""
+	config.Store = StoreMemory
+	s := NewServerWithConfig(config)
+	defer s.Logfile.Close()
+	l := newPipeListener()
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(l) }()
+
+	alice, bob := l.dial(), l.dial()
+	for _, c := range []*TestClient{alice, bob} {
+		if err := c.expectMessage(t, "Welcome"); err != nil {
+			t.Fatalf("No welcome: %v", err)
+		}
+	}
+	alice.sendMessage("Alice")
+	bob.sendMessage("Bob")
+	if err := alice.expectMessage(t, "Bob joined"); err != nil {
+		t.Fatalf("Bob did not join: %v", err)
+	}
+	alice.sendMessage("hello over a pipe")
+	if err := bob.expectMessage(t, "hello over a pipe"); err != nil {
+		t.Errorf("message not delivered: %v", err)
+	}
+	alice.close()
+	bob.close()
+
+	s.Shutdown()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Serve returned %v after shutdown", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Serve did not return after shutdown")
+	}
+	select {
+	case <-l.closed:
+	default:
+		t.Error("Serve left the listener open")
+	}
+}
@@ -0,0 +1,294 @@
+package chat
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BenchConfig describes a load simulation run with RunBench
+type BenchConfig struct {
+	Addr     string        // Server to connect to, host:port
+	Clients  int           // Synthetic clients to connect
+	Rate     float64       // Chat messages per second, across all clients
+	Duration time.Duration // How long to keep sending
+	Drain    time.Duration // How long to wait for late deliveries after the last send
+}
+
+// DefaultBenchConfig returns the settings of ./TCPChat bench without flags
+func DefaultBenchConfig() BenchConfig {
+	return BenchConfig{
+		Addr:     "localhost:8989",
+		Clients:  50,
+		Rate:     10,
+		Duration: 10 * time.Second,
+		Drain:    2 * time.Second,
+	}
+}
+
+// benchConnectTimeout bounds the login of one synthetic client, and
+// benchConcurrentDials how many log in at once
+const (
+	benchConnectTimeout  = 10 * time.Second
+	benchConcurrentDials = 50
+)
+
+// BenchReport is the outcome of RunBench
+type BenchReport struct {
+	Config      BenchConfig
+	Connected   int
+	Failed      int
+	ConnectErr  string // First login failure, if any
+	ConnectTime time.Duration
+	Sent        int
+	Expected    int // Deliveries owed: every message to every connected client
+	Delivered   int
+	Dropped     int
+	Kicked      int // Clients the server disconnected during the run
+	Latencies   []time.Duration
+}
+
+// benchClient is one synthetic client: the simulation writes its messages
+// and its own goroutine reads what the server delivers to it
+type benchClient struct {
+	name      string
+	conn      net.Conn
+	reader    *bufio.Reader
+	done      chan struct{}
+	closed    atomic.Bool // Set by the simulation before it hangs up
+	kicked    bool
+	latencies []time.Duration
+}
+
+// RunBench connects cfg.Clients synthetic clients to cfg.Addr, has them
+// send cfg.Rate messages per second between them for cfg.Duration, and
+// measures how long each message takes to reach every client in the room,
+// sender included, and how many never arrive. The clients speak the JSON
+// protocol and join the lobby, so the server's client limit and flood
+// control apply to them like to anyone else.
+func RunBench(cfg BenchConfig) (*BenchReport, error) {
+	if cfg.Clients < 1 || cfg.Rate <= 0 || cfg.Duration <= 0 {
+		return nil, fmt.Errorf("bench needs at least one client, a positive rate and a positive duration")
+	}
+	report := &BenchReport{Config: cfg}
+	run := strconv.FormatUint(rand.Uint64()%0xffff, 16)
+
+	// Log everyone in before sending so every message is owed to all
+	start := time.Now()
+	var clients []*benchClient
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, benchConcurrentDials)
+	for i := 0; i < cfg.Clients; i++ {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(name string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			bc, err := dialBenchClient(cfg.Addr, name)
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil {
+				report.Failed++
+				if report.ConnectErr == "" {
+					report.ConnectErr = err.Error()
+				}
+				return
+			}
+			clients = append(clients, bc)
+		}(fmt.Sprintf("bench%s%d", run, i))
+	}
+	wg.Wait()
+	report.ConnectTime = time.Since(start)
+	report.Connected = len(clients)
+	if len(clients) == 0 {
+		return report, fmt.Errorf("no client could connect: %s", report.ConnectErr)
+	}
+	for _, bc := range clients {
+		go bc.receive(run)
+	}
+
+	// Take turns sending at the given rate
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / cfg.Rate))
+	defer ticker.Stop()
+	deadline := time.After(cfg.Duration)
+	next := 0
+send:
+	for {
+		select {
+		case <-deadline:
+			break send
+		case <-ticker.C:
+		}
+		bc := clients[next%len(clients)]
+		next++
+		if bc.send(fmt.Sprintf("bench %s %d", run, time.Now().UnixNano())) {
+			report.Sent++
+		}
+	}
+
+	// Late deliveries still count, then everyone hangs up
+	time.Sleep(cfg.Drain)
+	for _, bc := range clients {
+		bc.closed.Store(true)
+		bc.conn.Close()
+	}
+	for _, bc := range clients {
+		<-bc.done
+		if bc.kicked {
+			report.Kicked++
+		}
+		report.Latencies = append(report.Latencies, bc.latencies...)
+	}
+	report.Expected = report.Sent * report.Connected
+	report.Delivered = len(report.Latencies)
+	report.Dropped = max(report.Expected-report.Delivered, 0)
+	sort.Slice(report.Latencies, func(i, j int) bool { return report.Latencies[i] < report.Latencies[j] })
+	return report, nil
+}
+
+// dialBenchClient connects to addr in the JSON protocol and logs in as
+// name, returning once the client is in the lobby
+func dialBenchClient(addr, name string) (*benchClient, error) {
+	conn, reader, _, err := dialJSON(addr, "")
+	if err != nil {
+		return nil, fmt.Errorf("login as %s failed: %v", name, err)
+	}
+	conn.SetDeadline(time.Now().Add(benchConnectTimeout))
+	bc := &benchClient{name: name, conn: conn, reader: reader, done: make(chan struct{})}
+	fail := func(err error) (*benchClient, error) {
+		conn.Close()
+		return nil, err
+	}
+
+	named := false
+	for {
+		event, err := bc.readEvent()
+		if err != nil {
+			return fail(fmt.Errorf("login as %s failed: %v", name, err))
+		}
+		switch {
+		case event.Type == "proto" && !named:
+			if err := bc.writeRequest(jsonRequest{Content: name}); err != nil {
+				return fail(err)
+			}
+			named = true
+		case event.Type == "system" && event.Content == name+" joined the room":
+			conn.SetDeadline(time.Time{})
+			return bc, nil
+		case event.Type == "system" && strings.HasPrefix(event.Content, "Invalid name"):
+			return fail(fmt.Errorf("login as %s failed: %s", name, event.Content))
+		}
+	}
+}
+
+// readEvent reads the next JSON event, skipping anything else
+func (bc *benchClient) readEvent() (jsonEvent, error) {
+	for {
+		line, err := bc.reader.ReadBytes('\n')
+		if err != nil {
+			if err == io.EOF && len(line) > 0 {
+				return jsonEvent{}, fmt.Errorf("disconnected: %s", strings.TrimSpace(string(line)))
+			}
+			return jsonEvent{}, err
+		}
+		var event jsonEvent
+		if json.Unmarshal(line, &event) == nil && event.Type != "" {
+			return event, nil
+		}
+	}
+}
+
+func (bc *benchClient) writeRequest(req jsonRequest) error {
+	line, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	bc.conn.SetWriteDeadline(time.Now().Add(benchConnectTimeout))
+	_, err = bc.conn.Write(append(line, '\n'))
+	return err
+}
+
+// send posts a chat message, reporting whether it went out
+func (bc *benchClient) send(content string) bool {
+	select {
+	case <-bc.done:
+		return false
+	default:
+	}
+	return bc.writeRequest(jsonRequest{Content: content}) == nil
+}
+
+// receive records the latency of every message of this run that reaches
+// the client, until the connection closes
+func (bc *benchClient) receive(run string) {
+	defer close(bc.done)
+	for {
+		event, err := bc.readEvent()
+		if err != nil {
+			// The server hung up unless the simulation did
+			bc.kicked = !bc.closed.Load()
+			return
+		}
+		if event.Type != "chat" {
+			continue
+		}
+		fields := strings.Fields(event.Content)
+		if len(fields) != 3 || fields[0] != "bench" || fields[1] != run {
+			continue
+		}
+		sent, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		bc.latencies = append(bc.latencies, time.Since(time.Unix(0, sent)))
+	}
+}
+
+// percentile returns the latency below which the fraction p of deliveries
+// arrived
+func (r *BenchReport) percentile(p float64) time.Duration {
+	if len(r.Latencies) == 0 {
+		return 0
+	}
+	i := min(int(p*float64(len(r.Latencies))), len(r.Latencies)-1)
+	return r.Latencies[i]
+}
+
+// String formats the report for the terminal
+func (r *BenchReport) String() string {
+	var b strings.Builder
+	cfg := r.Config
+	fmt.Fprintf(&b, "Bench against %s: %d clients, %g messages/s for %s\n", cfg.Addr, cfg.Clients, cfg.Rate, cfg.Duration)
+	fmt.Fprintf(&b, "Connected:    %d/%d in %s\n", r.Connected, cfg.Clients, r.ConnectTime.Round(time.Millisecond))
+	if r.Failed > 0 {
+		fmt.Fprintf(&b, "Failed:       %d (%s)\n", r.Failed, r.ConnectErr)
+	}
+	fmt.Fprintf(&b, "Sent:         %d messages\n", r.Sent)
+	var dropRate float64
+	if r.Expected > 0 {
+		dropRate = 100 * float64(r.Dropped) / float64(r.Expected)
+	}
+	fmt.Fprintf(&b, "Delivered:    %d/%d (%d dropped, %.2f%%)\n", r.Delivered, r.Expected, r.Dropped, dropRate)
+	if r.Kicked > 0 {
+		fmt.Fprintf(&b, "Disconnected: %d clients during the run\n", r.Kicked)
+	}
+	if n := len(r.Latencies); n > 0 {
+		var sum time.Duration
+		for _, l := range r.Latencies {
+			sum += l
+		}
+		fmt.Fprintf(&b, "Latency:      min %s, mean %s, p50 %s, p90 %s, p99 %s, max %s\n",
+			r.Latencies[0], sum/time.Duration(n), r.percentile(0.5), r.percentile(0.9), r.percentile(0.99), r.Latencies[n-1])
+	}
+	return b.String()
+}
@@ -0,0 +1,56 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunBench(t *testing.T) {
+	report, err := RunBench(BenchConfig{
+		Addr:     setupTestServer(t),
+		Clients:  5,
+		Rate:     50,
+		Duration: 200 * time.Millisecond,
+		Drain:    200 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("RunBench failed: %v", err)
+	}
+	if report.Connected != 5 || report.Failed != 0 {
+		t.Errorf("connected %d, failed %d (%s)", report.Connected, report.Failed, report.ConnectErr)
+	}
+	if report.Sent == 0 {
+		t.Fatal("no messages sent")
+	}
+	if report.Expected != report.Sent*5 || report.Delivered != report.Expected || report.Dropped != 0 {
+		t.Errorf("sent %d, delivered %d/%d, dropped %d", report.Sent, report.Delivered, report.Expected, report.Dropped)
+	}
+	if len(report.Latencies) > 1 && report.Latencies[0] > report.Latencies[len(report.Latencies)-1] {
+		t.Error("latencies not sorted")
+	}
+
+	out := report.String()
+	for _, want := range []string{"Connected:    5/5", "dropped, 0.00%", "p99"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("report missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRunBenchNoServer(t *testing.T) {
+	cfg := DefaultBenchConfig()
+	cfg.Addr = "localhost:1"
+	cfg.Clients = 2
+	report, err := RunBench(cfg)
+	if err == nil {
+		t.Fatal("RunBench succeeded without a server")
+	}
+	if report.Failed != 2 || report.ConnectErr == "" {
+		t.Errorf("failed %d (%q), want 2 with the dial error", report.Failed, report.ConnectErr)
+	}
+	cfg.Rate = 0
+	if _, err := RunBench(cfg); err == nil {
+		t.Error("RunBench accepted a zero rate")
+	}
+}
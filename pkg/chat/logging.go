@@ -0,0 +1,51 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Log formats selectable with Config.LogFormat
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// parseLogLevel accepts debug, info, warn and error; empty means info
+func parseLogLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info", "":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return slog.LevelInfo, fmt.Errorf("unknown log level %q (debug, info, warn, error)", name)
+}
+
+// newLogger writes records at config.LogLevel and above to w in
+// config.LogFormat. Unknown settings fall back to info and text; the
+// returned error says which one was ignored.
+func newLogger(w io.Writer, config *Config) (*slog.Logger, error) {
+	level, err := parseLogLevel(config.LogLevel)
+	options := &slog.HandlerOptions{Level: level}
+	switch config.LogFormat {
+	case LogFormatJSON:
+		return slog.New(slog.NewJSONHandler(w, options)), err
+	case LogFormatText, "":
+	default:
+		err = errors.Join(err, fmt.Errorf("unknown log format %q (text, json)", config.LogFormat))
+	}
+	return slog.New(slog.NewTextHandler(w, options)), err
+}
+
+// clientAttrs are the fields identifying c in log records
+func clientAttrs(c *Client) []any {
+	return []any{"client", c.name, "remote", remoteIP(c.conn)}
+}
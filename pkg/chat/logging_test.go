@@ -0,0 +1,82 @@
+package chat
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestParseLogLevel(t *testing.T) {
+	tests := []struct {
+		in    string
+		level slog.Level
+		ok    bool
+	}{
+		{"", slog.LevelInfo, true},
+		{"debug", slog.LevelDebug, true},
+		{"WARN", slog.LevelWarn, true},
+		{"error", slog.LevelError, true},
+		{"loud", slog.LevelInfo, false},
+	}
+	for _, tt := range tests {
+		level, err := parseLogLevel(tt.in)
+		if level != tt.level || (err == nil) != tt.ok {
+			t.Errorf("parseLogLevel(%q) = %v, %v", tt.in, level, err)
+		}
+	}
+}
+
+func TestStructuredLogging(t *testing.T) {
+	config := DefaultConfig()
+	config.LogLevel = "warn"
+	config.LogFormat = LogFormatJSON
+	var logged bytes.Buffer
+	logger, err := newLogger(&logged, config)
+	if err != nil {
+		t.Fatalf("newLogger: %v", err)
+	}
+	config.AccountsFile = ""
+	config.RoomsFile = ""
+	config.Store = StoreMemory
+	s := New(WithConfig(config), WithLogger(logger))
+
+	alice := newPipeClient(t, "Alice")
+	s.logActivity("Room created", "client", alice.name, "room", "dev")
+	s.accounts.update("Alice", func(a *Account) error {
+		a.PasswordHash = hashPassword("secret")
+		return nil
+	})
+	identifyCommand(s, alice, []string{"wrong"})
+
+	lines := strings.Split(strings.TrimSpace(logged.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("want only the warning at level warn, got %q", logged.String())
+	}
+	var record map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatalf("record is not JSON: %v", err)
+	}
+	if record["level"] != "WARN" || record["msg"] != "Failed identify" || record["client"] != "Alice" {
+		t.Errorf("unexpected record %v", record)
+	}
+	if _, ok := record["remote"]; !ok {
+		t.Errorf("record has no remote address: %v", record)
+	}
+}
+
+func TestInvalidLogSettings(t *testing.T) {
+	config := DefaultConfig()
+	config.LogLevel = "loud"
+	config.LogFormat = "xml"
+	var logged bytes.Buffer
+	logger, err := newLogger(&logged, config)
+	if err == nil {
+		t.Fatal("no error for unknown level and format")
+	}
+	logger.Info("still logging")
+	if !strings.Contains(logged.String(), `msg="still logging"`) {
+		t.Errorf("fallback logger is not text at info: %q", logged.String())
+	}
+}
@@ -1,11 +1,13 @@
 // main_test.go
-package internal
+package chat
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"net"
-	"strconv"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -15,7 +17,9 @@ const (
 	dialTimeout      = 2 * time.Second
 	messageTimeout   = 500 * time.Millisecond
 	serverStartDelay = 100 * time.Millisecond
-	defaultTestPort  = "8989"
+	// passwordTimeout is for replies that wait on the deliberately slow
+	// password hash, such as logins, /register and password rooms
+	passwordTimeout = 5 * time.Second
 )
 
 type TestClient struct {
@@ -37,8 +41,12 @@ func newTestClient(t *testing.T, address string) (*TestClient, error) {
 }
 
 func (c *TestClient) expectMessage(t *testing.T, expected string) error {
-	deadline := time.Now().Add(messageTimeout)
-	c.conn.SetReadDeadline(deadline)
+	return c.expectMessageWithin(t, expected, messageTimeout)
+}
+
+// expectMessageWithin is expectMessage with a timeout other than messageTimeout
+func (c *TestClient) expectMessageWithin(t *testing.T, expected string, timeout time.Duration) error {
+	c.conn.SetReadDeadline(time.Now().Add(timeout))
 
 	for {
 		msg, err := c.reader.ReadString('\n')
@@ -63,97 +71,127 @@ func (c *TestClient) close() {
 	}
 }
 
-func setupTestServer(port string) error {
-	// Validate port number before attempting to start server
-	if portNum, err := strconv.Atoi(port); err != nil || portNum < 0 || portNum > 65535 {
-		return fmt.Errorf("invalid port number: %s", port)
+// TestMain runs the tests in a temp dir, so servers built from
+// DefaultConfig keep their log, accounts and rooms out of the source tree
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "netcat-test")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
+	if err := os.Chdir(dir); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
 
-	errChan := make(chan error, 1)
-	readyChan := make(chan bool, 1)
-
-	go func() {
-		s := NewServer()
-		defer s.Logfile.Close()
+func setupTestServer(tb testing.TB) string {
+	return setupTestServerWithConfig(tb, DefaultConfig())
+}
 
-		// Send ready signal before starting the server
-		readyChan <- true
+// setupTestServerWithConfig runs a server with config on an ephemeral
+// loopback port and returns its address. The files config leaves at
+// their defaults go to a temp dir of the test, and the server is shut
+// down when the test ends.
+func setupTestServerWithConfig(tb testing.TB, config *Config) string {
+	tb.Helper()
+	useTempFiles(tb, config)
+	s := NewServerWithConfig(config)
+	// listen wraps the listener in TLS when the config asks for it
+	l, err := s.listen("127.0.0.1:0")
+	if err != nil {
+		s.Logfile.Close()
+		tb.Fatalf("Server setup failed: %v", err)
+	}
 
-		err := s.Start(port)
-		if err != nil {
-			errChan <- err
-			return
-		}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.ServeContext(ctx, l)
 	}()
+	tb.Cleanup(func() {
+		cancel()
+		<-done
+		s.Logfile.Close()
+	})
+	return l.Addr().String()
+}
 
-	// Wait for either ready signal or error
-	select {
-	case <-readyChan:
-		time.Sleep(serverStartDelay)
-		// Try to connect to verify server is actually running
-		conn, err := net.DialTimeout("tcp", "localhost:"+port, dialTimeout)
-		if err != nil {
-			return fmt.Errorf("server started but connection failed: %v", err)
+// useTempFiles moves the files config leaves at their defaults into a temp
+// dir of the test, so that tests do not see each other's accounts or rooms
+func useTempFiles(tb testing.TB, config *Config) {
+	dir := tb.TempDir()
+	defaults := DefaultConfig()
+	for _, f := range []struct{ path, def *string }{
+		{&config.LogFile, &defaults.LogFile},
+		{&config.AuditFile, &defaults.AuditFile},
+		{&config.AccountsFile, &defaults.AccountsFile},
+		{&config.RoomsFile, &defaults.RoomsFile},
+		{&config.BanFile, &defaults.BanFile},
+		{&config.ExportDir, &defaults.ExportDir},
+		{&config.SSHHostKey, &defaults.SSHHostKey},
+	} {
+		if *f.path != "" && *f.path == *f.def {
+			*f.path = filepath.Join(dir, *f.def)
 		}
-		conn.Close()
-		return nil
-	case err := <-errChan:
-		return err
-	case <-time.After(dialTimeout):
-		return fmt.Errorf("server startup timeout")
 	}
 }
 
+// freeAddr returns a loopback address nothing listens on, for the
+// listeners a test has the server open itself
+func freeAddr(tb testing.TB) string {
+	tb.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		tb.Fatalf("Listen failed: %v", err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
 func TestServerStartup(t *testing.T) {
+	t.Run("EphemeralPort", func(t *testing.T) {
+		client, err := newTestClient(t, setupTestServer(t))
+		if err != nil {
+			t.Fatalf("Client connection failed: %v", err)
+		}
+		defer client.close()
+
+		if err := client.expectMessage(t, "Welcome"); err != nil {
+			t.Errorf("Welcome message test failed: %v", err)
+		}
+	})
+
 	tests := []struct {
-		name    string
-		port    string
-		wantErr bool
+		name string
+		port string
 	}{
-		{"DefaultPort", defaultTestPort, false},
-		{"CustomPort", "9090", false},
-		{"InvalidPort", "99999", true},
-		{"NegativePort", "-1", true},
-		{"NonNumericPort", "abc", true},
+		{"InvalidPort", "99999"},
+		{"NegativePort", "-1"},
+		{"NonNumericPort", "abc"},
 	}
-
 	for _, tt := range tests {
-		tt := tt // Capture range variable
 		t.Run(tt.name, func(t *testing.T) {
-			err := setupTestServer(tt.port)
-
-			if tt.wantErr {
-				if err == nil {
-					t.Errorf("Expected error for port %s, got nil", tt.port)
-				}
-				return
-			}
-
-			if err != nil {
-				t.Fatalf("Unexpected error for port %s: %v", tt.port, err)
-			}
-
-			client, err := newTestClient(t, "localhost:"+tt.port)
-			if err != nil {
-				t.Fatalf("Client connection failed: %v", err)
-			}
-			defer client.close()
-
-			if err := client.expectMessage(t, "Welcome"); err != nil {
-				t.Errorf("Welcome message test failed: %v", err)
+			config := DefaultConfig()
+			useTempFiles(t, config)
+			s := NewServerWithConfig(config)
+			defer s.Logfile.Close()
+			if err := s.Start(tt.port); err == nil {
+				t.Errorf("Expected error for port %s, got nil", tt.port)
 			}
 		})
 	}
 }
 
 func TestClientConnection(t *testing.T) {
-	err := setupTestServer( "8991")
-	if err != nil {
-		t.Fatalf("Server setup failed: %v", err)
-	}
+	addr := setupTestServer(t)
 
 	t.Run("SingleClient", func(t *testing.T) {
-		client, err := newTestClient(t, "localhost:8991")
+		client, err := newTestClient(t, addr)
 		if err != nil {
 			t.Fatalf("Client connection failed: %v", err)
 		}
@@ -179,7 +217,7 @@ func TestClientConnection(t *testing.T) {
 		}()
 
 		for i := 0; i < 3; i++ {
-			client, err := newTestClient(t, "localhost:8991")
+			client, err := newTestClient(t, addr)
 			if err != nil {
 				t.Fatalf("Client %d connection failed: %v", i, err)
 			}
@@ -199,18 +237,15 @@ func TestClientConnection(t *testing.T) {
 }
 
 func TestMessageBroadcast(t *testing.T) {
-	err := setupTestServer("8992")
-	if err != nil {
-		t.Fatalf("Server setup failed: %v", err)
-	}
+	addr := setupTestServer(t)
 
-	client1, err := newTestClient(t, "localhost:8992")
+	client1, err := newTestClient(t, addr)
 	if err != nil {
 		t.Fatalf("Client1 connection failed: %v", err)
 	}
 	defer client1.close()
 
-	client2, err := newTestClient(t, "localhost:8992")
+	client2, err := newTestClient(t, addr)
 	if err != nil {
 		t.Fatalf("Client2 connection failed: %v", err)
 	}
@@ -247,17 +282,14 @@ func TestMessageBroadcast(t *testing.T) {
 }
 
 func TestDisconnect(t *testing.T) {
-	err := setupTestServer("8993")
-	if err != nil {
-		t.Fatalf("Server setup failed: %v", err)
-	}
+	addr := setupTestServer(t)
 
-	client1, err := newTestClient(t, "localhost:8993")
+	client1, err := newTestClient(t, addr)
 	if err != nil {
 		t.Fatalf("Client1 connection failed: %v", err)
 	}
 
-	client2, err := newTestClient(t, "localhost:8993")
+	client2, err := newTestClient(t, addr)
 	if err != nil {
 		t.Fatalf("Client2 connection failed: %v", err)
 	}
@@ -270,12 +302,18 @@ func TestDisconnect(t *testing.T) {
 	if err := client1.sendMessage("User1"); err != nil {
 		t.Fatalf("Client1 name failed: %v", err)
 	}
+	if err := client1.expectMessage(t, "joined"); err != nil {
+		t.Fatalf("Client1 join failed: %v", err)
+	}
 	if err := client2.expectMessage(t, "Welcome"); err != nil {
 		t.Fatalf("Client2 welcome failed: %v", err)
 	}
 	if err := client2.sendMessage("User2"); err != nil {
 		t.Fatalf("Client2 name failed: %v", err)
 	}
+	if err := client2.expectMessage(t, "joined"); err != nil {
+		t.Fatalf("Client2 join failed: %v", err)
+	}
 
 	// Test disconnection
 	client1.close()
@@ -0,0 +1,376 @@
+package chat
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// mDNS (RFC 6762) service discovery for TCPChat servers on the local network.
+// Servers answer PTR queries for mdnsService; clients browse for them.
+
+const (
+	mdnsService = "_tcpchat._tcp.local."
+	mdnsAddr    = "224.0.0.251:5353"
+	mdnsTTL     = 120
+
+	dnsTypeA   = 1
+	dnsTypePTR = 12
+	dnsTypeTXT = 16
+	dnsTypeSRV = 33
+	dnsClassIN = 1
+	// Set on records owned by a single responder (cache-flush bit)
+	dnsClassFlush = 0x8000
+)
+
+// DiscoveredServer is a chat server found on the local network
+type DiscoveredServer struct {
+	Name string
+	Addr string // host:port
+}
+
+type dnsQuestion struct {
+	Name string
+	Type uint16
+}
+
+type dnsRecord struct {
+	Name   string
+	Type   uint16
+	Class  uint16
+	TTL    uint32
+	Target string // PTR and SRV
+	Port   uint16 // SRV
+	IP     net.IP // A
+	Text   []string
+}
+
+type dnsMessage struct {
+	Response  bool
+	Questions []dnsQuestion
+	Records   []dnsRecord // Answers and additional records
+}
+
+func appendDNSName(b []byte, name string) []byte {
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if label == "" {
+			continue
+		}
+		b = append(b, byte(len(label)))
+		b = append(b, label...)
+	}
+	return append(b, 0)
+}
+
+func (m *dnsMessage) pack() []byte {
+	b := make([]byte, 12)
+	if m.Response {
+		binary.BigEndian.PutUint16(b[2:], 0x8400) // Response, authoritative
+	}
+	binary.BigEndian.PutUint16(b[4:], uint16(len(m.Questions)))
+	binary.BigEndian.PutUint16(b[6:], uint16(len(m.Records)))
+
+	for _, q := range m.Questions {
+		b = appendDNSName(b, q.Name)
+		b = binary.BigEndian.AppendUint16(b, q.Type)
+		b = binary.BigEndian.AppendUint16(b, dnsClassIN)
+	}
+
+	for _, r := range m.Records {
+		var data []byte
+		switch r.Type {
+		case dnsTypeA:
+			data = r.IP.To4()
+		case dnsTypePTR:
+			data = appendDNSName(nil, r.Target)
+		case dnsTypeSRV:
+			data = make([]byte, 6) // Priority and weight stay zero
+			binary.BigEndian.PutUint16(data[4:], r.Port)
+			data = appendDNSName(data, r.Target)
+		case dnsTypeTXT:
+			for _, t := range r.Text {
+				data = append(data, byte(len(t)))
+				data = append(data, t...)
+			}
+		}
+		b = appendDNSName(b, r.Name)
+		b = binary.BigEndian.AppendUint16(b, r.Type)
+		b = binary.BigEndian.AppendUint16(b, r.Class)
+		b = binary.BigEndian.AppendUint32(b, r.TTL)
+		b = binary.BigEndian.AppendUint16(b, uint16(len(data)))
+		b = append(b, data...)
+	}
+	return b
+}
+
+var errDNSMalformed = errors.New("malformed DNS message")
+
+// readDNSName decodes a possibly compressed name starting at off
+func readDNSName(msg []byte, off int) (string, int, error) {
+	var labels []string
+	end := -1
+	for jumps := 0; jumps < 16; {
+		if off >= len(msg) {
+			return "", 0, errDNSMalformed
+		}
+		length := int(msg[off])
+		switch {
+		case length == 0:
+			if end < 0 {
+				end = off + 1
+			}
+			return strings.Join(labels, ".") + ".", end, nil
+		case length&0xC0 == 0xC0:
+			if off+1 >= len(msg) {
+				return "", 0, errDNSMalformed
+			}
+			if end < 0 {
+				end = off + 2
+			}
+			off = int(binary.BigEndian.Uint16(msg[off:]) & 0x3FFF)
+			jumps++
+		default:
+			if off+1+length > len(msg) {
+				return "", 0, errDNSMalformed
+			}
+			labels = append(labels, string(msg[off+1:off+1+length]))
+			off += 1 + length
+		}
+	}
+	return "", 0, errDNSMalformed
+}
+
+func parseDNSMessage(msg []byte) (*dnsMessage, error) {
+	if len(msg) < 12 {
+		return nil, errDNSMalformed
+	}
+	m := &dnsMessage{Response: msg[2]&0x80 != 0}
+	qdCount := int(binary.BigEndian.Uint16(msg[4:]))
+	rrCount := int(binary.BigEndian.Uint16(msg[6:])) +
+		int(binary.BigEndian.Uint16(msg[8:])) +
+		int(binary.BigEndian.Uint16(msg[10:]))
+
+	off := 12
+	for i := 0; i < qdCount; i++ {
+		name, next, err := readDNSName(msg, off)
+		if err != nil || next+4 > len(msg) {
+			return nil, errDNSMalformed
+		}
+		m.Questions = append(m.Questions, dnsQuestion{Name: name, Type: binary.BigEndian.Uint16(msg[next:])})
+		off = next + 4
+	}
+
+	for i := 0; i < rrCount; i++ {
+		name, next, err := readDNSName(msg, off)
+		if err != nil || next+10 > len(msg) {
+			return nil, errDNSMalformed
+		}
+		r := dnsRecord{
+			Name:  name,
+			Type:  binary.BigEndian.Uint16(msg[next:]),
+			Class: binary.BigEndian.Uint16(msg[next+2:]),
+			TTL:   binary.BigEndian.Uint32(msg[next+4:]),
+		}
+		length := int(binary.BigEndian.Uint16(msg[next+8:]))
+		start := next + 10
+		if start+length > len(msg) {
+			return nil, errDNSMalformed
+		}
+		data := msg[start : start+length]
+
+		switch r.Type {
+		case dnsTypeA:
+			if length == 4 {
+				r.IP = net.IP(append([]byte(nil), data...))
+			}
+		case dnsTypePTR:
+			r.Target, _, _ = readDNSName(msg, start)
+		case dnsTypeSRV:
+			if length >= 7 {
+				r.Port = binary.BigEndian.Uint16(data[4:])
+				r.Target, _, _ = readDNSName(msg, start+6)
+			}
+		case dnsTypeTXT:
+			for j := 0; j < len(data); {
+				n := int(data[j])
+				if j+1+n > len(data) {
+					break
+				}
+				r.Text = append(r.Text, string(data[j+1:j+1+n]))
+				j += 1 + n
+			}
+		}
+		m.Records = append(m.Records, r)
+		off = start + length
+	}
+	return m, nil
+}
+
+// mdnsInstanceName escapes dots so the server name stays a single label
+func mdnsInstanceName(name string) string {
+	return strings.ReplaceAll(name, ".", "-") + "." + mdnsService
+}
+
+// localIPv4 returns the first non-loopback IPv4 address of this host
+func localIPv4() net.IP {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil
+	}
+	for _, a := range addrs {
+		if ipnet, ok := a.(*net.IPNet); ok && !ipnet.IP.IsLoopback() && ipnet.IP.To4() != nil {
+			return ipnet.IP.To4()
+		}
+	}
+	return nil
+}
+
+// announceMDNS answers service queries so clients can discover this server
+func (s *Server) announceMDNS(ctx context.Context, name, port string) {
+	group, err := net.ResolveUDPAddr("udp4", mdnsAddr)
+	if err != nil {
+		s.log.Warn("mDNS disabled", "err", err)
+		return
+	}
+	conn, err := net.ListenMulticastUDP("udp4", nil, group)
+	if err != nil {
+		s.log.Warn("mDNS disabled", "err", err)
+		return
+	}
+	defer conn.Close()
+	context.AfterFunc(ctx, func() { conn.Close() })
+
+	portNum, _ := strconv.Atoi(port)
+	instance := mdnsInstanceName(name)
+	host := strings.ReplaceAll(name, ".", "-") + ".local."
+	s.logActivity("Announcing via mDNS", "name", instance)
+
+	buf := make([]byte, 9000)
+	for {
+		n, src, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if ctx.Err() == nil {
+				s.log.Warn("mDNS read error", "err", err)
+			}
+			return
+		}
+		query, err := parseDNSMessage(buf[:n])
+		if err != nil || query.Response || !asksForService(query) {
+			continue
+		}
+
+		ip := localIPv4()
+		records := []dnsRecord{
+			{Name: mdnsService, Type: dnsTypePTR, Class: dnsClassIN, TTL: mdnsTTL, Target: instance},
+			{Name: instance, Type: dnsTypeSRV, Class: dnsClassIN | dnsClassFlush, TTL: mdnsTTL, Target: host, Port: uint16(portNum)},
+			{Name: instance, Type: dnsTypeTXT, Class: dnsClassIN | dnsClassFlush, TTL: mdnsTTL, Text: []string{"name=" + name}},
+		}
+		if ip != nil {
+			records = append(records, dnsRecord{Name: host, Type: dnsTypeA, Class: dnsClassIN | dnsClassFlush, TTL: mdnsTTL, IP: ip})
+		}
+		reply := (&dnsMessage{Response: true, Records: records}).pack()
+
+		// Legacy one-shot queriers expect a unicast reply on their own port
+		dst := group
+		if src.Port != 5353 {
+			dst = src
+		}
+		conn.WriteToUDP(reply, dst)
+	}
+}
+
+func asksForService(m *dnsMessage) bool {
+	for _, q := range m.Questions {
+		if strings.EqualFold(q.Name, mdnsService) && (q.Type == dnsTypePTR || q.Type == 255) {
+			return true
+		}
+	}
+	return false
+}
+
+// BrowseServers queries the local network for chat servers until the
+// timeout elapses, reporting each server once as it is discovered
+func BrowseServers(timeout time.Duration, found func(DiscoveredServer)) error {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	group, err := net.ResolveUDPAddr("udp4", mdnsAddr)
+	if err != nil {
+		return err
+	}
+	query := (&dnsMessage{Questions: []dnsQuestion{{Name: mdnsService, Type: dnsTypePTR}}}).pack()
+
+	seen := make(map[string]bool)
+	deadline := time.Now().Add(timeout)
+	buf := make([]byte, 9000)
+	for time.Now().Before(deadline) {
+		// Re-query every second so late responders are picked up
+		if _, err := conn.WriteToUDP(query, group); err != nil {
+			return err
+		}
+		next := time.Now().Add(time.Second)
+		if next.After(deadline) {
+			next = deadline
+		}
+		conn.SetReadDeadline(next)
+
+		for {
+			n, src, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				break
+			}
+			msg, err := parseDNSMessage(buf[:n])
+			if err != nil || !msg.Response {
+				continue
+			}
+			for _, server := range serversFromResponse(msg, src.IP) {
+				if !seen[server.Addr] {
+					seen[server.Addr] = true
+					found(server)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// serversFromResponse resolves PTR -> SRV -> A chains in a response
+func serversFromResponse(m *dnsMessage, fallback net.IP) []DiscoveredServer {
+	srv := make(map[string]dnsRecord)
+	ips := make(map[string]net.IP)
+	for _, r := range m.Records {
+		switch r.Type {
+		case dnsTypeSRV:
+			srv[strings.ToLower(r.Name)] = r
+		case dnsTypeA:
+			ips[strings.ToLower(r.Name)] = r.IP
+		}
+	}
+
+	var servers []DiscoveredServer
+	for _, r := range m.Records {
+		if r.Type != dnsTypePTR || !strings.EqualFold(r.Name, mdnsService) {
+			continue
+		}
+		target, ok := srv[strings.ToLower(r.Target)]
+		if !ok {
+			continue
+		}
+		ip := ips[strings.ToLower(target.Target)]
+		if ip == nil {
+			ip = fallback
+		}
+		servers = append(servers, DiscoveredServer{
+			Name: strings.TrimSuffix(r.Target, "."+mdnsService),
+			Addr: net.JoinHostPort(ip.String(), strconv.Itoa(int(target.Port))),
+		})
+	}
+	return servers
+}
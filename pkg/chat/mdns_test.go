@@ -0,0 +1,57 @@
+package chat
+
+import (
+	"net"
+	"testing"
+)
+
+func TestMDNSResponseRoundTrip(t *testing.T) {
+	instance := mdnsInstanceName("office.chat")
+	reply := (&dnsMessage{Response: true, Records: []dnsRecord{
+		{Name: mdnsService, Type: dnsTypePTR, Class: dnsClassIN, TTL: mdnsTTL, Target: instance},
+		{Name: instance, Type: dnsTypeSRV, Class: dnsClassIN, TTL: mdnsTTL, Target: "office-chat.local.", Port: 8989},
+		{Name: instance, Type: dnsTypeTXT, Class: dnsClassIN, TTL: mdnsTTL, Text: []string{"name=office.chat"}},
+		{Name: "office-chat.local.", Type: dnsTypeA, Class: dnsClassIN, TTL: mdnsTTL, IP: net.IPv4(192, 168, 1, 20)},
+	}}).pack()
+
+	msg, err := parseDNSMessage(reply)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	servers := serversFromResponse(msg, net.IPv4(10, 0, 0, 1))
+	if len(servers) != 1 {
+		t.Fatalf("expected one server, got %+v", servers)
+	}
+	if servers[0].Name != "office-chat" || servers[0].Addr != "192.168.1.20:8989" {
+		t.Errorf("unexpected server: %+v", servers[0])
+	}
+}
+
+func TestMDNSQueryDetection(t *testing.T) {
+	query := (&dnsMessage{Questions: []dnsQuestion{{Name: mdnsService, Type: dnsTypePTR}}}).pack()
+	msg, err := parseDNSMessage(query)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if msg.Response || !asksForService(msg) {
+		t.Errorf("query not recognised: %+v", msg)
+	}
+}
+
+func TestDNSNameCompression(t *testing.T) {
+	// "local." at offset 12, then "a" + pointer back to it
+	msg := make([]byte, 12)
+	msg = append(msg, 5, 'l', 'o', 'c', 'a', 'l', 0)
+	msg = append(msg, 1, 'a', 0xC0, 12)
+
+	name, next, err := readDNSName(msg, 19)
+	if err != nil || name != "a.local." || next != len(msg) {
+		t.Errorf("got %q, %d, %v", name, next, err)
+	}
+
+	// A pointer loop must not hang
+	loop := append(make([]byte, 12), 0xC0, 12)
+	if _, _, err := readDNSName(loop, 12); err == nil {
+		t.Error("expected error for pointer loop")
+	}
+}
@@ -0,0 +1,126 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// maxMentions is how many recent mentions /mentions keeps per client
+const maxMentions = 20
+
+// mentionedNames returns the lowercase names @mentioned in content
+func mentionedNames(content string) map[string]bool {
+	if !strings.Contains(content, "@") {
+		return nil
+	}
+	names := make(map[string]bool)
+	for _, match := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		names[strings.ToLower(match[1])] = true
+	}
+	return names
+}
+
+// wantsMention reports whether c should be notified that from mentioned it
+func (s *Server) wantsMention(c *Client, from string) bool {
+	if strings.EqualFold(c.name, from) || c.ignores(from) {
+		return false
+	}
+	account, ok := s.accounts.get(c.name)
+	return !ok || !account.MentionsOff
+}
+
+// mention records a mention of c and returns the notification for it.
+// The caller holds s.mutex.
+func (c *Client) mention(msg Message, room string) Message {
+	notice := mentionNotice(msg, room)
+	c.mentions = append(c.mentions, notice)
+	if len(c.mentions) > maxMentions {
+		c.mentions = c.mentions[len(c.mentions)-maxMentions:]
+	}
+	c.unreadMentions++
+	return notice
+}
+
+// mentionNotice turns a chat message of room into a mention notification
+func mentionNotice(msg Message, room string) Message {
+	return Message{
+		Type:      MessageTypeMention,
+		From:      msg.From,
+		To:        room,
+		Content:   msg.Content,
+		Timestamp: msg.Timestamp,
+		ID:        msg.ID,
+	}
+}
+
+// deliverMentions notifies mentioned users who are in other rooms. Those
+// in the room itself get the highlighted message from deliverToRoom.
+// Outsiders of a room with a password, invitations or hidden from /rooms
+// only learn that they were mentioned, not what was said.
+func (s *Server) deliverMentions(room string, msg Message) {
+	names := mentionedNames(msg.Content)
+	if len(names) == 0 {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	ephemeral := s.isEphemeral(room)
+	r, exists := s.rooms[room]
+	private := exists && (r.passwordHash != "" || r.inviteOnly || r.hidden)
+	for _, c := range s.clients.all() {
+		if c.room != room && names[strings.ToLower(c.name)] && s.wantsMention(c, msg.From) {
+			if private && !(r.isMember(c.name) && s.holdsName(c)) {
+				c.sendMessage(Message{
+					Type:      MessageTypeSystem,
+					Content:   fmt.Sprintf("%s mentioned you in %s", msg.From, room),
+					Timestamp: msg.Timestamp,
+				})
+			} else if ephemeral {
+				c.sendMessage(mentionNotice(msg, room))
+			} else {
+				c.sendMessage(c.mention(msg, room))
+			}
+		}
+	}
+}
+
+// mentionsCommand lists recent mentions, or turns notifications on or off
+func mentionsCommand(s *Server, c *Client, args []string) error {
+	if len(args) > 0 {
+		mode := strings.ToLower(args[0])
+		if mode != "on" && mode != "off" {
+			return fmt.Errorf("usage: /mentions [on|off]")
+		}
+		if !s.ownsAccount(c) {
+			return errNotOwner
+		}
+		err := s.accounts.update(c.name, func(a *Account) error {
+			a.MentionsOff = mode == "off"
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		c.write([]byte(fmt.Sprintf("Mention notifications: %s\n", mode)))
+		return nil
+	}
+
+	s.mutex.Lock()
+	mentions := append([]Message(nil), c.mentions...)
+	unread := c.unreadMentions
+	c.unreadMentions = 0
+	s.mutex.Unlock()
+
+	if len(mentions) == 0 {
+		c.write([]byte("Nobody has mentioned you yet\n"))
+		return nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Recent mentions (%d new):\n", unread)
+	for _, m := range mentions {
+		fmt.Fprintf(&b, "[%s] %s in %s: %s\n", m.Timestamp.Format(time.TimeOnly), m.From, m.To, m.Content)
+	}
+	c.write([]byte(b.String()))
+	return nil
+}
@@ -0,0 +1,128 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMentions(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountsFile = ""
+	config.RoomsFile = ""
+	addr := setupTestServerWithConfig(t, config)
+	join := func(name string) *TestClient {
+		c, err := newTestClient(t, addr)
+		if err != nil {
+			t.Fatalf("Client connection failed: %v", err)
+		}
+		c.sendMessage(name)
+		if err := c.expectMessage(t, name+" joined"); err != nil {
+			t.Fatalf("Join as %s failed: %v", name, err)
+		}
+		return c
+	}
+	alice := join("Alice")
+	defer alice.close()
+	bob := join("Bob")
+	defer bob.close()
+	carol := join("Carol")
+	defer carol.close()
+
+	carol.sendMessage("/create dev")
+	if err := carol.expectMessage(t, "Carol joined the room"); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+
+	alice.sendMessage("standup in 5, @bob and @Carol")
+	if err := bob.expectMessage(t, "\a["); err != nil {
+		t.Fatalf("no bell on mention: %v", err)
+	}
+	if err := carol.expectMessage(t, "[Alice in general mentioned you]: standup in 5"); err != nil {
+		t.Fatalf("no mention in another room: %v", err)
+	}
+
+	carol.sendMessage("/mentions off")
+	if err := carol.expectMessage(t, errNotOwner.Error()); err != nil {
+		t.Fatalf("mentions turned off for a guest: %v", err)
+	}
+	carol.sendMessage("/register secret1")
+	if err := carol.expectMessageWithin(t, "now registered", passwordTimeout); err != nil {
+		t.Fatalf("Registration failed: %v", err)
+	}
+	carol.sendMessage("/mentions off")
+	if err := carol.expectMessage(t, "Mention notifications: off"); err != nil {
+		t.Fatalf("mentions not turned off: %v", err)
+	}
+	alice.sendMessage("@carol ping")
+	carol.sendMessage("/mentions")
+	carol.conn.SetReadDeadline(time.Now().Add(messageTimeout))
+	for {
+		line, err := carol.reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("/mentions failed: %v", err)
+		}
+		if strings.Contains(line, "ping") {
+			t.Fatalf("notified after turning mentions off: %q", line)
+		}
+		if strings.Contains(line, "Recent mentions (1 new)") {
+			break
+		}
+	}
+
+	bob.sendMessage("/mentions")
+	if err := bob.expectMessage(t, "Alice in general: standup in 5, @bob and @Carol"); err != nil {
+		t.Fatalf("mention not listed: %v", err)
+	}
+}
+
+func TestMentionsFromPrivateRoom(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountsFile = ""
+	config.RoomsFile = ""
+	addr := setupTestServerWithConfig(t, config)
+	join := func(name string) *TestClient {
+		c, err := newTestClient(t, addr)
+		if err != nil {
+			t.Fatalf("Client connection failed: %v", err)
+		}
+		c.sendMessage(name)
+		if err := c.expectMessage(t, name+" joined"); err != nil {
+			t.Fatalf("Join as %s failed: %v", name, err)
+		}
+		return c
+	}
+	alice := join("Alice")
+	defer alice.close()
+	bob := join("Bob")
+	defer bob.close()
+
+	alice.sendMessage("/create dev")
+	alice.expectMessage(t, "Alice joined the room")
+	alice.sendMessage("/inviteonly on")
+	alice.expectMessage(t, "invite-only")
+
+	// An outsider hears of the mention but not what was said
+	alice.sendMessage("@bob the secret plan")
+	bob.conn.SetReadDeadline(time.Now().Add(messageTimeout))
+	for {
+		line, err := bob.reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("mention notice not delivered: %v", err)
+		}
+		if strings.Contains(line, "secret plan") {
+			t.Fatalf("message of an invite-only room leaked: %q", line)
+		}
+		if strings.Contains(line, "Alice mentioned you in dev") {
+			break
+		}
+	}
+
+	// Once invited, Bob may read along
+	alice.sendMessage("/invite Bob")
+	bob.expectMessage(t, "Alice invited you to dev")
+	alice.sendMessage("@bob the plan, for you")
+	if err := bob.expectMessage(t, "[Alice in dev mentioned you]: @bob the plan, for you"); err != nil {
+		t.Errorf("invited user not shown the mention: %v", err)
+	}
+}
@@ -0,0 +1,228 @@
+package chat
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// latencyBuckets are the upper bounds of the broadcast latency histogram;
+// slower broadcasts land in a final overflow bucket
+var latencyBuckets = [...]time.Duration{
+	100 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+}
+
+// latencyHistogram counts durations into latencyBuckets without locking
+type latencyHistogram struct {
+	counts [len(latencyBuckets) + 1]atomic.Int64
+	sum    atomic.Int64 // Nanoseconds
+}
+
+func (h *latencyHistogram) observe(d time.Duration) {
+	i := sort.Search(len(latencyBuckets), func(i int) bool { return d <= latencyBuckets[i] })
+	h.counts[i].Add(1)
+	h.sum.Add(int64(d))
+}
+
+// latencyBucket is one histogram bucket as reported by the admin API
+type latencyBucket struct {
+	LE    string `json:"le"` // Upper bound, "+Inf" for the overflow bucket
+	Count int64  `json:"count"`
+}
+
+// latencySnapshot is a point-in-time copy of a latencyHistogram
+type latencySnapshot struct {
+	Count   int64           `json:"count"`
+	Mean    string          `json:"mean"`
+	Buckets []latencyBucket `json:"buckets"`
+}
+
+func (h *latencyHistogram) snapshot() latencySnapshot {
+	var snap latencySnapshot
+	for i := range h.counts {
+		le := "+Inf"
+		if i < len(latencyBuckets) {
+			le = latencyBuckets[i].String()
+		}
+		n := h.counts[i].Load()
+		snap.Buckets = append(snap.Buckets, latencyBucket{LE: le, Count: n})
+		snap.Count += n
+	}
+	var mean time.Duration
+	if snap.Count > 0 {
+		mean = time.Duration(h.sum.Load() / snap.Count)
+	}
+	snap.Mean = mean.String()
+	return snap
+}
+
+// quantile returns the upper bound of the bucket holding the q-th
+// fraction of observations, or "" when there are none
+func (s latencySnapshot) quantile(q float64) string {
+	target := int64(q * float64(s.Count))
+	var seen int64
+	for _, b := range s.Buckets {
+		seen += b.Count
+		if seen > target || (seen == s.Count && seen > 0) {
+			return b.LE
+		}
+	}
+	return ""
+}
+
+// serverStats holds the counters reported by /stats and GET /api/metrics
+type serverStats struct {
+	messages         atomic.Int64 // Chat lines received from clients
+	broadcasts       atomic.Int64 // Messages fanned out to a room or everyone
+	bytesWritten     atomic.Int64
+	commands         atomic.Int64
+	rejectedJoins    atomic.Int64
+	floodWarnings    atomic.Int64
+	floodKicks       atomic.Int64
+	broadcastLatency latencyHistogram
+
+	commandMutex  sync.Mutex
+	commandCounts map[string]int64
+}
+
+// countCommand records an invocation of the named command
+func (st *serverStats) countCommand(name string) {
+	st.commands.Add(1)
+	st.commandMutex.Lock()
+	defer st.commandMutex.Unlock()
+	if st.commandCounts == nil {
+		st.commandCounts = make(map[string]int64)
+	}
+	st.commandCounts[name]++
+}
+
+// observeBroadcast records a fan-out that started at start
+func (st *serverStats) observeBroadcast(start time.Time) {
+	st.broadcasts.Add(1)
+	st.broadcastLatency.observe(time.Since(start))
+}
+
+// meteredConn adds the bytes written to a connection to a counter
+type meteredConn struct {
+	net.Conn
+	written *atomic.Int64
+}
+
+func (m *meteredConn) Write(p []byte) (int, error) {
+	n, err := m.Conn.Write(p)
+	m.written.Add(int64(n))
+	return n, err
+}
+
+// meter counts what the server writes to conn in the bytes written total.
+// Gateways apply it to the raw connection, beneath any protocol wrapper.
+func (s *Server) meter(conn net.Conn) net.Conn {
+	return &meteredConn{Conn: conn, written: &s.stats.bytesWritten}
+}
+
+// metricsSnapshot is the JSON body of GET /api/metrics
+type metricsSnapshot struct {
+	Uptime            float64          `json:"uptime_seconds"`
+	Clients           int              `json:"clients"`
+	MaxClients        int              `json:"max_clients"`
+	Rooms             int              `json:"rooms"`
+	MessagesReceived  int64            `json:"messages_received"`
+	MessagesBroadcast int64            `json:"messages_broadcast"`
+	BytesWritten      int64            `json:"bytes_written"`
+	Commands          int64            `json:"commands"`
+	CommandCounts     map[string]int64 `json:"command_counts"`
+	RejectedJoins     int64            `json:"rejected_joins"`
+	FloodWarnings     int64            `json:"flood_warnings"`
+	FloodKicks        int64            `json:"flood_kicks"`
+	BroadcastLatency  latencySnapshot  `json:"broadcast_latency"`
+}
+
+// metrics reads every counter and gauge
+func (s *Server) metrics() metricsSnapshot {
+	s.mutex.RLock()
+	m := metricsSnapshot{
+		Uptime:     time.Since(s.startTime).Seconds(),
+		Clients:    s.clients.len(),
+		MaxClients: s.maxClients,
+		Rooms:      len(s.rooms),
+	}
+	s.mutex.RUnlock()
+
+	st := &s.stats
+	m.MessagesReceived = st.messages.Load()
+	m.MessagesBroadcast = st.broadcasts.Load()
+	m.BytesWritten = st.bytesWritten.Load()
+	m.Commands = st.commands.Load()
+	m.RejectedJoins = st.rejectedJoins.Load()
+	m.FloodWarnings = st.floodWarnings.Load()
+	m.FloodKicks = st.floodKicks.Load()
+	m.BroadcastLatency = st.broadcastLatency.snapshot()
+	st.commandMutex.Lock()
+	m.CommandCounts = make(map[string]int64, len(st.commandCounts))
+	for name, n := range st.commandCounts {
+		m.CommandCounts[name] = n
+	}
+	st.commandMutex.Unlock()
+	return m
+}
+
+// apiMetrics serves GET /api/metrics
+func (s *Server) apiMetrics(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.metrics())
+}
+
+// topCommands lists the n most used commands as "name (count)"
+func topCommands(counts map[string]int64, n int) []string {
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	top := make([]string, 0, n)
+	for _, name := range names[:min(n, len(names))] {
+		top = append(top, fmt.Sprintf("/%s (%d)", name, counts[name]))
+	}
+	return top
+}
+
+func statsCommand(s *Server, c *Client, args []string) error {
+	m := s.metrics()
+	lines := []string{
+		"Server statistics:",
+		fmt.Sprintf("Uptime:          %s", time.Duration(m.Uptime*float64(time.Second)).Round(time.Second)),
+		fmt.Sprintf("Clients:         %d/%d", m.Clients, m.MaxClients),
+		fmt.Sprintf("Rooms:           %d", m.Rooms),
+		fmt.Sprintf("Messages:        %d", m.MessagesReceived),
+		fmt.Sprintf("Broadcasts:      %d", m.MessagesBroadcast),
+		fmt.Sprintf("Bytes written:   %d", m.BytesWritten),
+		fmt.Sprintf("Commands:        %d", m.Commands),
+		fmt.Sprintf("Rejected joins:  %d", m.RejectedJoins),
+		fmt.Sprintf("Flood warnings:  %d", m.FloodWarnings),
+		fmt.Sprintf("Flood kicks:     %d", m.FloodKicks),
+	}
+	if top := topCommands(m.CommandCounts, 5); len(top) > 0 {
+		lines = append(lines, fmt.Sprintf("Top commands:    %s", strings.Join(top, ", ")))
+	}
+	if l := m.BroadcastLatency; l.Count > 0 {
+		lines = append(lines, fmt.Sprintf("Broadcast time:  mean %s, p50 <= %s, p99 <= %s",
+			l.Mean, l.quantile(0.5), l.quantile(0.99)))
+	}
+	c.write([]byte(strings.Join(lines, "\n") + "\n"))
+	return nil
+}
@@ -0,0 +1,110 @@
+package chat
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingConn keeps what is written to it instead of sending it
+type recordingConn struct {
+	net.Conn
+	mutex   sync.Mutex
+	written bytes.Buffer
+}
+
+func (r *recordingConn) Write(p []byte) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.written.Write(p)
+}
+
+func (r *recordingConn) String() string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.written.String()
+}
+
+func TestLatencyHistogram(t *testing.T) {
+	var h latencyHistogram
+	if q := h.snapshot().quantile(0.5); q != "" {
+		t.Errorf("quantile of an empty histogram = %q", q)
+	}
+	for i := 0; i < 98; i++ {
+		h.observe(50 * time.Microsecond)
+	}
+	h.observe(3 * time.Millisecond)
+	h.observe(time.Second)
+
+	snap := h.snapshot()
+	if snap.Count != 100 {
+		t.Errorf("count = %d, want 100", snap.Count)
+	}
+	if snap.Buckets[0].Count != 98 || snap.Buckets[3].Count != 1 || snap.Buckets[len(snap.Buckets)-1].Count != 1 {
+		t.Errorf("unexpected buckets %+v", snap.Buckets)
+	}
+	if q := snap.quantile(0.5); q != "100µs" {
+		t.Errorf("p50 = %q, want 100µs", q)
+	}
+	if q := snap.quantile(0.99); q != "+Inf" {
+		t.Errorf("p99 = %q, want +Inf", q)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	s, _ := newEmailTestServer(t)
+	s.config.AdminToken = "let-me-in"
+	api := httptest.NewServer(s.newAdminHandler())
+	defer api.Close()
+
+	server, client := net.Pipe()
+	t.Cleanup(func() { server.Close(); client.Close() })
+	written := &recordingConn{Conn: server}
+	alice := &Client{conn: s.meter(written), name: "Alice", joinTime: time.Now()}
+	s.clients.add(alice.conn, alice)
+	s.joinRoom(alice, "general", "")
+	s.handleCommand(alice, "/list")
+	s.handleCommand(alice, "/list")
+	s.handleCommand(alice, "/stats")
+
+	filter, _ := newIPFilter(nil, []string{"0.0.0.0/0"})
+	rejected, peer := net.Pipe()
+	defer peer.Close()
+	if s.admit(rejected, filter) {
+		t.Fatal("denied address admitted")
+	}
+
+	req, _ := http.NewRequest("GET", api.URL+"/api/metrics", nil)
+	req.Header.Set("Authorization", "Bearer let-me-in")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /api/metrics failed: %v", err)
+	}
+	defer resp.Body.Close()
+	var m metricsSnapshot
+	if err := json.NewDecoder(resp.Body).Decode(&m); err != nil {
+		t.Fatalf("bad metrics body: %v", err)
+	}
+
+	if m.Commands != 3 || m.CommandCounts["list"] != 2 || m.CommandCounts["stats"] != 1 {
+		t.Errorf("commands = %d %v, want 3 with /list twice", m.Commands, m.CommandCounts)
+	}
+	if m.RejectedJoins != 1 {
+		t.Errorf("rejected joins = %d, want 1", m.RejectedJoins)
+	}
+	if m.MessagesBroadcast == 0 || m.BroadcastLatency.Count != m.MessagesBroadcast {
+		t.Errorf("broadcasts = %d, latency samples = %d", m.MessagesBroadcast, m.BroadcastLatency.Count)
+	}
+	if m.BytesWritten == 0 || m.Clients != 1 {
+		t.Errorf("bytes written = %d, clients = %d", m.BytesWritten, m.Clients)
+	}
+	if !strings.Contains(written.String(), "Top commands:    /list (2), /stats (1)") {
+		t.Errorf("/stats output missing command counts: %q", written.String())
+	}
+}
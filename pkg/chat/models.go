@@ -0,0 +1,90 @@
+package chat
+
+import (
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// Client represents a connected chat client
+type Client struct {
+	conn     net.Conn
+	writer   *clientWriter // Outbound queue; nil writes synchronously
+	name     string
+	joinTime time.Time
+	room     string // Current room name
+	role     Role
+	bot      bool // Authenticated with a bot token
+	color    bool // Messages are sent with ANSI colors (/color)
+
+	identified    bool        // Proved ownership of a registered nickname
+	identifyTimer *time.Timer // Renames the client if it does not identify
+
+	// keywords are the /notify keywords of the account the client owns.
+	// Broadcasts read them without holding the server lock.
+	keywords atomic.Pointer[[]string]
+
+	tasks chan func()   // Work other goroutines hand the connection goroutine
+	done  chan struct{} // Closed once the connection goroutine stops reading
+
+	flood         *rateLimiter // Incoming message rate
+	floodWarnings int
+
+	ignored map[string]bool // Lowercase names whose messages are not delivered
+
+	// Paging state of /history
+	historyRoom string
+	historyPage int
+	historySeen int
+
+	// Results of the last /search, shown a page at a time
+	searchHits []searchHit
+	searchSeen int
+
+	mutedUntil time.Time
+	mutedRoom  string      // Empty for a server-wide mute
+	muteTimer  *time.Timer // Lifts the mute when it expires
+
+	lastActive  time.Time
+	idleWarned  bool
+	lastMessage time.Time // Last chat message, shown by /whois
+	lastText    string    // Its text and room, kept for /seen
+	lastRoom    string
+	sent        int    // Chat messages sent this session
+	leaveReason string // Appended to the leave broadcast
+	quit        bool   // Set by /quit to end the session
+
+	away      string // Away message; empty when present
+	awaySince time.Time
+	autoAway  bool // Set by the auto-away watcher rather than /away
+
+	mentions       []Message // Recent @mentions, listed by /mentions
+	unreadMentions int
+
+	unreadPrivate []Message // Private messages whose senders await a read receipt
+
+	resume map[string]int64 // Room to last message ID seen, from RESUME lines
+}
+
+// Message represents a chat message
+type Message struct {
+	Type      int
+	From      string
+	To        string // For private messages
+	Content   string
+	Timestamp time.Time
+	ID        int64 `json:",omitempty"` // Per-room sequence number, set when recorded; server-wide for private messages
+	Ref       int64 `json:",omitempty"` // Message a reaction belongs to or a reply quotes
+}
+
+// Message types for different kinds of messages
+const (
+	MessageTypeChat = iota
+	MessageTypeSystem
+	MessageTypePrivate
+	MessageTypeError
+	MessageTypeMention  // Someone @mentioned the recipient; To holds the room
+	MessageTypeReaction // Content is the emoji, Ref the message reacted to
+	MessageTypeReceipt  // From read or received private message Ref; Content is the state
+	MessageTypeKeyword  // A message matched one of the recipient's keywords; To holds the room
+)
@@ -0,0 +1,164 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+func kickCommand(s *Server, c *Client, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: /kick <user> [reason]")
+	}
+	reason := strings.Join(args[1:], " ")
+
+	s.mutex.Lock()
+	target := s.findClient(args[0])
+	if target == nil {
+		s.mutex.Unlock()
+		return fmt.Errorf("user %s not found", args[0])
+	}
+	if target.role >= c.role {
+		s.mutex.Unlock()
+		return fmt.Errorf("you can only kick users below your own role (%s)", c.role)
+	}
+	target.leaveReason = "kicked by " + c.name
+	if reason != "" {
+		target.leaveReason += ": " + reason
+	}
+	s.mutex.Unlock()
+
+	notice := fmt.Sprintf("You have been kicked by %s.", c.name)
+	if reason != "" {
+		notice = fmt.Sprintf("You have been kicked by %s: %s", c.name, reason)
+	}
+	target.sendMessage(Message{
+		Type:      MessageTypeError,
+		Content:   notice,
+		Timestamp: time.Now(),
+	})
+	// The leave broadcast in handleConnection announces the kick and reason
+	target.closeConn()
+
+	s.logActivity("Kicked", "client", c.name, "target", target.name, "remote", remoteIP(target.conn), "reason", reason)
+	s.audit("kick", c, target.name, reason)
+	return nil
+}
+
+// muteRemaining reports how long c stays muted when speaking in room, or
+// zero. An empty room checks only server-wide mutes. Caller holds s.mutex.
+func (s *Server) muteRemaining(c *Client, room string) time.Duration {
+	remaining := time.Until(c.mutedUntil)
+	if remaining <= 0 {
+		return 0
+	}
+	if c.mutedRoom != "" && !strings.EqualFold(c.mutedRoom, room) {
+		return 0
+	}
+	// Round up so a mute about to expire never reports zero
+	return (remaining + time.Second - 1).Truncate(time.Second)
+}
+
+// liftMute clears the mute on c if it is still the one that ends at until
+func (s *Server) liftMute(c *Client, until time.Time) {
+	s.mutex.Lock()
+	if !c.mutedUntil.Equal(until) {
+		s.mutex.Unlock()
+		return
+	}
+	c.mutedUntil = time.Time{}
+	c.mutedRoom = ""
+	c.muteTimer = nil
+	s.mutex.Unlock()
+
+	c.sendMessage(Message{
+		Type:      MessageTypeSystem,
+		Content:   "You are no longer muted.",
+		Timestamp: time.Now(),
+	})
+}
+
+func muteCommand(s *Server, c *Client, args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("usage: /mute <user> <duration> [room]")
+	}
+	duration, err := time.ParseDuration(args[1])
+	if err != nil || duration <= 0 {
+		return fmt.Errorf("invalid duration %q (e.g. 30s, 10m, 1h)", args[1])
+	}
+	room := ""
+	if len(args) > 2 {
+		room = args[2]
+	}
+
+	s.mutex.Lock()
+	target := s.findClient(args[0])
+	if target == nil {
+		s.mutex.Unlock()
+		return fmt.Errorf("user %s not found", args[0])
+	}
+	if target.role >= c.role {
+		s.mutex.Unlock()
+		return fmt.Errorf("you can only mute users below your own role (%s)", c.role)
+	}
+	if room != "" {
+		if _, exists := s.rooms[room]; !exists {
+			s.mutex.Unlock()
+			return fmt.Errorf("room %s does not exist", room)
+		}
+	}
+	if target.muteTimer != nil {
+		target.muteTimer.Stop()
+	}
+	until := time.Now().Add(duration)
+	target.mutedUntil = until
+	target.mutedRoom = room
+	target.muteTimer = time.AfterFunc(duration, func() { s.liftMute(target, until) })
+	s.mutex.Unlock()
+
+	scope := "server-wide"
+	if room != "" {
+		scope = "in " + room
+	}
+	s.logActivity("Muted", "client", c.name, "target", target.name, "duration", duration, "scope", scope)
+	s.audit("mute", c, target.name, fmt.Sprintf("%s %s", duration, scope))
+	s.broadcast(Message{
+		Type:      MessageTypeSystem,
+		Content:   fmt.Sprintf("%s was muted %s for %s by %s", target.name, scope, duration, c.name),
+		Timestamp: time.Now(),
+	}, nil)
+	return nil
+}
+
+func unmuteCommand(s *Server, c *Client, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: /unmute <user>")
+	}
+
+	s.mutex.Lock()
+	target := s.findClient(args[0])
+	if target == nil {
+		s.mutex.Unlock()
+		return fmt.Errorf("user %s not found", args[0])
+	}
+	if target.mutedUntil.IsZero() {
+		s.mutex.Unlock()
+		return fmt.Errorf("%s is not muted", target.name)
+	}
+	if target.muteTimer != nil {
+		target.muteTimer.Stop()
+		target.muteTimer = nil
+	}
+	target.mutedUntil = time.Time{}
+	target.mutedRoom = ""
+	s.mutex.Unlock()
+
+	s.logActivity("Unmuted", "client", c.name, "target", target.name)
+	s.audit("unmute", c, target.name, "")
+	s.broadcast(Message{
+		Type:      MessageTypeSystem,
+		Content:   fmt.Sprintf("%s was unmuted by %s", target.name, c.name),
+		Timestamp: time.Now(),
+	}, nil)
+	return nil
+}
@@ -0,0 +1,108 @@
+package chat
+
+import (
+	"testing"
+)
+
+func TestKickCommand(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountsFile = tempAccountsFile(t)
+	config.AuthRequired = true
+	config.Roles = map[string]Role{"alice": RoleModerator}
+	SetPassword(config, "Alice", "secret1")
+	SetPassword(config, "Bob", "secret2")
+	SetPassword(config, "Carol", "secret3")
+	addr := setupTestServerWithConfig(t, config)
+
+	login := func(name, password string) *TestClient {
+		c, err := newTestClient(t, addr)
+		if err != nil {
+			t.Fatalf("Client connection failed: %v", err)
+		}
+		c.expectMessage(t, "Welcome")
+		c.sendMessage(name)
+		c.sendMessage(password)
+		if err := c.expectMessage(t, name+" joined"); err != nil {
+			t.Fatalf("Login as %s failed: %v", name, err)
+		}
+		return c
+	}
+	alice := login("Alice", "secret1")
+	defer alice.close()
+	bob := login("Bob", "secret2")
+	defer bob.close()
+	carol := login("Carol", "secret3")
+	defer carol.close()
+
+	bob.sendMessage("/kick Carol")
+	if err := bob.expectMessage(t, "permission denied: /kick requires moderator"); err != nil {
+		t.Fatalf("User allowed to kick: %v", err)
+	}
+
+	alice.sendMessage("/kick Carol spamming links")
+	if err := carol.expectMessage(t, "kicked by Alice: spamming links"); err != nil {
+		t.Errorf("Target not told about the kick: %v", err)
+	}
+	if err := bob.expectMessage(t, "Carol has left our chat... (kicked by Alice: spamming links)"); err != nil {
+		t.Fatalf("Kick not announced: %v", err)
+	}
+
+	alice.sendMessage("/kick Nobody")
+	if err := alice.expectMessage(t, "user Nobody not found"); err != nil {
+		t.Errorf("Unknown target not reported: %v", err)
+	}
+}
+
+func TestMuteCommand(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountsFile = tempAccountsFile(t)
+	config.AuthRequired = true
+	config.Roles = map[string]Role{"alice": RoleModerator}
+	SetPassword(config, "Alice", "secret1")
+	SetPassword(config, "Bob", "secret2")
+	addr := setupTestServerWithConfig(t, config)
+
+	login := func(name, password string) *TestClient {
+		c, err := newTestClient(t, addr)
+		if err != nil {
+			t.Fatalf("Client connection failed: %v", err)
+		}
+		c.expectMessage(t, "Welcome")
+		c.sendMessage(name)
+		c.sendMessage(password)
+		if err := c.expectMessage(t, name+" joined"); err != nil {
+			t.Fatalf("Login as %s failed: %v", name, err)
+		}
+		return c
+	}
+	alice := login("Alice", "secret1")
+	defer alice.close()
+	bob := login("Bob", "secret2")
+	defer bob.close()
+
+	alice.sendMessage("/mute Bob 300ms")
+	if err := bob.expectMessage(t, "Bob was muted server-wide for 300ms by Alice"); err != nil {
+		t.Fatalf("Mute not announced: %v", err)
+	}
+	bob.sendMessage("hello?")
+	if err := bob.expectMessage(t, "You are muted"); err != nil {
+		t.Fatalf("Muted message not refused: %v", err)
+	}
+	bob.sendMessage("/msg Alice psst")
+	if err := bob.expectMessage(t, "you are muted"); err != nil {
+		t.Errorf("Muted private message not refused: %v", err)
+	}
+
+	if err := bob.expectMessage(t, "You are no longer muted"); err != nil {
+		t.Fatalf("Mute did not expire: %v", err)
+	}
+	bob.sendMessage("back again")
+	if err := alice.expectMessage(t, "back again"); err != nil {
+		t.Errorf("Message lost after the mute expired: %v", err)
+	}
+
+	alice.sendMessage("/unmute Bob")
+	if err := alice.expectMessage(t, "Bob is not muted"); err != nil {
+		t.Errorf("Unmute of unmuted user not reported: %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package chat
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// motdFile is the message of the day, re-read whenever the file's size or
+// modification time changes so edits show up without a restart
+type motdFile struct {
+	path    string
+	log     *slog.Logger
+	mutex   sync.Mutex
+	modTime time.Time
+	size    int64
+	text    string
+}
+
+func newMOTDFile(path string, logger *slog.Logger) *motdFile {
+	return &motdFile{path: path, log: logger}
+}
+
+// get returns the current message of the day, or "" when none is configured
+// or the file is missing
+func (m *motdFile) get() string {
+	if m == nil || m.path == "" {
+		return ""
+	}
+	info, err := os.Stat(m.path)
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if err != nil {
+		m.text, m.modTime, m.size = "", time.Time{}, 0
+		return ""
+	}
+	if info.ModTime().Equal(m.modTime) && info.Size() == m.size {
+		return m.text
+	}
+
+	data, err := os.ReadFile(m.path)
+	if err != nil {
+		m.log.Error("Error reading MOTD file", "path", m.path, "err", err)
+		return m.text
+	}
+	m.text = strings.TrimSpace(strings.ReplaceAll(string(data), "\r\n", "\n"))
+	m.modTime, m.size = info.ModTime(), info.Size()
+	return m.text
+}
+
+// reset makes the next get read the file again
+func (m *motdFile) reset() {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.modTime, m.size = time.Time{}, 0
+}
+
+// sendMOTD shows the message of the day to c, if there is one
+func (s *Server) sendMOTD(c *Client) bool {
+	text := s.motd.get()
+	if text == "" {
+		return false
+	}
+	c.write([]byte(fmt.Sprintf("--- Message of the day ---\n%s\n---\n", text)))
+	return true
+}
+
+func motdCommand(s *Server, c *Client, args []string) error {
+	if !s.sendMOTD(c) {
+		return fmt.Errorf("there is no message of the day")
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package chat
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMOTD(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "motd.txt")
+	if err := os.WriteFile(path, []byte("Be nice.\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	config := DefaultConfig()
+	config.AccountsFile = ""
+	config.RoomsFile = ""
+	config.MOTDFile = path
+	addr := setupTestServerWithConfig(t, config)
+
+	client, err := newTestClient(t, addr)
+	if err != nil {
+		t.Fatalf("Client connection failed: %v", err)
+	}
+	defer client.close()
+	client.sendMessage("Alice")
+	if err := client.expectMessage(t, "Be nice."); err != nil {
+		t.Fatalf("MOTD not sent after login: %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte("Maintenance at 10pm.\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	client.sendMessage("/motd")
+	if err := client.expectMessage(t, "Maintenance at 10pm."); err != nil {
+		t.Fatalf("MOTD not reloaded: %v", err)
+	}
+
+	os.Remove(path)
+	client.sendMessage("/motd")
+	if err := client.expectMessage(t, "there is no message of the day"); err != nil {
+		t.Fatalf("missing MOTD not reported: %v", err)
+	}
+}
@@ -0,0 +1,166 @@
+package chat
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// isRoomOperator reports whether c may manage room: its operators, who
+// include the creator, and server moderators can. Ops are kept by name,
+// so a registered name must be identified to use them. Caller holds
+// s.mutex.
+func (s *Server) isRoomOperator(room *ChatRoom, c *Client) bool {
+	return c.role >= RoleModerator || (room.ops[strings.ToLower(c.name)] && s.holdsName(c))
+}
+
+// opNames returns the room's operators, sorted
+func (r *ChatRoom) opNames() []string {
+	names := make([]string, 0, len(r.ops))
+	for name := range r.ops {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// opCommand makes a user an operator of the current room
+func opCommand(s *Server, c *Client, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: /op <user>")
+	}
+	s.mutex.Lock()
+	room, exists := s.rooms[c.room]
+	if !exists {
+		s.mutex.Unlock()
+		return fmt.Errorf("you are not in any room")
+	}
+	if !s.isRoomOperator(room, c) {
+		s.mutex.Unlock()
+		return fmt.Errorf("only room operators can appoint operators")
+	}
+	if room.name == s.lobby() {
+		s.mutex.Unlock()
+		return fmt.Errorf("%s is run by the server moderators", room.name)
+	}
+	target := s.findClient(args[0])
+	if target == nil {
+		s.mutex.Unlock()
+		return fmt.Errorf("user %s not found", args[0])
+	}
+	if room.ops[strings.ToLower(target.name)] {
+		s.mutex.Unlock()
+		return fmt.Errorf("%s is already an operator of %s", target.name, room.name)
+	}
+	room.ops[strings.ToLower(target.name)] = true
+	if err := s.saveRooms(); err != nil {
+		s.log.Error("Error saving rooms", "room", room.name, "err", err)
+	}
+	s.mutex.Unlock()
+
+	s.logActivity("Operator added", "client", c.name, "target", target.name, "room", room.name)
+	s.audit("op", c, target.name, room.name)
+	s.broadcastToRoom(room, Message{
+		Type:      MessageTypeSystem,
+		Content:   fmt.Sprintf("%s made %s a room operator", c.name, target.name),
+		Timestamp: time.Now(),
+	}, nil)
+	return nil
+}
+
+// deopCommand takes operator status in the current room away. Only the
+// creator and server moderators can deop the creator.
+func deopCommand(s *Server, c *Client, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: /deop <user>")
+	}
+	name := strings.ToLower(args[0])
+	s.mutex.Lock()
+	room, exists := s.rooms[c.room]
+	if !exists {
+		s.mutex.Unlock()
+		return fmt.Errorf("you are not in any room")
+	}
+	if !s.isRoomOperator(room, c) {
+		s.mutex.Unlock()
+		return fmt.Errorf("only room operators can remove operators")
+	}
+	if !room.ops[name] {
+		s.mutex.Unlock()
+		return fmt.Errorf("%s is not an operator of %s", args[0], room.name)
+	}
+	if strings.EqualFold(room.creator, name) && !strings.EqualFold(c.name, name) && c.role < RoleModerator {
+		s.mutex.Unlock()
+		return fmt.Errorf("only %s or a moderator can deop the room creator", room.creator)
+	}
+	delete(room.ops, name)
+	target := args[0]
+	if client := s.findClient(name); client != nil {
+		target = client.name
+	}
+	if err := s.saveRooms(); err != nil {
+		s.log.Error("Error saving rooms", "room", room.name, "err", err)
+	}
+	s.mutex.Unlock()
+
+	s.logActivity("Operator removed", "client", c.name, "target", target, "room", room.name)
+	s.audit("deop", c, target, room.name)
+	s.broadcastToRoom(room, Message{
+		Type:      MessageTypeSystem,
+		Content:   fmt.Sprintf("%s is no longer a room operator (by %s)", target, c.name),
+		Timestamp: time.Now(),
+	}, nil)
+	return nil
+}
+
+// kickRoomCommand sends a user from the current room back to the lobby
+func kickRoomCommand(s *Server, c *Client, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: /kickroom <user> [reason]")
+	}
+	reason := strings.Join(args[1:], " ")
+
+	s.mutex.Lock()
+	room, exists := s.rooms[c.room]
+	if !exists {
+		s.mutex.Unlock()
+		return fmt.Errorf("you are not in any room")
+	}
+	if !s.isRoomOperator(room, c) {
+		s.mutex.Unlock()
+		return fmt.Errorf("only room operators can remove users from the room")
+	}
+	if room.name == s.lobby() {
+		s.mutex.Unlock()
+		return fmt.Errorf("nobody can be removed from %s; moderators can /kick", room.name)
+	}
+	target := s.findClient(args[0])
+	if target == nil || target.room != room.name {
+		s.mutex.Unlock()
+		return fmt.Errorf("%s is not in %s", args[0], room.name)
+	}
+	if s.isRoomOperator(room, target) && c.role <= target.role {
+		s.mutex.Unlock()
+		return fmt.Errorf("%s is an operator of %s", target.name, room.name)
+	}
+	s.mutex.Unlock()
+
+	notice := fmt.Sprintf("%s removed you from %s", c.name, room.name)
+	if reason != "" {
+		notice += ": " + reason
+	}
+	target.sendMessage(Message{Type: MessageTypeError, Content: notice, Timestamp: time.Now()})
+	if err := s.joinRoom(target, s.lobby(), ""); err != nil {
+		return err
+	}
+
+	s.logActivity("Removed from room", "client", c.name, "target", target.name, "room", room.name, "reason", reason)
+	s.audit("kickroom", c, target.name, strings.TrimSpace(room.name+" "+reason))
+	s.broadcastToRoom(room, Message{
+		Type:      MessageTypeSystem,
+		Content:   fmt.Sprintf("%s removed %s from the room", c.name, target.name),
+		Timestamp: time.Now(),
+	}, nil)
+	return nil
+}
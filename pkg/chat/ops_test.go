@@ -0,0 +1,147 @@
+package chat
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRoomOperators(t *testing.T) {
+	config := DefaultConfig()
+	config.RoomsFile = ""
+	addr := setupTestServerWithConfig(t, config)
+	join := func(name string) *TestClient {
+		c, err := newTestClient(t, addr)
+		if err != nil {
+			t.Fatalf("Client connection failed: %v", err)
+		}
+		c.sendMessage(name)
+		if err := c.expectMessage(t, name+" joined"); err != nil {
+			t.Fatalf("Join as %s failed: %v", name, err)
+		}
+		return c
+	}
+	alice := join("Alice")
+	defer alice.close()
+	bob := join("Bob")
+	defer bob.close()
+	carol := join("Carol")
+	defer carol.close()
+
+	alice.sendMessage("/create dev")
+	bob.expectMessage(t, "Alice left the room")
+	carol.expectMessage(t, "Alice left the room")
+	bob.sendMessage("/join dev")
+	alice.expectMessage(t, "Bob joined the room")
+	carol.sendMessage("/join dev")
+	alice.expectMessage(t, "Carol joined the room")
+
+	bob.sendMessage("/kickroom Carol")
+	if err := bob.expectMessage(t, "only room operators can remove users from the room"); err != nil {
+		t.Fatalf("non-operator kicked: %v", err)
+	}
+
+	alice.sendMessage("/op bob")
+	if err := carol.expectMessage(t, "Alice made Bob a room operator"); err != nil {
+		t.Fatalf("op not announced: %v", err)
+	}
+	bob.sendMessage("/who")
+	if err := bob.expectMessage(t, "@Bob"); err != nil {
+		t.Fatalf("operator not marked in /who: %v", err)
+	}
+	bob.sendMessage("/topic ship it")
+	if err := carol.expectMessage(t, "ship it"); err != nil {
+		t.Fatalf("operator could not set the topic: %v", err)
+	}
+	bob.sendMessage("/deop alice")
+	if err := bob.expectMessage(t, "only Alice or a moderator can deop the room creator"); err != nil {
+		t.Fatalf("creator deopped: %v", err)
+	}
+	bob.sendMessage("/kickroom alice")
+	if err := bob.expectMessage(t, "Alice is an operator of dev"); err != nil {
+		t.Fatalf("operator kicked another operator: %v", err)
+	}
+
+	bob.sendMessage("/kickroom carol spamming")
+	if err := carol.expectMessage(t, "Bob removed you from dev: spamming"); err != nil {
+		t.Fatalf("kicked user not told: %v", err)
+	}
+	if err := alice.expectMessage(t, "Bob removed Carol from the room"); err != nil {
+		t.Fatalf("kick not announced: %v", err)
+	}
+	carol.sendMessage("/who")
+	if err := carol.expectMessage(t, "Users in room general"); err != nil {
+		t.Fatalf("kicked user not back in general: %v", err)
+	}
+
+	alice.sendMessage("/deop bob")
+	if err := bob.expectMessage(t, "Bob is no longer a room operator (by Alice)"); err != nil {
+		t.Fatalf("deop not announced: %v", err)
+	}
+	bob.sendMessage("/topic mine")
+	if err := bob.expectMessage(t, "only room operators can change the topic"); err != nil {
+		t.Fatalf("deopped user set the topic: %v", err)
+	}
+}
+
+func TestRoomOperatorsNeedIdentify(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountsFile = tempAccountsFile(t)
+	config.RoomsFile = filepath.Join(t.TempDir(), "rooms.json")
+	SetPassword(config, "Alice", "secret1")
+	SetPassword(config, "Carol", "secret2")
+	rooms, _ := json.Marshal([]RoomInfo{{Name: "dev", Creator: "Alice", Ops: []string{"Alice"}}})
+	os.WriteFile(config.RoomsFile, rooms, 0o600)
+	addr := setupTestServerWithConfig(t, config)
+	join := func(name string) *TestClient {
+		c, err := newTestClient(t, addr)
+		if err != nil {
+			t.Fatalf("Client connection failed: %v", err)
+		}
+		c.sendMessage(name)
+		if err := c.expectMessage(t, name+" joined"); err != nil {
+			t.Fatalf("Join as %s failed: %v", name, err)
+		}
+		return c
+	}
+
+	// Ops restored from the rooms file don't go to whoever connects with
+	// the name first
+	alice := join("Alice")
+	defer alice.close()
+	alice.sendMessage("/join dev")
+	alice.expectMessage(t, "Alice joined the room")
+	alice.sendMessage("/topic hijacked")
+	if err := alice.expectMessage(t, "only room operators can change the topic"); err != nil {
+		t.Fatalf("unidentified client used the operator rights of a registered name: %v", err)
+	}
+	alice.sendMessage("/identify secret1")
+	if err := alice.expectMessageWithin(t, "now identified as Alice", passwordTimeout); err != nil {
+		t.Fatalf("Identify failed: %v", err)
+	}
+	alice.sendMessage("/topic ship it")
+	if err := alice.expectMessage(t, "ship it"); err != nil {
+		t.Fatalf("identified operator could not set the topic: %v", err)
+	}
+
+	// Neither is an invitation to a registered name
+	alice.sendMessage("/inviteonly on")
+	alice.expectMessage(t, "invite-only")
+	carol := join("Carol")
+	defer carol.close()
+	alice.sendMessage("/invite Carol")
+	carol.expectMessage(t, "Alice invited you to dev")
+	carol.sendMessage("/join dev")
+	if err := carol.expectMessage(t, "room dev is invite-only"); err != nil {
+		t.Errorf("unidentified client used an invitation to a registered name: %v", err)
+	}
+	carol.sendMessage("/identify secret2")
+	if err := carol.expectMessageWithin(t, "now identified as Carol", passwordTimeout); err != nil {
+		t.Fatalf("Identify failed: %v", err)
+	}
+	carol.sendMessage("/join dev")
+	if err := alice.expectMessage(t, "Carol joined the room"); err != nil {
+		t.Errorf("invitation lost after identifying: %v", err)
+	}
+}
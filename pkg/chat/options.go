@@ -0,0 +1,66 @@
+package chat
+
+import (
+	"log/slog"
+	"time"
+)
+
+// Option configures a Server built by New
+type Option func(*options)
+
+type options struct {
+	config  *Config
+	store   Store
+	logger  *slog.Logger
+	signals bool
+}
+
+// New builds a server from DefaultConfig adjusted by opts. Start it with
+// Start or StartContext and stop it with Shutdown.
+func New(opts ...Option) *Server {
+	o := options{config: DefaultConfig()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return newServer(o)
+}
+
+// WithConfig replaces the default configuration. Options after it adjust
+// config itself.
+func WithConfig(config *Config) Option {
+	return func(o *options) { o.config = config }
+}
+
+// WithMaxClients caps how many clients are connected at once
+func WithMaxClients(n int) Option {
+	return func(o *options) { o.config.MaxClients = n }
+}
+
+// WithStore keeps messages, rooms and accounts in store instead of the
+// backend selected by Config.Store. The server closes it on Shutdown.
+func WithStore(store Store) Option {
+	return func(o *options) { o.store = store }
+}
+
+// WithLogger sends the activity log (logins, moderation, room changes) to
+// logger instead of chat.log
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) { o.logger = logger }
+}
+
+// WithSignalHandling makes the server reload its files on SIGHUP and shut
+// down on SIGINT or SIGTERM. Without it the embedding program keeps its own
+// signal handling.
+func WithSignalHandling() Option {
+	return func(o *options) { o.signals = true }
+}
+
+// WithLobby names the room new clients are put in
+func WithLobby(room string) Option {
+	return func(o *options) { o.config.Lobby = room }
+}
+
+// WithIdleTimeout disconnects clients that send nothing for d
+func WithIdleTimeout(d time.Duration) Option {
+	return func(o *options) { o.config.IdleTimeout = d }
+}
@@ -0,0 +1,45 @@
+package chat
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestNewWithOptions(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountsFile = ""
+	config.RoomsFile = ""
+	store := NewMemoryStore()
+	var logged bytes.Buffer
+	s := New(
+		WithConfig(config),
+		WithMaxClients(3),
+		WithStore(store),
+		WithLogger(slog.New(slog.NewTextHandler(&logged, nil))),
+		WithLobby("lobby"),
+	)
+
+	if s.maxClients != 3 {
+		t.Errorf("maxClients = %d, want 3", s.maxClients)
+	}
+	if s.store != store {
+		t.Error("WithStore ignored")
+	}
+	if s.Logfile != nil {
+		t.Error("chat.log opened despite WithLogger")
+	}
+	if _, exists := s.rooms["lobby"]; !exists {
+		t.Error("lobby not created")
+	}
+
+	admin := newPipeClient(t, "Alice")
+	admin.role = RoleAdmin
+	if err := setLimitCommand(s, admin, []string{"5"}); err != nil {
+		t.Fatalf("/setlimit failed: %v", err)
+	}
+	if !strings.Contains(logged.String(), `msg="Client limit changed" client=Alice old=3 new=5`) {
+		t.Errorf("activity not sent to the logger: %q", logged.String())
+	}
+}
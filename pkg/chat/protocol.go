@@ -0,0 +1,148 @@
+package chat
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// protoJSON is the line a client sends in place of its name to switch the
+// connection to newline-delimited JSON
+const protoJSON = "PROTO json"
+
+// jsonEvent is one line the server sends in JSON mode
+type jsonEvent struct {
+	Type      string    `json:"type"` // chat, system, private, error, mention, keyword, reaction, receipt, roster or proto
+	Room      string    `json:"room,omitempty"`
+	From      string    `json:"from,omitempty"`
+	To        string    `json:"to,omitempty"`
+	Content   string    `json:"content"`
+	Timestamp time.Time `json:"time"`
+	ID        int64     `json:"id,omitempty"`
+	Ref       int64     `json:"ref,omitempty"`   // Message a reaction belongs to or a reply quotes
+	Rooms     []string  `json:"rooms,omitempty"` // For roster: the rooms the client can see
+	Users     []string  `json:"users,omitempty"` // For roster: who is in the client's room
+}
+
+// jsonRequest is one line a client sends in JSON mode
+type jsonRequest struct {
+	Type    string   `json:"type"` // message (default), command, private or read
+	Content string   `json:"content"`
+	Command string   `json:"command"` // For type command, without the slash
+	Args    []string `json:"args"`
+	To      string   `json:"to"`  // For type private
+	Ref     int64    `json:"ref"` // For type read: last private message read, 0 for all
+}
+
+// text turns a request into the line a text client would have typed
+func (r jsonRequest) text() (string, error) {
+	switch r.Type {
+	case "", "message":
+		return r.Content, nil
+	case "command":
+		if r.Command == "" {
+			return "", fmt.Errorf("command missing")
+		}
+		return strings.TrimSpace("/" + strings.TrimPrefix(r.Command, "/") + " " + strings.Join(r.Args, " ")), nil
+	case "private":
+		if r.To == "" {
+			return "", fmt.Errorf("recipient missing")
+		}
+		return "/msg " + r.To + " " + r.Content, nil
+	case "read":
+		if r.Ref > 0 {
+			return fmt.Sprintf("/read %d", r.Ref), nil
+		}
+		return "/read", nil
+	default:
+		return "", fmt.Errorf("unknown request type %q", r.Type)
+	}
+}
+
+// jsonConn speaks JSON lines to the client while the rest of the server
+// keeps reading and writing text. Reads decode requests into text lines;
+// plain text writes become system events, and Client.sendMessage writes
+// structured events through writeEvent.
+type jsonConn struct {
+	net.Conn
+	src     *bufio.Reader // Drains anything buffered before the switch
+	pending []byte
+}
+
+func newJSONConn(conn net.Conn, src *bufio.Reader) *jsonConn {
+	return &jsonConn{Conn: conn, src: src}
+}
+
+func (j *jsonConn) Read(p []byte) (int, error) {
+	for len(j.pending) == 0 {
+		line, err := j.src.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var req jsonRequest
+			text, decodeErr := "", json.Unmarshal(line, &req)
+			if decodeErr == nil {
+				text, decodeErr = req.text()
+			}
+			if decodeErr != nil {
+				j.writeEvent(jsonEvent{Type: "error", Content: "invalid request: " + decodeErr.Error(), Timestamp: time.Now()})
+			} else {
+				j.pending = []byte(strings.ReplaceAll(text, "\n", " ") + "\n")
+			}
+		}
+		if err != nil && len(j.pending) == 0 {
+			return 0, err
+		}
+	}
+	n := copy(p, j.pending)
+	j.pending = j.pending[n:]
+	return n, nil
+}
+
+// Write sends each non-empty line of text as a system event
+func (j *jsonConn) Write(p []byte) (int, error) {
+	var out bytes.Buffer
+	for _, line := range strings.Split(string(p), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			data, _ := json.Marshal(jsonEvent{Type: "system", Content: line, Timestamp: time.Now()})
+			out.Write(append(data, '\n'))
+		}
+	}
+	if out.Len() > 0 {
+		if _, err := j.Conn.Write(out.Bytes()); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (j *jsonConn) writeEvent(e jsonEvent) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = j.Conn.Write(append(data, '\n'))
+	return err
+}
+
+// messageEvent is the JSON form of msg as seen by a client in room
+func messageEvent(msg Message, room string) jsonEvent {
+	e := jsonEvent{
+		Type:      messageTypeNames[msg.Type],
+		From:      msg.From,
+		To:        msg.To,
+		Content:   msg.Content,
+		Timestamp: msg.Timestamp,
+		ID:        msg.ID,
+		Ref:       msg.Ref,
+	}
+	switch msg.Type {
+	case MessageTypeChat, MessageTypeReaction:
+		e.Room = room
+	case MessageTypeMention, MessageTypeKeyword:
+		e.Room, e.To = msg.To, ""
+	}
+	return e
+}
@@ -0,0 +1,79 @@
+package chat
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// expectEvent reads JSON events until one matches typ and contains content
+func (c *TestClient) expectEvent(t *testing.T, typ, content string) (jsonEvent, error) {
+	c.conn.SetReadDeadline(time.Now().Add(messageTimeout))
+	defer c.conn.SetReadDeadline(time.Time{})
+	for {
+		line, err := c.reader.ReadString('\n')
+		if err != nil {
+			return jsonEvent{}, err
+		}
+		var e jsonEvent
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("server sent a non-JSON line: %q", line)
+		}
+		if e.Type == typ && strings.Contains(e.Content, content) {
+			return e, nil
+		}
+	}
+}
+
+func TestJSONProtocol(t *testing.T) {
+	addr := setupTestServer(t)
+
+	alice, err := newTestClient(t, addr)
+	if err != nil {
+		t.Fatalf("Client connection failed: %v", err)
+	}
+	defer alice.close()
+	alice.expectMessage(t, "ENTER YOUR NAME")
+	alice.sendMessage(protoJSON)
+	if _, err := alice.expectEvent(t, "proto", "json"); err != nil {
+		t.Fatalf("JSON mode not acknowledged: %v", err)
+	}
+	alice.sendMessage(`{"content":"Alice"}`)
+	if _, err := alice.expectEvent(t, "system", "Alice joined"); err != nil {
+		t.Fatalf("JSON login failed: %v", err)
+	}
+
+	bob, err := newTestClient(t, addr)
+	if err != nil {
+		t.Fatalf("Client connection failed: %v", err)
+	}
+	defer bob.close()
+	bob.expectMessage(t, "ENTER YOUR NAME")
+	bob.sendMessage("Bob")
+	alice.expectEvent(t, "system", "Bob joined")
+
+	bob.sendMessage("hello json")
+	e, err := alice.expectEvent(t, "chat", "hello json")
+	if err != nil {
+		t.Fatalf("Chat message not delivered as JSON: %v", err)
+	}
+	if e.From != "Bob" || e.Room != "general" {
+		t.Errorf("chat event = %+v, want from Bob in general", e)
+	}
+
+	alice.sendMessage(`{"type":"private","to":"Bob","content":"psst"}`)
+	if err := bob.expectMessage(t, "[PM #1 from Alice]: psst"); err != nil {
+		t.Errorf("JSON private message not delivered: %v", err)
+	}
+
+	alice.sendMessage(`{"type":"command","command":"nope"}`)
+	if _, err := alice.expectEvent(t, "error", "Unknown command"); err != nil {
+		t.Errorf("Command error not sent as JSON: %v", err)
+	}
+
+	alice.sendMessage(`not json`)
+	if _, err := alice.expectEvent(t, "error", "invalid request"); err != nil {
+		t.Errorf("Malformed request not reported: %v", err)
+	}
+}
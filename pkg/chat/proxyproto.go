@@ -0,0 +1,124 @@
+package chat
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// proxyHeaderTimeout bounds how long a load balancer may take to send the
+// PROXY header
+const proxyHeaderTimeout = 5 * time.Second
+
+// proxyV2Signature starts every PROXY protocol v2 header
+var proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// proxyConn reports the client address from a PROXY header instead of the
+// load balancer's
+type proxyConn struct {
+	net.Conn
+	reader *bufio.Reader // Holds any bytes read past the header
+	remote net.Addr
+}
+
+func (p *proxyConn) Read(b []byte) (int, error) { return p.reader.Read(b) }
+
+func (p *proxyConn) RemoteAddr() net.Addr { return p.remote }
+
+// acceptProxy reads the PROXY v1 or v2 header that must open conn and
+// returns a connection reporting the original client address. LOCAL and
+// UNKNOWN headers (health checks) keep the load balancer's address.
+func acceptProxy(conn net.Conn) (net.Conn, error) {
+	conn.SetReadDeadline(time.Now().Add(proxyHeaderTimeout))
+	defer conn.SetReadDeadline(time.Time{})
+
+	reader := bufio.NewReader(conn)
+	start, err := reader.Peek(len(proxyV2Signature))
+	if err != nil && len(start) < 5 {
+		return nil, fmt.Errorf("reading PROXY header: %v", err)
+	}
+
+	var remote net.Addr
+	switch {
+	case bytes.Equal(start, proxyV2Signature):
+		remote, err = readProxyV2(reader)
+	case bytes.HasPrefix(start, []byte("PROXY")):
+		remote, err = readProxyV1(reader)
+	default:
+		return nil, errors.New("missing PROXY header")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if remote == nil {
+		remote = conn.RemoteAddr()
+	}
+	return &proxyConn{Conn: conn, reader: reader, remote: remote}, nil
+}
+
+// readProxyV1 parses "PROXY TCP4 <src> <dst> <sport> <dport>\r\n"
+func readProxyV1(reader *bufio.Reader) (net.Addr, error) {
+	var line []byte
+	for len(line) < 107 { // Longest valid v1 header
+		b, err := reader.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("reading PROXY header: %v", err)
+		}
+		line = append(line, b)
+		if b == '\n' {
+			break
+		}
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, errors.New("PROXY v1 header too long")
+	}
+
+	fields := strings.Fields(string(line))
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, fmt.Errorf("malformed PROXY v1 header %q", strings.TrimSpace(string(line)))
+	}
+	ip := net.ParseIP(fields[2])
+	port, err := strconv.Atoi(fields[4])
+	if ip == nil || err != nil || port < 0 || port > 65535 {
+		return nil, fmt.Errorf("malformed PROXY v1 header %q", strings.TrimSpace(string(line)))
+	}
+	return &net.TCPAddr{IP: ip, Port: port}, nil
+}
+
+// readProxyV2 parses the binary v2 header
+func readProxyV2(reader *bufio.Reader) (net.Addr, error) {
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, fmt.Errorf("reading PROXY header: %v", err)
+	}
+	if header[12]>>4 != 2 {
+		return nil, fmt.Errorf("unsupported PROXY version %d", header[12]>>4)
+	}
+	body := make([]byte, binary.BigEndian.Uint16(header[14:16]))
+	if _, err := io.ReadFull(reader, body); err != nil {
+		return nil, fmt.Errorf("reading PROXY header: %v", err)
+	}
+
+	if header[12]&0x0F == 0 { // LOCAL
+		return nil, nil
+	}
+	switch family := header[13] >> 4; {
+	case family == 1 && len(body) >= 12: // AF_INET
+		return &net.TCPAddr{IP: net.IP(body[0:4]), Port: int(binary.BigEndian.Uint16(body[8:10]))}, nil
+	case family == 2 && len(body) >= 36: // AF_INET6
+		return &net.TCPAddr{IP: net.IP(body[0:16]), Port: int(binary.BigEndian.Uint16(body[32:34]))}, nil
+	default:
+		// AF_UNIX or unspecified: nothing useful to report
+		return nil, nil
+	}
+}
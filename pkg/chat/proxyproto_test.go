@@ -0,0 +1,130 @@
+package chat
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// proxyV2Header builds a v2 PROXY header for an IPv4 TCP connection
+func proxyV2Header(src net.IP, port uint16) []byte {
+	header := append([]byte(nil), proxyV2Signature...)
+	header = append(header, 0x21, 0x11, 0, 12)
+	header = append(header, src.To4()...)
+	header = append(header, 192, 0, 2, 1)
+	header = binary.BigEndian.AppendUint16(header, port)
+	return binary.BigEndian.AppendUint16(header, 8989)
+}
+
+func TestAcceptProxy(t *testing.T) {
+	tests := []struct {
+		name   string
+		header []byte
+		want   string // Remote address, empty for an error
+	}{
+		{"V1", []byte("PROXY TCP4 203.0.113.7 192.0.2.1 51234 8989\r\n"), "203.0.113.7:51234"},
+		{"V1IPv6", []byte("PROXY TCP6 2001:db8::7 2001:db8::1 51234 8989\r\n"), "[2001:db8::7]:51234"},
+		{"V1Unknown", []byte("PROXY UNKNOWN\r\n"), "pipe"},
+		{"V2", proxyV2Header(net.ParseIP("198.51.100.3"), 40000), "198.51.100.3:40000"},
+		{"Missing", []byte("Alice\n"), ""},
+		{"Malformed", []byte("PROXY TCP4 nonsense\r\n"), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, client := net.Pipe()
+			defer server.Close()
+			go func() {
+				client.Write(append(tt.header, "hello\n"...))
+				io.Copy(io.Discard, client)
+			}()
+			defer client.Close()
+
+			conn, err := acceptProxy(server)
+			if tt.want == "" {
+				if err == nil {
+					t.Fatalf("accepted %q", tt.header)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("acceptProxy failed: %v", err)
+			}
+			if got := conn.RemoteAddr().String(); got != tt.want {
+				t.Errorf("RemoteAddr = %s, want %s", got, tt.want)
+			}
+			buf := make([]byte, 6)
+			if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "hello\n" {
+				t.Errorf("data after the header = %q, %v", buf, err)
+			}
+		})
+	}
+}
+
+func TestProxyProtocolBans(t *testing.T) {
+	config := DefaultConfig()
+	config.ProxyProtocol = true
+	config.BanFile = filepath.Join(t.TempDir(), "bans.txt")
+	os.WriteFile(config.BanFile, []byte("203.0.113.7\n"), 0o600)
+	addr := setupTestServerWithConfig(t, config)
+
+	banned, err := newTestClient(t, addr)
+	if err != nil {
+		t.Fatalf("Client connection failed: %v", err)
+	}
+	defer banned.close()
+	banned.sendMessage("PROXY TCP4 203.0.113.7 192.0.2.1 51234 9027\r")
+	if err := banned.expectMessage(t, "You are banned"); err != nil {
+		t.Errorf("Ban not applied to the proxied address: %v", err)
+	}
+
+	allowed, err := newTestClient(t, addr)
+	if err != nil {
+		t.Fatalf("Client connection failed: %v", err)
+	}
+	defer allowed.close()
+	allowed.sendMessage("PROXY TCP4 198.51.100.3 192.0.2.1 51234 9027\r")
+	if err := allowed.expectMessage(t, "Welcome"); err != nil {
+		t.Errorf("Proxied client not welcomed: %v", err)
+	}
+}
+
+func TestProxyProtocolTLS(t *testing.T) {
+	config := DefaultConfig()
+	config.ProxyProtocol = true
+	config.TLSCert, config.TLSKey = writeTestCertificate(t)
+	config.BanFile = filepath.Join(t.TempDir(), "bans.txt")
+	os.WriteFile(config.BanFile, []byte("203.0.113.7\n"), 0o600)
+	addr := setupTestServerWithConfig(t, config)
+
+	// dial sends the plaintext header, then starts the TLS handshake
+	dial := func(header []byte) *TestClient {
+		conn, err := net.DialTimeout("tcp", addr, dialTimeout)
+		if err != nil {
+			t.Fatalf("Client connection failed: %v", err)
+		}
+		if _, err := conn.Write(header); err != nil {
+			t.Fatalf("Writing the PROXY header failed: %v", err)
+		}
+		secure := tls.Client(conn, &tls.Config{InsecureSkipVerify: true})
+		t.Cleanup(func() { secure.Close() })
+		return &TestClient{conn: secure, reader: bufio.NewReader(secure)}
+	}
+
+	for name, header := range map[string][]byte{
+		"V1": []byte("PROXY TCP4 198.51.100.3 192.0.2.1 51234 8989\r\n"),
+		"V2": proxyV2Header(net.ParseIP("198.51.100.4"), 40000),
+	} {
+		if err := dial(header).expectMessage(t, "Welcome"); err != nil {
+			t.Errorf("%s: TLS client behind the proxy not welcomed: %v", name, err)
+		}
+	}
+	banned := dial([]byte("PROXY TCP4 203.0.113.7 192.0.2.1 51234 8989\r\n"))
+	if err := banned.expectMessage(t, "You are banned"); err != nil {
+		t.Errorf("Ban not applied to the proxied TLS client: %v", err)
+	}
+}
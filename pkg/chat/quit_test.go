@@ -0,0 +1,38 @@
+package chat
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+func TestQuit(t *testing.T) {
+	addr := setupTestServer(t)
+	join := func(name string) *TestClient {
+		c, err := newTestClient(t, addr)
+		if err != nil {
+			t.Fatalf("Client connection failed: %v", err)
+		}
+		c.sendMessage(name)
+		if err := c.expectMessage(t, name+" joined"); err != nil {
+			t.Fatalf("Join as %s failed: %v", name, err)
+		}
+		return c
+	}
+	alice := join("Alice")
+	defer alice.close()
+	bob := join("Bob")
+	defer bob.close()
+
+	bob.sendMessage("/quit see you tomorrow")
+	if err := bob.expectMessage(t, "Goodbye!"); err != nil {
+		t.Fatalf("no goodbye: %v", err)
+	}
+	bob.conn.SetReadDeadline(time.Now().Add(messageTimeout))
+	if _, err := bob.reader.ReadString('\n'); err != io.EOF {
+		t.Errorf("expected the server to close the connection, got %v", err)
+	}
+	if err := alice.expectMessage(t, "Bob has left our chat... (quit: see you tomorrow)"); err != nil {
+		t.Fatalf("no quit broadcast: %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+package chat
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxQuoteLength bounds how much of the quoted message is repeated, in runes
+const maxQuoteLength = 60
+
+// quotePrefix renders the quotation put in front of a reply to msg
+func quotePrefix(msg Message) string {
+	text := strings.Join(strings.Fields(msg.Content), " ")
+	if runes := []rune(text); len(runes) > maxQuoteLength {
+		text = strings.TrimSpace(string(runes[:maxQuoteLength])) + "..."
+	}
+	return fmt.Sprintf("> #%d %s: \"%s\"", msg.ID, msg.From, text)
+}
+
+func quoteCommand(s *Server, c *Client, args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("usage: /quote <id> <text>")
+	}
+	id, err := strconv.ParseInt(strings.TrimPrefix(args[0], "#"), 10, 64)
+	if err != nil || id <= 0 {
+		return fmt.Errorf("usage: /quote <id> <text>")
+	}
+
+	s.mutex.Lock()
+	room, exists := s.rooms[c.room]
+	ephemeral := exists && room.ephemeral
+	s.mutex.Unlock()
+	if !exists {
+		return fmt.Errorf("you are not in any room")
+	}
+	if ephemeral {
+		return errNoHistory(room.name)
+	}
+	history, err := s.store.RecentMessages(room.name, 0)
+	if err != nil {
+		return fmt.Errorf("failed to load history: %v", err)
+	}
+	var target *Message
+	for i := range history {
+		if history[i].Type == MessageTypeChat && history[i].ID == id {
+			target = &history[i]
+		}
+	}
+	if target == nil {
+		return fmt.Errorf("message #%d not found in %s", id, room.name)
+	}
+
+	return s.postChat(c, Message{
+		Type:      MessageTypeChat,
+		From:      c.name,
+		Content:   quotePrefix(*target) + " " + strings.Join(args[1:], " "),
+		Timestamp: time.Now(),
+		Ref:       id,
+	})
+}
@@ -0,0 +1,55 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestQuotePrefix(t *testing.T) {
+	short := Message{ID: 3, From: "Alice", Content: "ship   it?"}
+	if got := quotePrefix(short); got != `> #3 Alice: "ship it?"` {
+		t.Errorf("quotePrefix = %q", got)
+	}
+
+	long := Message{ID: 4, From: "Bob", Content: strings.Repeat("é", maxQuoteLength+10)}
+	want := `> #4 Bob: "` + strings.Repeat("é", maxQuoteLength) + `..."`
+	if got := quotePrefix(long); got != want {
+		t.Errorf("quotePrefix = %q, want %q", got, want)
+	}
+}
+
+func TestQuote(t *testing.T) {
+	config := DefaultConfig()
+	config.RoomsFile = ""
+	config.HistoryFile = ""
+	addr := setupTestServerWithConfig(t, config)
+	join := func(name string) *TestClient {
+		c, err := newTestClient(t, addr)
+		if err != nil {
+			t.Fatalf("Client connection failed: %v", err)
+		}
+		c.sendMessage(name)
+		if err := c.expectMessage(t, name+" joined"); err != nil {
+			t.Fatalf("Join as %s failed: %v", name, err)
+		}
+		return c
+	}
+	alice := join("Alice")
+	defer alice.close()
+	bob := join("Bob")
+	defer bob.close()
+
+	alice.sendMessage("shall we ship on Friday?")
+	if err := bob.expectMessage(t, "[#3][Alice]: shall we ship on Friday?"); err != nil {
+		t.Fatalf("message not delivered: %v", err)
+	}
+	bob.sendMessage("/quote 3 agreed")
+	if err := alice.expectMessage(t, `[Bob]: > #3 Alice: "shall we ship on Friday?" agreed`); err != nil {
+		t.Fatalf("quote not delivered: %v", err)
+	}
+
+	bob.sendMessage("/quote 99 what?")
+	if err := bob.expectMessage(t, "message #99 not found in general"); err != nil {
+		t.Fatalf("unknown message not reported: %v", err)
+	}
+}
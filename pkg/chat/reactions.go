@@ -0,0 +1,102 @@
+package chat
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxReactionLength bounds the emoji (or :shortcode:) of a reaction
+const maxReactionLength = 32
+
+// reactionTally summarises the reactions to message id in history, in the
+// order each emoji was first used
+func reactionTally(history []Message, id int64) string {
+	counts := make(map[string]int)
+	var order []string
+	for _, msg := range history {
+		if msg.Type != MessageTypeReaction || msg.Ref != id {
+			continue
+		}
+		if counts[msg.Content] == 0 {
+			order = append(order, msg.Content)
+		}
+		counts[msg.Content]++
+	}
+	parts := make([]string, len(order))
+	for i, emoji := range order {
+		parts[i] = fmt.Sprintf("%s %d", emoji, counts[emoji])
+	}
+	return strings.Join(parts, ", ")
+}
+
+func reactCommand(s *Server, c *Client, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: /react <id> <emoji>")
+	}
+	id, err := strconv.ParseInt(strings.TrimPrefix(args[0], "#"), 10, 64)
+	if err != nil || id <= 0 {
+		return fmt.Errorf("usage: /react <id> <emoji>")
+	}
+	emoji := args[1]
+	if len(emoji) > maxReactionLength || !utf8.ValidString(emoji) {
+		return fmt.Errorf("reactions are limited to %d bytes", maxReactionLength)
+	}
+
+	s.mutex.Lock()
+	room, exists := s.rooms[c.room]
+	ephemeral := exists && room.ephemeral
+	frozen := exists && s.frozenFor(room, c)
+	s.mutex.Unlock()
+	if !exists {
+		return fmt.Errorf("you are not in any room")
+	}
+	if ephemeral {
+		return errNoHistory(room.name)
+	}
+	if frozen {
+		return errArchived(room.name)
+	}
+	history, err := s.store.RecentMessages(room.name, 0)
+	if err != nil {
+		return fmt.Errorf("failed to load history: %v", err)
+	}
+
+	var target *Message
+	for i := range history {
+		msg := &history[i]
+		if msg.Type == MessageTypeChat && msg.ID == id {
+			target = msg
+		}
+		if msg.Type == MessageTypeReaction && msg.Ref == id && msg.Content == emoji && strings.EqualFold(msg.From, c.name) {
+			return fmt.Errorf("you already reacted %s to #%d", emoji, id)
+		}
+	}
+	if target == nil {
+		return fmt.Errorf("message #%d not found in %s", id, room.name)
+	}
+
+	reaction := s.record(room.name, Message{
+		Type:      MessageTypeReaction,
+		From:      c.name,
+		Content:   emoji,
+		Ref:       id,
+		Timestamp: time.Now(),
+	})
+	history = append(history, reaction)
+
+	// The tally is an event, not history; the reaction itself is stored
+	notice := Message{
+		Type:      MessageTypeSystem,
+		Content:   fmt.Sprintf("%s reacted %s to #%d (%s): %s", c.name, emoji, id, target.From, reactionTally(history, id)),
+		Timestamp: reaction.Timestamp,
+	}
+	for _, client := range room.members() {
+		if !client.ignores(c.name) {
+			client.sendMessage(notice)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package chat
+
+import "testing"
+
+func TestReactionTally(t *testing.T) {
+	history := []Message{
+		{Type: MessageTypeChat, ID: 1, From: "Alice", Content: "ship it?"},
+		{Type: MessageTypeReaction, Ref: 1, From: "Bob", Content: "👍"},
+		{Type: MessageTypeReaction, Ref: 2, From: "Bob", Content: "👎"},
+		{Type: MessageTypeReaction, Ref: 1, From: "Carol", Content: "🎉"},
+		{Type: MessageTypeReaction, Ref: 1, From: "Dave", Content: "👍"},
+	}
+	if got := reactionTally(history, 1); got != "👍 2, 🎉 1" {
+		t.Errorf("tally = %q", got)
+	}
+}
+
+func TestReact(t *testing.T) {
+	config := DefaultConfig()
+	config.RoomsFile = ""
+	config.HistoryFile = ""
+	addr := setupTestServerWithConfig(t, config)
+	join := func(name string) *TestClient {
+		c, err := newTestClient(t, addr)
+		if err != nil {
+			t.Fatalf("Client connection failed: %v", err)
+		}
+		c.sendMessage(name)
+		if err := c.expectMessage(t, name+" joined"); err != nil {
+			t.Fatalf("Join as %s failed: %v", name, err)
+		}
+		return c
+	}
+	alice := join("Alice")
+	defer alice.close()
+	bob := join("Bob")
+	defer bob.close()
+
+	// Joins are numbered too, so the chat message is #3
+	alice.sendMessage("ship it?")
+	if err := bob.expectMessage(t, "[#3][Alice]: ship it?"); err != nil {
+		t.Fatalf("message has no ID: %v", err)
+	}
+
+	bob.sendMessage("/react 3 👍")
+	if err := alice.expectMessage(t, "Bob reacted 👍 to #3 (Alice): 👍 1"); err != nil {
+		t.Fatalf("no tally: %v", err)
+	}
+	alice.sendMessage("/react #3 👍")
+	if err := bob.expectMessage(t, "Alice reacted 👍 to #3 (Alice): 👍 2"); err != nil {
+		t.Fatalf("tally not updated: %v", err)
+	}
+	bob.sendMessage("/react 3 👍")
+	if err := bob.expectMessage(t, "you already reacted 👍 to #3"); err != nil {
+		t.Fatalf("duplicate reaction accepted: %v", err)
+	}
+	bob.sendMessage("/react 99 👍")
+	if err := bob.expectMessage(t, "message #99 not found in general"); err != nil {
+		t.Fatalf("unknown message accepted: %v", err)
+	}
+
+	// Reactions are kept with the history
+	bob.sendMessage("/history 5")
+	if err := bob.expectMessage(t, "Alice reacted 👍 to #3"); err != nil {
+		t.Fatalf("reaction missing from history: %v", err)
+	}
+}
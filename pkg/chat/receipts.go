@@ -0,0 +1,120 @@
+package chat
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Receipt states reported to the sender of a private message
+const (
+	receiptDelivered = "delivered"
+	receiptRead      = "read"
+)
+
+// maxUnreadPrivate bounds the private messages a client keeps waiting for
+// a read marker
+const maxUnreadPrivate = 100
+
+// wantsReceipts reports whether name asked for receipts with /receipts on
+func (s *Server) wantsReceipts(name string) bool {
+	account, ok := s.accounts.get(name)
+	return ok && account.Receipts
+}
+
+// sendReceipt tells the sender of msg, if online, that it reached state.
+// Callers must hold s.mutex.
+func (s *Server) sendReceipt(msg Message, state string) {
+	receipt := Message{
+		Type:      MessageTypeReceipt,
+		From:      msg.To,
+		To:        msg.From,
+		Content:   state,
+		Timestamp: time.Now(),
+		Ref:       msg.ID,
+	}
+	s.clients.each(func(_ net.Conn, c *Client) bool {
+		if c.name == msg.From {
+			c.sendMessage(receipt)
+		}
+		return true
+	})
+}
+
+// privateDelivered is called once msg has been written to its recipient c.
+// If the sender wants receipts it is told, and msg waits for c's read
+// marker. Callers must hold s.mutex.
+func (s *Server) privateDelivered(c *Client, msg Message) {
+	if msg.ID == 0 || !s.wantsReceipts(msg.From) {
+		return
+	}
+	s.sendReceipt(msg, receiptDelivered)
+	if len(c.unreadPrivate) >= maxUnreadPrivate {
+		c.unreadPrivate = c.unreadPrivate[1:]
+	}
+	c.unreadPrivate = append(c.unreadPrivate, msg)
+}
+
+// receiptsCommand turns receipts for the client's private messages on or off
+func receiptsCommand(s *Server, c *Client, args []string) error {
+	if len(args) == 0 {
+		mode := "off"
+		if s.wantsReceipts(c.name) {
+			mode = "on"
+		}
+		c.write([]byte(fmt.Sprintf("Delivery and read receipts: %s\n", mode)))
+		return nil
+	}
+	mode := strings.ToLower(args[0])
+	if mode != "on" && mode != "off" {
+		return fmt.Errorf("usage: /receipts [on|off]")
+	}
+	if !s.ownsAccount(c) {
+		return errNotOwner
+	}
+	err := s.accounts.update(c.name, func(a *Account) error {
+		a.Receipts = mode == "on"
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	c.write([]byte(fmt.Sprintf("Delivery and read receipts: %s\n", mode)))
+	return nil
+}
+
+// readCommand marks private messages as read, up to and including
+// message id or all of them, and sends the senders their read receipts
+func readCommand(s *Server, c *Client, args []string) error {
+	var id int64
+	if len(args) > 0 {
+		var err error
+		id, err = strconv.ParseInt(strings.TrimPrefix(args[0], "#"), 10, 64)
+		if err != nil || id <= 0 {
+			return fmt.Errorf("usage: /read [id]")
+		}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	n := len(c.unreadPrivate)
+	if id > 0 {
+		n = -1
+		for i, msg := range c.unreadPrivate {
+			if msg.ID == id {
+				n = i + 1
+			}
+		}
+		if n < 0 {
+			return fmt.Errorf("no unread private message #%d", id)
+		}
+	}
+	for _, msg := range c.unreadPrivate[:n] {
+		s.sendReceipt(msg, receiptRead)
+	}
+	c.unreadPrivate = c.unreadPrivate[n:]
+	c.write([]byte(fmt.Sprintf("Marked %d private messages as read\n", n)))
+	return nil
+}
@@ -0,0 +1,74 @@
+package chat
+
+import "testing"
+
+func TestReadRequest(t *testing.T) {
+	for ref, want := range map[int64]string{0: "/read", 7: "/read 7"} {
+		got, err := jsonRequest{Type: "read", Ref: ref}.text()
+		if err != nil || got != want {
+			t.Errorf("read request ref %d = %q, %v; want %q", ref, got, err, want)
+		}
+	}
+}
+
+func TestReceipts(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountsFile = ""
+	config.RoomsFile = ""
+	addr := setupTestServerWithConfig(t, config)
+	join := func(name string) *TestClient {
+		c, err := newTestClient(t, addr)
+		if err != nil {
+			t.Fatalf("Client connection failed: %v", err)
+		}
+		c.sendMessage(name)
+		if err := c.expectMessage(t, name+" joined"); err != nil {
+			t.Fatalf("Join as %s failed: %v", name, err)
+		}
+		return c
+	}
+	alice := join("Alice")
+	defer alice.close()
+	bob := join("Bob")
+	defer bob.close()
+
+	alice.sendMessage("/receipts on")
+	if err := alice.expectMessage(t, errNotOwner.Error()); err != nil {
+		t.Fatalf("receipts turned on for a guest: %v", err)
+	}
+	alice.sendMessage("/register secret1")
+	if err := alice.expectMessageWithin(t, "now registered", passwordTimeout); err != nil {
+		t.Fatalf("Registration failed: %v", err)
+	}
+	alice.sendMessage("/receipts on")
+	if err := alice.expectMessage(t, "Delivery and read receipts: on"); err != nil {
+		t.Fatalf("receipts not enabled: %v", err)
+	}
+	alice.sendMessage("/msg Bob lunch?")
+	if err := bob.expectMessage(t, "[PM #1 from Alice]: lunch?"); err != nil {
+		t.Fatalf("private message not delivered: %v", err)
+	}
+	if err := alice.expectMessage(t, "PM #1 to Bob: delivered"); err != nil {
+		t.Fatalf("delivery receipt missing: %v", err)
+	}
+	alice.sendMessage("/msg Bob 1pm?")
+	if err := alice.expectMessage(t, "PM #2 to Bob: delivered"); err != nil {
+		t.Fatalf("delivery receipt missing: %v", err)
+	}
+
+	bob.sendMessage("/read 1")
+	if err := bob.expectMessage(t, "Marked 1 private messages as read"); err != nil {
+		t.Fatalf("read marker not accepted: %v", err)
+	}
+	if err := alice.expectMessage(t, "PM #1 to Bob: read"); err != nil {
+		t.Fatalf("read receipt missing: %v", err)
+	}
+	bob.sendMessage("/read 1")
+	if err := bob.expectMessage(t, "no unread private message #1"); err != nil {
+		t.Fatalf("repeated read marker not rejected: %v", err)
+	}
+	bob.sendMessage("/read")
+	if err := alice.expectMessage(t, "PM #2 to Bob: read"); err != nil {
+		t.Fatalf("read receipt missing: %v", err)
+	}
+}
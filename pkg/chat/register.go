@@ -0,0 +1,166 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+// registered reports whether the account owns its nickname
+func (a Account) registered() bool {
+	return a.PasswordHash != "" || a.BotTokenHash != ""
+}
+
+// requireIdentify gives a client using a registered nickname
+// IdentifyTimeout to prove ownership before being renamed to a guest name
+func (s *Server) requireIdentify(c *Client) {
+	if s.config.AuthRequired || c.bot || c.identified {
+		return
+	}
+	account, ok := s.accounts.get(c.name)
+	if !ok || !account.registered() {
+		return
+	}
+
+	name := c.name
+	s.mutex.Lock()
+	if c.identifyTimer != nil {
+		c.identifyTimer.Stop()
+	}
+	c.identifyTimer = time.AfterFunc(s.config.IdentifyTimeout, func() {
+		c.run(func() { s.renameUnidentified(c, name) })
+	})
+	s.mutex.Unlock()
+
+	c.sendMessage(Message{
+		Type: MessageTypeSystem,
+		Content: fmt.Sprintf("The nickname %s is registered. Use /identify <password> within %s or you will be renamed.",
+			name, s.config.IdentifyTimeout),
+		Timestamp: time.Now(),
+	})
+}
+
+// renameUnidentified moves c to a guest name if it still holds name
+// without having identified. It runs on the goroutine serving c, like
+// every other change of its name.
+func (s *Server) renameUnidentified(c *Client, name string) {
+	s.mutex.Lock()
+	if c.identified || c.name != name {
+		s.mutex.Unlock()
+		return
+	}
+	guest := name
+	for s.isNameTaken(guest) {
+		guest = fmt.Sprintf("Guest%04d", rand.IntN(10000))
+	}
+	c.name = guest
+	c.identifyTimer = nil
+	s.mutex.Unlock()
+	if irc, ok := c.conn.(*ircConn); ok {
+		irc.renamed(guest)
+	}
+
+	s.logActivity("Renamed unidentified client", "client", name, "name", guest)
+	s.auditAs("nick-force", "server", name, "renamed to "+guest+": nickname not identified")
+	s.broadcast(Message{
+		Type:      MessageTypeSystem,
+		Content:   fmt.Sprintf("%s changed name to %s (nickname not identified)", name, guest),
+		Timestamp: time.Now(),
+	}, nil)
+}
+
+func registerCommand(s *Server, c *Client, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: /register <password>")
+	}
+	if s.config.AuthRequired || c.bot {
+		return fmt.Errorf("accounts on this server are managed by the operator")
+	}
+	password := strings.Join(args, " ")
+	if err := validatePassword(password); err != nil {
+		return err
+	}
+
+	err := s.accounts.update(c.name, func(a *Account) error {
+		if a.registered() {
+			return fmt.Errorf("%s is already registered; use /identify <password>", c.name)
+		}
+		a.PasswordHash = hashPassword(password)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.markIdentified(c)
+	s.logActivity("Nickname registered", "client", c.name)
+	c.write([]byte(fmt.Sprintf("The nickname %s is now registered to you\n", c.name)))
+	return nil
+}
+
+func identifyCommand(s *Server, c *Client, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: /identify <password>")
+	}
+	account, ok := s.accounts.get(c.name)
+	if !ok || account.PasswordHash == "" {
+		return fmt.Errorf("%s is not registered", c.name)
+	}
+	if !checkPassword(account.PasswordHash, strings.Join(args, " ")) {
+		s.log.Warn("Failed identify", clientAttrs(c)...)
+		return fmt.Errorf("wrong password")
+	}
+
+	s.markIdentified(c)
+	c.write([]byte(fmt.Sprintf("You are now identified as %s\n", c.name)))
+	s.deliverInbox(c)
+	return nil
+}
+
+// markIdentified records that c owns its nickname and grants the role the
+// configuration assigns to that name
+func (s *Server) markIdentified(c *Client) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	c.identified = true
+	s.cacheKeywords(c)
+	if c.identifyTimer != nil {
+		c.identifyTimer.Stop()
+		c.identifyTimer = nil
+	}
+	if role := s.rolesFor(c.name); role > c.role {
+		c.role = role
+	}
+}
+
+// errNotOwner refuses account settings to clients that have not proved
+// they own their nickname, so that nobody edits another user's account and
+// guests do not get one
+var errNotOwner = errors.New("register your nickname and /identify first")
+
+// holdsName reports whether c may use what was granted to its nickname,
+// such as room operator rights and invitations: an unregistered name is
+// anyone's, a registered one only its proven owner's. Caller holds
+// s.mutex.
+func (s *Server) holdsName(c *Client) bool {
+	if c.identified || c.bot || s.config.AuthRequired {
+		return true
+	}
+	account, ok := s.accounts.get(c.name)
+	return !ok || !account.registered()
+}
+
+// ownsAccount reports whether c has proved it owns the registered account
+// of its name, with /identify or by logging in with a password or token
+func (s *Server) ownsAccount(c *Client) bool {
+	s.mutex.RLock()
+	proved := c.identified || c.bot || s.config.AuthRequired
+	s.mutex.RUnlock()
+	if !proved {
+		return false
+	}
+	account, ok := s.accounts.get(c.name)
+	return ok && account.registered()
+}
@@ -0,0 +1,72 @@
+package chat
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNicknameRegistration(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountsFile = tempAccountsFile(t)
+	config.IdentifyTimeout = 300 * time.Millisecond
+	addr := setupTestServerWithConfig(t, config)
+
+	join := func(name string) *TestClient {
+		c, err := newTestClient(t, addr)
+		if err != nil {
+			t.Fatalf("Client connection failed: %v", err)
+		}
+		c.expectMessage(t, "Welcome")
+		c.sendMessage(name)
+		if err := c.expectMessage(t, name+" joined"); err != nil {
+			t.Fatalf("Join as %s failed: %v", name, err)
+		}
+		return c
+	}
+
+	owner := join("Alice")
+	owner.sendMessage("/register secret1")
+	if err := owner.expectMessage(t, "now registered"); err != nil {
+		t.Fatalf("Registration failed: %v", err)
+	}
+	owner.sendMessage("/register secret2")
+	if err := owner.expectMessage(t, "already registered"); err != nil {
+		t.Errorf("Nickname registered twice: %v", err)
+	}
+	owner.close()
+
+	t.Run("Impostor", func(t *testing.T) {
+		impostor := join("Alice")
+		defer impostor.close()
+		if err := impostor.expectMessage(t, "is registered"); err != nil {
+			t.Fatalf("No identify warning: %v", err)
+		}
+		impostor.sendMessage("/identify guess12")
+		if err := impostor.expectMessage(t, "wrong password"); err != nil {
+			t.Errorf("Wrong password accepted: %v", err)
+		}
+		if err := impostor.expectMessage(t, "Alice changed name to Guest"); err != nil {
+			t.Fatalf("Unidentified user kept the nickname: %v", err)
+		}
+		audit, _ := os.ReadFile(config.AuditFile)
+		if !strings.Contains(string(audit), `"action":"nick-force","actor":"server","target":"Alice"`) {
+			t.Errorf("forced rename not audited: %s", audit)
+		}
+	})
+
+	t.Run("Owner", func(t *testing.T) {
+		returning := join("Alice")
+		defer returning.close()
+		returning.sendMessage("/identify secret1")
+		if err := returning.expectMessage(t, "now identified as Alice"); err != nil {
+			t.Fatalf("Identify failed: %v", err)
+		}
+		time.Sleep(config.IdentifyTimeout)
+		returning.sendMessage("/who")
+		if err := returning.expectMessage(t, "changed name"); err == nil {
+			t.Error("identified user was renamed")
+		}
+	})
+}
@@ -0,0 +1,128 @@
+package chat
+
+import (
+	"net"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// The client registry is split into registryShards independently locked
+// maps
+const (
+	registryShardBits = 5
+	registryShards    = 1 << registryShardBits
+)
+
+// clientRegistry maps connections to their clients. It is split into
+// shards by connection so joins, leaves and lookups on different
+// connections do not wait on each other or on Server.mutex. Shard locks
+// are taken last: callers may hold Server.mutex or a room's mutex, but
+// must not add or remove clients from inside each.
+type clientRegistry struct {
+	shards [registryShards]registryShard
+	count  atomic.Int64
+}
+
+type registryShard struct {
+	mutex   sync.RWMutex
+	clients map[net.Conn]*Client
+}
+
+func newClientRegistry() *clientRegistry {
+	r := &clientRegistry{}
+	for i := range r.shards {
+		r.shards[i].clients = make(map[net.Conn]*Client)
+	}
+	return r
+}
+
+// shard picks the shard of conn from the address it points to, so the
+// same connection always lands in the same shard
+func (r *clientRegistry) shard(conn net.Conn) *registryShard {
+	v := reflect.ValueOf(conn)
+	if v.Kind() != reflect.Pointer {
+		return &r.shards[0]
+	}
+	// Fibonacci hashing spreads the aligned addresses over the shards
+	h := uint64(v.Pointer()) * 0x9E3779B97F4A7C15
+	return &r.shards[h>>(64-registryShardBits)]
+}
+
+// add registers c under conn, replacing any client already there
+func (r *clientRegistry) add(conn net.Conn, c *Client) {
+	shard := r.shard(conn)
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+	if _, exists := shard.clients[conn]; !exists {
+		r.count.Add(1)
+	}
+	shard.clients[conn] = c
+}
+
+// remove unregisters conn and reports whether it was registered
+func (r *clientRegistry) remove(conn net.Conn) bool {
+	shard := r.shard(conn)
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+	if _, exists := shard.clients[conn]; !exists {
+		return false
+	}
+	delete(shard.clients, conn)
+	r.count.Add(-1)
+	return true
+}
+
+// get returns the client of conn
+func (r *clientRegistry) get(conn net.Conn) (*Client, bool) {
+	shard := r.shard(conn)
+	shard.mutex.RLock()
+	defer shard.mutex.RUnlock()
+	c, ok := shard.clients[conn]
+	return c, ok
+}
+
+// len returns the number of registered clients
+func (r *clientRegistry) len() int {
+	return int(r.count.Load())
+}
+
+// each calls fn for every client, one shard at a time under that shard's
+// read lock, until fn returns false. Clients joining or leaving meanwhile
+// may or may not be visited.
+func (r *clientRegistry) each(fn func(conn net.Conn, c *Client) bool) {
+	for i := range r.shards {
+		shard := &r.shards[i]
+		shard.mutex.RLock()
+		for conn, c := range shard.clients {
+			if !fn(conn, c) {
+				shard.mutex.RUnlock()
+				return
+			}
+		}
+		shard.mutex.RUnlock()
+	}
+}
+
+// all returns a snapshot of every registered client
+func (r *clientRegistry) all() []*Client {
+	clients := make([]*Client, 0, r.len())
+	r.each(func(_ net.Conn, c *Client) bool {
+		clients = append(clients, c)
+		return true
+	})
+	return clients
+}
+
+// find returns the first client for which match returns true
+func (r *clientRegistry) find(match func(c *Client) bool) *Client {
+	var found *Client
+	r.each(func(_ net.Conn, c *Client) bool {
+		if match(c) {
+			found = c
+			return false
+		}
+		return true
+	})
+	return found
+}
@@ -0,0 +1,70 @@
+package chat
+
+import (
+	"net"
+	"sync"
+	"testing"
+)
+
+func TestClientRegistry(t *testing.T) {
+	r := newClientRegistry()
+	conns := make([]net.Conn, 200)
+	for i := range conns {
+		server, client := net.Pipe()
+		defer server.Close()
+		defer client.Close()
+		conns[i] = server
+	}
+
+	// Concurrent joins and leaves on different connections
+	var wg sync.WaitGroup
+	for i, conn := range conns {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.add(conn, &Client{conn: conn, name: string(rune('A' + i%26))})
+			if i%2 == 1 && !r.remove(conn) {
+				t.Errorf("client %d was not registered", i)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if r.len() != len(conns)/2 {
+		t.Errorf("len = %d, want %d", r.len(), len(conns)/2)
+	}
+	if len(r.all()) != r.len() {
+		t.Errorf("all returned %d clients, len is %d", len(r.all()), r.len())
+	}
+	if _, ok := r.get(conns[0]); !ok {
+		t.Error("get missed a registered client")
+	}
+	if _, ok := r.get(conns[1]); ok {
+		t.Error("get found a removed client")
+	}
+	if r.remove(conns[1]) {
+		t.Error("removed a client twice")
+	}
+
+	used := 0
+	for i := range r.shards {
+		if len(r.shards[i].clients) > 0 {
+			used++
+		}
+	}
+	if used < registryShards/2 {
+		t.Errorf("clients landed in only %d of %d shards", used, registryShards)
+	}
+
+	visited := 0
+	r.each(func(net.Conn, *Client) bool {
+		visited++
+		return visited < 3
+	})
+	if visited != 3 {
+		t.Errorf("each visited %d clients after being stopped at 3", visited)
+	}
+	if c := r.find(func(c *Client) bool { return c.conn == conns[4] }); c == nil {
+		t.Error("find missed a registered client")
+	}
+}
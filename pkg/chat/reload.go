@@ -0,0 +1,66 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// reload re-reads the server's files: the config file, the ban list, the
+// accounts, the saved room settings and the message of the day. Connected clients stay;
+// a room missing from the file is kept. It returns a summary of what was
+// loaded and every error met on the way.
+func (s *Server) reload() (string, error) {
+	var errs []error
+	if s.config.ConfigFile != "" {
+		if err := s.reloadConfigFile(); err != nil {
+			errs = append(errs, fmt.Errorf("config file: %v", err))
+		}
+	}
+	bans, err := s.bans.reload()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("ban list: %v", err))
+	}
+	accounts, err := s.accounts.reload()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("accounts: %v", err))
+	}
+
+	s.mutex.Lock()
+	// Keywords come from the accounts just read
+	for _, c := range s.clients.all() {
+		if c.identified || c.bot || s.config.AuthRequired {
+			s.cacheKeywords(c)
+		}
+	}
+	for _, name := range s.config.DefaultRooms {
+		if _, exists := s.rooms[name]; !exists {
+			s.rooms[name] = newChatRoom(name)
+		}
+	}
+	if err := s.restoreRooms(); err != nil {
+		errs = append(errs, fmt.Errorf("rooms: %v", err))
+	}
+	rooms := len(s.rooms)
+	s.mutex.Unlock()
+
+	s.motd.reset()
+	motd := "no MOTD"
+	if s.motd.get() != "" {
+		motd = "MOTD"
+	}
+	return fmt.Sprintf("%d bans, %d accounts, %d rooms, %s", bans, accounts, rooms, motd), errors.Join(errs...)
+}
+
+// reloadCommand re-reads the server's files without disconnecting anyone
+func reloadCommand(s *Server, c *Client, args []string) error {
+	summary, err := s.reload()
+	s.logActivity("Configuration reloaded", "client", c.name, "summary", summary)
+	s.audit("reload", c, "", "")
+	if err != nil {
+		return fmt.Errorf("reloaded with errors (%s): %s", summary,
+			strings.ReplaceAll(err.Error(), "\n", "; "))
+	}
+	c.write([]byte(fmt.Sprintf("Reloaded: %s\n", summary)))
+	return nil
+}
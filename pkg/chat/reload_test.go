@@ -0,0 +1,177 @@
+package chat
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	config := DefaultConfig()
+	config.AccountsFile = filepath.Join(dir, "accounts.json")
+	config.RoomsFile = filepath.Join(dir, "rooms.json")
+	config.BanFile = filepath.Join(dir, "bans.txt")
+	config.MOTDFile = filepath.Join(dir, "motd.txt")
+	s := NewServerWithConfig(config)
+	defer s.Logfile.Close()
+
+	admin := newPipeClient(t, "Alice")
+	admin.role = RoleAdmin
+	if err := s.createRoom(admin, "dev", "", ""); err != nil {
+		t.Fatalf("createRoom failed: %v", err)
+	}
+
+	// Edit the files behind the server's back
+	os.WriteFile(config.BanFile, []byte("203.0.113.9\n"), 0o644)
+	os.WriteFile(config.MOTDFile, []byte("Maintenance at noon"), 0o644)
+	rooms, _ := json.Marshal([]RoomInfo{
+		{Name: "dev", Creator: "Alice", Topic: "release week", Ops: []string{"Alice"}},
+		{Name: "ops", Creator: "Bob"},
+	})
+	os.WriteFile(config.RoomsFile, rooms, 0o644)
+	accounts, _ := json.Marshal([]*Account{{Name: "Carol", PasswordHash: hashPassword("secret")}})
+	os.WriteFile(config.AccountsFile, accounts, 0o644)
+
+	if err := reloadCommand(s, admin, nil); err != nil {
+		t.Fatalf("/reload failed: %v", err)
+	}
+	if !s.bans.contains("203.0.113.9") {
+		t.Error("ban list not reloaded")
+	}
+	if s.motd.get() != "Maintenance at noon" {
+		t.Errorf("MOTD is %q after reload", s.motd.get())
+	}
+	if s.rooms["dev"].topic != "release week" {
+		t.Errorf("dev topic is %q after reload", s.rooms["dev"].topic)
+	}
+	if _, exists := s.rooms["ops"]; !exists {
+		t.Error("room from the file not created")
+	}
+	if _, ok := s.accounts.get("carol"); !ok {
+		t.Error("accounts not reloaded")
+	}
+
+	// A broken file is reported, and the old bans stay
+	os.WriteFile(config.RoomsFile, []byte("{not json"), 0o644)
+	if err := reloadCommand(s, admin, nil); err == nil {
+		t.Error("/reload ignored a broken rooms file")
+	}
+	if !s.bans.contains("203.0.113.9") {
+		t.Error("bans lost on a failed reload")
+	}
+}
+
+func TestReloadOnSIGHUP(t *testing.T) {
+	dir := t.TempDir()
+	config := DefaultConfig()
+	config.AccountsFile = ""
+	config.RoomsFile = ""
+	config.Store = StoreMemory
+	config.BanFile = filepath.Join(dir, "bans.txt")
+	s := New(WithConfig(config), WithSignalHandling())
+	defer s.Logfile.Close()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	go s.Serve(l)
+	defer s.Shutdown()
+	time.Sleep(serverStartDelay)
+
+	os.WriteFile(config.BanFile, []byte("198.51.100.7\n"), 0o644)
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatalf("kill failed: %v", err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for !s.bans.contains("198.51.100.7") {
+		if time.Now().After(deadline) {
+			t.Fatal("SIGHUP did not reload the ban list")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestReloadConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	config := DefaultConfig()
+	config.AccountsFile = ""
+	config.RoomsFile = ""
+	config.Store = StoreMemory
+	config.ConfigFile = filepath.Join(dir, "tcpchat.toml")
+	os.WriteFile(config.ConfigFile, []byte(`default_rooms = ["dev"]`), 0o600)
+	if _, err := LoadConfigFile(config.ConfigFile, config); err != nil {
+		t.Fatalf("LoadConfigFile failed: %v", err)
+	}
+	s := NewServerWithConfig(config)
+	defer s.Logfile.Close()
+
+	os.WriteFile(config.ConfigFile, []byte(`default_rooms = ["dev", "ops"]
+persistent_rooms = ["archive"]
+max_clients = 20
+max_conns_per_ip = 2
+
+[limits]
+flood_burst = 5
+flood_window = "1s"
+flood_warnings = 3
+`), 0o600)
+	if _, err := s.reload(); err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	if s.maxClients != 20 || s.config.MaxConnsPerIP != 2 {
+		t.Errorf("client limits are %d and %d per IP after reload, want 20 and 2",
+			s.maxClients, s.config.MaxConnsPerIP)
+	}
+	if s.config.FloodBurst != 5 || s.config.FloodWindow != time.Second || s.config.FloodWarnings != 3 {
+		t.Errorf("flood limits not reloaded: %d lines per %s, %d warnings",
+			s.config.FloodBurst, s.config.FloodWindow, s.config.FloodWarnings)
+	}
+
+	// /setlimit survives a reload that leaves max_clients alone
+	admin := newPipeClient(t, "Alice")
+	admin.role = RoleAdmin
+	if err := setLimitCommand(s, admin, []string{"30"}); err != nil {
+		t.Fatalf("/setlimit failed: %v", err)
+	}
+	if _, err := s.reload(); err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	if s.maxClients != 30 {
+		t.Errorf("client limit is %d after reload, want the /setlimit value 30", s.maxClients)
+	}
+	if _, exists := s.rooms["ops"]; !exists {
+		t.Error("default room added to the config file not created")
+	}
+	if !s.isPersistentRoom("archive") {
+		t.Error("persistent rooms not reloaded")
+	}
+
+	// A broken file or one with unusable limits is reported and the
+	// running settings stay
+	for _, doc := range []string{`default_rooms = 7`, `max_clients = 0`} {
+		os.WriteFile(config.ConfigFile, []byte(doc), 0o600)
+		if _, err := s.reload(); err == nil || !strings.Contains(err.Error(), "config file") {
+			t.Errorf("reload of %q returned %v", doc, err)
+		}
+		if !s.isPersistentRoom("archive") || s.maxClients != 30 {
+			t.Errorf("settings lost on a failed reload of %q", doc)
+		}
+	}
+
+	// Flags keep overriding the file
+	s.config.Overrides = func(c *Config) { c.FloodBurst = 8 }
+	os.WriteFile(config.ConfigFile, []byte("[limits]\nflood_burst = 5\nflood_warnings = 4\n"), 0o600)
+	if _, err := s.reload(); err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	if s.config.FloodBurst != 8 || s.config.FloodWarnings != 4 {
+		t.Errorf("flood limits are %d lines and %d warnings after reload, want the flag's 8 and the file's 4",
+			s.config.FloodBurst, s.config.FloodWarnings)
+	}
+}
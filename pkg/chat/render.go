@@ -0,0 +1,126 @@
+package chat
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Messages may use markdown-lite markers: *bold*, _italic_ and `code`.
+// Text clients get the markers exactly as typed; views that can show
+// attributes parse the content into spans and render those instead.
+
+// textStyle is a set of attributes applied to a span of text
+type textStyle uint8
+
+const (
+	styleBold textStyle = 1 << iota
+	styleItalic
+	styleCode
+)
+
+var markupMarkers = map[rune]textStyle{
+	'*': styleBold,
+	'_': styleItalic,
+	'`': styleCode,
+}
+
+// textSpan is a run of text with a single style
+type textSpan struct {
+	Text  string
+	Style textStyle
+}
+
+// parseMarkup splits text into styled spans. A marker opens at the start
+// of a word and closes at the end of one, so snake_case and 2*3*4 stay
+// literal, as does any marker without a partner. Code spans are not
+// parsed further.
+func parseMarkup(text string) []textSpan {
+	return appendMarkup(nil, []rune(text), 0)
+}
+
+func appendMarkup(spans []textSpan, text []rune, style textStyle) []textSpan {
+	start := 0
+	for i := 0; i < len(text); i++ {
+		marker, ok := markupMarkers[text[i]]
+		if !ok || style&marker != 0 || !opensMarkup(text, i) {
+			continue
+		}
+		j := closingMarker(text, i)
+		if j < 0 {
+			continue
+		}
+		spans = appendSpan(spans, string(text[start:i]), style)
+		if marker == styleCode {
+			spans = appendSpan(spans, string(text[i+1:j]), style|styleCode)
+		} else {
+			spans = appendMarkup(spans, text[i+1:j], style|marker)
+		}
+		start, i = j+1, j
+	}
+	return appendSpan(spans, string(text[start:]), style)
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// opensMarkup reports whether the marker at i can start a span
+func opensMarkup(text []rune, i int) bool {
+	return (i == 0 || !isWordRune(text[i-1])) && i+1 < len(text) && !unicode.IsSpace(text[i+1])
+}
+
+// closingMarker returns the index of the marker closing the one at i, or -1
+func closingMarker(text []rune, i int) int {
+	for j := i + 2; j < len(text); j++ {
+		if text[j] == text[i] && !unicode.IsSpace(text[j-1]) && (j+1 == len(text) || !isWordRune(text[j+1])) {
+			return j
+		}
+	}
+	return -1
+}
+
+// appendSpan adds text to spans, merging it with the last span of the same style
+func appendSpan(spans []textSpan, text string, style textStyle) []textSpan {
+	if text == "" {
+		return spans
+	}
+	if n := len(spans); n > 0 && spans[n-1].Style == style {
+		spans[n-1].Text += text
+		return spans
+	}
+	return append(spans, textSpan{Text: text, Style: style})
+}
+
+// renderANSI turns spans into text with SGR attributes. Italic is shown
+// underlined and code in cyan, which every terminal and gocui can display.
+func renderANSI(spans []textSpan) string {
+	var b strings.Builder
+	for _, span := range spans {
+		if span.Style == 0 {
+			b.WriteString(span.Text)
+			continue
+		}
+		var codes []string
+		if span.Style&styleBold != 0 {
+			codes = append(codes, "1")
+		}
+		if span.Style&styleItalic != 0 {
+			codes = append(codes, "4")
+		}
+		if span.Style&styleCode != 0 {
+			codes = append(codes, "36")
+		}
+		b.WriteString(paint("\x1b["+strings.Join(codes, ";")+"m", span.Text))
+	}
+	return b.String()
+}
+
+// renderMessage is formatMessage with the markup of user-written content
+// rendered as ANSI attributes
+func renderMessage(msg Message) string {
+	switch msg.Type {
+	case MessageTypeChat, MessageTypePrivate, MessageTypeMention, MessageTypeKeyword:
+		msg.Content = renderANSI(parseMarkup(msg.Content))
+	}
+	return formatMessage(msg)
+}
@@ -0,0 +1,41 @@
+package chat
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseMarkup(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []textSpan
+	}{
+		{"plain text", []textSpan{{"plain text", 0}}},
+		{"a *bold* move", []textSpan{{"a ", 0}, {"bold", styleBold}, {" move", 0}}},
+		{"_so_ `go vet`!", []textSpan{{"so", styleItalic}, {" ", 0}, {"go vet", styleCode}, {"!", 0}}},
+		{"*very _nested_*", []textSpan{{"very ", styleBold}, {"nested", styleBold | styleItalic}}},
+		{"`*not bold*`", []textSpan{{"*not bold*", styleCode}}},
+		{"snake_case_name", []textSpan{{"snake_case_name", 0}}},
+		{"2*3*4 and * lone", []textSpan{{"2*3*4 and * lone", 0}}},
+		{"* spaced *", []textSpan{{"* spaced *", 0}}},
+		{"**", []textSpan{{"**", 0}}},
+	}
+	for _, tt := range tests {
+		if got := parseMarkup(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseMarkup(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRenderMessage(t *testing.T) {
+	msg := Message{Type: MessageTypeChat, From: "Alice", Content: "*ship* it", Timestamp: time.Now()}
+	want := "[" + msg.Timestamp.Format("2006-01-02 15:04:05") + "][Alice]: \x1b[1mship\x1b[0m it"
+	if got := renderMessage(msg); got != want {
+		t.Errorf("renderMessage = %q, want %q", got, want)
+	}
+	// Text clients keep the markers
+	if got := formatMessage(msg); got != "["+msg.Timestamp.Format("2006-01-02 15:04:05")+"][Alice]: *ship* it" {
+		t.Errorf("formatMessage = %q", got)
+	}
+}
@@ -0,0 +1,103 @@
+package chat
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// resumePrefix starts the line a reconnecting client sends instead of a
+// name to be replayed only what it missed: RESUME <room> <id>
+const resumePrefix = "RESUME "
+
+// resumeReplayLimit caps how many missed messages are replayed per room
+const resumeReplayLimit = 500
+
+// messageSeeker is implemented by stores that can look messages up by ID
+// instead of reading a room's whole history
+type messageSeeker interface {
+	// MessagesSince returns up to limit of the newest messages of room
+	// with an ID above after, oldest first
+	MessagesSince(room string, after int64, limit int) ([]Message, error)
+}
+
+// messagesSince returns up to limit of the newest messages of room after
+// the given ID, oldest first
+func messagesSince(store Store, room string, after int64, limit int) ([]Message, error) {
+	if seeker, ok := store.(messageSeeker); ok {
+		return seeker.MessagesSince(room, after, limit)
+	}
+	history, err := store.RecentMessages(room, 0)
+	if err != nil {
+		return nil, err
+	}
+	return lastMessages(filterSince(history, after), limit), nil
+}
+
+// filterSince keeps the messages with an ID above after
+func filterSince(messages []Message, after int64) []Message {
+	var kept []Message
+	for _, msg := range messages {
+		if msg.ID > after {
+			kept = append(kept, msg)
+		}
+	}
+	return kept
+}
+
+func (m *memoryMessages) MessagesSince(room string, after int64, limit int) ([]Message, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	ring, ok := m.rooms[room]
+	if !ok {
+		return nil, nil
+	}
+	return lastMessages(filterSince(ring.last(0), after), limit), nil
+}
+
+// parseResume reads a RESUME line into its room and message ID
+func parseResume(line string) (string, int64, error) {
+	fields := strings.Fields(strings.TrimPrefix(line, resumePrefix))
+	if len(fields) != 2 {
+		return "", 0, fmt.Errorf("usage: RESUME <room> <last message id>")
+	}
+	id, err := strconv.ParseInt(strings.TrimPrefix(fields[1], "#"), 10, 64)
+	if err != nil || id < 0 {
+		return "", 0, fmt.Errorf("usage: RESUME <room> <last message id>")
+	}
+	return fields[0], id, nil
+}
+
+// replayHistory sends c the history of room as it joins: what it missed
+// since its resume point for the room, or else the newest HistoryReplay
+// messages. Caller holds s.mutex.
+func (s *Server) replayHistory(c *Client, room *ChatRoom) {
+	after, resuming := c.resume[room.name]
+	if !resuming {
+		history, err := s.store.RecentMessages(room.name, s.config.HistoryReplay)
+		if err != nil {
+			s.log.Error("Error loading history", "room", room.name, "err", err)
+		}
+		for _, msg := range history {
+			c.sendMessage(msg)
+		}
+		return
+	}
+
+	// A resume point is used once; later joins replay as usual
+	delete(c.resume, room.name)
+	missed, err := messagesSince(s.store, room.name, after, resumeReplayLimit)
+	if err != nil {
+		s.log.Error("Error loading history", "room", room.name, "err", err)
+		return
+	}
+	notice := fmt.Sprintf("Resuming %s after #%d: %d new messages", room.name, after, len(missed))
+	if len(missed) > 0 && missed[0].ID > after+1 {
+		notice += fmt.Sprintf(" (%d older ones are not replayed; see /history)", missed[0].ID-after-1)
+	}
+	c.sendMessage(Message{Type: MessageTypeSystem, Content: notice, Timestamp: time.Now()})
+	for _, msg := range missed {
+		c.sendMessage(msg)
+	}
+}
@@ -0,0 +1,120 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseResume(t *testing.T) {
+	tests := []struct {
+		line string
+		room string
+		id   int64
+		ok   bool
+	}{
+		{"RESUME general 41\n", "general", 41, true},
+		{"RESUME dev #7", "dev", 7, true},
+		{"RESUME general", "", 0, false},
+		{"RESUME general -1", "", 0, false},
+		{"RESUME general many", "", 0, false},
+	}
+	for _, tt := range tests {
+		room, id, err := parseResume(tt.line)
+		if room != tt.room || id != tt.id || (err == nil) != tt.ok {
+			t.Errorf("parseResume(%q) = %q, %d, %v", tt.line, room, id, err)
+		}
+	}
+}
+
+func TestMessagesSince(t *testing.T) {
+	store := newMemoryStore(3, nil)
+	for id := int64(1); id <= 5; id++ {
+		store.AppendMessage("general", Message{ID: id, Content: fmt.Sprint(id)})
+	}
+	// The ring only keeps #3 to #5
+	missed, _ := messagesSince(store, "general", 1, 10)
+	if len(missed) != 3 || missed[0].ID != 3 {
+		t.Errorf("since #1 = %v, want #3 to #5", missed)
+	}
+	missed, _ = messagesSince(store, "general", 3, 1)
+	if len(missed) != 1 || missed[0].ID != 5 {
+		t.Errorf("since #3 limited to 1 = %v, want #5", missed)
+	}
+	// Stores without MessagesSince fall back to filtering the history
+	fallback := struct{ Store }{store}
+	missed, _ = messagesSince(fallback, "general", 4, 10)
+	if len(missed) != 1 || missed[0].ID != 5 {
+		t.Errorf("fallback since #4 = %v, want #5", missed)
+	}
+}
+
+func TestResume(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountsFile = ""
+	config.RoomsFile = ""
+	config.Store = StoreMemory
+	s := NewServerWithConfig(config)
+	defer s.Logfile.Close()
+	l := newPipeListener()
+	go s.Serve(l)
+	defer s.Shutdown()
+
+	alice := l.dial()
+	defer alice.close()
+	alice.expectMessage(t, "Welcome")
+	alice.sendMessage("Alice")
+	for _, text := range []string{"first", "second", "third", "fourth"} {
+		alice.sendMessage(text)
+		if err := alice.expectMessage(t, text); err != nil {
+			t.Fatalf("%s not echoed: %v", text, err)
+		}
+	}
+	var second int64
+	history, _ := s.store.RecentMessages("general", 0)
+	for _, msg := range history {
+		if msg.Content == "second" {
+			second = msg.ID
+		}
+	}
+	if second == 0 {
+		t.Fatal("message not numbered")
+	}
+
+	bob := l.dial()
+	defer bob.close()
+	bob.expectMessage(t, "Welcome")
+	bob.sendMessage(fmt.Sprintf("RESUME general %d", second))
+	if err := bob.expectMessage(t, fmt.Sprintf("Resuming general after #%d", second)); err != nil {
+		t.Fatalf("RESUME not acknowledged: %v", err)
+	}
+	bob.sendMessage("Bob")
+
+	var replayed []string
+	bob.conn.SetReadDeadline(time.Now().Add(messageTimeout))
+	for {
+		line, err := bob.reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("no join after replay: %v (got %q)", err, replayed)
+		}
+		if strings.Contains(line, "Bob joined the room") {
+			break
+		}
+		replayed = append(replayed, line)
+	}
+	got := strings.Join(replayed, "")
+	if !strings.Contains(got, "Resuming general after") || !strings.Contains(got, "2 new messages") {
+		t.Errorf("missing resume notice in %q", got)
+	}
+	for _, text := range []string{"first", "second"} {
+		if strings.Contains(got, "]: "+text) {
+			t.Errorf("%q was replayed again", text)
+		}
+	}
+	for _, text := range []string{"third", "fourth"} {
+		if !strings.Contains(got, "]: "+text) {
+			t.Errorf("missed %q not replayed", text)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetentionRule bounds how much history a room keeps in the store. Zero
+// fields are not enforced.
+type RetentionRule struct {
+	MaxAge      time.Duration
+	MaxMessages int
+}
+
+func (r RetentionRule) enabled() bool {
+	return r.MaxAge > 0 || r.MaxMessages > 0
+}
+
+// ParseRetentionRule parses "<age>/<messages>", e.g. "30d/10000", "12h/0"
+// or "30d". Ages accept a d suffix for days.
+func ParseRetentionRule(spec string) (RetentionRule, error) {
+	var rule RetentionRule
+	age, count, hasCount := strings.Cut(spec, "/")
+	if age != "" && age != "0" {
+		if days, ok := strings.CutSuffix(age, "d"); ok {
+			n, err := strconv.Atoi(days)
+			if err != nil || n < 0 {
+				return rule, fmt.Errorf("invalid retention age %q", age)
+			}
+			rule.MaxAge = time.Duration(n) * 24 * time.Hour
+		} else {
+			d, err := time.ParseDuration(age)
+			if err != nil || d < 0 {
+				return rule, fmt.Errorf("invalid retention age %q", age)
+			}
+			rule.MaxAge = d
+		}
+	}
+	if hasCount {
+		n, err := strconv.Atoi(count)
+		if err != nil || n < 0 {
+			return rule, fmt.Errorf("invalid retention count %q", count)
+		}
+		rule.MaxMessages = n
+	}
+	return rule, nil
+}
+
+// pruneMessages applies a retention cut-off and count to messages, which
+// are oldest first. A zero before or keep skips that check.
+func pruneMessages(messages []Message, before time.Time, keep int) []Message {
+	start := 0
+	if !before.IsZero() {
+		for start < len(messages) && messages[start].Timestamp.Before(before) {
+			start++
+		}
+	}
+	if keep > 0 && len(messages)-start > keep {
+		start = len(messages) - keep
+	}
+	return messages[start:]
+}
+
+// retentionRule returns the rule that applies to room
+func (s *Server) retentionRule(room string) RetentionRule {
+	if rule, ok := s.config.RoomRetention[room]; ok {
+		return rule
+	}
+	return s.config.Retention
+}
+
+// retentionEnabled reports whether any retention rule is configured
+func (s *Server) retentionEnabled() bool {
+	if s.config.Retention.enabled() {
+		return true
+	}
+	for _, rule := range s.config.RoomRetention {
+		if rule.enabled() {
+			return true
+		}
+	}
+	return false
+}
+
+// retentionLoop prunes stored history on RetentionInterval
+func (s *Server) retentionLoop(ctx context.Context) {
+	ticker := time.NewTicker(s.config.RetentionInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.applyRetention(time.Now())
+		}
+	}
+}
+
+// applyRetention prunes every room once and returns how many messages
+// were removed
+func (s *Server) applyRetention(now time.Time) int {
+	s.mutex.Lock()
+	rooms := []string{""} // Server-wide notices
+	for name := range s.rooms {
+		rooms = append(rooms, name)
+	}
+	s.mutex.Unlock()
+
+	total := 0
+	for _, room := range rooms {
+		rule := s.retentionRule(room)
+		if !rule.enabled() {
+			continue
+		}
+		var before time.Time
+		if rule.MaxAge > 0 {
+			before = now.Add(-rule.MaxAge)
+		}
+		removed, err := s.store.PruneMessages(room, before, rule.MaxMessages)
+		if err != nil {
+			s.log.Error("Error pruning history", "room", room, "err", err)
+			continue
+		}
+		total += removed
+	}
+	if total > 0 {
+		s.logActivity("Retention removed messages", "messages", total)
+	}
+	return total
+}
@@ -0,0 +1,87 @@
+package chat
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseRetentionRule(t *testing.T) {
+	tests := []struct {
+		spec string
+		want RetentionRule
+		ok   bool
+	}{
+		{"30d", RetentionRule{MaxAge: 30 * 24 * time.Hour}, true},
+		{"12h/500", RetentionRule{MaxAge: 12 * time.Hour, MaxMessages: 500}, true},
+		{"0/1000", RetentionRule{MaxMessages: 1000}, true},
+		{"/1000", RetentionRule{MaxMessages: 1000}, true},
+		{"soon", RetentionRule{}, false},
+		{"1d/many", RetentionRule{}, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseRetentionRule(tt.spec)
+		if (err == nil) != tt.ok || (tt.ok && got != tt.want) {
+			t.Errorf("ParseRetentionRule(%q) = %+v, %v", tt.spec, got, err)
+		}
+	}
+}
+
+// testStorePruning checks PruneMessages drops old messages first and
+// leaves other rooms alone
+func testStorePruning(t *testing.T, store Store) {
+	now := time.Now()
+	for i, age := range []time.Duration{72 * time.Hour, 48 * time.Hour, 2 * time.Hour, time.Hour, 0} {
+		store.AppendMessage("general", Message{Content: string(rune('a' + i)), Timestamp: now.Add(-age)})
+	}
+	store.AppendMessage("random", Message{Content: "old", Timestamp: now.Add(-72 * time.Hour)})
+
+	removed, err := store.PruneMessages("general", now.Add(-24*time.Hour), 0)
+	if err != nil || removed != 2 {
+		t.Fatalf("prune by age removed %d, %v; want 2", removed, err)
+	}
+	removed, err = store.PruneMessages("general", time.Time{}, 2)
+	if err != nil || removed != 1 {
+		t.Fatalf("prune by count removed %d, %v; want 1", removed, err)
+	}
+	recent, _ := store.RecentMessages("general", 0)
+	if len(recent) != 2 || recent[0].Content != "d" || recent[1].Content != "e" {
+		t.Errorf("after pruning general = %+v, want d, e", recent)
+	}
+	if recent, _ := store.RecentMessages("random", 0); len(recent) != 1 {
+		t.Errorf("pruning general touched random: %+v", recent)
+	}
+}
+
+func TestMemoryStorePruning(t *testing.T) {
+	testStorePruning(t, NewMemoryStore())
+}
+
+func TestFileStorePruning(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	testStorePruning(t, newFileStore("", "", path))
+
+	reopened := newFileStore("", "", path)
+	if recent, _ := reopened.RecentMessages("general", 0); len(recent) != 2 {
+		t.Errorf("pruned history not persisted: got %d messages, want 2", len(recent))
+	}
+}
+
+func TestApplyRetention(t *testing.T) {
+	s, _ := newEmailTestServer(t)
+	s.config.Retention = RetentionRule{MaxMessages: 1}
+	s.config.RoomRetention = map[string]RetentionRule{"general": {MaxMessages: 3}}
+	s.rooms["random"] = &ChatRoom{name: "random", clients: make(map[net.Conn]*Client)}
+	for i := 0; i < 5; i++ {
+		s.store.AppendMessage("general", Message{Content: "hi", Timestamp: time.Now()})
+		s.store.AppendMessage("random", Message{Content: "hi", Timestamp: time.Now()})
+	}
+
+	if removed := s.applyRetention(time.Now()); removed != 6 {
+		t.Errorf("applyRetention removed %d, want 6", removed)
+	}
+	if recent, _ := s.store.RecentMessages("general", 0); len(recent) != 3 {
+		t.Errorf("general kept %d messages, want 3", len(recent))
+	}
+}
@@ -0,0 +1,28 @@
+package chat
+
+// messageRing holds the newest messages of a room, overwriting the oldest
+// once limit is reached. A limit of zero or less never drops messages.
+type messageRing struct {
+	limit int
+	buf   []Message
+	next  int // Slot the next message overwrites once buf is full
+}
+
+func newMessageRing(limit int) *messageRing {
+	return &messageRing{limit: limit}
+}
+
+func (r *messageRing) push(msg Message) {
+	if r.limit <= 0 || len(r.buf) < r.limit {
+		r.buf = append(r.buf, msg)
+		return
+	}
+	r.buf[r.next] = msg
+	r.next = (r.next + 1) % r.limit
+}
+
+// last copies up to n of the newest messages, oldest first
+func (r *messageRing) last(n int) []Message {
+	ordered := append(append([]Message(nil), r.buf[r.next:]...), r.buf[:r.next]...)
+	return lastMessages(ordered, n)
+}
@@ -0,0 +1,148 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Role ranks what a client is allowed to do; higher roles include the
+// permissions of lower ones
+type Role int
+
+const (
+	RoleUser Role = iota
+	RoleModerator
+	RoleAdmin
+	RoleOwner
+)
+
+var roleNames = map[Role]string{
+	RoleUser:      "user",
+	RoleModerator: "moderator",
+	RoleAdmin:     "admin",
+	RoleOwner:     "owner",
+}
+
+func (r Role) String() string {
+	return roleNames[r]
+}
+
+// ParseRole converts a role name such as "admin" into a Role
+func ParseRole(name string) (Role, error) {
+	for role, n := range roleNames {
+		if strings.EqualFold(n, name) {
+			return role, nil
+		}
+	}
+	return RoleUser, fmt.Errorf("unknown role %q (owner, admin, moderator, user)", name)
+}
+
+// commandRoles lists the minimum role needed for restricted commands
+var commandRoles = map[string]Role{
+	"kick":      RoleModerator,
+	"mute":      RoleModerator,
+	"unmute":    RoleModerator,
+	"ban":       RoleModerator,
+	"unban":     RoleModerator,
+	"promote":   RoleModerator,
+	"demote":    RoleModerator,
+	"shutdown":  RoleAdmin,
+	"setlimit":  RoleAdmin,
+	"reload":    RoleAdmin,
+	"auditlog":  RoleAdmin,
+	"export":    RoleAdmin,
+	"searchall": RoleAdmin,
+	"bottoken":  RoleAdmin,
+	"unalias":   RoleAdmin,
+	"delete":    RoleAdmin,
+	"archive":   RoleAdmin,
+	"unarchive": RoleAdmin,
+}
+
+// configuredRole returns the role granted to an account name by the config.
+// Names are only trusted in auth mode; elsewhere roles are granted on
+// /identify.
+func (s *Server) configuredRole(name string) Role {
+	if !s.config.AuthRequired {
+		return RoleUser
+	}
+	return s.rolesFor(name)
+}
+
+// rolesFor looks up the configured role of name without checking that the
+// client owns it
+func (s *Server) rolesFor(name string) Role {
+	for n, role := range s.config.Roles {
+		if strings.EqualFold(n, name) {
+			return role
+		}
+	}
+	return RoleUser
+}
+
+func (s *Server) findClient(name string) *Client {
+	return s.clients.find(func(c *Client) bool { return strings.EqualFold(c.name, name) })
+}
+
+// setRole changes target's role on behalf of actor, who must outrank both
+// the target's current and new role
+func (s *Server) setRole(actor *Client, targetName string, role Role) error {
+	s.mutex.Lock()
+	target := s.findClient(targetName)
+	if target == nil {
+		s.mutex.Unlock()
+		return fmt.Errorf("user %s not found", targetName)
+	}
+	if target.role >= actor.role || role >= actor.role {
+		s.mutex.Unlock()
+		return fmt.Errorf("you can only manage roles below your own (%s)", actor.role)
+	}
+	old := target.role
+	target.role = role
+	s.mutex.Unlock()
+
+	s.logActivity("Role changed", "client", actor.name, "target", target.name, "old", old, "new", role)
+	action := "promote"
+	if role < old {
+		action = "demote"
+	}
+	s.audit(action, actor, target.name, fmt.Sprintf("%s -> %s", old, role))
+	s.broadcast(Message{
+		Type:      MessageTypeSystem,
+		Content:   fmt.Sprintf("%s is now %s (set by %s)", target.name, role, actor.name),
+		Timestamp: time.Now(),
+	}, nil)
+	return nil
+}
+
+func promoteCommand(s *Server, c *Client, args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("usage: /promote <user> <role>")
+	}
+	role, err := ParseRole(args[1])
+	if err != nil {
+		return err
+	}
+	return s.setRole(c, args[0], role)
+}
+
+func demoteCommand(s *Server, c *Client, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: /demote <user> [role]")
+	}
+	role := RoleUser
+	if len(args) > 1 {
+		var err error
+		if role, err = ParseRole(args[1]); err != nil {
+			return err
+		}
+	}
+	return s.setRole(c, args[0], role)
+}
+
+func shutdownCommand(s *Server, c *Client, args []string) error {
+	s.logActivity("Shutdown requested", "client", c.name)
+	go s.Shutdown()
+	return nil
+}
@@ -0,0 +1,70 @@
+package chat
+
+import (
+	"testing"
+)
+
+func TestParseRole(t *testing.T) {
+	tests := map[string]Role{
+		"owner":     RoleOwner,
+		"Admin":     RoleAdmin,
+		"MODERATOR": RoleModerator,
+		"user":      RoleUser,
+	}
+	for name, want := range tests {
+		if got, err := ParseRole(name); err != nil || got != want {
+			t.Errorf("ParseRole(%q) = %s, %v; want %s", name, got, err, want)
+		}
+	}
+	if _, err := ParseRole("superuser"); err == nil {
+		t.Error("expected error for unknown role")
+	}
+}
+
+func TestRolePermissions(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountsFile = tempAccountsFile(t)
+	config.AuthRequired = true
+	config.Roles = map[string]Role{"alice": RoleOwner}
+	SetPassword(config, "Alice", "secret1")
+	SetPassword(config, "Bob", "secret2")
+	addr := setupTestServerWithConfig(t, config)
+
+	login := func(name, password string) *TestClient {
+		c, err := newTestClient(t, addr)
+		if err != nil {
+			t.Fatalf("Client connection failed: %v", err)
+		}
+		c.expectMessage(t, "Welcome")
+		c.sendMessage(name)
+		c.sendMessage(password)
+		if err := c.expectMessageWithin(t, name+" joined", passwordTimeout); err != nil {
+			t.Fatalf("Login as %s failed: %v", name, err)
+		}
+		return c
+	}
+	alice := login("Alice", "secret1")
+	defer alice.close()
+	bob := login("Bob", "secret2")
+	defer bob.close()
+
+	bob.sendMessage("/promote Bob admin")
+	if err := bob.expectMessage(t, "permission denied: /promote requires moderator"); err != nil {
+		t.Fatalf("User promoted themselves: %v", err)
+	}
+
+	alice.sendMessage("/promote Bob moderator")
+	if err := bob.expectMessage(t, "Bob is now moderator"); err != nil {
+		t.Fatalf("Promotion failed: %v", err)
+	}
+
+	bob.sendMessage("/demote Alice")
+	if err := bob.expectMessage(t, "only manage roles below"); err != nil {
+		t.Fatalf("Moderator demoted the owner: %v", err)
+	}
+
+	bob.sendMessage("/shutdown")
+	if err := bob.expectMessage(t, "/shutdown requires admin"); err != nil {
+		t.Fatalf("Moderator allowed to shut down: %v", err)
+	}
+}
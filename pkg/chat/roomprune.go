@@ -0,0 +1,111 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"time"
+)
+
+// removeClient takes conn out of the room, noting when it became empty.
+// Caller holds s.mutex.
+func (r *ChatRoom) removeClient(conn net.Conn) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.clients, conn)
+	if len(r.clients) == 0 {
+		r.emptySince = time.Now()
+	}
+}
+
+// isPersistentRoom reports whether a room is exempt from pruning: the
+// lobby, the default rooms and those in Config.PersistentRooms
+func (s *Server) isPersistentRoom(name string) bool {
+	return name == s.lobby() || slices.Contains(s.config.DefaultRooms, name) ||
+		slices.Contains(s.config.PersistentRooms, name)
+}
+
+// removeRoom deletes a room with its history and returns the clients that
+// were in it. Caller holds s.mutex.
+func (s *Server) removeRoom(room *ChatRoom) []*Client {
+	delete(s.rooms, room.name)
+	if err := s.saveRooms(); err != nil {
+		s.log.Error("Error saving rooms", "room", room.name, "err", err)
+	}
+	if _, err := s.store.PruneMessages(room.name, time.Now().Add(time.Second), 0); err != nil {
+		s.log.Error("Error deleting history", "room", room.name, "err", err)
+	}
+	return room.members()
+}
+
+// deleteCommand removes a room for good, sending its members to the lobby
+func deleteCommand(s *Server, c *Client, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: /delete <room>")
+	}
+	s.mutex.Lock()
+	room, exists := s.rooms[args[0]]
+	if !exists {
+		s.mutex.Unlock()
+		return fmt.Errorf("room does not exist")
+	}
+	if room.name == s.lobby() {
+		s.mutex.Unlock()
+		return fmt.Errorf("%s cannot be deleted", room.name)
+	}
+	members := s.removeRoom(room)
+	s.mutex.Unlock()
+
+	for _, member := range members {
+		member.sendMessage(Message{
+			Type:      MessageTypeError,
+			Content:   fmt.Sprintf("%s deleted %s", c.name, room.name),
+			Timestamp: time.Now(),
+		})
+		if err := s.joinRoom(member, s.lobby(), ""); err != nil {
+			s.log.Error("Error moving client to the lobby", "client", member.name, "room", s.lobby(), "err", err)
+		}
+	}
+
+	s.logActivity("Room deleted", "client", c.name, "room", room.name)
+	s.audit("delete", c, room.name, "")
+	c.write([]byte(fmt.Sprintf("Deleted room %s\n", room.name)))
+	return nil
+}
+
+// pruneRooms periodically deletes rooms that stayed empty for
+// Config.RoomIdleTimeout
+func (s *Server) pruneRooms(ctx context.Context) {
+	ticker := time.NewTicker(checkInterval(s.config.RoomIdleTimeout, time.Minute))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !s.checkEmptyRooms(time.Now()) {
+			return
+		}
+	}
+}
+
+// checkEmptyRooms handles one pruning pass. It returns false once the
+// server is shutting down.
+func (s *Server) checkEmptyRooms(now time.Time) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closing {
+		return false
+	}
+	for name, room := range s.rooms {
+		if room.size() > 0 || room.archived || s.isPersistentRoom(name) || now.Sub(room.emptySince) < s.config.RoomIdleTimeout {
+			continue
+		}
+		s.removeRoom(room)
+		s.logActivity("Room pruned", "room", name, "idle", s.config.RoomIdleTimeout)
+	}
+	return true
+}
@@ -0,0 +1,74 @@
+package chat
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeleteRoom(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountsFile = ""
+	config.RoomsFile = ""
+	config.Store = StoreMemory
+	s := NewServerWithConfig(config)
+	defer s.Logfile.Close()
+
+	alice := newPipeClient(t, "Alice")
+	alice.role = RoleAdmin
+	bob := newPipeClient(t, "Bob")
+	if err := s.joinRoom(alice, "general", ""); err != nil {
+		t.Fatalf("join general failed: %v", err)
+	}
+	if err := s.createRoom(bob, "junk", "", ""); err != nil {
+		t.Fatalf("createRoom failed: %v", err)
+	}
+	s.record("junk", Message{Type: MessageTypeChat, From: "Bob", Content: "hello", Timestamp: time.Now()})
+
+	if err := deleteCommand(s, alice, []string{"general"}); err == nil {
+		t.Error("general was deleted")
+	}
+	if err := deleteCommand(s, alice, []string{"junk"}); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	if _, exists := s.rooms["junk"]; exists {
+		t.Error("room still exists")
+	}
+	if bob.room != "general" {
+		t.Errorf("member left in %q, want general", bob.room)
+	}
+	if history, _ := s.store.RecentMessages("junk", 0); len(history) != 0 {
+		t.Errorf("history of deleted room kept: %v", history)
+	}
+}
+
+func TestPruneEmptyRooms(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountsFile = ""
+	config.RoomsFile = ""
+	config.RoomIdleTimeout = time.Hour
+	config.PersistentRooms = []string{"lobby"}
+	s := NewServerWithConfig(config)
+	defer s.Logfile.Close()
+
+	alice := newPipeClient(t, "Alice")
+	for _, name := range []string{"lobby", "stale", "busy"} {
+		if err := s.createRoom(alice, name, "", ""); err != nil {
+			t.Fatalf("createRoom %s failed: %v", name, err)
+		}
+	}
+	// Alice stays in busy, leaving lobby and stale empty
+	s.checkEmptyRooms(time.Now().Add(30 * time.Minute))
+	if _, exists := s.rooms["stale"]; !exists {
+		t.Fatal("room pruned before the idle timeout")
+	}
+
+	s.checkEmptyRooms(time.Now().Add(2 * time.Hour))
+	if _, exists := s.rooms["stale"]; exists {
+		t.Error("empty room not pruned")
+	}
+	for _, name := range []string{"general", "lobby", "busy"} {
+		if _, exists := s.rooms[name]; !exists {
+			t.Errorf("room %s pruned", name)
+		}
+	}
+}
@@ -0,0 +1,332 @@
+package chat
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// ChatRoom represents a separate chat room
+type ChatRoom struct {
+	name    string
+	clients map[net.Conn]*Client
+	mutex   sync.RWMutex // Guards clients; changes also hold s.mutex
+
+	creator      string
+	description  string // What the room is for, set at /create
+	topic        string
+	passwordHash string // Empty for rooms anyone may join
+	created      time.Time
+
+	inviteOnly bool
+	invited    map[string]bool // Lowercase names with a pending /invite
+	admitted   map[string]bool // Lowercase names that redeemed an invite code
+	ops        map[string]bool // Lowercase names of the room operators
+	hidden     bool            // Left out of /rooms for non-members
+	emptySince time.Time       // When the last member left, for pruning
+	ephemeral  bool            // Messages are delivered but never recorded
+
+	slowMode time.Duration        // Minimum interval between posts of one user
+	lastPost map[string]time.Time // Lowercase name to last post, for slowMode
+
+	archived bool // Read-only for everyone but admins
+}
+
+func newChatRoom(name string) *ChatRoom {
+	return &ChatRoom{
+		name:       name,
+		clients:    make(map[net.Conn]*Client),
+		created:    time.Now(),
+		emptySince: time.Now(),
+		invited:    make(map[string]bool),
+		admitted:   make(map[string]bool),
+		ops:        make(map[string]bool),
+		lastPost:   make(map[string]time.Time),
+	}
+}
+
+// isMember reports whether name belongs to the room while offline: its
+// creator, an operator, or someone with a pending invitation or redeemed
+// invite code. Caller holds s.mutex.
+func (r *ChatRoom) isMember(name string) bool {
+	key := strings.ToLower(name)
+	return strings.EqualFold(r.creator, name) || r.ops[key] || r.invited[key] || r.admitted[key]
+}
+
+// addClient puts c in the room. Caller holds s.mutex.
+func (r *ChatRoom) addClient(c *Client) {
+	r.mutex.Lock()
+	r.clients[c.conn] = c
+	r.mutex.Unlock()
+}
+
+// members returns a snapshot of the room's clients, so a room can be
+// written to without holding s.mutex
+func (r *ChatRoom) members() []*Client {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	members := make([]*Client, 0, len(r.clients))
+	for _, c := range r.clients {
+		members = append(members, c)
+	}
+	return members
+}
+
+// size returns the number of clients in the room
+func (r *ChatRoom) size() int {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return len(r.clients)
+}
+
+func (s *Server) broadcastToRoom(room *ChatRoom, msg Message, exclude net.Conn) {
+	s.deliverToRoom(room, msg, exclude)
+	// Other instances and bridged services would keep a copy
+	if room.ephemeral {
+		return
+	}
+	s.publishRoomMessage(room, msg)
+	s.relayToBridges(room.name, msg)
+}
+
+// deliverToRoom records the message and writes it to the room's local clients
+func (s *Server) deliverToRoom(room *ChatRoom, msg Message, exclude net.Conn) {
+	defer s.stats.observeBroadcast(time.Now())
+	if room.ephemeral {
+		msg.ID = s.messageIDs.next(room.name, s.store)
+	} else {
+		msg = s.record(room.name, msg)
+	}
+	var mentioned, words map[string]bool
+	if msg.Type == MessageTypeChat {
+		mentioned = mentionedNames(msg.Content)
+		words = messageWords(msg.Content)
+	}
+	text := newSharedText(msg)
+	for _, client := range room.members() {
+		if client.conn == exclude || client.ignores(msg.From) {
+			continue
+		}
+		if mentioned[strings.ToLower(client.name)] && s.wantsMention(client, msg.From) {
+			if room.ephemeral {
+				client.sendMessage(mentionNotice(msg, room.name))
+			} else {
+				client.sendMessage(client.mention(msg, room.name))
+			}
+			continue
+		}
+		if keywordHit(client, msg, words) != "" {
+			client.sendMessage(keywordAlert(msg, room.name))
+			continue
+		}
+		client.sendShared(msg, text)
+	}
+}
+
+// maxDescriptionLength caps room descriptions, in runes
+const maxDescriptionLength = 120
+
+func (s *Server) createRoom(c *Client, roomName, password, description string) error {
+	if utf8.RuneCountInString(description) > maxDescriptionLength {
+		return fmt.Errorf("room descriptions are limited to %d characters", maxDescriptionLength)
+	}
+	// Hashing is slow on purpose, so it is done before taking the lock
+	passwordHash := ""
+	if password != "" {
+		passwordHash = hashPassword(password)
+	}
+	s.mutex.Lock()
+	if _, exists := s.rooms[roomName]; exists {
+		s.mutex.Unlock()
+		return fmt.Errorf("room already exists")
+	}
+
+	room := newChatRoom(roomName)
+	room.creator = c.name
+	room.description = description
+	room.ops[strings.ToLower(c.name)] = true
+	room.passwordHash = passwordHash
+	s.rooms[roomName] = room
+	if err := s.saveRooms(); err != nil {
+		s.log.Error("Error saving rooms", "room", room.name, "err", err)
+	}
+	s.mutex.Unlock()
+
+	s.logActivity("Room created", "client", c.name, "room", roomName)
+	return s.joinRoom(c, roomName, password)
+}
+
+func (s *Server) joinRoom(c *Client, roomName, password string) error {
+	// The password is checked against a snapshot of the room's hash
+	// without the lock, since checking takes a while on purpose
+	s.mutex.RLock()
+	room, exists := s.rooms[roomName]
+	var checkedHash string
+	if exists {
+		checkedHash = room.passwordHash
+	}
+	s.mutex.RUnlock()
+	if !exists {
+		return fmt.Errorf("room does not exist")
+	}
+	passwordOK := checkedHash != "" && password != "" && checkPassword(checkedHash, password)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	room, exists = s.rooms[roomName]
+	if !exists {
+		return fmt.Errorf("room does not exist")
+	}
+	// Invitations are kept by name too, so a registered name must be
+	// identified to use them
+	holdsName := s.holdsName(c)
+	admitted := room.admitted[strings.ToLower(c.name)] && holdsName
+	if admitted {
+		delete(room.admitted, strings.ToLower(c.name))
+	}
+	if s.frozenFor(room, c) {
+		return fmt.Errorf("room %s is archived", roomName)
+	}
+	if room.passwordHash != "" && !admitted && (room.passwordHash != checkedHash || !passwordOK) {
+		return fmt.Errorf("room %s requires a password: /join %s <password>", roomName, roomName)
+	}
+	if room.inviteOnly && !admitted && !s.isRoomOperator(room, c) {
+		if !room.invited[strings.ToLower(c.name)] || !holdsName {
+			return fmt.Errorf("room %s is invite-only", roomName)
+		}
+		delete(room.invited, strings.ToLower(c.name))
+	}
+
+	// Remove from current room if any
+	oldRoom := c.room
+	if c.room != "" {
+		if old, exists := s.rooms[c.room]; exists {
+			old.removeClient(c.conn)
+			if old != room {
+				s.broadcastToRoom(old, Message{
+					Type:      MessageTypeSystem,
+					Content:   fmt.Sprintf("%s left the room", c.name),
+					Timestamp: time.Now(),
+				}, nil)
+			}
+		}
+	}
+
+	// Add to new room
+	room.addClient(c)
+	c.room = roomName
+	if irc, ok := c.conn.(*ircConn); ok {
+		irc.joined(oldRoom, room)
+	}
+
+	// Send room history
+	if !room.ephemeral {
+		s.replayHistory(c, room)
+	}
+	if room.topic != "" {
+		c.sendMessage(Message{
+			Type:      MessageTypeSystem,
+			Content:   fmt.Sprintf("Topic for %s: %s", roomName, room.topic),
+			Timestamp: time.Now(),
+		})
+	}
+
+	s.broadcastToRoom(room, Message{
+		Type:      MessageTypeSystem,
+		Content:   fmt.Sprintf("%s joined the room", c.name),
+		Timestamp: time.Now(),
+	}, nil)
+	s.presenceChanged()
+
+	return nil
+}
+
+// leaveCommand moves the client from its room back to the lobby
+func leaveCommand(s *Server, c *Client, args []string) error {
+	if c.room == s.lobby() {
+		return fmt.Errorf("you are already in %s", c.room)
+	}
+	return s.joinRoom(c, s.lobby(), "")
+}
+
+func (s *Server) listRooms(c *Client) error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var rooms []string
+	for name, room := range s.rooms {
+		if !s.visibleTo(room, c) {
+			continue
+		}
+		entry := fmt.Sprintf("%s (%d users)", name, room.size())
+		if room.hidden {
+			entry += " [hidden]"
+		}
+		if room.passwordHash != "" {
+			entry += " [password]"
+		}
+		if room.inviteOnly {
+			entry += " [invite-only]"
+		}
+		if room.archived {
+			entry += " [archived]"
+		}
+		var details []string
+		if room.description != "" {
+			details = append(details, room.description)
+		}
+		if room.topic != "" {
+			details = append(details, "topic: "+room.topic)
+		}
+		if len(details) > 0 {
+			entry += " - " + strings.Join(details, "; ")
+		}
+		rooms = append(rooms, entry)
+	}
+	sort.Strings(rooms)
+
+	response := fmt.Sprintf("Available rooms:\n%s\n",
+		strings.Join(rooms, "\n"))
+	c.write([]byte(response))
+	return nil
+}
+
+func topicCommand(s *Server, c *Client, args []string) error {
+	s.mutex.Lock()
+	room, exists := s.rooms[c.room]
+	if !exists {
+		s.mutex.Unlock()
+		return fmt.Errorf("you are not in any room")
+	}
+	if len(args) == 0 {
+		topic := room.topic
+		s.mutex.Unlock()
+		if topic == "" {
+			topic = "(no topic set)"
+		}
+		c.write([]byte(fmt.Sprintf("Topic for %s: %s\n", room.name, topic)))
+		return nil
+	}
+	if !s.isRoomOperator(room, c) {
+		s.mutex.Unlock()
+		return fmt.Errorf("only room operators can change the topic")
+	}
+	room.topic = strings.Join(args, " ")
+	if err := s.saveRooms(); err != nil {
+		s.log.Error("Error saving rooms", "room", room.name, "err", err)
+	}
+	s.mutex.Unlock()
+
+	s.logActivity("Topic changed", "client", c.name, "room", room.name, "topic", room.topic)
+	s.broadcastToRoom(room, Message{
+		Type:      MessageTypeSystem,
+		Content:   fmt.Sprintf("%s changed the topic to: %s", c.name, room.topic),
+		Timestamp: time.Now(),
+	}, nil)
+	return nil
+}
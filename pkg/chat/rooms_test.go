@@ -0,0 +1,225 @@
+package chat
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLeaveReturnsToGeneral(t *testing.T) {
+	config := DefaultConfig()
+	config.RoomsFile = ""
+	addr := setupTestServerWithConfig(t, config)
+	join := func(name string) *TestClient {
+		c, err := newTestClient(t, addr)
+		if err != nil {
+			t.Fatalf("Client connection failed: %v", err)
+		}
+		c.sendMessage(name)
+		if err := c.expectMessage(t, name+" joined"); err != nil {
+			t.Fatalf("Join as %s failed: %v", name, err)
+		}
+		return c
+	}
+	alice := join("Alice")
+	defer alice.close()
+	bob := join("Bob")
+	defer bob.close()
+	carol := join("Carol")
+	defer carol.close()
+
+	bob.sendMessage("/leave")
+	if err := bob.expectMessage(t, "you are already in general"); err != nil {
+		t.Fatalf("leaving general not refused: %v", err)
+	}
+
+	bob.sendMessage("/create dev")
+	if err := alice.expectMessage(t, "Bob left the room"); err != nil {
+		t.Fatalf("no part message in general: %v", err)
+	}
+	carol.sendMessage("/join dev")
+	if err := bob.expectMessage(t, "Carol joined the room"); err != nil {
+		t.Fatalf("Carol did not join dev: %v", err)
+	}
+
+	bob.sendMessage("/leave")
+	if err := carol.expectMessage(t, "Bob left the room"); err != nil {
+		t.Fatalf("no part message in dev: %v", err)
+	}
+	if err := alice.expectMessage(t, "Bob joined the room"); err != nil {
+		t.Fatalf("no join message in general: %v", err)
+	}
+	bob.sendMessage("back")
+	if err := alice.expectMessage(t, "back"); err != nil {
+		t.Fatalf("Bob is not in general: %v", err)
+	}
+}
+
+func TestInviteOnlyRoom(t *testing.T) {
+	config := DefaultConfig()
+	config.RoomsFile = ""
+	addr := setupTestServerWithConfig(t, config)
+	join := func(name string) *TestClient {
+		c, err := newTestClient(t, addr)
+		if err != nil {
+			t.Fatalf("Client connection failed: %v", err)
+		}
+		c.sendMessage(name)
+		if err := c.expectMessage(t, name+" joined"); err != nil {
+			t.Fatalf("Join as %s failed: %v", name, err)
+		}
+		return c
+	}
+	alice := join("Alice")
+	defer alice.close()
+	bob := join("Bob")
+	defer bob.close()
+
+	alice.sendMessage("/create secret")
+	alice.sendMessage("/inviteonly on")
+	if err := alice.expectMessage(t, "Alice made the room now invite-only"); err != nil {
+		t.Fatalf("invite-only not set: %v", err)
+	}
+
+	bob.sendMessage("/inviteonly off")
+	if err := bob.expectMessage(t, "only room operators"); err != nil {
+		t.Fatalf("non-operator changed the flag: %v", err)
+	}
+	bob.sendMessage("/join secret")
+	if err := bob.expectMessage(t, "room secret is invite-only"); err != nil {
+		t.Fatalf("uninvited join not refused: %v", err)
+	}
+
+	alice.sendMessage("/invite bob")
+	if err := bob.expectMessage(t, "Alice invited you to secret"); err != nil {
+		t.Fatalf("invitation not delivered: %v", err)
+	}
+	bob.sendMessage("/join secret")
+	if err := alice.expectMessage(t, "Bob joined the room"); err != nil {
+		t.Fatalf("invited join failed: %v", err)
+	}
+
+	// Invitations are used up by joining
+	bob.sendMessage("/leave")
+	bob.sendMessage("/join secret")
+	if err := bob.expectMessage(t, "room secret is invite-only"); err != nil {
+		t.Fatalf("invitation reused: %v", err)
+	}
+}
+
+func TestRoomDescriptions(t *testing.T) {
+	config := DefaultConfig()
+	config.RoomsFile = ""
+	addr := setupTestServerWithConfig(t, config)
+	client, err := newTestClient(t, addr)
+	if err != nil {
+		t.Fatalf("Client connection failed: %v", err)
+	}
+	defer client.close()
+	client.sendMessage("Alice")
+	if err := client.expectMessage(t, "Alice joined"); err != nil {
+		t.Fatalf("Join failed: %v", err)
+	}
+
+	client.sendMessage("/create dev hunter22 -- Backend work and code review")
+	if err := client.expectMessageWithin(t, "Alice joined the room", passwordTimeout); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	client.sendMessage("/topic release on Friday")
+	client.expectMessage(t, "release on Friday")
+	client.sendMessage("/rooms")
+	if err := client.expectMessage(t, "dev (1 users) [password] - Backend work and code review; topic: release on Friday"); err != nil {
+		t.Fatalf("description not listed: %v", err)
+	}
+
+	client.sendMessage("/create extra a b")
+	if err := client.expectMessage(t, "usage: /create <room> [password] [-- description]"); err != nil {
+		t.Fatalf("stray arguments accepted: %v", err)
+	}
+}
+
+func TestDefaultRoomsAndLobby(t *testing.T) {
+	config := DefaultConfig()
+	config.RoomsFile = ""
+	config.DefaultRooms = []string{"random", "help"}
+	config.Lobby = "lobby"
+	addr := setupTestServerWithConfig(t, config)
+	client, err := newTestClient(t, addr)
+	if err != nil {
+		t.Fatalf("Client connection failed: %v", err)
+	}
+	defer client.close()
+	client.sendMessage("Alice")
+	if err := client.expectMessage(t, "Alice joined"); err != nil {
+		t.Fatalf("Join failed: %v", err)
+	}
+
+	client.sendMessage("/who")
+	if err := client.expectMessage(t, "Users in room lobby"); err != nil {
+		t.Fatalf("new client not put in the lobby: %v", err)
+	}
+	client.sendMessage("/join help")
+	if err := client.expectMessage(t, "Alice joined the room"); err != nil {
+		t.Fatalf("default room not created: %v", err)
+	}
+	client.sendMessage("/join general")
+	if err := client.expectMessage(t, "room does not exist"); err != nil {
+		t.Fatalf("general created despite a configured lobby: %v", err)
+	}
+	client.sendMessage("/leave")
+	client.sendMessage("/who")
+	if err := client.expectMessage(t, "Users in room lobby"); err != nil {
+		t.Fatalf("/leave did not return to the lobby: %v", err)
+	}
+}
+
+func TestReadersDoNotSerialize(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountsFile = ""
+	config.RoomsFile = ""
+	config.Store = StoreMemory
+	s := NewServerWithConfig(config)
+	defer s.Logfile.Close()
+
+	alice := newPipeClient(t, "Alice")
+	bob := newPipeClient(t, "Bob")
+	if err := s.createRoom(alice, "dev", "", ""); err != nil {
+		t.Fatalf("createRoom failed: %v", err)
+	}
+	if err := s.joinRoom(bob, "dev", ""); err != nil {
+		t.Fatalf("join failed: %v", err)
+	}
+	room := s.rooms["dev"]
+
+	// A reader holding the server lock does not hold up other readers,
+	// and rooms are written to without the server lock at all
+	s.mutex.RLock()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := s.listRooms(bob); err != nil {
+			t.Errorf("listRooms failed: %v", err)
+		}
+		if n := len(room.members()); n != 2 {
+			t.Errorf("dev has %d members, want 2", n)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("readers serialized on the server lock")
+	}
+	s.mutex.RUnlock()
+
+	s.mutex.Lock()
+	delivered := make(chan struct{})
+	go func() {
+		s.deliverToRoom(room, Message{Type: MessageTypeSystem, Content: "hello", Timestamp: time.Now()}, nil)
+		close(delivered)
+	}()
+	select {
+	case <-delivered:
+	case <-time.After(time.Second):
+		t.Error("delivery to a room waited for the server lock")
+	}
+	s.mutex.Unlock()
+}
@@ -0,0 +1,83 @@
+package chat
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// RoomInfo is the persistent metadata of a room
+type RoomInfo struct {
+	Name         string    `json:"name"`
+	Creator      string    `json:"creator,omitempty"`
+	Description  string    `json:"description,omitempty"`
+	Topic        string    `json:"topic,omitempty"`
+	PasswordHash string    `json:"password_hash,omitempty"`
+	Created      time.Time `json:"created"`
+	InviteOnly   bool      `json:"invite_only,omitempty"`
+	Ops          []string  `json:"ops,omitempty"`
+	Hidden       bool      `json:"hidden,omitempty"`
+	Ephemeral    bool      `json:"ephemeral,omitempty"`
+	SlowMode     int       `json:"slow_mode,omitempty"` // Seconds
+	Archived     bool      `json:"archived,omitempty"`
+}
+
+// saveRooms writes the metadata of every room. Caller holds s.mutex.
+func (s *Server) saveRooms() error {
+	rooms := make([]RoomInfo, 0, len(s.rooms))
+	for _, room := range s.rooms {
+		rooms = append(rooms, room.info())
+	}
+	sort.Slice(rooms, func(i, j int) bool { return rooms[i].Name < rooms[j].Name })
+	return s.store.SaveRooms(rooms)
+}
+
+func (r *ChatRoom) info() RoomInfo {
+	return RoomInfo{
+		Name:         r.name,
+		Creator:      r.creator,
+		Description:  r.description,
+		Topic:        r.topic,
+		PasswordHash: r.passwordHash,
+		Created:      r.created,
+		InviteOnly:   r.inviteOnly,
+		Ops:          r.opNames(),
+		Hidden:       r.hidden,
+		Ephemeral:    r.ephemeral,
+		SlowMode:     int(r.slowMode / time.Second),
+		Archived:     r.archived,
+	}
+}
+
+// restoreRooms recreates the rooms saved by a previous run
+func (s *Server) restoreRooms() error {
+	rooms, err := s.store.LoadRooms()
+	for _, info := range rooms {
+		room, exists := s.rooms[info.Name]
+		if !exists {
+			room = newChatRoom(info.Name)
+			s.rooms[info.Name] = room
+		}
+		room.creator = info.Creator
+		room.description = info.Description
+		room.topic = info.Topic
+		room.passwordHash = info.PasswordHash
+		room.inviteOnly = info.InviteOnly
+		room.hidden = info.Hidden
+		room.ephemeral = info.Ephemeral
+		room.slowMode = time.Duration(info.SlowMode) * time.Second
+		room.archived = info.Archived
+		room.ops = make(map[string]bool)
+		for _, name := range info.Ops {
+			room.ops[strings.ToLower(name)] = true
+		}
+		// Rooms saved before operators existed are run by their creator
+		if len(info.Ops) == 0 && info.Creator != "" {
+			room.ops[strings.ToLower(info.Creator)] = true
+		}
+		if !info.Created.IsZero() {
+			room.created = info.Created
+		}
+	}
+	return err
+}
@@ -0,0 +1,43 @@
+package chat
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestRoomsSurviveRestart(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountsFile = ""
+	config.RoomsFile = filepath.Join(t.TempDir(), "rooms.json")
+
+	first := NewServerWithConfig(config)
+	defer first.Logfile.Close()
+	alice := newPipeClient(t, "Alice")
+	if err := first.createRoom(alice, "dev", "hunter22", "Backend work"); err != nil {
+		t.Fatalf("createRoom failed: %v", err)
+	}
+	if err := topicCommand(first, alice, []string{"release", "planning"}); err != nil {
+		t.Fatalf("topic failed: %v", err)
+	}
+
+	second := NewServerWithConfig(config)
+	defer second.Logfile.Close()
+	room, exists := second.rooms["dev"]
+	if !exists {
+		t.Fatal("room dev not restored")
+	}
+	if room.creator != "Alice" || room.description != "Backend work" || room.topic != "release planning" || room.created.IsZero() {
+		t.Errorf("unexpected restored metadata %+v", room.info())
+	}
+
+	bob := newPipeClient(t, "Bob")
+	if err := second.joinRoom(bob, "dev", "wrong"); err == nil {
+		t.Error("joined a password protected room with the wrong password")
+	}
+	if err := second.joinRoom(bob, "dev", "hunter22"); err != nil {
+		t.Errorf("join with the right password failed: %v", err)
+	}
+	if err := topicCommand(second, bob, []string{"hijacked"}); err == nil {
+		t.Error("non-creator changed the topic")
+	}
+}
@@ -0,0 +1,53 @@
+package chat
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// rosterCommand shows the client's room, the rooms it can see and who is in
+// its room. JSON clients get it as one "roster" event, which is what the
+// client TUI keeps its room and user lists up to date with.
+func rosterCommand(s *Server, c *Client, args []string) error {
+	s.mutex.RLock()
+	var rooms, users []string
+	for name, room := range s.rooms {
+		if s.visibleTo(room, c) {
+			rooms = append(rooms, name)
+		}
+	}
+	if room, ok := s.rooms[c.room]; ok {
+		for _, member := range room.members() {
+			name := member.name
+			if room.ops[strings.ToLower(name)] {
+				name = "@" + name
+			}
+			users = append(users, name)
+		}
+	}
+	for _, m := range s.remoteMembers(c.room, time.Now()) {
+		users = append(users, m.Name+" [remote]")
+	}
+	s.mutex.RUnlock()
+
+	sort.Strings(rooms)
+	sort.Slice(users, func(i, j int) bool {
+		return strings.ToLower(strings.TrimPrefix(users[i], "@")) < strings.ToLower(strings.TrimPrefix(users[j], "@"))
+	})
+
+	if _, ok := c.conn.(*jsonConn); ok {
+		c.enqueue(outbound{event: &jsonEvent{
+			Type:      "roster",
+			Room:      c.room,
+			Rooms:     rooms,
+			Users:     users,
+			Timestamp: time.Now(),
+		}})
+		return nil
+	}
+	c.write([]byte(fmt.Sprintf("Room: %s\nRooms: %s\nUsers: %s\n",
+		c.room, strings.Join(rooms, ", "), strings.Join(users, ", "))))
+	return nil
+}
@@ -0,0 +1,72 @@
+package chat
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRoster(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountsFile = ""
+	config.RoomsFile = ""
+	config.Store = StoreMemory
+	s := NewServerWithConfig(config)
+	defer s.Logfile.Close()
+	l := newPipeListener()
+	go s.Serve(l)
+	defer s.Shutdown()
+
+	alice := l.dial()
+	defer alice.close()
+	alice.expectMessage(t, "Welcome")
+	alice.sendMessage(protoJSON)
+	alice.expectMessage(t, `"type":"proto"`)
+	alice.expectMessage(t, "ENTER YOUR NAME")
+	alice.sendMessage(`{"content":"Alice"}`)
+	if err := alice.expectMessage(t, "Alice joined the room"); err != nil {
+		t.Fatalf("JSON login failed: %v", err)
+	}
+
+	bob := l.dial()
+	defer bob.close()
+	bob.expectMessage(t, "Welcome")
+	bob.sendMessage("Bob")
+	if err := bob.expectMessage(t, "Bob joined the room"); err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	bob.sendMessage("/create dev")
+	bob.expectMessage(t, "dev")
+	bob.sendMessage("/leave")
+	bob.expectMessage(t, "joined the room")
+
+	// Text clients get the roster as text
+	bob.sendMessage("/roster")
+	for _, want := range []string{"Room: general", "Rooms: dev, general", "Users: Alice, Bob"} {
+		if err := bob.expectMessage(t, want); err != nil {
+			t.Fatalf("missing %q: %v", want, err)
+		}
+	}
+
+	// JSON clients get one roster event
+	alice.sendMessage(`{"type":"command","command":"roster"}`)
+	alice.conn.SetReadDeadline(time.Now().Add(messageTimeout))
+	for {
+		line, err := alice.reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("no roster event: %v", err)
+		}
+		if !strings.Contains(line, `"type":"roster"`) {
+			continue
+		}
+		var e jsonEvent
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("bad roster event %q: %v", line, err)
+		}
+		if e.Room != "general" || strings.Join(e.Rooms, ",") != "dev,general" || strings.Join(e.Users, ",") != "Alice,Bob" {
+			t.Errorf("roster = room %q, rooms %v, users %v", e.Room, e.Rooms, e.Users)
+		}
+		break
+	}
+}
@@ -0,0 +1,61 @@
+package chat
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Sanitization policies for client input
+const (
+	SanitizeStrip  = "strip"  // Remove offending sequences and keep the rest
+	SanitizeReject = "reject" // Refuse the whole line
+)
+
+// ansiPattern matches CSI, OSC and two-byte escape sequences
+var ansiPattern = regexp.MustCompile(`\x1b(\[[0-?]*[ -/]*[@-~]|\][^\x07\x1b]*(\x07|\x1b\\)?|[@-Z\\-_])`)
+
+// isUnsafeRune reports characters that can corrupt other users' terminals:
+// C0/C1 controls (other than tab) and bidirectional overrides
+func isUnsafeRune(r rune) bool {
+	if r == '\t' {
+		return false
+	}
+	if unicode.IsControl(r) {
+		return true
+	}
+	return (r >= 0x202A && r <= 0x202E) || (r >= 0x2066 && r <= 0x2069)
+}
+
+// sanitizeInput strips escape sequences, control characters and invalid
+// UTF-8 from a line. changed reports whether anything was removed.
+func sanitizeInput(line string) (clean string, changed bool) {
+	stripped := ansiPattern.ReplaceAllString(line, "")
+	changed = stripped != line
+
+	var b strings.Builder
+	for i := 0; i < len(stripped); {
+		r, size := utf8.DecodeRuneInString(stripped[i:])
+		i += size
+		if (r == utf8.RuneError && size == 1) || isUnsafeRune(r) {
+			changed = true
+			continue
+		}
+		if r == '\t' {
+			r = ' '
+		}
+		b.WriteRune(r)
+	}
+	return b.String(), changed
+}
+
+// applySanitizePolicy cleans a line according to the configured policy
+func (s *Server) applySanitizePolicy(line string) (string, error) {
+	clean, changed := sanitizeInput(line)
+	if changed && s.config.SanitizePolicy == SanitizeReject {
+		return "", fmt.Errorf("input contains control characters or escape sequences")
+	}
+	return clean, nil
+}
@@ -0,0 +1,51 @@
+package chat
+
+import "testing"
+
+func TestSanitizeInput(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		changed bool
+	}{
+		{"Plain", "hello world", "hello world", false},
+		{"Unicode", "héllo 世界 👋", "héllo 世界 👋", false},
+		{"ColorCodes", "\x1b[31mred\x1b[0m text", "red text", true},
+		{"ClearScreen", "\x1b[2J\x1b[Hboo", "boo", true},
+		{"TitleChange", "\x1b]0;pwned\x07hi", "hi", true},
+		{"Bell", "ding\x07dong", "dingdong", true},
+		{"Backspaces", "abc\x08\x08\x08xyz", "abcxyz", true},
+		{"CarriageReturn", "real\rfake", "realfake", true},
+		{"C1Control", "a\u009bb", "ab", true},
+		{"BidiOverride", "abc\u202edef", "abcdef", true},
+		{"InvalidUTF8", "ok\xff\xfeok", "okok", true},
+		{"Tab", "a\tb", "a b", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed := sanitizeInput(tt.input)
+			if got != tt.want || changed != tt.changed {
+				t.Errorf("sanitizeInput(%q) = %q, %v; want %q, %v", tt.input, got, changed, tt.want, tt.changed)
+			}
+		})
+	}
+}
+
+func TestSanitizePolicy(t *testing.T) {
+	s := NewServerWithConfig(DefaultConfig())
+	defer s.Logfile.Close()
+
+	if got, err := s.applySanitizePolicy("\x1b[31mhi"); err != nil || got != "hi" {
+		t.Errorf("strip policy: got %q, %v", got, err)
+	}
+
+	s.config.SanitizePolicy = SanitizeReject
+	if _, err := s.applySanitizePolicy("\x1b[31mhi"); err == nil {
+		t.Error("reject policy accepted an escape sequence")
+	}
+	if got, err := s.applySanitizePolicy("hi"); err != nil || got != "hi" {
+		t.Errorf("reject policy refused clean input: %q, %v", got, err)
+	}
+}
@@ -0,0 +1,96 @@
+package chat
+
+import (
+	"math"
+	"unicode/utf8"
+
+	"github.com/jroimartin/gocui"
+)
+
+// Scroll distances that reach the oldest and the newest messages
+const (
+	scrollTop    = math.MinInt32
+	scrollBottom = math.MaxInt32
+)
+
+// bindScrollKeys makes PgUp/PgDn page through the view named by view and
+// Home/End jump to its oldest and newest lines, calling changed after each
+// so the view's title can say how far back it is
+func bindScrollKeys(g *gocui.Gui, view func() string, changed func(*gocui.Gui) error) error {
+	keys := map[gocui.Key]struct {
+		lines int
+		pages bool
+	}{
+		gocui.KeyPgup: {-1, true},
+		gocui.KeyPgdn: {1, true},
+		gocui.KeyHome: {scrollTop, false},
+		gocui.KeyEnd:  {scrollBottom, false},
+	}
+	for key, move := range keys {
+		err := g.SetKeybinding("", key, gocui.ModNone, func(g *gocui.Gui, _ *gocui.View) error {
+			v, err := g.View(view())
+			if err != nil {
+				return err
+			}
+			delta := move.lines
+			if move.pages {
+				_, height := v.Size()
+				delta *= max(height-1, 1)
+			}
+			scrollView(v, delta)
+			return changed(g)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scrollView moves the origin of v by delta lines. Autoscroll stays off
+// while v is scrolled back, so new messages do not pull it down.
+func scrollView(v *gocui.View, delta int) {
+	width, height := v.Size()
+	last := max(viewLineCount(v.BufferLines(), width)-height, 0)
+	_, oy := v.Origin()
+	oy = scrollPosition(oy, last, delta, v.Autoscroll)
+	v.Autoscroll = oy == last
+	v.SetOrigin(0, oy)
+}
+
+// scrollPosition returns the first line to show after scrolling by delta
+// from oy, or from the bottom if the view follows new messages, where
+// last is the first line shown at the bottom
+func scrollPosition(oy, last, delta int, following bool) int {
+	if following {
+		oy = last
+	}
+	return min(max(oy+delta, 0), last)
+}
+
+// linesBelow returns how many lines of v are below what it shows
+func linesBelow(v *gocui.View) int {
+	if v.Autoscroll {
+		return 0
+	}
+	width, height := v.Size()
+	_, oy := v.Origin()
+	return max(viewLineCount(v.BufferLines(), width)-oy-height, 0)
+}
+
+// viewLineCount returns how many lines the buffer lines take in a view
+// of the given width, wrapped the way gocui wraps them
+func viewLineCount(lines []string, width int) int {
+	if width < 1 {
+		return len(lines)
+	}
+	count := 0
+	for _, line := range lines {
+		if n := utf8.RuneCountInString(line); n >= width {
+			count += n/width + 1
+		} else {
+			count++
+		}
+	}
+	return count
+}
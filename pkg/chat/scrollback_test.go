@@ -0,0 +1,39 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestViewLineCount(t *testing.T) {
+	lines := []string{"", "short", strings.Repeat("x", 9), strings.Repeat("x", 10), strings.Repeat("é", 25)}
+	// Lines as long as the view take an extra, empty line like in gocui
+	if got := viewLineCount(lines, 10); got != 1+1+1+2+3 {
+		t.Errorf("viewLineCount = %d, want 8", got)
+	}
+	if got := viewLineCount(lines, 0); got != len(lines) {
+		t.Errorf("viewLineCount without a width = %d, want %d", got, len(lines))
+	}
+}
+
+func TestScrollPosition(t *testing.T) {
+	tests := []struct {
+		name                  string
+		oy, last, delta, want int
+		following             bool
+	}{
+		{"page up from the bottom", 0, 100, -20, 80, true},
+		{"page up", 50, 100, -20, 30, false},
+		{"page up past the top", 10, 100, -20, 0, false},
+		{"page down", 30, 100, 20, 50, false},
+		{"page down past the bottom", 90, 100, 20, 100, false},
+		{"home", 50, 100, scrollTop, 0, false},
+		{"end", 50, 100, scrollBottom, 100, false},
+		{"short buffer", 0, 0, -20, 0, true},
+	}
+	for _, tc := range tests {
+		if got := scrollPosition(tc.oy, tc.last, tc.delta, tc.following); got != tc.want {
+			t.Errorf("%s: scrollPosition = %d, want %d", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package chat
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// searchPageSize is how many matches /search shows at a time
+const searchPageSize = 20
+
+// searchHit is a message matched by /search or /searchall
+type searchHit struct {
+	Room string
+	Message
+}
+
+// searchMessages returns the chat messages of room whose sender or text
+// contains every term, ignoring case, newest first
+func searchMessages(room string, messages []Message, terms []string) []searchHit {
+	hits := []searchHit{}
+	for i := len(messages) - 1; i >= 0; i-- {
+		msg := messages[i]
+		if msg.Type != MessageTypeChat {
+			continue
+		}
+		text := strings.ToLower(msg.From + " " + msg.Content)
+		matched := true
+		for _, term := range terms {
+			if !strings.Contains(text, term) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			hits = append(hits, searchHit{Room: room, Message: msg})
+		}
+	}
+	return hits
+}
+
+func searchCommand(s *Server, c *Client, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: /search <terms> or /search more")
+	}
+	if len(args) == 1 && strings.EqualFold(args[0], "more") {
+		return s.showSearchPage(c)
+	}
+	if c.room == "" {
+		return fmt.Errorf("you are not in any room")
+	}
+	s.mutex.Lock()
+	ephemeral := s.isEphemeral(c.room)
+	s.mutex.Unlock()
+	if ephemeral {
+		return errNoHistory(c.room)
+	}
+	messages, err := s.store.RecentMessages(c.room, 0)
+	if err != nil {
+		return fmt.Errorf("failed to load history: %v", err)
+	}
+	c.searchHits = searchMessages(c.room, messages, searchTerms(args))
+	c.searchSeen = 0
+	return s.showSearchPage(c)
+}
+
+// searchAllCommand searches the history of every room (admins)
+func searchAllCommand(s *Server, c *Client, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: /searchall <terms>")
+	}
+	s.mutex.Lock()
+	rooms := make([]string, 0, len(s.rooms))
+	for name := range s.rooms {
+		rooms = append(rooms, name)
+	}
+	s.mutex.Unlock()
+
+	terms := searchTerms(args)
+	hits := []searchHit{}
+	for _, room := range rooms {
+		messages, err := s.store.RecentMessages(room, 0)
+		if err != nil {
+			return fmt.Errorf("failed to load history of %s: %v", room, err)
+		}
+		hits = append(hits, searchMessages(room, messages, terms)...)
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Timestamp.After(hits[j].Timestamp) })
+
+	c.searchHits, c.searchSeen = hits, 0
+	return s.showSearchPage(c)
+}
+
+func searchTerms(args []string) []string {
+	terms := make([]string, len(args))
+	for i, arg := range args {
+		terms[i] = strings.ToLower(arg)
+	}
+	return terms
+}
+
+// showSearchPage writes the next page of the client's last search
+func (s *Server) showSearchPage(c *Client) error {
+	if c.searchHits == nil && c.searchSeen == 0 {
+		return fmt.Errorf("use /search <terms> first")
+	}
+	total := len(c.searchHits)
+	if total == 0 {
+		c.write([]byte("No messages found\n"))
+		return nil
+	}
+	if c.searchSeen >= total {
+		c.write([]byte("No more results\n"))
+		return nil
+	}
+
+	page := c.searchHits[c.searchSeen:min(total, c.searchSeen+searchPageSize)]
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- Results %d-%d of %d ---\n", c.searchSeen+1, c.searchSeen+len(page), total)
+	for _, hit := range page {
+		fmt.Fprintf(&b, "[%s][%s #%d][%s]: %s\n", hit.Timestamp.Format("2006-01-02 15:04:05"),
+			hit.Room, hit.ID, hit.From, hit.Content)
+	}
+	c.searchSeen += len(page)
+	if c.searchSeen < total {
+		b.WriteString("--- /search more for older results ---\n")
+	}
+	c.write([]byte(b.String()))
+	return nil
+}
@@ -0,0 +1,88 @@
+package chat
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSearchMessages(t *testing.T) {
+	messages := []Message{
+		{Type: MessageTypeChat, ID: 1, From: "Alice", Content: "Deploy starts at noon"},
+		{Type: MessageTypeSystem, ID: 2, Content: "deploy bot joined the room"},
+		{Type: MessageTypeChat, ID: 3, From: "Bob", Content: "deploy done"},
+		{Type: MessageTypeChat, ID: 4, From: "Bob", Content: "lunch?"},
+	}
+	hits := searchMessages("general", messages, searchTerms([]string{"DEPLOY"}))
+	if len(hits) != 2 || hits[0].ID != 3 || hits[1].ID != 1 {
+		t.Errorf("expected #3 and #1, newest first, got %+v", hits)
+	}
+	if hits := searchMessages("general", messages, []string{"bob", "lunch"}); len(hits) != 1 || hits[0].ID != 4 {
+		t.Errorf("sender and text should both match, got %+v", hits)
+	}
+}
+
+func TestSearchPaging(t *testing.T) {
+	s, _ := newEmailTestServer(t)
+	for i := 1; i <= searchPageSize+5; i++ {
+		s.record("general", Message{Type: MessageTypeChat, From: "Alice", Content: fmt.Sprintf("build %d passed", i), Timestamp: time.Now()})
+	}
+
+	server, client := net.Pipe()
+	defer client.Close()
+	c := &Client{conn: server, name: "Bob", room: "general"}
+	reader := bufio.NewReader(client)
+	run := func(args ...string) string {
+		go func() {
+			if err := searchCommand(s, c, args); err != nil {
+				server.Write([]byte(err.Error() + "\n"))
+			}
+			server.Write([]byte("END\n"))
+		}()
+		var out strings.Builder
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil || line == "END\n" {
+				return out.String()
+			}
+			out.WriteString(line)
+		}
+	}
+
+	first := run("build")
+	if !strings.Contains(first, "Results 1-20 of 25") || !strings.Contains(first, "build 25 passed") || !strings.Contains(first, "/search more") {
+		t.Errorf("unexpected first page:\n%s", first)
+	}
+	second := run("more")
+	if !strings.Contains(second, "Results 21-25 of 25") || !strings.Contains(second, "[general #1][Alice]: build 1 passed") {
+		t.Errorf("unexpected second page:\n%s", second)
+	}
+	if out := run("more"); !strings.Contains(out, "No more results") {
+		t.Errorf("expected end of results, got:\n%s", out)
+	}
+	if out := run("nothing-like-this"); !strings.Contains(out, "No messages found") {
+		t.Errorf("expected no results, got:\n%s", out)
+	}
+}
+
+func TestSearchAllRequiresAdmin(t *testing.T) {
+	addr := setupTestServer(t)
+	c, err := newTestClient(t, addr)
+	if err != nil {
+		t.Fatalf("Client connection failed: %v", err)
+	}
+	defer c.close()
+	c.sendMessage("Alice")
+	c.sendMessage("release notes are up")
+	c.sendMessage("/search release")
+	if err := c.expectMessage(t, "[general #2][Alice]: release notes are up"); err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	c.sendMessage("/searchall release")
+	if err := c.expectMessage(t, "permission denied: /searchall requires admin"); err != nil {
+		t.Fatalf("/searchall allowed for a user: %v", err)
+	}
+}
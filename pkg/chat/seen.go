@@ -0,0 +1,128 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SeenInfo records when a nickname was last connected and its last public
+// message, for /seen
+type SeenInfo struct {
+	At     time.Time `json:"at"`
+	Said   string    `json:"said,omitempty"`
+	SaidIn string    `json:"said_in,omitempty"`
+	SaidAt time.Time `json:"said_at"`
+}
+
+// maxGuestsSeen bounds the last-seen records of unregistered nicknames,
+// which are kept in memory only; the oldest are forgotten first
+const maxGuestsSeen = 1000
+
+// guestSeen is the last-seen record of an unregistered nickname
+type guestSeen struct {
+	name string
+	seen SeenInfo
+}
+
+// public reports whether anyone may read along in the room, so that what
+// is said there may be repeated by /seen. Caller holds s.mutex.
+func (r *ChatRoom) public() bool {
+	return r.passwordHash == "" && !r.inviteOnly && !r.hidden && !r.ephemeral
+}
+
+// recordSeen notes that c's nickname was connected until now: in the
+// account of a registered nickname, and in memory for any other
+func (s *Server) recordSeen(c *Client) {
+	s.mutex.RLock()
+	name := c.name
+	seen := SeenInfo{At: time.Now(), Said: c.lastText, SaidIn: c.lastRoom, SaidAt: c.lastMessage}
+	s.mutex.RUnlock()
+
+	if account, ok := s.accounts.get(name); !ok || !account.registered() {
+		s.recordGuestSeen(name, seen)
+		return
+	}
+	err := s.accounts.update(name, func(a *Account) error {
+		if seen.Said == "" && a.Seen != nil {
+			// Nothing said this session; keep what was said before
+			seen.Said, seen.SaidIn, seen.SaidAt = a.Seen.Said, a.Seen.SaidIn, a.Seen.SaidAt
+		}
+		a.Seen = &seen
+		return nil
+	})
+	if err != nil {
+		s.log.Error("Error saving last seen", "client", name, "err", err)
+	}
+}
+
+// recordGuestSeen keeps the last-seen record of an unregistered nickname,
+// forgetting the oldest record once there are maxGuestsSeen
+func (s *Server) recordGuestSeen(name string, seen SeenInfo) {
+	key := strings.ToLower(name)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if prev, ok := s.guestsSeen[key]; ok && seen.Said == "" {
+		seen.Said, seen.SaidIn, seen.SaidAt = prev.seen.Said, prev.seen.SaidIn, prev.seen.SaidAt
+	}
+	s.guestsSeen[key] = guestSeen{name: name, seen: seen}
+	if len(s.guestsSeen) <= maxGuestsSeen {
+		return
+	}
+	oldest := ""
+	for k, g := range s.guestsSeen {
+		if oldest == "" || g.seen.At.Before(s.guestsSeen[oldest].seen.At) {
+			oldest = k
+		}
+	}
+	delete(s.guestsSeen, oldest)
+}
+
+func seenCommand(s *Server, c *Client, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: /seen <user>")
+	}
+	name := args[0]
+	if strings.EqualFold(name, c.name) {
+		c.write([]byte("You are right here\n"))
+		return nil
+	}
+
+	account, _ := s.accounts.get(name)
+	now := time.Now()
+	var lines []string
+	var seen SeenInfo
+	s.mutex.RLock()
+	if guest, ok := s.guestsSeen[strings.ToLower(name)]; ok && account.Seen == nil {
+		account.Name, account.Seen = guest.name, &guest.seen
+	}
+	if online := s.findClient(name); online != nil {
+		lines = append(lines, fmt.Sprintf("%s is online in %s (connected %s ago)", online.name, s.roomNameFor(online.room, c),
+			now.Sub(online.joinTime).Round(time.Second)))
+		seen = SeenInfo{Said: online.lastText, SaidIn: online.lastRoom, SaidAt: online.lastMessage}
+	}
+	s.mutex.RUnlock()
+
+	switch {
+	case len(lines) > 0:
+		if seen.Said == "" && account.Seen != nil {
+			seen = *account.Seen
+		}
+	case account.Seen == nil:
+		return fmt.Errorf("%s has not been seen here", name)
+	default:
+		seen = *account.Seen
+		lines = append(lines, fmt.Sprintf("%s was last seen %s ago (%s)", account.Name,
+			now.Sub(seen.At).Round(time.Second), seen.At.Format("2006-01-02 15:04:05")))
+	}
+	// What was said in a room c cannot list stays there
+	s.mutex.RLock()
+	hidden := s.roomNameFor(seen.SaidIn, c) != seen.SaidIn
+	s.mutex.RUnlock()
+	if seen.Said != "" && !hidden {
+		lines = append(lines, fmt.Sprintf("Last said in %s %s ago: %s", seen.SaidIn,
+			now.Sub(seen.SaidAt).Round(time.Second), seen.Said))
+	}
+	c.write([]byte(strings.Join(lines, "\n") + "\n"))
+	return nil
+}
@@ -0,0 +1,110 @@
+package chat
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// tempAccountsFile returns an accounts file in a temp dir of the test. The
+// test server is set up after it, so its cleanup shuts the server down,
+// with every last-seen record saved, before the directory is removed.
+func tempAccountsFile(t *testing.T) string {
+	return filepath.Join(t.TempDir(), "accounts.json")
+}
+
+func TestSeen(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountsFile = ""
+	config.RoomsFile = ""
+	addr := setupTestServerWithConfig(t, config)
+	join := func(name string) *TestClient {
+		c, err := newTestClient(t, addr)
+		if err != nil {
+			t.Fatalf("Client connection failed: %v", err)
+		}
+		c.sendMessage(name)
+		if err := c.expectMessage(t, name+" joined"); err != nil {
+			t.Fatalf("Join as %s failed: %v", name, err)
+		}
+		return c
+	}
+	alice := join("Alice")
+	bob := join("Bob")
+	defer bob.close()
+
+	alice.sendMessage("brb, coffee")
+	if err := bob.expectMessage(t, "brb, coffee"); err != nil {
+		t.Fatalf("message not delivered: %v", err)
+	}
+	bob.sendMessage("/seen alice")
+	if err := bob.expectMessage(t, "Alice is online in general"); err != nil {
+		t.Fatalf("online user not reported: %v", err)
+	}
+
+	alice.sendMessage("/quit")
+	if err := bob.expectMessage(t, "Alice has left"); err != nil {
+		t.Fatalf("quit not seen: %v", err)
+	}
+	alice.close()
+	bob.sendMessage("/seen Alice")
+	for _, want := range []string{"Alice was last seen 0s ago", "Last said in general 0s ago: brb, coffee"} {
+		if err := bob.expectMessage(t, want); err != nil {
+			t.Fatalf("missing %q: %v", want, err)
+		}
+	}
+
+	bob.sendMessage("/seen Nobody")
+	if err := bob.expectMessage(t, "Nobody has not been seen here"); err != nil {
+		t.Fatalf("unknown user not reported: %v", err)
+	}
+}
+
+func TestSeenRecords(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountsFile = ""
+	config.RoomsFile = ""
+	config.Store = StoreMemory
+	s := NewServerWithConfig(config)
+	defer s.Logfile.Close()
+
+	// What is said in a password-protected room is not repeated, and an
+	// unregistered nickname gets no account
+	alice := newPipeClient(t, "Alice")
+	if err := s.createRoom(alice, "vault", "hunter22", ""); err != nil {
+		t.Fatalf("createRoom failed: %v", err)
+	}
+	s.postChat(alice, Message{Type: MessageTypeChat, From: "Alice", Content: "the code is 1234", Timestamp: time.Now()})
+	s.recordSeen(alice)
+	if _, ok := s.accounts.get("Alice"); ok {
+		t.Error("unregistered nickname saved to the accounts")
+	}
+	if guest, ok := s.guestsSeen["alice"]; !ok || guest.seen.Said != "" {
+		t.Errorf("expected a record without the private message, got %+v", guest)
+	}
+
+	s.accounts.update("Bob", func(a *Account) error {
+		a.PasswordHash = "registered"
+		return nil
+	})
+	bob := newPipeClient(t, "Bob")
+	if err := s.createRoom(bob, "open", "", ""); err != nil {
+		t.Fatalf("createRoom failed: %v", err)
+	}
+	s.postChat(bob, Message{Type: MessageTypeChat, From: "Bob", Content: "hello all", Timestamp: time.Now()})
+	s.recordSeen(bob)
+	if account, _ := s.accounts.get("Bob"); account.Seen == nil || account.Seen.Said != "hello all" {
+		t.Errorf("registered nickname's last message not saved: %+v", account.Seen)
+	}
+
+	for i := 0; i < maxGuestsSeen+10; i++ {
+		s.recordGuestSeen(fmt.Sprintf("Guest%d", i), SeenInfo{At: time.Unix(int64(i+1), 0)})
+	}
+	if len(s.guestsSeen) != maxGuestsSeen {
+		t.Errorf("expected %d guest records, got %d", maxGuestsSeen, len(s.guestsSeen))
+	}
+	if _, ok := s.guestsSeen["guest0"]; ok {
+		t.Error("oldest guest record not forgotten")
+	}
+}
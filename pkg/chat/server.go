@@ -0,0 +1,1257 @@
+package chat
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CommandFunc represents a command handler function
+type CommandFunc func(s *Server, c *Client, args []string) error
+
+// Server represents the chat server
+type Server struct {
+	clients     *clientRegistry
+	mutex       sync.RWMutex // Guards the server maps; taken before any room's mutex
+	store       Store
+	maxClients  int
+	Logfile     *os.File     // Config.LogFile, nil for stderr or a logger given to New
+	log         *slog.Logger // Activity log, to Logfile unless set by WithLogger
+	rooms       map[string]*ChatRoom
+	commands    map[string]CommandFunc
+	aliases     map[string]string // Alias name to command line, guarded by mutex
+	port        string
+	config      *Config
+	instanceID  string
+	cluster     ClusterBus
+	rosters     map[string]clusterRoster // Clients of other instances by instance ID, guarded by mutex
+	presence    chan struct{}            // Signals a change of the local roster to sharePresence
+	bridges     map[string][]*roomBridge
+	accounts    *accountStore
+	emails      *emailNotifier
+	bans        *banList
+	motd        *motdFile
+	unfurler    *unfurler // Nil unless link previews are enabled
+	auditLog    *auditLog
+	stats       serverStats
+	messageIDs  messageIDs
+	privateID   int64                          // Last private message ID, guarded by mutex
+	inviteCodes map[string]*inviteCode         // Room invite codes, guarded by mutex
+	guestsSeen  map[string]guestSeen           // Last seen of unregistered nicknames, guarded by mutex
+	onRecord    func(room string, msg Message) // Set by the server UI to show room traffic
+	startTime   time.Time
+	listeners   []net.Listener
+	proxies     *ipFilter // Load balancers allowed to send PROXY headers
+	closing     bool
+	signals     bool           // Set by WithSignalHandling
+	connsByIP   map[string]int // Open connections per remote IP
+
+	handlers     sync.WaitGroup     // Running connection handlers, waited for at shutdown
+	workers      sync.WaitGroup     // Background workers started by serve, waited for at shutdown
+	ctx          context.Context    // Context of the workers, guarded by mutex
+	cancel       context.CancelFunc // Cancels ctx, guarded by mutex
+	shutdownOnce sync.Once
+	stopped      chan struct{} // Closed once Shutdown has finished
+}
+
+// Logo constant
+const Logo = `Welcome to TCP-Chat!
+         _nnnn_
+        dGGGGMMb
+       @p~qp~~qMb
+       M|@||@) M|
+       @,----.JM|
+      JS^\__/  qKL
+     dZP        qKRb
+    dZP          qKKb
+   fZP            SMMb
+   HZM            MMMM
+   FqM            MMMM
+ __| ".        |\dS"qML
+ |    '.       | '\ \Zq
+_)      \.___.,|     .'
+\____   )MMMMMP|   .'
+     '-'       '--'
+[ENTER YOUR NAME]:`
+
+func NewServer() *Server {
+	return NewServerWithConfig(DefaultConfig())
+}
+
+func NewServerWithConfig(config *Config) *Server {
+	return newServer(options{config: config})
+}
+
+func newServer(o options) *Server {
+	config := o.config
+	var Logfile *os.File
+	logger := o.logger
+	if logger == nil {
+		var out io.Writer = os.Stderr
+		var openErr error
+		if config.LogFile != "" {
+			Logfile, openErr = os.OpenFile(config.LogFile,
+				os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+			if openErr == nil {
+				out = Logfile
+			}
+		}
+		var err error
+		logger, err = newLogger(out, config)
+		if openErr != nil {
+			logger.Error("Error opening log file", "err", openErr)
+		}
+		if err != nil {
+			logger.Warn("Invalid log settings", "err", err)
+		}
+	}
+
+	s := &Server{
+		clients:     newClientRegistry(),
+		maxClients:  config.MaxClients,
+		Logfile:     Logfile,
+		log:         logger,
+		rooms:       make(map[string]*ChatRoom),
+		commands:    make(map[string]CommandFunc),
+		aliases:     maps.Clone(defaultAliases),
+		config:      config,
+		instanceID:  newInstanceID(),
+		presence:    make(chan struct{}, 1),
+		bridges:     make(map[string][]*roomBridge),
+		startTime:   time.Now(),
+		connsByIP:   make(map[string]int),
+		inviteCodes: make(map[string]*inviteCode),
+		guestsSeen:  make(map[string]guestSeen),
+		stopped:     make(chan struct{}),
+		signals:     o.signals,
+	}
+
+	store := o.store
+	if store == nil {
+		var err error
+		if store, err = OpenStore(config); err != nil {
+			logger.Error("Error opening store, keeping data in memory", "store", config.Store, "err", err)
+			store = NewMemoryStore()
+		}
+	}
+	s.store = store
+
+	// Create the default rooms and bring back rooms from previous runs
+	s.rooms[s.lobby()] = newChatRoom(s.lobby())
+	for _, name := range config.DefaultRooms {
+		if _, exists := s.rooms[name]; !exists {
+			s.rooms[name] = newChatRoom(name)
+		}
+	}
+	if err := s.restoreRooms(); err != nil {
+		s.log.Error("Error loading rooms", "err", err)
+	}
+
+	// Join the cluster, falling back to standalone mode on failure
+	cluster, err := newClusterBus(config, s.log)
+	if err != nil {
+		s.log.Error("Clustering disabled", "err", err)
+	} else if cluster != nil {
+		s.cluster = cluster
+		cluster.Subscribe(s.handleClusterEvent)
+	}
+
+	s.openBridges()
+
+	accounts, err := loadAccountStore(s.store)
+	if err != nil {
+		s.log.Error("Error loading accounts", "err", err)
+	}
+	s.accounts = accounts
+	s.emails = newEmailNotifier(config, s.log)
+
+	bans, err := loadBanList(config.BanFile)
+	if err != nil {
+		s.log.Error("Error loading ban list", "err", err)
+	}
+	s.bans = bans
+	s.motd = newMOTDFile(config.MOTDFile, s.log)
+	if config.UnfurlLinks {
+		s.unfurler = newUnfurler(config.UnfurlAllow)
+	}
+	auditLog, err := openAuditLog(config.AuditFile)
+	if err != nil {
+		s.log.Error("Error opening audit log", "err", err)
+	}
+	s.auditLog = auditLog
+
+	// Register commands
+	s.registerCommands()
+	return s
+}
+
+func (s *Server) registerCommands() {
+	s.commands = map[string]CommandFunc{
+		"help": func(s *Server, c *Client, args []string) error {
+			help := `Available commands:
+/help           - Show this help
+/alias          - List command aliases such as /j for /join
+/list           - List online users
+/nick <name>    - Change your nickname
+/msg <user> <message> - Send private message
+/receipts [on|off] - Get delivery and read receipts for your private messages
+/read [id]      - Mark private messages as read, up to message #id
+/register <password> - Register your current nickname
+/identify <password> - Prove you own a registered nickname
+/who            - Show users in current room
+/roster         - Show your room, the rooms you can see and who is in your room
+/whois <user>   - Show a user's room, join time and idle time
+/seen <user>    - Show when a user was last connected and what they last said
+/motd           - Show the message of the day
+/away [message] - Mark yourself away; /away again to come back
+/quit [message] - Leave the chat
+/color on|off   - Show nicknames and messages in color
+/mentions [on|off] - List recent @mentions, or turn mention notifications on or off
+/notify add|remove <word>, /notify list - Get alerted when anyone says a keyword
+/react <id> <emoji> - React to message #id of the room
+/quote <id> <text> - Reply to message #id, quoting the start of it
+/join <room> [password] - Join a room; /join #code redeems an invite code
+/leave          - Leave your room and return to the lobby
+/invite <user>  - Invite a user to your room (room operators)
+/invitecode <room> [duration] - Create a code that admits anyone to a room, once or until it expires (room operators)
+/inviteonly on|off - Only admit invited users to your room (room operators)
+/hidden on|off  - Leave your room out of /rooms; others join by exact name (room operators)
+/ephemeral on|off - Stop recording and replaying your room's messages (room operators)
+/slowmode [seconds|off] - Show or set the minimum time between a user's messages (room operators)
+/create <room> [password] [-- description] - Create a room, optionally password protected
+/rooms          - List rooms
+/topic [text]   - Show or set the room topic (room operators)
+/op <user>      - Make a user an operator of your room (room operators)
+/deop <user>    - Take operator status away (room operators)
+/kickroom <user> [reason] - Send a user back to the lobby (room operators)
+/history [N]    - Show the last N messages of the room; /history more pages back
+/search <terms> - Search the room's history; /search more for the next page
+/ignore [user]  - Stop receiving a user's messages, or list ignored users
+/unignore <user> - Receive a user's messages again
+/stats          - Show server statistics
+/kick <user> [reason] - Disconnect a user (moderators)
+/mute <user> <duration> [room] - Silence a user server-wide or in one room (moderators)
+/unmute <user>  - Lift a mute (moderators)
+/ban <user|ip> [reason] - Ban a user's address (moderators)
+/unban <ip>     - Lift a ban (moderators)
+/promote <user> <role> - Grant moderator/admin/owner below your own role
+/demote <user> [role]  - Lower a user's role (default: user)
+/auditlog [count] - Show recent moderation actions (admins)
+/export <room> [json|csv] - Write a room's history to a file (admins)
+/searchall <terms> - Search the history of every room (admins)
+/delete <room>  - Delete a room and its history (admins)
+/archive <room> - Make a room read-only, keeping its history (admins)
+/unarchive <room> - Reopen an archived room (admins)
+/alias <name> <command> [args] - Define an alias (admins)
+/unalias <name> - Remove an alias (admins)
+/shutdown       - Stop the server (admins)
+/setlimit [clients] - Show or change how many clients may connect (admins)
+/reload         - Re-read the ban list, accounts, rooms and MOTD from disk (admins)
+/bottoken add|revoke <name>, /bottoken list - Manage bot tokens (admins)
+/sshkey add <key>, /sshkey list|clear - Manage SSH keys for your account
+/email <address> - Set the address for offline notifications
+/verify <code>  - Confirm your email address
+/emailnotify off|immediate|digest - Email PMs and mentions while offline
+`
+			if _, ok := s.commands["roll"]; ok {
+				help += funHelp
+			}
+			c.write([]byte(help))
+			return nil
+		},
+
+		"list": func(s *Server, c *Client, args []string) error {
+			s.mutex.RLock()
+			var users []string
+			for _, client := range s.clients.all() {
+				entry := fmt.Sprintf("%s (in %s)", client.name, s.roomNameFor(client.room, c))
+				if client.bot {
+					entry += " [bot]"
+				}
+				if client.away != "" {
+					entry += " [away]"
+				}
+				users = append(users, entry)
+			}
+			// Clients of other cluster instances
+			for _, m := range s.remoteMembers("", time.Now()) {
+				room := s.roomNameFor(m.Room, c)
+				if m.Room == "" {
+					room = "a hidden room"
+				}
+				entry := fmt.Sprintf("%s (in %s) [remote]", m.Name, room)
+				if m.Away {
+					entry += " [away]"
+				}
+				users = append(users, entry)
+			}
+			s.mutex.RUnlock()
+			response := fmt.Sprintf("Online users (%d):\n%s\n",
+				len(users), strings.Join(users, "\n"))
+			c.write([]byte(response))
+			return nil
+		},
+
+		"nick": func(s *Server, c *Client, args []string) error {
+			if len(args) < 1 {
+				return fmt.Errorf("usage: /nick <new_name>")
+			}
+			if s.config.AuthRequired {
+				return fmt.Errorf("nicknames are tied to accounts on this server")
+			}
+			newName := args[0]
+			if err := s.ValidateName(newName); err != nil {
+				return err
+			}
+			oldName := c.name
+			s.recordSeen(c)
+			s.mutex.Lock()
+			c.name = newName
+			c.identified = false
+			c.keywords.Store(nil)
+			s.mutex.Unlock()
+			s.presenceChanged()
+			if irc, ok := c.conn.(*ircConn); ok {
+				irc.renamed(newName)
+			}
+			s.broadcast(Message{
+				Type:      MessageTypeSystem,
+				Content:   fmt.Sprintf("%s changed name to %s", oldName, newName),
+				Timestamp: time.Now(),
+			}, nil)
+			s.requireIdentify(c)
+			return nil
+		},
+
+		"join": func(s *Server, c *Client, args []string) error {
+			if len(args) < 1 {
+				return fmt.Errorf("usage: /join <room> [password] or /join #code")
+			}
+			password := ""
+			if len(args) > 1 {
+				password = args[1]
+			}
+			// #CODE redeems an invite code, unless a room has that name
+			s.mutex.Lock()
+			_, isRoom := s.rooms[args[0]]
+			s.mutex.Unlock()
+			if strings.HasPrefix(args[0], "#") && !isRoom {
+				return s.redeemInviteCode(c, args[0])
+			}
+			return s.joinRoom(c, args[0], password)
+		},
+
+		"create": func(s *Server, c *Client, args []string) error {
+			// Everything after "--" describes the room
+			description := ""
+			if i := slices.Index(args, "--"); i >= 0 {
+				description = strings.Join(args[i+1:], " ")
+				args = args[:i]
+			}
+			if len(args) < 1 || len(args) > 2 {
+				return fmt.Errorf("usage: /create <room> [password] [-- description]")
+			}
+			password := ""
+			if len(args) > 1 {
+				password = args[1]
+			}
+			return s.createRoom(c, args[0], password, description)
+		},
+
+		"quit": func(s *Server, c *Client, args []string) error {
+			s.mutex.Lock()
+			c.quit = true
+			if len(args) > 0 {
+				c.leaveReason = "quit: " + strings.Join(args, " ")
+			}
+			s.mutex.Unlock()
+			c.write([]byte("Goodbye!\n"))
+			return nil
+		},
+
+		"leave":      leaveCommand,
+		"invite":     inviteCommand,
+		"invitecode": inviteCodeCommand,
+		"op":         opCommand,
+		"deop":       deopCommand,
+		"kickroom":   kickRoomCommand,
+		"inviteonly": inviteOnlyCommand,
+		"hidden":     hiddenCommand,
+		"ephemeral":  ephemeralCommand,
+		"slowmode":   slowModeCommand,
+		"delete":     deleteCommand,
+		"archive":    archiveCommand,
+		"unarchive":  unarchiveCommand,
+
+		"rooms": func(s *Server, c *Client, args []string) error {
+			return s.listRooms(c)
+		},
+
+		"topic":     topicCommand,
+		"history":   historyCommand,
+		"search":    searchCommand,
+		"searchall": searchAllCommand,
+
+		"msg": func(s *Server, c *Client, args []string) error {
+			if len(args) < 2 {
+				return fmt.Errorf("usage: /msg <user> <message>")
+			}
+			return s.sendPrivateMessage(c, args[0], strings.Join(args[1:], " "))
+		},
+
+		"who": func(s *Server, c *Client, args []string) error {
+			if c.room == "" {
+				return fmt.Errorf("you are not in any room")
+			}
+			s.mutex.RLock()
+			room := s.rooms[c.room]
+			var users []string
+			for _, client := range room.members() {
+				name := client.name
+				if room.ops[strings.ToLower(name)] {
+					name = "@" + name
+				}
+				users = append(users, name)
+			}
+			for _, m := range s.remoteMembers(c.room, time.Now()) {
+				users = append(users, m.Name+" [remote]")
+			}
+			s.mutex.RUnlock()
+			response := fmt.Sprintf("Users in room %s (%d):\n%s\n",
+				c.room, len(users), strings.Join(users, ", "))
+			c.write([]byte(response))
+			return nil
+		},
+
+		"whois":       whoisCommand,
+		"roster":      rosterCommand,
+		"away":        awayCommand,
+		"mentions":    mentionsCommand,
+		"notify":      notifyCommand,
+		"seen":        seenCommand,
+		"motd":        motdCommand,
+		"color":       colorCommand,
+		"react":       reactCommand,
+		"alias":       aliasCommand,
+		"unalias":     unaliasCommand,
+		"quote":       quoteCommand,
+		"receipts":    receiptsCommand,
+		"read":        readCommand,
+		"register":    registerCommand,
+		"sshkey":      sshKeyCommand,
+		"identify":    identifyCommand,
+		"ignore":      ignoreCommand,
+		"unignore":    unignoreCommand,
+		"stats":       statsCommand,
+		"kick":        kickCommand,
+		"mute":        muteCommand,
+		"unmute":      unmuteCommand,
+		"ban":         banCommand,
+		"unban":       unbanCommand,
+		"promote":     promoteCommand,
+		"demote":      demoteCommand,
+		"shutdown":    shutdownCommand,
+		"setlimit":    setLimitCommand,
+		"reload":      reloadCommand,
+		"auditlog":    auditLogCommand,
+		"export":      exportCommand,
+		"bottoken":    botTokenCommand,
+		"email":       emailCommand,
+		"verify":      verifyCommand,
+		"emailnotify": emailNotifyCommand,
+	}
+	if !s.config.NoFunCommands {
+		maps.Copy(s.commands, funCommands)
+	}
+}
+
+// logActivity records an event at info level; args are slog key/value
+// pairs such as "client", c.name
+func (s *Server) logActivity(message string, args ...any) {
+	s.log.Info(message, args...)
+}
+
+func (s *Server) broadcast(msg Message, exclude net.Conn) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.deliverToAll(msg, exclude)
+	s.publishCluster("", msg)
+}
+
+// deliverToAll records the message and writes it to every local client
+func (s *Server) deliverToAll(msg Message, exclude net.Conn) {
+	defer s.stats.observeBroadcast(time.Now())
+	s.record("", msg)
+	text := newSharedText(msg)
+	s.clients.each(func(conn net.Conn, client *Client) bool {
+		if conn != exclude {
+			client.sendShared(msg, text)
+		}
+		return true
+	})
+}
+
+func (s *Server) handleCommand(client *Client, message string) bool {
+	if !strings.HasPrefix(message, "/") {
+		return false
+	}
+
+	parts := strings.Fields(message)
+	command := strings.TrimPrefix(parts[0], "/")
+	args := parts[1:]
+	command, args = s.resolveAlias(command, args)
+
+	handler, exists := s.commands[command]
+	if !exists {
+		client.sendMessage(Message{
+			Type:      MessageTypeError,
+			Content:   "Unknown command. Type /help for available commands.",
+			Timestamp: time.Now(),
+		})
+		return true
+	}
+
+	if required, restricted := commandRoles[command]; restricted && client.role < required {
+		client.sendMessage(Message{
+			Type:      MessageTypeError,
+			Content:   fmt.Sprintf("permission denied: /%s requires %s", command, required),
+			Timestamp: time.Now(),
+		})
+		return true
+	}
+
+	s.stats.countCommand(command)
+	if err := handler(s, client, args); err != nil {
+		client.sendMessage(Message{
+			Type:      MessageTypeError,
+			Content:   err.Error(),
+			Timestamp: time.Now(),
+		})
+	}
+	return true
+}
+
+func (s *Server) handleConnection(conn net.Conn) {
+	defer conn.Close()
+
+	if s.bans.contains(remoteIP(conn)) {
+		conn.Write([]byte("You are banned from this server.\n"))
+		s.stats.rejectedJoins.Add(1)
+		s.log.Warn("Rejected banned address", "remote", remoteIP(conn))
+		return
+	}
+
+	reader := bufio.NewReader(conn)
+	if s.config.JoinChallenge && !s.joinChallenge(conn, reader) {
+		s.stats.rejectedJoins.Add(1)
+		return
+	}
+
+	// Send welcome message
+	_, err := conn.Write([]byte(Logo))
+	if err != nil {
+		s.log.Debug("Error sending logo", "remote", remoteIP(conn), "err", err)
+		return
+	}
+
+	// Get and validate client name
+	var name string
+	var bot, identified bool
+	var resume map[string]int64
+	failedLogins := 0
+	for {
+		nameBytes, err := reader.ReadString('\n')
+		if err != nil {
+			s.log.Debug("Error reading name", "remote", remoteIP(conn), "err", err)
+			return
+		}
+
+		// Load balancer probes are answered before they become clients
+		if strings.TrimSpace(nameBytes) == healthProbe {
+			conn.Write([]byte(s.healthLine()))
+			return
+		}
+
+		if _, ok := conn.(*jsonConn); !ok && strings.EqualFold(strings.TrimSpace(nameBytes), protoJSON) {
+			jc := newJSONConn(conn, reader)
+			jc.writeEvent(jsonEvent{Type: "proto", Content: "json", Timestamp: time.Now()})
+			conn, reader = jc, bufio.NewReader(jc)
+			conn.Write([]byte("[ENTER YOUR NAME]:"))
+			continue
+		}
+
+		if strings.HasPrefix(nameBytes, resumePrefix) {
+			room, after, err := parseResume(nameBytes)
+			if err != nil {
+				conn.Write([]byte(err.Error() + "\n[ENTER YOUR NAME]:"))
+				continue
+			}
+			if resume == nil {
+				resume = make(map[string]int64)
+			}
+			resume[room] = after
+			conn.Write([]byte(fmt.Sprintf("Resuming %s after #%d\n[ENTER YOUR NAME]:", room, after)))
+			continue
+		}
+
+		if strings.HasPrefix(nameBytes, botPrefix) {
+			name, err = s.authenticateBot(conn, nameBytes)
+			if err != nil {
+				conn.Write([]byte(fmt.Sprintf("Bot login failed: %s\n", err)))
+				return
+			}
+			bot = true
+			break
+		}
+
+		name, err = s.applySanitizePolicy(strings.TrimSpace(nameBytes))
+		if err != nil {
+			conn.Write([]byte(fmt.Sprintf("Invalid name: %s\nPlease enter another name: ", err)))
+			continue
+		}
+		if err := s.ValidateName(name); err != nil {
+			conn.Write([]byte(fmt.Sprintf("Invalid name: %s\nPlease enter another name: ", err)))
+			continue
+		}
+
+		// SSH sessions that logged in with a key or password already
+		// proved they own the account
+		if account := sshIdentity(conn); account != "" && strings.EqualFold(account, name) {
+			name, identified = account, true
+			break
+		}
+
+		if s.config.AuthRequired {
+			account, err := s.authenticate(conn, reader, name)
+			if err == errAuthFailed {
+				failedLogins++
+				s.log.Warn("Failed login", "client", name, "remote", remoteIP(conn))
+				if failedLogins >= s.config.AuthMaxAttempts {
+					conn.Write([]byte("Too many failed login attempts. Goodbye.\n"))
+					s.stats.rejectedJoins.Add(1)
+					return
+				}
+				conn.Write([]byte("Authentication failed\nPlease enter your name: "))
+				continue
+			}
+			if err != nil {
+				s.log.Debug("Error reading password", "client", name, "remote", remoteIP(conn), "err", err)
+				return
+			}
+			name = account
+		}
+		break
+	}
+
+	// The flood limits change on reload
+	s.mutex.RLock()
+	flood := newRateLimiter(s.config.FloodBurst, s.config.FloodWindow)
+	if bot {
+		flood = newRateLimiter(s.config.BotFloodBurst, s.config.BotFloodWindow)
+	}
+	s.mutex.RUnlock()
+	client := &Client{
+		conn:       conn,
+		name:       name,
+		joinTime:   time.Now(),
+		role:       s.configuredRole(name),
+		bot:        bot,
+		flood:      flood,
+		lastActive: time.Now(),
+		resume:     resume,
+		tasks:      make(chan func()),
+		done:       make(chan struct{}),
+	}
+	client.startWriter(s.config.FlushInterval, s.log)
+
+	if irc, ok := conn.(*ircConn); ok {
+		irc.welcome(name)
+	} else {
+		s.sendMOTD(client)
+	}
+
+	// Add client to server and default room
+	s.clients.add(conn, client)
+
+	s.logActivity("User joined", clientAttrs(client)...)
+
+	// Join default room
+	s.joinRoom(client, s.lobby(), "")
+	if identified {
+		s.markIdentified(client)
+	}
+	s.requireIdentify(client)
+	if s.config.AuthRequired || bot || identified {
+		s.cacheKeywords(client)
+		s.deliverInbox(client)
+	}
+
+	// Lines are read on a goroutine of their own so that timers can hand
+	// work, like renaming an unidentified client, to this one
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			select {
+			case lines <- line:
+			case <-client.done:
+				return
+			}
+		}
+	}()
+
+	// Message handling loop
+	for {
+		message, ok := client.nextLine(lines)
+		if !ok {
+			break
+		}
+
+		s.touch(client)
+		message, err = s.applySanitizePolicy(strings.TrimSpace(message))
+		if err != nil {
+			client.sendMessage(Message{
+				Type:      MessageTypeError,
+				Content:   "Message rejected: " + err.Error(),
+				Timestamp: time.Now(),
+			})
+			continue
+		}
+		message = strings.TrimSpace(message)
+		if message == "" {
+			continue
+		}
+
+		allowed, keep := s.checkFlood(client)
+		if !keep {
+			break
+		}
+		if !allowed {
+			continue
+		}
+		s.stats.messages.Add(1)
+
+		// Handle commands
+		if s.handleCommand(client, message) {
+			if client.quit {
+				break
+			}
+			continue
+		}
+
+		// Regular message handling
+		if err := s.postChat(client, Message{
+			Type:      MessageTypeChat,
+			From:      client.name,
+			Content:   message,
+			Timestamp: time.Now(),
+		}); err != nil {
+			client.sendMessage(Message{
+				Type:      MessageTypeError,
+				Content:   err.Error(),
+				Timestamp: time.Now(),
+			})
+		}
+	}
+
+	// Handle disconnection, writing out what is still queued first
+	close(client.done)
+	client.flush()
+	s.clients.remove(conn)
+	s.mutex.Lock()
+	if client.muteTimer != nil {
+		client.muteTimer.Stop()
+	}
+	if client.identifyTimer != nil {
+		client.identifyTimer.Stop()
+	}
+	if client.room != "" {
+		if room, exists := s.rooms[client.room]; exists {
+			room.removeClient(conn)
+		}
+	}
+	s.mutex.Unlock()
+	s.recordSeen(client)
+	s.presenceChanged()
+
+	leave := fmt.Sprintf("%s has left our chat...", client.name)
+	if client.leaveReason != "" {
+		leave += " (" + client.leaveReason + ")"
+	}
+	s.broadcast(Message{
+		Type:      MessageTypeSystem,
+		Content:   leave,
+		Timestamp: time.Now(),
+	}, nil)
+	s.logActivity("User left", clientAttrs(client)...)
+}
+
+// nextLine waits for the next line from the connection, running the work
+// handed to c in the meantime. It returns false once the connection is
+// closed.
+func (c *Client) nextLine(lines <-chan string) (string, bool) {
+	for {
+		select {
+		case task := <-c.tasks:
+			task()
+		case line, ok := <-lines:
+			return line, ok
+		}
+	}
+}
+
+// run hands f to the goroutine serving c, which runs it between messages;
+// f is dropped once the connection has ended
+func (c *Client) run(f func()) {
+	select {
+	case c.tasks <- f:
+	case <-c.done:
+	}
+}
+
+// postChat sends a chat message written by c to c's room, unless c is muted
+func (s *Server) postChat(c *Client, msg Message) error {
+	s.mutex.Lock()
+	muted := s.muteRemaining(c, c.room)
+	room := s.rooms[c.room]
+	frozen := room != nil && s.frozenFor(room, c)
+	var wait time.Duration
+	if muted == 0 && room != nil && !frozen {
+		wait = s.slowModeWait(c, room, time.Now())
+	}
+	s.mutex.Unlock()
+	if muted > 0 {
+		return fmt.Errorf("You are muted for another %s", muted)
+	}
+	if room == nil {
+		return nil
+	}
+	if frozen {
+		return errArchived(room.name)
+	}
+	if wait > 0 {
+		return fmt.Errorf("slow mode is on in %s: wait %s before posting again", room.name, wait)
+	}
+
+	s.mutex.Lock()
+	c.lastMessage = msg.Timestamp
+	if room.public() {
+		c.lastText, c.lastRoom = msg.Content, room.name
+	}
+	c.sent++
+	s.mutex.Unlock()
+	// IRC clients show their own messages locally
+	var exclude net.Conn
+	if _, ok := c.conn.(*ircConn); ok {
+		exclude = c.conn
+	}
+	s.broadcastToRoom(room, msg, exclude)
+	// Offline notifications would keep a copy of the message
+	if !room.ephemeral {
+		s.notifyMentions(room.name, msg)
+	}
+	s.deliverMentions(room.name, msg)
+	s.deliverKeywords(room.name, msg)
+	if s.unfurler != nil {
+		// Shutdown waits for the fetch, so it is only started while the
+		// server is still running
+		s.mutex.RLock()
+		if ctx := s.ctx; ctx != nil && !s.closing {
+			s.goWorker(func() { s.unfurlLinks(ctx, room, msg) })
+		}
+		s.mutex.RUnlock()
+	}
+	s.replyAwayMentions(msg)
+	return nil
+}
+
+// listenAddress turns a bare port into ":port" and picks tcp4 or tcp6 for
+// literal IPs, so 0.0.0.0:8989 and [::]:8989 can be bound separately for
+// an explicit dual-stack setup. Hostnames and an empty host use tcp.
+func listenAddress(addr string) (network, address string, err error) {
+	if !strings.Contains(addr, ":") {
+		addr = ":" + addr
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid listen address %q: %v", addr, err)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+		return "", "", fmt.Errorf("invalid port in listen address %q", addr)
+	}
+	network = "tcp"
+	if ip := net.ParseIP(host); ip != nil {
+		network = "tcp6"
+		if ip.To4() != nil {
+			network = "tcp4"
+		}
+	}
+	return network, addr, nil
+}
+
+// listen opens the chat listener, wrapped in TLS when a certificate is configured
+func (s *Server) listen(addr string) (net.Listener, error) {
+	network, addr, err := listenAddress(addr)
+	if err != nil {
+		return nil, err
+	}
+	if s.config.TLSCert == "" {
+		return net.Listen(network, addr)
+	}
+
+	cert, err := tls.LoadX509KeyPair(s.config.TLSCert, s.config.TLSKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %v", err)
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if !s.config.TLSOptional && !s.config.ProxyProtocol {
+		return tls.Listen(network, addr, config)
+	}
+	// Optional TLS is sniffed per connection. Behind PROXY protocol the
+	// header comes before the handshake, so TLS starts once it is read.
+	listener, err := net.Listen(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	return &autoTLSListener{Listener: listener, config: config, required: !s.config.TLSOptional}, nil
+}
+
+// Start serves chat clients on addr, which is a port ("8989") or a full
+// listen address ("127.0.0.1:8989", "[::1]:8989"), plus every address in
+// Config.Listen. It blocks until the server shuts down.
+func (s *Server) Start(addr string) error {
+	return s.StartContext(context.Background(), addr)
+}
+
+// StartContext is Start with a context: cancelling ctx shuts the server
+// down, and StartContext returns once it has
+func (s *Server) StartContext(ctx context.Context, addr string) error {
+	var listeners []net.Listener
+	for _, a := range append([]string{addr}, s.config.Listen...) {
+		listener, err := s.listen(a)
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return fmt.Errorf("failed to start server: %v", err)
+		}
+		listeners = append(listeners, listener)
+	}
+	return s.serve(ctx, listeners)
+}
+
+// Serve accepts chat clients from l, which the caller has opened: a TCP
+// listener, a unix socket or an in-memory listener in tests. Config.Listen
+// and Config.TLSCert are not consulted. It blocks until the server shuts
+// down and closes l.
+func (s *Server) Serve(l net.Listener) error {
+	return s.ServeContext(context.Background(), l)
+}
+
+// ServeContext is Serve with a context, like StartContext
+func (s *Server) ServeContext(ctx context.Context, l net.Listener) error {
+	return s.serve(ctx, []net.Listener{l})
+}
+
+// serve runs the server on listeners, of which there is at least one,
+// closing them when it returns
+func (s *Server) serve(ctx context.Context, listeners []net.Listener) error {
+	defer func() {
+		for _, l := range listeners {
+			l.Close()
+		}
+	}()
+	filter, err := newIPFilter(s.config.AllowNetworks, s.config.DenyNetworks)
+	if err != nil {
+		return fmt.Errorf("failed to start server: %v", err)
+	}
+	if s.proxies, err = newIPFilter(s.config.ProxyTrusted, nil); err != nil {
+		return fmt.Errorf("failed to start server: %v", err)
+	}
+
+	_, port, _ := net.SplitHostPort(listeners[0].Addr().String())
+	// Cancelling ctx shuts the server down. Everything started below runs
+	// under a context of its own that Shutdown cancels once the clients
+	// have been told.
+	stop := context.AfterFunc(ctx, s.Shutdown)
+	defer stop()
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancel()
+	s.mutex.Lock()
+	s.port = port
+	s.listeners = listeners
+	s.ctx, s.cancel = ctx, cancel
+	s.mutex.Unlock()
+
+	for _, l := range listeners {
+		fmt.Printf("Listening on %s\n", l.Addr())
+		s.logActivity("Server started", "addr", l.Addr().String())
+	}
+
+	if s.config.HTTPAddr != "" {
+		go s.serveHTTP(ctx, s.config.HTTPAddr)
+	}
+	if s.config.WSAddr != "" {
+		go s.serveWebSocket(ctx, s.config.WSAddr, filter)
+	}
+	if s.config.IRCAddr != "" {
+		go s.serveIRC(ctx, s.config.IRCAddr, filter)
+	}
+	if s.config.SSHAddr != "" {
+		go s.serveSSH(ctx, s.config.SSHAddr, filter)
+	}
+	if s.config.AdminAddr != "" {
+		go s.serveAdminHTTP(ctx, s.config.AdminAddr)
+	}
+	if s.config.ConsoleAddr != "" {
+		go s.serveConsole(ctx, s.config.ConsoleAddr)
+	}
+	if s.config.IdleTimeout > 0 {
+		s.goWorker(func() { s.reapIdleClients(ctx) })
+	}
+	if s.config.AutoAway > 0 {
+		s.goWorker(func() { s.watchAutoAway(ctx) })
+	}
+	if s.config.RoomIdleTimeout > 0 {
+		s.goWorker(func() { s.pruneRooms(ctx) })
+	}
+	if s.retentionEnabled() && s.config.RetentionInterval > 0 {
+		s.goWorker(func() { s.retentionLoop(ctx) })
+	}
+	if s.emails.enabled() && s.config.DigestInterval > 0 {
+		s.goWorker(func() { s.emails.digestLoop(ctx) })
+	}
+	if s.config.Announce || s.config.Beacon {
+		name := s.config.ServerName
+		if name == "" {
+			name, _ = os.Hostname()
+		}
+		if s.config.Announce {
+			s.goWorker(func() { s.announceMDNS(ctx, name, port) })
+		}
+		if s.config.Beacon {
+			s.goWorker(func() { s.sendBeacons(ctx, name, port) })
+		}
+	}
+
+	if s.cluster != nil {
+		s.goWorker(func() { s.sharePresence(ctx) })
+	}
+	s.runBridges(ctx)
+
+	if s.signals {
+		go s.handleSignals(ctx)
+	}
+
+	for _, l := range listeners[1:] {
+		go s.acceptLoop(ctx, l, filter)
+	}
+	s.acceptLoop(ctx, listeners[0], filter)
+	// The loop ends at shutdown; let it finish saving before returning
+	<-s.stopped
+	return nil
+}
+
+// goWorker runs f, a background worker that returns once the context
+// of serve is cancelled, on a goroutine that shutdown waits for
+func (s *Server) goWorker(f func()) {
+	s.workers.Add(1)
+	go func() {
+		defer s.workers.Done()
+		f()
+	}()
+}
+
+// acceptLoop admits connections from listener until the server shuts down
+func (s *Server) acceptLoop(ctx context.Context, listener net.Listener, filter *ipFilter) {
+	auto, _ := listener.(*autoTLSListener)
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			s.log.Error("Failed to accept connection", "err", err)
+			continue
+		}
+
+		if s.config.ProxyProtocol {
+			go s.serveProxied(ctx, conn, filter, auto)
+			continue
+		}
+		if auto != nil {
+			go s.serveAutoTLS(ctx, conn, filter, auto)
+			continue
+		}
+		if s.admit(conn, filter) {
+			go s.serveConn(ctx, newTelnetConn(s.meter(conn)))
+		}
+	}
+}
+
+// serveProxied reads the PROXY header of a connection from a load balancer
+// so bans, limits and logs see the real client address. The client's TLS
+// handshake, if any, follows the header.
+func (s *Server) serveProxied(ctx context.Context, conn net.Conn, filter *ipFilter, auto *autoTLSListener) {
+	if !s.proxies.permits(remoteIP(conn)) {
+		s.log.Warn("Rejected connection: not a trusted proxy", "remote", remoteIP(conn))
+		s.stats.rejectedJoins.Add(1)
+		conn.Close()
+		return
+	}
+	proxied, err := acceptProxy(conn)
+	if err != nil {
+		s.log.Warn("Rejected connection", "remote", remoteIP(conn), "err", err)
+		s.stats.rejectedJoins.Add(1)
+		conn.Close()
+		return
+	}
+	proxied = auto.upgrade(proxied)
+	if s.admit(proxied, filter) {
+		s.serveConn(ctx, newTelnetConn(s.meter(proxied)))
+	}
+}
+
+// admit applies the address filter and connection limits to a new
+// connection, closing it if it is refused. Every transport calls it before
+// serveConn.
+func (s *Server) admit(conn net.Conn, filter *ipFilter) bool {
+	ip := remoteIP(conn)
+	if !filter.permits(ip) {
+		s.stats.rejectedJoins.Add(1)
+		conn.Close()
+		s.log.Warn("Rejected connection: address not permitted", "remote", ip)
+		return false
+	}
+
+	s.mutex.Lock()
+	if s.clients.len() >= s.maxClients {
+		s.mutex.Unlock()
+		conn.Write([]byte("Chat is full. Please try again later.\n"))
+		conn.Close()
+		s.stats.rejectedJoins.Add(1)
+		return false
+	}
+	if limit := s.config.MaxConnsPerIP; limit > 0 && s.connsByIP[ip] >= limit {
+		s.mutex.Unlock()
+		conn.Write([]byte(fmt.Sprintf("Too many connections from your address (limit %d). Please close another session first.\n",
+			limit)))
+		conn.Close()
+		s.stats.rejectedJoins.Add(1)
+		s.log.Warn("Rejected connection: per-IP limit reached", "remote", ip)
+		return false
+	}
+	s.connsByIP[ip]++
+	s.mutex.Unlock()
+	return true
+}
+
+// serveConn runs an admitted connection to completion. Cancelling ctx
+// interrupts its read so the handler winds down and flushes the client.
+func (s *Server) serveConn(ctx context.Context, conn net.Conn) {
+	s.mutex.Lock()
+	if s.closing || ctx.Err() != nil {
+		s.mutex.Unlock()
+		conn.Close()
+		s.releaseIP(remoteIP(conn))
+		return
+	}
+	s.handlers.Add(1)
+	s.mutex.Unlock()
+	defer s.handlers.Done()
+	stop := context.AfterFunc(ctx, func() { conn.SetReadDeadline(time.Now()) })
+	defer stop()
+
+	s.handleConnection(conn)
+	s.releaseIP(remoteIP(conn))
+}
+
+// releaseIP forgets one connection from ip in the per-IP accounting
+func (s *Server) releaseIP(ip string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.connsByIP[ip]--
+	if s.connsByIP[ip] <= 0 {
+		delete(s.connsByIP, ip)
+	}
+}
+
+func (s *Server) sendPrivateMessage(from *Client, toName, content string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if muted := s.muteRemaining(from, ""); muted > 0 {
+		return fmt.Errorf("you are muted for another %s", muted)
+	}
+
+	to := s.clients.find(func(c *Client) bool { return c.name == toName })
+
+	s.privateID++
+	if to == nil {
+		queued, err := s.queueOffline(toName, Message{
+			Type:      MessageTypePrivate,
+			From:      from.name,
+			To:        toName,
+			Content:   content,
+			Timestamp: time.Now(),
+			ID:        s.privateID,
+		})
+		if err != nil {
+			return err
+		}
+		line := fmt.Sprintf("Private message from %s: %s", from.name, content)
+		emailed := s.notifyOffline(toName, line)
+		switch {
+		case queued && emailed:
+			from.write([]byte(fmt.Sprintf("%s is offline; your message will be delivered when they reconnect and they will be notified by email\n", toName)))
+		case queued:
+			from.write([]byte(fmt.Sprintf("%s is offline; your message will be delivered when they reconnect\n", toName)))
+		case emailed:
+			from.write([]byte(fmt.Sprintf("%s is offline and will be notified by email\n", toName)))
+		default:
+			return fmt.Errorf("user %s not found", toName)
+		}
+		return nil
+	}
+
+	msg := Message{
+		Type:      MessageTypePrivate,
+		From:      from.name,
+		To:        to.name,
+		Content:   content,
+		Timestamp: time.Now(),
+		ID:        s.privateID,
+	}
+
+	// The sender is not told when the recipient ignores them, so an
+	// ignored message still counts as delivered but is never read
+	from.sendMessage(msg)
+	if !to.ignores(from.name) {
+		to.sendMessage(msg)
+		s.privateDelivered(to, msg)
+	} else if s.wantsReceipts(from.name) {
+		s.sendReceipt(msg, receiptDelivered)
+	}
+	s.replyAway(from, to)
+	s.logActivity("Private message", "client", from.name, "target", to.name, "content", content)
+	return nil
+}
@@ -0,0 +1,115 @@
+package chat
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// shutdownTimeout bounds how long Shutdown waits for connection handlers
+// to finish once their clients are disconnected, and for background
+// workers to stop
+const shutdownTimeout = 5 * time.Second
+
+// handleSignals reloads the server's files on SIGHUP and shuts the server
+// down on SIGINT or SIGTERM. Once it has, the default handling is restored,
+// so a second signal kills the process right away.
+func (s *Server) handleSignals(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case sig := <-signals:
+			if sig == syscall.SIGHUP {
+				summary, err := s.reload()
+				s.logActivity("Reloaded on signal", "signal", sig.String(), "summary", summary)
+				if err != nil {
+					s.log.Error("Reload errors", "err", err)
+				}
+				continue
+			}
+			s.logActivity("Shutting down on signal", "signal", sig.String())
+			s.Shutdown()
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Shutdown stops accepting connections, tells every client, disconnects
+// them and saves the server's state. Calling it again waits for the first
+// call to finish.
+func (s *Server) Shutdown() {
+	s.shutdownOnce.Do(s.shutdown)
+	<-s.stopped
+}
+
+func (s *Server) shutdown() {
+	defer close(s.stopped)
+	s.broadcast(Message{
+		Type:      MessageTypeSystem,
+		Content:   "Server is shutting down",
+		Timestamp: time.Now(),
+	}, nil)
+
+	s.mutex.Lock()
+	s.closing = true
+	for _, l := range s.listeners {
+		l.Close()
+	}
+	clients := s.clients.all()
+	cancel := s.cancel
+	s.mutex.Unlock()
+	// Flush the shutdown notice to every client at once
+	var wg sync.WaitGroup
+	for _, c := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.closeConn()
+		}()
+	}
+	wg.Wait()
+	if cancel != nil {
+		// Stops the gateways, background workers and accept loops, and
+		// interrupts connections that never logged in. Only now, as an SSH
+		// session's read deadline also ends its pending writes.
+		cancel()
+	}
+
+	// Let the handlers record their clients leaving, and the background
+	// workers stop, before the store closes
+	stopped := make(chan struct{})
+	go func() {
+		s.handlers.Wait()
+		s.workers.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(shutdownTimeout):
+		s.log.Warn("Gave up waiting for connections and workers to stop", "timeout", shutdownTimeout)
+	}
+
+	s.mutex.Lock()
+	if err := s.saveRooms(); err != nil {
+		s.log.Error("Error saving rooms", "err", err)
+	}
+	s.mutex.Unlock()
+	if err := s.store.Close(); err != nil {
+		s.log.Error("Error closing store", "store", s.config.Store, "err", err)
+	}
+	if s.cluster != nil {
+		s.cluster.Close()
+	}
+	s.logActivity("Server stopped")
+	if s.Logfile != nil {
+		s.Logfile.Sync()
+	}
+}
@@ -0,0 +1,138 @@
+package chat
+
+import (
+	"context"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestShutdownOnSignal(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountsFile = ""
+	config.RoomsFile = ""
+	config.Store = StoreMemory
+	s := New(WithConfig(config), WithSignalHandling())
+	defer s.Logfile.Close()
+	addr := freeAddr(t)
+	done := make(chan error, 1)
+	go func() { done <- s.Start(addr) }()
+	time.Sleep(serverStartDelay)
+
+	alice, err := newTestClient(t, addr)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer alice.close()
+	if err := alice.expectMessage(t, "Welcome"); err != nil {
+		t.Fatalf("No welcome: %v", err)
+	}
+	alice.sendMessage("Alice")
+	alice.sendMessage("/create dev")
+	alice.sendMessage("/rooms")
+	if err := alice.expectMessage(t, "dev (1 users)"); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatalf("kill failed: %v", err)
+	}
+	if err := alice.expectMessage(t, "Server is shutting down"); err != nil {
+		t.Errorf("no shutdown notice: %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Start returned %v after shutdown", err)
+		}
+	case <-time.After(shutdownTimeout + time.Second):
+		t.Fatal("Start did not return after SIGTERM")
+	}
+
+	alice.conn.SetReadDeadline(time.Now().Add(time.Second))
+	for {
+		if _, err := alice.reader.ReadString('\n'); err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				t.Error("Alice still connected after shutdown")
+			}
+			break
+		}
+	}
+	s.Shutdown() // A second call returns at once
+}
+
+func TestStartContextCancel(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountsFile = ""
+	config.RoomsFile = ""
+	config.Store = StoreMemory
+	config.RoomIdleTimeout = time.Minute
+	config.SMTPAddr = "localhost:25"
+	config.DigestInterval = time.Minute
+	s := NewServerWithConfig(config)
+	defer s.Logfile.Close()
+	s.bridges[s.lobby()] = []*roomBridge{{
+		config:  BridgeConfig{Room: s.lobby(), Kind: "test", Token: "t", Channel: "c"},
+		service: nopBridge{},
+		queue:   make(chan outboundBridgeMessage, bridgeQueueSize),
+		log:     s.log,
+	}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	addr := freeAddr(t)
+	done := make(chan error, 1)
+	go func() { done <- s.StartContext(ctx, addr) }()
+	time.Sleep(serverStartDelay)
+
+	alice, err := newTestClient(t, addr)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer alice.close()
+	if err := alice.expectMessage(t, "Welcome"); err != nil {
+		t.Fatalf("No welcome: %v", err)
+	}
+	alice.sendMessage("Alice")
+	alice.sendMessage("/list")
+	if err := alice.expectMessage(t, "Online users (1)"); err != nil {
+		t.Fatalf("Alice did not join: %v", err)
+	}
+
+	cancel()
+	if err := alice.expectMessage(t, "Server is shutting down"); err != nil {
+		t.Errorf("no shutdown notice: %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("StartContext returned %v after cancel", err)
+		}
+	case <-time.After(shutdownTimeout + time.Second):
+		t.Fatal("StartContext did not return after cancel")
+	}
+	if conn, err := net.DialTimeout("tcp", addr, dialTimeout); err == nil {
+		conn.Close()
+		t.Error("still accepting after cancel")
+	}
+
+	// The digest, pruning and bridge workers have stopped as well
+	workersDone := make(chan struct{})
+	go func() {
+		s.workers.Wait()
+		close(workersDone)
+	}()
+	select {
+	case <-workersDone:
+	case <-time.After(time.Second):
+		t.Error("background workers still running after cancel")
+	}
+}
+
+// nopBridge is a bridge service with nothing to say
+type nopBridge struct{}
+
+func (nopBridge) Send(from, content string) error { return nil }
+
+func (nopBridge) Poll() ([]bridgeMessage, error) { return nil, nil }
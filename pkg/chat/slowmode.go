@@ -0,0 +1,78 @@
+package chat
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSlowMode caps the interval /slowmode accepts
+const maxSlowMode = time.Hour
+
+// slowModeWait returns how long c has to wait before posting in room
+// again, rounded up to whole seconds, and otherwise notes the post.
+// Operators are exempt. Caller holds s.mutex.
+func (s *Server) slowModeWait(c *Client, room *ChatRoom, now time.Time) time.Duration {
+	if room.slowMode <= 0 || s.isRoomOperator(room, c) {
+		return 0
+	}
+	name := strings.ToLower(c.name)
+	if wait := room.slowMode - now.Sub(room.lastPost[name]); wait > 0 {
+		return (wait + time.Second - 1).Truncate(time.Second)
+	}
+	room.lastPost[name] = now
+	return 0
+}
+
+// slowModeCommand shows or sets the minimum interval between two messages
+// of one user in the current room; 0 or off turns slow mode off
+func slowModeCommand(s *Server, c *Client, args []string) error {
+	s.mutex.Lock()
+	room, exists := s.rooms[c.room]
+	if !exists {
+		s.mutex.Unlock()
+		return fmt.Errorf("you are not in any room")
+	}
+	if len(args) == 0 {
+		interval := room.slowMode
+		s.mutex.Unlock()
+		if interval == 0 {
+			c.write([]byte(fmt.Sprintf("Slow mode is off in %s\n", room.name)))
+		} else {
+			c.write([]byte(fmt.Sprintf("Slow mode in %s: one message every %s\n", room.name, interval)))
+		}
+		return nil
+	}
+	if !s.isRoomOperator(room, c) {
+		s.mutex.Unlock()
+		return fmt.Errorf("only room operators can change slow mode")
+	}
+	seconds, err := strconv.Atoi(args[0])
+	if strings.EqualFold(args[0], "off") {
+		seconds, err = 0, nil
+	}
+	interval := time.Duration(seconds) * time.Second
+	if err != nil || len(args) != 1 || seconds < 0 || interval > maxSlowMode {
+		s.mutex.Unlock()
+		return fmt.Errorf("usage: /slowmode <seconds>|off, at most %d seconds", int(maxSlowMode.Seconds()))
+	}
+	room.slowMode = interval
+	room.lastPost = make(map[string]time.Time)
+	if err := s.saveRooms(); err != nil {
+		s.log.Error("Error saving rooms", "room", room.name, "err", err)
+	}
+	s.mutex.Unlock()
+
+	state := fmt.Sprintf("turned on slow mode: one message every %s", interval)
+	if interval == 0 {
+		state = "turned off slow mode"
+	}
+	s.logActivity("Room setting changed", "client", c.name, "room", room.name, "setting", state)
+	s.broadcastToRoom(room, Message{
+		Type:      MessageTypeSystem,
+		Content:   fmt.Sprintf("%s %s", c.name, state),
+		Timestamp: time.Now(),
+	}, nil)
+	return nil
+}
@@ -0,0 +1,68 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlowMode(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountsFile = ""
+	config.RoomsFile = ""
+	config.Store = StoreMemory
+	s := NewServerWithConfig(config)
+	defer s.Logfile.Close()
+
+	alice := newPipeClient(t, "Alice")
+	bob := newPipeClient(t, "Bob")
+	if err := s.createRoom(alice, "dev", "", ""); err != nil {
+		t.Fatalf("createRoom failed: %v", err)
+	}
+	if err := s.joinRoom(bob, "dev", ""); err != nil {
+		t.Fatalf("join failed: %v", err)
+	}
+
+	if err := slowModeCommand(s, bob, []string{"30"}); err == nil {
+		t.Error("non-operator turned on slow mode")
+	}
+	for _, arg := range []string{"-1", "soon", "7200"} {
+		if err := slowModeCommand(s, alice, []string{arg}); err == nil {
+			t.Errorf("/slowmode %s accepted", arg)
+		}
+	}
+	if err := slowModeCommand(s, alice, []string{"30"}); err != nil {
+		t.Fatalf("slowmode failed: %v", err)
+	}
+
+	post := func(c *Client, text string) error {
+		return s.postChat(c, Message{Type: MessageTypeChat, From: c.name, Content: text, Timestamp: time.Now()})
+	}
+	if err := post(bob, "first"); err != nil {
+		t.Fatalf("first message refused: %v", err)
+	}
+	err := post(bob, "second")
+	if err == nil || !strings.Contains(err.Error(), "wait 30s before posting again") {
+		t.Errorf("second message: got %v, want a 30s countdown", err)
+	}
+	if err := post(alice, "operators are exempt"); err != nil {
+		t.Errorf("operator slowed down: %v", err)
+	}
+	if err := post(alice, "twice"); err != nil {
+		t.Errorf("operator slowed down: %v", err)
+	}
+
+	s.mutex.Lock()
+	wait := s.slowModeWait(bob, s.rooms["dev"], time.Now().Add(20*time.Second))
+	s.mutex.Unlock()
+	if wait != 10*time.Second {
+		t.Errorf("wait after 20s = %s, want 10s", wait)
+	}
+
+	if err := slowModeCommand(s, alice, []string{"off"}); err != nil {
+		t.Fatalf("slowmode off failed: %v", err)
+	}
+	if err := post(bob, "free again"); err != nil {
+		t.Errorf("message refused after slow mode off: %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+package chat
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// fakeSOCKS5 accepts one connection, checks the credentials and relays it
+// to the requested host, which must be "localhost"
+func fakeSOCKS5(t *testing.T, listener net.Listener) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	buf := make([]byte, 2)
+	io.ReadFull(conn, buf)
+	methods := make([]byte, buf[1])
+	io.ReadFull(conn, methods)
+	conn.Write([]byte{5, socksUserPass})
+
+	io.ReadFull(conn, buf)
+	user := make([]byte, buf[1])
+	io.ReadFull(conn, user)
+	io.ReadFull(conn, buf[:1])
+	password := make([]byte, buf[0])
+	io.ReadFull(conn, password)
+	if string(user) != "alice" || string(password) != "secret" {
+		conn.Write([]byte{1, 1})
+		return
+	}
+	conn.Write([]byte{1, 0})
+
+	header := make([]byte, 5)
+	io.ReadFull(conn, header)
+	if header[3] != socksAtypDomain {
+		t.Errorf("expected a domain address, got type %d", header[3])
+		return
+	}
+	rest := make([]byte, int(header[4])+2)
+	io.ReadFull(conn, rest)
+	host := string(rest[:header[4]])
+	port := binary.BigEndian.Uint16(rest[header[4]:])
+
+	target, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(port))))
+	if err != nil {
+		conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer target.Close()
+	conn.Write([]byte{5, 0, 0, 1, 127, 0, 0, 1, 0, 0})
+	go io.Copy(target, conn)
+	io.Copy(conn, target)
+}
+
+func TestSOCKS5Client(t *testing.T) {
+	// The proxy expects a host name rather than an IP
+	_, port, _ := net.SplitHostPort(setupTestServer(t))
+	addr := net.JoinHostPort("localhost", port)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer listener.Close()
+	go fakeSOCKS5(t, listener)
+
+	conn, err := dialServer(addr, "socks5://alice:secret@"+listener.Addr().String())
+	if err != nil {
+		t.Fatalf("dial through proxy failed: %v", err)
+	}
+	client := &TestClient{conn: conn, reader: bufio.NewReader(conn)}
+	defer client.close()
+	if err := client.expectMessage(t, "Welcome"); err != nil {
+		t.Fatalf("no welcome through proxy: %v", err)
+	}
+}
+
+func TestSOCKS5AuthFailure(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer listener.Close()
+	go fakeSOCKS5(t, listener)
+
+	u, _ := parseProxyURL("socks5://alice:wrong@" + listener.Addr().String())
+	if _, err := dialSOCKS5(u, "localhost:1", time.Second); err == nil {
+		t.Error("expected authentication failure")
+	}
+}
+
+func TestParseProxyURL(t *testing.T) {
+	u, err := parseProxyURL("socks5://127.0.0.1")
+	if err != nil || u.Host != "127.0.0.1:1080" {
+		t.Errorf("expected default port, got %v, %v", u, err)
+	}
+	if _, err := parseProxyURL("http://127.0.0.1:8080"); err == nil {
+		t.Error("expected http proxy to be rejected")
+	}
+}
@@ -0,0 +1,7 @@
+//go:build sqlite
+
+package chat
+
+// Registers the pure-Go "sqlite" database/sql driver used by sqlStore.
+// It is only built with -tags sqlite, so default builds stay small.
+import _ "modernc.org/sqlite"
@@ -0,0 +1,198 @@
+//go:build ssh
+
+package chat
+
+// The SSH gateway is built with -tags ssh and uses golang.org/x/crypto/ssh.
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// sshAccountKey carries the account proven during authentication into the
+// session
+const sshAccountKey = "account"
+
+// serveSSH accepts SSH clients until the listener fails. Each session
+// lands in the chat under its SSH user name.
+func (s *Server) serveSSH(ctx context.Context, addr string, filter *ipFilter) {
+	config, err := s.sshServerConfig()
+	if err != nil {
+		s.log.Error("SSH gateway disabled", "addr", addr, "err", err)
+		return
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		s.log.Error("SSH gateway disabled", "addr", addr, "err", err)
+		return
+	}
+	defer listener.Close()
+	context.AfterFunc(ctx, func() { listener.Close() })
+	s.logActivity("SSH gateway listening", "addr", addr)
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() == nil {
+				s.log.Error("SSH gateway stopped", "addr", addr, "err", err)
+			}
+			return
+		}
+		if s.admit(conn, filter) {
+			go s.handleSSH(ctx, s.meter(conn), config)
+		}
+	}
+}
+
+func (s *Server) sshServerConfig() (*ssh.ServerConfig, error) {
+	signer, err := loadSSHHostKey(s.config.SSHHostKey)
+	if err != nil {
+		return nil, fmt.Errorf("host key: %v", err)
+	}
+	config := &ssh.ServerConfig{
+		PublicKeyCallback:           s.sshPublicKey,
+		PasswordCallback:            s.sshPassword,
+		KeyboardInteractiveCallback: s.sshGuest,
+	}
+	config.AddHostKey(signer)
+	return config, nil
+}
+
+// loadSSHHostKey reads the host key at path, creating an ed25519 key on
+// first start
+func loadSSHHostKey(path string) (ssh.Signer, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		block, err := ssh.MarshalPrivateKey(key, "netcat host key")
+		if err != nil {
+			return nil, err
+		}
+		data = pem.EncodeToMemory(block)
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	return ssh.ParsePrivateKey(data)
+}
+
+// sshPublicKey accepts keys added to the user's account with /sshkey
+func (s *Server) sshPublicKey(meta ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+	account, ok := s.accounts.get(meta.User())
+	if !ok {
+		return nil, errors.New("no keys registered")
+	}
+	for _, line := range account.SSHKeys {
+		authorized, _, _, _, err := ssh.ParseAuthorizedKey([]byte(line))
+		if err == nil && bytes.Equal(authorized.Marshal(), key.Marshal()) {
+			return &ssh.Permissions{Extensions: map[string]string{sshAccountKey: account.Name}}, nil
+		}
+	}
+	return nil, errors.New("unknown key")
+}
+
+// sshPassword checks the account password of a registered user
+func (s *Server) sshPassword(meta ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+	account, ok := s.accounts.get(meta.User())
+	if !ok || account.PasswordHash == "" || !checkPassword(account.PasswordHash, string(password)) {
+		s.log.Warn("Failed SSH login", "client", meta.User(), "remote", meta.RemoteAddr().String())
+		return nil, errors.New("authentication failed")
+	}
+	return &ssh.Permissions{Extensions: map[string]string{sshAccountKey: account.Name}}, nil
+}
+
+// sshGuest lets unregistered names in without credentials, as a plain TCP
+// connection would be. Registered names and auth-only servers must use a
+// key or password.
+func (s *Server) sshGuest(meta ssh.ConnMetadata, _ ssh.KeyboardInteractiveChallenge) (*ssh.Permissions, error) {
+	if s.config.AuthRequired {
+		return nil, errors.New("authentication required")
+	}
+	if account, ok := s.accounts.get(meta.User()); ok && account.registered() {
+		return nil, errors.New("nickname is registered")
+	}
+	return &ssh.Permissions{}, nil
+}
+
+// handleSSH runs the first shell session of an SSH connection as a chat
+// client through serveConn, like any other connection
+func (s *Server) handleSSH(ctx context.Context, conn net.Conn, config *ssh.ServerConfig) {
+	defer conn.Close()
+	served := false
+	defer func() {
+		if !served {
+			s.releaseIP(remoteIP(conn))
+		}
+	}()
+	// Until a shell starts, shutdown just drops the connection
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	server, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		s.log.Warn("SSH handshake failed", "remote", remoteIP(conn), "err", err)
+		return
+	}
+	defer server.Close()
+	go ssh.DiscardRequests(requests)
+
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "only shell sessions are supported")
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+		pty, ok := waitForShell(requests)
+		if !ok {
+			channel.Close()
+			continue
+		}
+		go ssh.DiscardRequests(requests)
+
+		account := ""
+		if server.Permissions != nil {
+			account = server.Permissions.Extensions[sshAccountKey]
+		}
+		stop()
+		served = true
+		s.serveConn(ctx, newSSHConn(channel, conn, server.User(), account, pty))
+		return
+	}
+}
+
+// waitForShell answers session requests until the client asks for a shell
+// and reports whether it requested a terminal
+func waitForShell(requests <-chan *ssh.Request) (pty bool, ok bool) {
+	for req := range requests {
+		switch req.Type {
+		case "pty-req":
+			pty = true
+			req.Reply(true, nil)
+		case "shell":
+			req.Reply(true, nil)
+			return pty, true
+		default: // env, exec, subsystem, ...
+			if req.WantReply {
+				req.Reply(false, nil)
+			}
+		}
+	}
+	return pty, false
+}
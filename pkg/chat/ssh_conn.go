@@ -0,0 +1,147 @@
+package chat
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// sshConn carries one chat session over an SSH channel. The SSH user name
+// is fed to the server as the first input line. When the client requested
+// a terminal it is in raw mode, so sshConn also echoes input and handles
+// line editing itself.
+type sshConn struct {
+	channel io.ReadWriteCloser
+	conn    net.Conn // Underlying TCP connection, for addresses and deadlines
+	account string   // Account proven by SSH authentication, if any
+	pty     bool
+
+	pending []byte
+
+	mutex  sync.Mutex // Guards the terminal state shared by Read and Write
+	line   []byte     // Line being typed in terminal mode
+	lastCR bool
+	escape int // Progress through an ANSI escape sequence (arrow keys etc.)
+}
+
+func newSSHConn(channel io.ReadWriteCloser, conn net.Conn, user, account string, pty bool) *sshConn {
+	return &sshConn{channel: channel, conn: conn, account: account, pty: pty, pending: []byte(user + "\n")}
+}
+
+// sshIdentity returns the account an SSH connection authenticated as
+func sshIdentity(conn net.Conn) string {
+	if c, ok := conn.(*sshConn); ok {
+		return c.account
+	}
+	return ""
+}
+
+func (c *sshConn) Read(p []byte) (int, error) {
+	buf := make([]byte, 256)
+	for len(c.pending) == 0 {
+		n, err := c.channel.Read(buf)
+		if c.pty {
+			if quit := c.edit(buf[:n]); quit {
+				return 0, io.EOF
+			}
+		} else {
+			c.pending = append(c.pending, buf[:n]...)
+		}
+		if err != nil && len(c.pending) == 0 {
+			return 0, err
+		}
+	}
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+// edit applies terminal input to the current line, echoing as it goes,
+// and queues finished lines. It reports Ctrl-C or Ctrl-D on an empty line.
+func (c *sshConn) edit(input []byte) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var echo []byte
+	defer func() {
+		if len(echo) > 0 {
+			c.channel.Write(echo)
+		}
+	}()
+	for _, b := range input {
+		if c.escape > 0 {
+			// ESC [ ... final byte; ignore the whole sequence
+			if c.escape == 1 && b == '[' {
+				c.escape = 2
+			} else if c.escape == 1 || (b >= 0x40 && b <= 0x7E) {
+				c.escape = 0
+			}
+			continue
+		}
+		lastCR := c.lastCR
+		c.lastCR = b == '\r'
+
+		switch {
+		case b == '\n' && lastCR:
+		case b == '\r' || b == '\n':
+			echo = append(echo, '\r', '\n')
+			c.pending = append(append(c.pending, c.line...), '\n')
+			c.line = nil
+		case b == 0x7F || b == 0x08: // Backspace
+			if len(c.line) > 0 {
+				_, size := utf8.DecodeLastRune(c.line)
+				c.line = c.line[:len(c.line)-size]
+				echo = append(echo, "\b \b"...)
+			}
+		case b == 0x15: // Ctrl-U clears the line
+			echo = append(echo, bytes.Repeat([]byte("\b \b"), utf8.RuneCount(c.line))...)
+			c.line = nil
+		case b == 0x03 || (b == 0x04 && len(c.line) == 0):
+			echo = append(echo, '\r', '\n')
+			return true
+		case b == 0x1B:
+			c.escape = 1
+		case b < 0x20:
+		default:
+			c.line = append(c.line, b)
+			echo = append(echo, b)
+		}
+	}
+	return false
+}
+
+// Write sends server output. In terminal mode newlines become CRLF and a
+// partly typed line is redrawn below the output.
+func (c *sshConn) Write(p []byte) (int, error) {
+	if !c.pty {
+		return c.channel.Write(p)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	var out []byte
+	if len(c.line) > 0 {
+		out = append(out, "\r\x1b[K"...)
+	}
+	out = append(out, bytes.ReplaceAll(p, []byte("\n"), []byte("\r\n"))...)
+	out = append(out, c.line...)
+	if _, err := c.channel.Write(out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close ends the session along with its SSH connection
+func (c *sshConn) Close() error {
+	c.channel.Close()
+	return c.conn.Close()
+}
+
+func (c *sshConn) LocalAddr() net.Addr                { return c.conn.LocalAddr() }
+func (c *sshConn) RemoteAddr() net.Addr               { return c.conn.RemoteAddr() }
+func (c *sshConn) SetDeadline(t time.Time) error      { return c.conn.SetDeadline(t) }
+func (c *sshConn) SetReadDeadline(t time.Time) error  { return c.conn.SetReadDeadline(t) }
+func (c *sshConn) SetWriteDeadline(t time.Time) error { return c.conn.SetWriteDeadline(t) }
@@ -0,0 +1,133 @@
+package chat
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSSHConnTerminal(t *testing.T) {
+	channel, client := net.Pipe()
+	tcp, _ := net.Pipe()
+	conn := newSSHConn(channel, tcp, "Alice", "", true)
+	defer conn.Close()
+
+	echoed := make(chan string, 1)
+	go func() {
+		// Typo, backspace, an arrow key, then Enter
+		client.Write([]byte("hx\x7fi\x1b[A\r"))
+		buf := make([]byte, 64)
+		var out []byte
+		for !strings.HasSuffix(string(out), "\r\n") {
+			n, err := client.Read(buf)
+			if err != nil {
+				break
+			}
+			out = append(out, buf[:n]...)
+		}
+		echoed <- string(out)
+	}()
+
+	reader := bufio.NewReader(conn)
+	for _, want := range []string{"Alice\n", "hi\n"} {
+		if line, err := reader.ReadString('\n'); err != nil || line != want {
+			t.Fatalf("read %q, %v; want %q", line, err, want)
+		}
+	}
+	if got := <-echoed; got != "hx\b \bi\r\n" {
+		t.Errorf("echo = %q", got)
+	}
+}
+
+func TestSSHConnWriteRedrawsLine(t *testing.T) {
+	recorder := &writeRecorder{}
+	tcp, _ := net.Pipe()
+	conn := newSSHConn(recorder, tcp, "Alice", "", true)
+	defer conn.Close()
+
+	conn.edit([]byte("half"))
+	recorder.written = nil
+	conn.Write([]byte("news\n"))
+	if got := string(recorder.written); got != "\r\x1b[Knews\r\nhalf" {
+		t.Errorf("output = %q", got)
+	}
+}
+
+// writeRecorder stands in for an SSH channel and keeps what was written
+type writeRecorder struct {
+	written []byte
+}
+
+func (w *writeRecorder) Read(p []byte) (int, error) { return 0, io.EOF }
+func (w *writeRecorder) Close() error               { return nil }
+func (w *writeRecorder) Write(p []byte) (int, error) {
+	w.written = append(w.written, p...)
+	return len(p), nil
+}
+
+func TestSSHIdentifiedLogin(t *testing.T) {
+	s, _ := newEmailTestServer(t)
+	s.accounts.update("Alice", func(a *Account) error {
+		a.PasswordHash = hashPassword("secret1")
+		return nil
+	})
+
+	channel, client := net.Pipe()
+	tcp, _ := net.Pipe()
+	go s.handleConnection(newSSHConn(channel, tcp, "alice", "Alice", false))
+	defer client.Close()
+
+	reader := bufio.NewReader(client)
+	client.SetReadDeadline(time.Now().Add(time.Second))
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("no join message: %v", err)
+		}
+		if strings.Contains(line, "is registered") {
+			t.Fatalf("key-authenticated user asked to identify: %q", line)
+		}
+		if strings.Contains(line, "] Alice joined") {
+			break
+		}
+	}
+	s.mutex.Lock()
+	c := s.findClient("Alice")
+	identified := c != nil && c.identified
+	s.mutex.Unlock()
+	if !identified {
+		t.Error("SSH login did not mark the client identified")
+	}
+}
+
+func TestSSHKeyCommand(t *testing.T) {
+	s, _ := newEmailTestServer(t)
+	alice := newPipeClient(t, "Alice")
+	key := "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIBp0wQ5rjcS2bTzg1eLkl5y0EeuUJeERMkc8n/Vp5jSe alice@laptop"
+
+	if err := sshKeyCommand(s, alice, []string{"add", key}); err == nil {
+		t.Error("unidentified client allowed to add a key")
+	}
+	alice.identified = true
+	if err := sshKeyCommand(s, alice, []string{"add", "ssh-ed25519", "not*base64"}); err == nil {
+		t.Error("invalid key accepted")
+	}
+	if err := sshKeyCommand(s, alice, append([]string{"add"}, strings.Fields(key)...)); err != nil {
+		t.Fatalf("add failed: %v", err)
+	}
+	if err := sshKeyCommand(s, alice, append([]string{"add"}, strings.Fields(key)...)); err == nil {
+		t.Error("duplicate key accepted")
+	}
+	if account, _ := s.accounts.get("Alice"); len(account.SSHKeys) != 1 || account.SSHKeys[0] != key {
+		t.Errorf("stored keys = %q", account.SSHKeys)
+	}
+	if err := sshKeyCommand(s, alice, []string{"clear"}); err != nil {
+		t.Fatalf("clear failed: %v", err)
+	}
+	if account, _ := s.accounts.get("Alice"); len(account.SSHKeys) != 0 {
+		t.Errorf("keys left after clear: %q", account.SSHKeys)
+	}
+}
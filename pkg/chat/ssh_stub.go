@@ -0,0 +1,13 @@
+//go:build !ssh
+
+package chat
+
+import (
+	"context"
+)
+
+// serveSSH reports that this binary was built without the SSH gateway,
+// which needs golang.org/x/crypto/ssh and the ssh build tag
+func (s *Server) serveSSH(ctx context.Context, addr string, filter *ipFilter) {
+	s.log.Warn("SSH gateway disabled: rebuild with -tags ssh", "addr", addr)
+}
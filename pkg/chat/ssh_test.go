@@ -0,0 +1,125 @@
+//go:build ssh
+
+package chat
+
+import (
+	"bufio"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestSSHSessionEndsOnShutdown(t *testing.T) {
+	config := DefaultConfig()
+	config.AccountsFile = ""
+	config.RoomsFile = ""
+	config.Store = StoreMemory
+	config.SSHAddr = freeAddr(t)
+	config.SSHHostKey = filepath.Join(t.TempDir(), "ssh_host_key")
+	s := NewServerWithConfig(config)
+	defer s.Logfile.Close()
+	done := make(chan error, 1)
+	go func() { done <- s.Start(freeAddr(t)) }()
+
+	clientConfig := &ssh.ClientConfig{
+		User: "Alice",
+		Auth: []ssh.AuthMethod{ssh.KeyboardInteractive(
+			func(string, string, []string, []bool) ([]string, error) { return nil, nil },
+		)},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         time.Second,
+	}
+	// The gateway's listener opens once the server runs
+	var client *ssh.Client
+	var err error
+	for i := 0; i < 20; i++ {
+		if client, err = ssh.Dial("tcp", config.SSHAddr, clientConfig); err == nil {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("SSH connection failed: %v", err)
+	}
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		t.Fatalf("Session failed: %v", err)
+	}
+	defer session.Close()
+	// Without an open stdin the client sends EOF and the user leaves
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		t.Fatalf("Stdin failed: %v", err)
+	}
+	defer stdin.Close()
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		t.Fatalf("Stdout failed: %v", err)
+	}
+	if err := session.Shell(); err != nil {
+		t.Fatalf("Shell failed: %v", err)
+	}
+
+	lines := make(chan string, 100)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	// waitFor reads until a line containing text, or until the session ends
+	waitFor := func(text string) bool {
+		timeout := time.After(shutdownTimeout)
+		for {
+			select {
+			case line, ok := <-lines:
+				if !ok {
+					return text == io.EOF.Error()
+				}
+				if strings.Contains(line, text) {
+					return true
+				}
+			case <-timeout:
+				return false
+			}
+		}
+	}
+	if !waitFor("Alice joined") {
+		t.Fatal("SSH user did not join")
+	}
+	// A connection that never asks for a shell is dropped too
+	clientConfig.User = "Bob"
+	idle, err := ssh.Dial("tcp", config.SSHAddr, clientConfig)
+	if err != nil {
+		t.Fatalf("SSH connection failed: %v", err)
+	}
+	defer idle.Close()
+	idleClosed := make(chan struct{})
+	go func() {
+		idle.Wait()
+		close(idleClosed)
+	}()
+
+	s.Shutdown()
+	if !waitFor("Server is shutting down") {
+		t.Error("SSH session was not told about the shutdown")
+	}
+	if !waitFor(io.EOF.Error()) {
+		t.Error("SSH session outlived Shutdown")
+	}
+	select {
+	case <-idleClosed:
+	case <-time.After(shutdownTimeout):
+		t.Error("SSH connection without a shell outlived Shutdown")
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Start returned %v", err)
+	}
+}
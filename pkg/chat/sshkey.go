@@ -0,0 +1,89 @@
+package chat
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// maxSSHKeys bounds how many keys one account can register
+const maxSSHKeys = 10
+
+// parseAuthorizedKey checks the shape of an authorized_keys line
+// ("<type> <base64> [comment]") and returns it normalised. The SSH
+// gateway parses it fully when a client offers a key.
+func parseAuthorizedKey(line string) (string, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return "", fmt.Errorf("expected <type> <base64 key> [comment]")
+	}
+	if !strings.HasPrefix(fields[0], "ssh-") && !strings.HasPrefix(fields[0], "ecdsa-") && !strings.HasPrefix(fields[0], "sk-") {
+		return "", fmt.Errorf("unknown key type %q", fields[0])
+	}
+	if _, err := base64.StdEncoding.DecodeString(fields[1]); err != nil {
+		return "", fmt.Errorf("key is not valid base64")
+	}
+	return strings.Join(fields, " "), nil
+}
+
+func sshKeyCommand(s *Server, c *Client, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: /sshkey add <key> | /sshkey list | /sshkey clear")
+	}
+	if c.bot || !(c.identified || s.config.AuthRequired) {
+		return fmt.Errorf("register and identify before adding SSH keys")
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "add":
+		key, err := parseAuthorizedKey(strings.Join(args[1:], " "))
+		if err != nil {
+			return err
+		}
+		err = s.accounts.update(c.name, func(a *Account) error {
+			for _, existing := range a.SSHKeys {
+				if strings.Join(strings.Fields(existing)[:2], " ") == strings.Join(strings.Fields(key)[:2], " ") {
+					return fmt.Errorf("that key is already registered")
+				}
+			}
+			if len(a.SSHKeys) >= maxSSHKeys {
+				return fmt.Errorf("at most %d keys per account", maxSSHKeys)
+			}
+			a.SSHKeys = append(a.SSHKeys, key)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		s.logActivity("SSH key added", "client", c.name)
+		c.write([]byte("SSH key added. You can now log in with: ssh " + c.name + "@<server> -p <ssh port>\n"))
+	case "list":
+		account, _ := s.accounts.get(c.name)
+		if len(account.SSHKeys) == 0 {
+			c.write([]byte("No SSH keys registered\n"))
+			return nil
+		}
+		lines := make([]string, len(account.SSHKeys))
+		for i, key := range account.SSHKeys {
+			fields := strings.Fields(key)
+			summary := fields[0] + " ..." + fields[1][max(0, len(fields[1])-12):]
+			if len(fields) > 2 {
+				summary += " " + strings.Join(fields[2:], " ")
+			}
+			lines[i] = fmt.Sprintf("%d. %s", i+1, summary)
+		}
+		c.write([]byte(fmt.Sprintf("SSH keys (%d):\n%s\n", len(lines), strings.Join(lines, "\n"))))
+	case "clear":
+		if err := s.accounts.update(c.name, func(a *Account) error {
+			a.SSHKeys = nil
+			return nil
+		}); err != nil {
+			return err
+		}
+		s.logActivity("SSH keys removed", "client", c.name)
+		c.write([]byte("SSH keys removed\n"))
+	default:
+		return fmt.Errorf("usage: /sshkey add <key> | /sshkey list | /sshkey clear")
+	}
+	return nil
+}
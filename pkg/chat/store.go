@@ -0,0 +1,179 @@
+package chat
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Store backends selectable with Config.Store
+const (
+	StoreMemory = "memory"
+	StoreFile   = "file"
+	StoreSQLite = "sqlite"
+)
+
+// Store persists messages, rooms and accounts. The server only talks to
+// this interface, so backends can be swapped and tests can run without
+// touching the filesystem.
+type Store interface {
+	// AppendMessage records a message of room; server-wide notices use
+	// the empty room name
+	AppendMessage(room string, msg Message) error
+	// RecentMessages returns up to limit of the newest messages of room,
+	// oldest first. A limit of zero or less returns everything.
+	RecentMessages(room string, limit int) ([]Message, error)
+	// PruneMessages deletes messages of room older than before and all
+	// but the newest keep; zero values skip that check
+	PruneMessages(room string, before time.Time, keep int) (int, error)
+
+	LoadRooms() ([]RoomInfo, error)
+	SaveRooms(rooms []RoomInfo) error
+
+	LoadAccounts() ([]*Account, error)
+	SaveAccounts(accounts []*Account) error
+
+	Close() error
+}
+
+// OpenStore opens the backend selected by config.Store
+func OpenStore(config *Config) (Store, error) {
+	switch config.Store {
+	case StoreMemory:
+		return newMemoryStore(config.HistoryLimit, config.RoomHistoryLimits), nil
+	case StoreFile, "":
+		store := newFileStore(config.AccountsFile, config.RoomsFile, config.HistoryFile)
+		store.memory = newMemoryMessages(config.HistoryLimit, config.RoomHistoryLimits)
+		return store, nil
+	case StoreSQLite:
+		return openSQLStore("sqlite", config.DatabasePath)
+	}
+	return nil, fmt.Errorf("unknown store %q (memory, file, sqlite)", config.Store)
+}
+
+// defaultHistoryLimit is how many messages per room the in-memory
+// history keeps unless configured otherwise
+const defaultHistoryLimit = 1000
+
+// memoryMessages keeps the newest messages of each room in ring buffers
+// so long-running servers do not grow without bound
+type memoryMessages struct {
+	mutex      sync.Mutex
+	limit      int            // Messages kept per room; <= 0 keeps all
+	roomLimits map[string]int // Per-room overrides of limit
+	rooms      map[string]*messageRing
+}
+
+func newMemoryMessages(limit int, roomLimits map[string]int) *memoryMessages {
+	return &memoryMessages{limit: limit, roomLimits: roomLimits, rooms: make(map[string]*messageRing)}
+}
+
+func (m *memoryMessages) AppendMessage(room string, msg Message) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	ring, ok := m.rooms[room]
+	if !ok {
+		limit, ok := m.roomLimits[room]
+		if !ok {
+			limit = m.limit
+		}
+		ring = newMessageRing(limit)
+		m.rooms[room] = ring
+	}
+	ring.push(msg)
+	return nil
+}
+
+func (m *memoryMessages) RecentMessages(room string, limit int) ([]Message, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	ring, ok := m.rooms[room]
+	if !ok {
+		return nil, nil
+	}
+	return ring.last(limit), nil
+}
+
+func (m *memoryMessages) PruneMessages(room string, before time.Time, keep int) (int, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	ring, ok := m.rooms[room]
+	if !ok {
+		return 0, nil
+	}
+	messages := ring.last(0)
+	kept := pruneMessages(messages, before, keep)
+	if len(kept) == len(messages) {
+		return 0, nil
+	}
+	pruned := newMessageRing(ring.limit)
+	for _, msg := range kept {
+		pruned.push(msg)
+	}
+	m.rooms[room] = pruned
+	return len(messages) - len(kept), nil
+}
+
+// lastMessages copies up to limit messages from the end of messages
+func lastMessages(messages []Message, limit int) []Message {
+	if limit > 0 && len(messages) > limit {
+		messages = messages[len(messages)-limit:]
+	}
+	return append([]Message(nil), messages...)
+}
+
+// memoryStore keeps everything in memory; nothing survives a restart
+type memoryStore struct {
+	*memoryMessages
+	mutex    sync.Mutex
+	rooms    []RoomInfo
+	accounts []*Account
+}
+
+// NewMemoryStore returns a Store that never touches the filesystem
+func NewMemoryStore() Store {
+	return newMemoryStore(defaultHistoryLimit, nil)
+}
+
+func newMemoryStore(limit int, roomLimits map[string]int) *memoryStore {
+	return &memoryStore{memoryMessages: newMemoryMessages(limit, roomLimits)}
+}
+
+func (m *memoryStore) LoadRooms() ([]RoomInfo, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return append([]RoomInfo(nil), m.rooms...), nil
+}
+
+func (m *memoryStore) SaveRooms(rooms []RoomInfo) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.rooms = append([]RoomInfo(nil), rooms...)
+	return nil
+}
+
+func (m *memoryStore) LoadAccounts() ([]*Account, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	accounts := make([]*Account, len(m.accounts))
+	for i, a := range m.accounts {
+		copied := *a
+		accounts[i] = &copied
+	}
+	return accounts, nil
+}
+
+func (m *memoryStore) SaveAccounts(accounts []*Account) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.accounts = make([]*Account, len(accounts))
+	for i, a := range accounts {
+		copied := *a
+		m.accounts[i] = &copied
+	}
+	return nil
+}
+
+func (m *memoryStore) Close() error {
+	return nil
+}
@@ -0,0 +1,195 @@
+package chat
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
+// fileStore keeps accounts and rooms in JSON files and, when historyPath
+// is set, appends messages to a JSON-lines file. An empty path keeps that
+// part in memory only.
+type fileStore struct {
+	accountsPath string
+	roomsPath    string
+	historyPath  string
+
+	memory *memoryMessages // History when historyPath is empty
+	mutex  sync.Mutex      // Serialises history appends and reads
+}
+
+func newFileStore(accountsPath, roomsPath, historyPath string) *fileStore {
+	return &fileStore{
+		accountsPath: accountsPath,
+		roomsPath:    roomsPath,
+		historyPath:  historyPath,
+		memory:       newMemoryMessages(defaultHistoryLimit, nil),
+	}
+}
+
+// storedMessage is a history line of the JSON-lines file
+type storedMessage struct {
+	Room string `json:"room"`
+	Message
+}
+
+func (f *fileStore) AppendMessage(room string, msg Message) error {
+	if f.historyPath == "" {
+		return f.memory.AppendMessage(room, msg)
+	}
+	data, err := json.Marshal(storedMessage{Room: room, Message: msg})
+	if err != nil {
+		return err
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	file, err := os.OpenFile(f.historyPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(append(data, '\n'))
+	return err
+}
+
+func (f *fileStore) RecentMessages(room string, limit int) ([]Message, error) {
+	if f.historyPath == "" {
+		return f.memory.RecentMessages(room, limit)
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	file, err := os.Open(f.historyPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var messages []Message
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var stored storedMessage
+		if json.Unmarshal(scanner.Bytes(), &stored) == nil && stored.Room == room {
+			messages = append(messages, stored.Message)
+		}
+	}
+	return lastMessages(messages, limit), scanner.Err()
+}
+
+func (f *fileStore) PruneMessages(room string, before time.Time, keep int) (int, error) {
+	if f.historyPath == "" {
+		return f.memory.PruneMessages(room, before, keep)
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	data, err := os.ReadFile(f.historyPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	// Split the log into the room's messages and everything else, keeping
+	// other lines byte for byte
+	var lines [][]byte
+	var messages []Message
+	var positions []int
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		var stored storedMessage
+		if json.Unmarshal(line, &stored) == nil && stored.Room == room {
+			messages = append(messages, stored.Message)
+			positions = append(positions, len(lines))
+		}
+		lines = append(lines, line)
+	}
+	kept := pruneMessages(messages, before, keep)
+	removed := len(messages) - len(kept)
+	if removed == 0 {
+		return 0, nil
+	}
+
+	// pruneMessages only drops from the front, so the first removed
+	// entries are the ones to skip
+	drop := make(map[int]bool, removed)
+	for _, pos := range positions[:removed] {
+		drop[pos] = true
+	}
+	var out bytes.Buffer
+	for i, line := range lines {
+		if !drop[i] {
+			out.Write(line)
+			out.WriteByte('\n')
+		}
+	}
+
+	tmp := f.historyPath + ".tmp"
+	if err := os.WriteFile(tmp, out.Bytes(), 0o600); err != nil {
+		return 0, err
+	}
+	return removed, os.Rename(tmp, f.historyPath)
+}
+
+func (f *fileStore) LoadRooms() ([]RoomInfo, error) {
+	var rooms []RoomInfo
+	err := readJSONFile(f.roomsPath, &rooms)
+	return rooms, err
+}
+
+func (f *fileStore) SaveRooms(rooms []RoomInfo) error {
+	return writeJSONFile(f.roomsPath, rooms)
+}
+
+func (f *fileStore) LoadAccounts() ([]*Account, error) {
+	var accounts []*Account
+	err := readJSONFile(f.accountsPath, &accounts)
+	return accounts, err
+}
+
+func (f *fileStore) SaveAccounts(accounts []*Account) error {
+	return writeJSONFile(f.accountsPath, accounts)
+}
+
+func (f *fileStore) Close() error {
+	return nil
+}
+
+// readJSONFile decodes path into v; an empty path or missing file leaves
+// v untouched
+func readJSONFile(path string, v any) error {
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func writeJSONFile(path string, v any) error {
+	if path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
@@ -0,0 +1,228 @@
+package chat
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// sqlStore persists everything in a SQL database. It only uses portable
+// SQL; the driver is registered by sqlite.go when building with -tags sqlite.
+type sqlStore struct {
+	db *sql.DB
+}
+
+const sqlStoreSchema = `
+CREATE TABLE IF NOT EXISTS messages (
+	id        INTEGER PRIMARY KEY AUTOINCREMENT,
+	room      TEXT    NOT NULL,
+	type      INTEGER NOT NULL,
+	sender    TEXT    NOT NULL DEFAULT '',
+	recipient TEXT    NOT NULL DEFAULT '',
+	content   TEXT    NOT NULL,
+	sent_at   INTEGER NOT NULL,
+	msg_id    INTEGER NOT NULL DEFAULT 0,
+	ref_id    INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS messages_room ON messages (room, id);
+CREATE TABLE IF NOT EXISTS rooms (
+	name          TEXT PRIMARY KEY,
+	creator       TEXT    NOT NULL DEFAULT '',
+	topic         TEXT    NOT NULL DEFAULT '',
+	password_hash TEXT    NOT NULL DEFAULT '',
+	created_at    INTEGER NOT NULL,
+	settings      TEXT    NOT NULL DEFAULT '{}'
+);
+CREATE TABLE IF NOT EXISTS accounts (
+	name TEXT PRIMARY KEY,
+	data TEXT NOT NULL
+);
+`
+
+func openSQLStore(driver, dsn string) (*sqlStore, error) {
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%v (was the server built with -tags sqlite?)", err)
+	}
+	if _, err := db.Exec(sqlStoreSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create schema: %v", err)
+	}
+	// Databases created before these columns existed; the error for a
+	// column that is already there is expected
+	db.Exec(`ALTER TABLE rooms ADD COLUMN settings TEXT NOT NULL DEFAULT '{}'`)
+	db.Exec(`ALTER TABLE messages ADD COLUMN msg_id INTEGER NOT NULL DEFAULT 0`)
+	db.Exec(`ALTER TABLE messages ADD COLUMN ref_id INTEGER NOT NULL DEFAULT 0`)
+	return &sqlStore{db: db}, nil
+}
+
+func (st *sqlStore) AppendMessage(room string, msg Message) error {
+	_, err := st.db.Exec(
+		`INSERT INTO messages (room, type, sender, recipient, content, sent_at, msg_id, ref_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		room, msg.Type, msg.From, msg.To, msg.Content, msg.Timestamp.UnixNano(), msg.ID, msg.Ref)
+	return err
+}
+
+func (st *sqlStore) RecentMessages(room string, limit int) ([]Message, error) {
+	if limit <= 0 {
+		limit = -1 // SQLite's "no limit"
+	}
+	return st.queryMessages(
+		`SELECT type, sender, recipient, content, sent_at, msg_id, ref_id FROM messages
+		 WHERE room = ? ORDER BY id DESC LIMIT ?`, room, limit)
+}
+
+func (st *sqlStore) MessagesSince(room string, after int64, limit int) ([]Message, error) {
+	if limit <= 0 {
+		limit = -1
+	}
+	return st.queryMessages(
+		`SELECT type, sender, recipient, content, sent_at, msg_id, ref_id FROM messages
+		 WHERE room = ? AND msg_id > ? ORDER BY id DESC LIMIT ?`, room, after, limit)
+}
+
+// queryMessages runs a query selecting messages newest first and returns
+// them oldest first
+func (st *sqlStore) queryMessages(query string, args ...any) ([]Message, error) {
+	rows, err := st.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []Message
+	for rows.Next() {
+		var msg Message
+		var sentAt int64
+		if err := rows.Scan(&msg.Type, &msg.From, &msg.To, &msg.Content, &sentAt, &msg.ID, &msg.Ref); err != nil {
+			return nil, err
+		}
+		msg.Timestamp = time.Unix(0, sentAt)
+		messages = append(messages, msg)
+	}
+
+	// Rows come newest first; replay wants them oldest first
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+	return messages, rows.Err()
+}
+
+func (st *sqlStore) PruneMessages(room string, before time.Time, keep int) (int, error) {
+	removed := 0
+	if !before.IsZero() {
+		res, err := st.db.Exec(`DELETE FROM messages WHERE room = ? AND sent_at < ?`, room, before.UnixNano())
+		if err != nil {
+			return removed, err
+		}
+		n, _ := res.RowsAffected()
+		removed += int(n)
+	}
+	if keep > 0 {
+		res, err := st.db.Exec(
+			`DELETE FROM messages WHERE room = ? AND id NOT IN
+			 (SELECT id FROM messages WHERE room = ? ORDER BY id DESC LIMIT ?)`, room, room, keep)
+		if err != nil {
+			return removed, err
+		}
+		n, _ := res.RowsAffected()
+		removed += int(n)
+	}
+	return removed, nil
+}
+
+func (st *sqlStore) LoadRooms() ([]RoomInfo, error) {
+	rows, err := st.db.Query(`SELECT name, creator, topic, password_hash, created_at, settings FROM rooms ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rooms []RoomInfo
+	for rows.Next() {
+		var info RoomInfo
+		var created int64
+		var settings string
+		if err := rows.Scan(&info.Name, &info.Creator, &info.Topic, &info.PasswordHash, &created, &settings); err != nil {
+			return nil, err
+		}
+		// Room options added after the original columns live in settings
+		json.Unmarshal([]byte(settings), &info)
+		info.Created = time.Unix(0, created)
+		rooms = append(rooms, info)
+	}
+	return rooms, rows.Err()
+}
+
+func (st *sqlStore) SaveRooms(rooms []RoomInfo) error {
+	tx, err := st.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM rooms`); err != nil {
+		return err
+	}
+	for _, info := range rooms {
+		settings, _ := json.Marshal(info)
+		_, err := tx.Exec(
+			`INSERT INTO rooms (name, creator, topic, password_hash, created_at, settings) VALUES (?, ?, ?, ?, ?, ?)`,
+			info.Name, info.Creator, info.Topic, info.PasswordHash, info.Created.UnixNano(), string(settings))
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Accounts are stored as JSON documents so new profile fields need no
+// schema migration
+func (st *sqlStore) LoadAccounts() ([]*Account, error) {
+	rows, err := st.db.Query(`SELECT data FROM accounts`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accounts []*Account
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var a Account
+		if err := json.Unmarshal([]byte(data), &a); err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, &a)
+	}
+	return accounts, rows.Err()
+}
+
+func (st *sqlStore) SaveAccounts(accounts []*Account) error {
+	tx, err := st.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM accounts`); err != nil {
+		return err
+	}
+	for _, a := range accounts {
+		data, err := json.Marshal(a)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`INSERT INTO accounts (name, data) VALUES (?, ?)`, a.Name, string(data)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (st *sqlStore) Close() error {
+	return st.db.Close()
+}
@@ -0,0 +1,69 @@
+//go:build sqlite
+
+package chat
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func openTestSQLStore(t *testing.T, path string) *sqlStore {
+	t.Helper()
+	store, err := openSQLStore("sqlite", path)
+	if err != nil {
+		t.Fatalf("openSQLStore failed: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestSQLiteStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chat.db")
+	testStoreContract(t, openTestSQLStore(t, path))
+
+	// A fresh store over the same database sees the same data
+	reopened := openTestSQLStore(t, path)
+	if recent, _ := reopened.RecentMessages("general", 0); len(recent) != 3 {
+		t.Errorf("history not persisted: got %d messages, want 3", len(recent))
+	}
+	if rooms, _ := reopened.LoadRooms(); len(rooms) != 1 {
+		t.Errorf("rooms not persisted: %+v", rooms)
+	}
+	if accounts, _ := reopened.LoadAccounts(); len(accounts) != 1 {
+		t.Errorf("accounts not persisted: %+v", accounts)
+	}
+}
+
+func TestSQLiteStorePruning(t *testing.T) {
+	testStorePruning(t, openTestSQLStore(t, filepath.Join(t.TempDir(), "chat.db")))
+}
+
+func TestSQLiteMessagesSince(t *testing.T) {
+	store := openTestSQLStore(t, filepath.Join(t.TempDir(), "chat.db"))
+	for id := int64(1); id <= 5; id++ {
+		store.AppendMessage("general", Message{ID: id, Content: fmt.Sprint(id)})
+	}
+	missed, err := messagesSince(store, "general", 3, 10)
+	if err != nil || len(missed) != 2 || missed[0].ID != 4 || missed[1].ID != 5 {
+		t.Errorf("since #3 = %v, %v; want #4 and #5", missed, err)
+	}
+	missed, _ = messagesSince(store, "general", 1, 1)
+	if len(missed) != 1 || missed[0].ID != 5 {
+		t.Errorf("since #1 limited to 1 = %v, want #5", missed)
+	}
+}
+
+func TestOpenSQLiteStore(t *testing.T) {
+	config := DefaultConfig()
+	config.Store = StoreSQLite
+	config.DatabasePath = filepath.Join(t.TempDir(), "chat.db")
+	store, err := OpenStore(config)
+	if err != nil {
+		t.Fatalf("OpenStore(sqlite) failed: %v", err)
+	}
+	defer store.Close()
+	if err := store.AppendMessage("general", Message{Content: "hello"}); err != nil {
+		t.Errorf("AppendMessage failed: %v", err)
+	}
+}
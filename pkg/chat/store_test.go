@@ -0,0 +1,110 @@
+package chat
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testStoreContract checks the behaviour every Store backend must share
+func testStoreContract(t *testing.T, store Store) {
+	for _, content := range []string{"one", "two", "three"} {
+		if err := store.AppendMessage("general", Message{Type: MessageTypeChat, From: "Alice", Content: content}); err != nil {
+			t.Fatalf("AppendMessage failed: %v", err)
+		}
+	}
+	store.AppendMessage("random", Message{Content: "elsewhere"})
+
+	recent, err := store.RecentMessages("general", 2)
+	if err != nil || len(recent) != 2 || recent[0].Content != "two" || recent[1].Content != "three" {
+		t.Errorf("RecentMessages(general, 2) = %+v, %v; want two, three", recent, err)
+	}
+
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	if err := store.SaveRooms([]RoomInfo{{Name: "dev", Creator: "Alice", Topic: "builds", Created: created}}); err != nil {
+		t.Fatalf("SaveRooms failed: %v", err)
+	}
+	rooms, err := store.LoadRooms()
+	if err != nil || len(rooms) != 1 || rooms[0].Topic != "builds" || !rooms[0].Created.Equal(created) {
+		t.Errorf("LoadRooms() = %+v, %v", rooms, err)
+	}
+
+	if err := store.SaveAccounts([]*Account{{Name: "Alice", Email: "alice@example.com"}}); err != nil {
+		t.Fatalf("SaveAccounts failed: %v", err)
+	}
+	accounts, err := store.LoadAccounts()
+	if err != nil || len(accounts) != 1 || accounts[0].Email != "alice@example.com" {
+		t.Errorf("LoadAccounts() = %+v, %v", accounts, err)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStoreContract(t, NewMemoryStore())
+}
+
+func TestFileStore(t *testing.T) {
+	dir := t.TempDir()
+	path := func(name string) string { return filepath.Join(dir, name) }
+	testStoreContract(t, newFileStore(path("accounts.json"), path("rooms.json"), path("history.jsonl")))
+
+	// A fresh store over the same files sees the same data
+	reopened := newFileStore(path("accounts.json"), path("rooms.json"), path("history.jsonl"))
+	if recent, _ := reopened.RecentMessages("general", 0); len(recent) != 3 {
+		t.Errorf("history not persisted: got %d messages, want 3", len(recent))
+	}
+	if rooms, _ := reopened.LoadRooms(); len(rooms) != 1 {
+		t.Errorf("rooms not persisted: %+v", rooms)
+	}
+}
+
+func TestOpenStore(t *testing.T) {
+	config := DefaultConfig()
+	config.Store = "postgres"
+	if _, err := OpenStore(config); err == nil {
+		t.Error("expected error for an unknown backend")
+	}
+	if _, err := openSQLStore("no-such-driver", "chat.db"); err == nil {
+		t.Error("expected an error for an unregistered driver")
+	}
+	config.Store = StoreMemory
+	if store, err := OpenStore(config); err != nil || store == nil {
+		t.Errorf("OpenStore(memory) = %v, %v", store, err)
+	}
+}
+
+func TestMessageRing(t *testing.T) {
+	ring := newMessageRing(3)
+	for i := 1; i <= 5; i++ {
+		ring.push(Message{Content: fmt.Sprint(i)})
+	}
+	got := ring.last(0)
+	if len(got) != 3 || got[0].Content != "3" || got[2].Content != "5" {
+		t.Errorf("ring kept %+v, want messages 3-5", got)
+	}
+	if got := ring.last(2); len(got) != 2 || got[0].Content != "4" {
+		t.Errorf("last(2) = %+v, want messages 4-5", got)
+	}
+
+	unbounded := newMessageRing(0)
+	for i := 0; i < 10; i++ {
+		unbounded.push(Message{})
+	}
+	if got := unbounded.last(0); len(got) != 10 {
+		t.Errorf("unbounded ring kept %d messages, want 10", len(got))
+	}
+}
+
+func TestPerRoomHistoryLimit(t *testing.T) {
+	store := newMemoryStore(5, map[string]int{"busy": 2})
+	for i := 0; i < 10; i++ {
+		store.AppendMessage("general", Message{})
+		store.AppendMessage("busy", Message{})
+	}
+	if got, _ := store.RecentMessages("general", 0); len(got) != 5 {
+		t.Errorf("general kept %d messages, want 5", len(got))
+	}
+	if got, _ := store.RecentMessages("busy", 0); len(got) != 2 {
+		t.Errorf("busy kept %d messages, want 2", len(got))
+	}
+}
@@ -0,0 +1,86 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+)
+
+// roomTabs are the room buffers of the server UI, in the order they were
+// opened, one of them shown at a time
+type roomTabs struct {
+	rooms   []string
+	current int
+}
+
+// room returns the room of the tab shown
+func (t *roomTabs) room() string {
+	if len(t.rooms) == 0 {
+		return ""
+	}
+	return t.rooms[t.current]
+}
+
+// find returns the tab of room, or -1 if it has none
+func (t *roomTabs) find(room string) int {
+	for i, r := range t.rooms {
+		if r == room {
+			return i
+		}
+	}
+	return -1
+}
+
+// open returns the tab of room, adding one at the end if it has none, and
+// whether it was added
+func (t *roomTabs) open(room string) (int, bool) {
+	if i := t.find(room); i >= 0 {
+		return i, false
+	}
+	t.rooms = append(t.rooms, room)
+	return len(t.rooms) - 1, true
+}
+
+// show makes tab i the one shown, reporting whether it exists
+func (t *roomTabs) show(i int) bool {
+	if i < 0 || i >= len(t.rooms) {
+		return false
+	}
+	t.current = i
+	return true
+}
+
+// next shows the tab after the current one, wrapping around
+func (t *roomTabs) next() {
+	if len(t.rooms) > 0 {
+		t.current = (t.current + 1) % len(t.rooms)
+	}
+}
+
+// close removes the tab shown, except the first, and shows the one before
+// it. It returns the room closed, or "" if none was.
+func (t *roomTabs) close() string {
+	if t.current == 0 {
+		return ""
+	}
+	room := t.rooms[t.current]
+	t.rooms = append(t.rooms[:t.current], t.rooms[t.current+1:]...)
+	t.current--
+	return room
+}
+
+// title lists the tabs by number, the shown one in brackets and the others
+// with their unread count
+func (t *roomTabs) title(unread map[string]int) string {
+	parts := make([]string, len(t.rooms))
+	for i, room := range t.rooms {
+		switch n := unread[room]; {
+		case i == t.current:
+			parts[i] = fmt.Sprintf("[%d:%s]", i+1, room)
+		case n > 0:
+			parts[i] = fmt.Sprintf("%d:%s(%d)", i+1, room, n)
+		default:
+			parts[i] = fmt.Sprintf("%d:%s", i+1, room)
+		}
+	}
+	return strings.Join(parts, " ")
+}
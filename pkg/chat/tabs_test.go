@@ -0,0 +1,50 @@
+package chat
+
+import "testing"
+
+func TestRoomTabs(t *testing.T) {
+	var tabs roomTabs
+	if tabs.room() != "" || tabs.close() != "" {
+		t.Fatal("Expected no room without tabs")
+	}
+	tabs.next()
+
+	if i, added := tabs.open("lobby"); i != 0 || !added {
+		t.Errorf("Expected lobby to open as tab 0, got %d, %v", i, added)
+	}
+	tabs.open("dev")
+	tabs.open("random")
+	if i, added := tabs.open("dev"); i != 1 || added {
+		t.Errorf("Expected dev to stay tab 1, got %d, %v", i, added)
+	}
+	if tabs.room() != "lobby" {
+		t.Errorf("Expected lobby shown, got %q", tabs.room())
+	}
+
+	unread := map[string]int{"dev": 3}
+	if got, want := tabs.title(unread), "[1:lobby] 2:dev(3) 3:random"; got != want {
+		t.Errorf("Expected title %q, got %q", want, got)
+	}
+
+	if tabs.show(3) || tabs.show(-1) {
+		t.Error("Expected missing tabs not to show")
+	}
+	if !tabs.show(2) || tabs.room() != "random" {
+		t.Errorf("Expected random shown, got %q", tabs.room())
+	}
+	tabs.next()
+	if tabs.room() != "lobby" {
+		t.Errorf("Expected next to wrap around to lobby, got %q", tabs.room())
+	}
+	if tabs.close() != "" || len(tabs.rooms) != 3 {
+		t.Error("Expected the first tab to stay open")
+	}
+
+	tabs.show(1)
+	if room := tabs.close(); room != "dev" {
+		t.Errorf("Expected dev closed, got %q", room)
+	}
+	if tabs.room() != "lobby" || tabs.find("dev") != -1 || tabs.find("random") != 1 {
+		t.Errorf("Expected lobby shown and random moved up, got %v shown %d", tabs.rooms, tabs.current)
+	}
+}
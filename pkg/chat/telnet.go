@@ -0,0 +1,109 @@
+package chat
+
+import "net"
+
+// Telnet command bytes (RFC 854)
+const (
+	telnetSE   = 240
+	telnetSB   = 250
+	telnetWILL = 251
+	telnetWONT = 252
+	telnetDO   = 253
+	telnetDONT = 254
+	telnetIAC  = 255
+)
+
+// Telnet input parser states
+const (
+	telnetData       = iota
+	telnetCommand    // After IAC
+	telnetOption     // After IAC WILL/WONT/DO/DONT
+	telnetSub        // Inside IAC SB ... IAC SE
+	telnetSubCommand // After IAC inside a subnegotiation
+	telnetCR         // After CR, which telnet may follow with NUL
+)
+
+// telnetConn strips telnet negotiation from client input so it never ends
+// up in names or messages. Option requests are refused, which leaves
+// telnet in its default line mode; plain TCP clients never send IAC and
+// pass through untouched.
+type telnetConn struct {
+	net.Conn
+	state   int
+	command byte
+}
+
+func newTelnetConn(conn net.Conn) *telnetConn {
+	return &telnetConn{Conn: conn}
+}
+
+func (t *telnetConn) Read(p []byte) (int, error) {
+	for {
+		n, err := t.Conn.Read(p)
+		if n = t.filter(p[:n]); n > 0 || err != nil {
+			return n, err
+		}
+	}
+}
+
+// filter removes telnet sequences from buf in place, sends any replies
+// and returns the length of the remaining data
+func (t *telnetConn) filter(buf []byte) int {
+	var replies []byte
+	out := 0
+	for _, b := range buf {
+		switch t.state {
+		case telnetData, telnetCR:
+			switch {
+			case b == telnetIAC:
+				t.state = telnetCommand
+			case b == 0 && t.state == telnetCR:
+				t.state = telnetData
+			default:
+				buf[out] = b
+				out++
+				t.state = telnetData
+				if b == '\r' {
+					t.state = telnetCR
+				}
+			}
+		case telnetCommand:
+			switch b {
+			case telnetIAC: // Escaped 0xFF data byte
+				buf[out] = b
+				out++
+				t.state = telnetData
+			case telnetWILL, telnetWONT, telnetDO, telnetDONT:
+				t.command = b
+				t.state = telnetOption
+			case telnetSB:
+				t.state = telnetSub
+			default: // NOP, AYT, GA, ...
+				t.state = telnetData
+			}
+		case telnetOption:
+			// Refuse whatever the client offers or asks for; WONT and DONT
+			// need no answer
+			switch t.command {
+			case telnetWILL:
+				replies = append(replies, telnetIAC, telnetDONT, b)
+			case telnetDO:
+				replies = append(replies, telnetIAC, telnetWONT, b)
+			}
+			t.state = telnetData
+		case telnetSub:
+			if b == telnetIAC {
+				t.state = telnetSubCommand
+			}
+		case telnetSubCommand:
+			t.state = telnetSub
+			if b == telnetSE {
+				t.state = telnetData
+			}
+		}
+	}
+	if len(replies) > 0 {
+		t.Conn.Write(replies)
+	}
+	return out
+}
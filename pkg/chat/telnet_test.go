@@ -0,0 +1,82 @@
+package chat
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+)
+
+func TestTelnetFilter(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     []byte
+		want      string
+		wantReply []byte
+	}{
+		{"Plain", []byte("hello\r\n"), "hello\r\n", nil},
+		{"WillRefused", []byte{telnetIAC, telnetWILL, 31, 'h', 'i'}, "hi", []byte{telnetIAC, telnetDONT, 31}},
+		{"DoRefused", []byte{telnetIAC, telnetDO, 1, 'h', 'i'}, "hi", []byte{telnetIAC, telnetWONT, 1}},
+		{"WontIgnored", []byte{telnetIAC, telnetWONT, 1, 'h', 'i'}, "hi", nil},
+		{"Subnegotiation", []byte{'a', telnetIAC, telnetSB, 24, 0, 'x', 't', telnetIAC, telnetSE, 'b'}, "ab", nil},
+		{"EscapedIAC", []byte{'a', telnetIAC, telnetIAC, 'b'}, "a\xffb", nil},
+		{"CRNUL", []byte("a\r\x00b"), "a\rb", nil},
+		{"NOP", []byte{'a', telnetIAC, 241, 'b'}, "ab", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, client := net.Pipe()
+			defer server.Close()
+			defer client.Close()
+			replies := make(chan []byte, 1)
+			go func() {
+				buf := make([]byte, 16)
+				n, _ := client.Read(buf)
+				replies <- buf[:n]
+			}()
+
+			conn := newTelnetConn(server)
+			buf := append([]byte(nil), tt.input...)
+			if got := string(buf[:conn.filter(buf)]); got != tt.want {
+				t.Errorf("filtered %q, want %q", got, tt.want)
+			}
+			if tt.wantReply != nil {
+				if reply := <-replies; !bytes.Equal(reply, tt.wantReply) {
+					t.Errorf("reply %v, want %v", reply, tt.wantReply)
+				}
+			}
+		})
+	}
+}
+
+func TestTelnetSplitSequence(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	go func() {
+		// IAC WILL split across writes, as a slow link might deliver it
+		client.Write([]byte{'h', telnetIAC})
+		client.Write([]byte{telnetWONT})
+		client.Write([]byte{3, 'i', '\n'})
+		client.Close()
+	}()
+	data, _ := io.ReadAll(newTelnetConn(server))
+	if string(data) != "hi\n" {
+		t.Errorf("read %q, want %q", data, "hi\n")
+	}
+}
+
+func TestTelnetClientName(t *testing.T) {
+	addr := setupTestServer(t)
+	client, err := newTestClient(t, addr)
+	if err != nil {
+		t.Fatalf("Client connection failed: %v", err)
+	}
+	defer client.close()
+
+	// What a telnet client sends when it connects and types a name
+	client.conn.Write([]byte{telnetIAC, telnetDO, 3, telnetIAC, telnetWILL, 24})
+	client.conn.Write([]byte("Alice\r\n"))
+	if err := client.expectMessage(t, "] Alice joined"); err != nil {
+		t.Errorf("Telnet name not cleaned: %v", err)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package chat
+
+import "fmt"
+
+// makeRaw reports that the line editor is only available on Linux; client
+// mode then reads whole lines as the terminal delivers them
+func makeRaw(fd int) (func(), error) {
+	return nil, fmt.Errorf("line editing is not supported on this platform")
+}
+
+func terminalWidth(fd int) int {
+	return 0
+}
@@ -0,0 +1,216 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/jroimartin/gocui"
+)
+
+// DefaultTheme is the theme of the server UI unless another is picked
+const DefaultTheme = "default"
+
+// Theme colors the server's terminal UI. Every color is a list of words:
+// one of the color names in themeColors and, optionally, bold or
+// underline, e.g. "bold red".
+type Theme struct {
+	Nicks     []string `json:"nicks"`     // Nicknames are spread over these
+	System    string   `json:"system"`    // What the server says
+	Private   string   `json:"private"`   // Private messages
+	Error     string   `json:"error"`     // Errors
+	Highlight string   `json:"highlight"` // Mentions, keyword alerts and the frame of the focused view
+	Border    string   `json:"border"`    // Frames of the other views
+	Selection string   `json:"selection"` // Background of the selected room
+}
+
+// themeColors maps color names to SGR foreground codes. gocui shows no
+// more than these eight colors.
+var themeColors = map[string]int{
+	"default": 39,
+	"black":   30,
+	"red":     31,
+	"green":   32,
+	"yellow":  33,
+	"blue":    34,
+	"magenta": 35,
+	"cyan":    36,
+	"white":   37,
+}
+
+// themeAttributes maps the attributes a color may add to SGR codes
+var themeAttributes = map[string]struct {
+	code int
+	attr gocui.Attribute
+}{
+	"bold":      {1, gocui.AttrBold},
+	"underline": {4, gocui.AttrUnderline},
+}
+
+// themePresets are the built-in themes
+var themePresets = map[string]Theme{
+	DefaultTheme: {
+		Nicks:     []string{"green", "yellow", "blue", "magenta", "cyan"},
+		System:    "yellow",
+		Private:   "magenta",
+		Error:     "bold red",
+		Highlight: "bold",
+		Border:    "default",
+		Selection: "green",
+	},
+	// For terminals with a light background, where yellow is hard to read
+	"light": {
+		Nicks:     []string{"blue", "magenta", "green", "cyan"},
+		System:    "blue",
+		Private:   "magenta",
+		Error:     "bold red",
+		Highlight: "bold blue",
+		Border:    "blue",
+		Selection: "cyan",
+	},
+	// Attributes only, for terminals without colors
+	"mono": {
+		Nicks:     []string{"default"},
+		System:    "default",
+		Private:   "underline",
+		Error:     "bold",
+		Highlight: "bold",
+		Border:    "default",
+		Selection: "white",
+	},
+}
+
+// LoadThemes reads custom themes from a JSON file mapping their names to
+// their colors. Colors a theme leaves out are those of the default theme.
+func LoadThemes(path string) (map[string]Theme, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid themes file %s: %v", path, err)
+	}
+	themes := make(map[string]Theme, len(raw))
+	for name, data := range raw {
+		theme := themePresets[DefaultTheme]
+		if err := json.Unmarshal(data, &theme); err != nil {
+			return nil, fmt.Errorf("invalid theme %s in %s: %v", name, path, err)
+		}
+		if err := theme.validate(); err != nil {
+			return nil, fmt.Errorf("invalid theme %s in %s: %v", name, path, err)
+		}
+		themes[name] = theme
+	}
+	return themes, nil
+}
+
+// lookupTheme returns the theme called name, custom themes first
+func lookupTheme(name string, custom map[string]Theme) (Theme, bool) {
+	if theme, ok := custom[name]; ok {
+		return theme, true
+	}
+	theme, ok := themePresets[name]
+	return theme, ok
+}
+
+// themeNames lists the presets and the custom themes, sorted
+func themeNames(custom map[string]Theme) []string {
+	names := make([]string, 0, len(themePresets)+len(custom))
+	for name := range themePresets {
+		if _, ok := custom[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	for name := range custom {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validate checks that every color of the theme can be shown
+func (t Theme) validate() error {
+	if len(t.Nicks) == 0 {
+		return fmt.Errorf("no nick colors")
+	}
+	colors := append([]string{t.System, t.Private, t.Error, t.Highlight, t.Border, t.Selection}, t.Nicks...)
+	for _, color := range colors {
+		if _, _, err := parseThemeColor(color); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseThemeColor returns the SGR sequence and the gocui attribute of a
+// color. Without a color name it keeps the default color.
+func parseThemeColor(color string) (string, gocui.Attribute, error) {
+	var codes []string
+	attr := gocui.ColorDefault
+	for _, word := range strings.Fields(strings.ToLower(color)) {
+		if a, ok := themeAttributes[word]; ok {
+			codes = append(codes, fmt.Sprint(a.code))
+			attr |= a.attr
+			continue
+		}
+		code, ok := themeColors[word]
+		if !ok {
+			return "", 0, fmt.Errorf("unknown color %q", word)
+		}
+		codes = append(codes, fmt.Sprint(code))
+		if code != 39 {
+			attr = attr&^0xff | gocui.Attribute(code-30+1)
+		}
+	}
+	if len(codes) == 0 {
+		return "", attr, nil
+	}
+	return "\x1b[" + strings.Join(codes, ";") + "m", attr, nil
+}
+
+// sgr returns the SGR sequence of a color, which validate has checked
+func sgr(color string) string {
+	code, _, _ := parseThemeColor(color)
+	return code
+}
+
+// attribute returns the gocui attribute of a color, which validate has
+// checked
+func attribute(color string) gocui.Attribute {
+	_, attr, _ := parseThemeColor(color)
+	return attr
+}
+
+// render is renderMessage in the theme's colors
+func (t Theme) render(msg Message) string {
+	switch msg.Type {
+	case MessageTypeChat, MessageTypePrivate, MessageTypeMention, MessageTypeKeyword:
+		msg.Content = renderANSI(parseMarkup(msg.Content))
+	}
+	var code string
+	switch msg.Type {
+	case MessageTypeChat:
+		msg.From = paint(t.nickColor(msg.From), msg.From)
+		return formatMessage(msg)
+	case MessageTypeMention, MessageTypeKeyword:
+		code = sgr(t.Highlight)
+		msg.From = paint(t.nickColor(msg.From), msg.From) + code
+	case MessageTypePrivate:
+		code = sgr(t.Private)
+	case MessageTypeError:
+		code = sgr(t.Error)
+	default:
+		code = sgr(t.System)
+	}
+	// The bell is for text clients
+	return paint(code, strings.TrimPrefix(formatMessage(msg), "\a"))
+}
+
+// nickColor returns the SGR sequence for name among the theme's nick
+// colors, the same for every run
+func (t Theme) nickColor(name string) string {
+	return sgr(t.Nicks[nickHash(name)%uint32(len(t.Nicks))])
+}
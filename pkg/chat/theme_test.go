@@ -0,0 +1,86 @@
+package chat
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jroimartin/gocui"
+)
+
+func TestThemePresets(t *testing.T) {
+	for name, theme := range themePresets {
+		if err := theme.validate(); err != nil {
+			t.Errorf("preset %s: %v", name, err)
+		}
+	}
+}
+
+func TestParseThemeColor(t *testing.T) {
+	tests := []struct {
+		color string
+		sgr   string
+		attr  gocui.Attribute
+	}{
+		{"", "", gocui.ColorDefault},
+		{"red", "\x1b[31m", gocui.ColorRed},
+		{"Bold Red", "\x1b[1;31m", gocui.ColorRed | gocui.AttrBold},
+		{"underline", "\x1b[4m", gocui.ColorDefault | gocui.AttrUnderline},
+		{"default", "\x1b[39m", gocui.ColorDefault},
+	}
+	for _, test := range tests {
+		sgr, attr, err := parseThemeColor(test.color)
+		if err != nil || sgr != test.sgr || attr != test.attr {
+			t.Errorf("%q: got %q, %v, %v, want %q, %v", test.color, sgr, attr, err, test.sgr, test.attr)
+		}
+	}
+	if _, _, err := parseThemeColor("bright red"); err == nil {
+		t.Error("expected an error for an unknown color")
+	}
+}
+
+func TestLoadThemes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "themes.json")
+	os.WriteFile(path, []byte(`{"solar": {"system": "cyan", "nicks": ["red", "blue"]}}`), 0o600)
+	themes, err := LoadThemes(path)
+	if err != nil {
+		t.Fatalf("LoadThemes failed: %v", err)
+	}
+	solar, ok := lookupTheme("solar", themes)
+	if !ok || solar.System != "cyan" || len(solar.Nicks) != 2 {
+		t.Fatalf("custom theme not loaded: %+v", solar)
+	}
+	if solar.Error != themePresets[DefaultTheme].Error {
+		t.Errorf("expected the default error color, got %q", solar.Error)
+	}
+	if got := strings.Join(themeNames(themes), " "); got != "default light mono solar" {
+		t.Errorf("expected sorted theme names, got %q", got)
+	}
+
+	os.WriteFile(path, []byte(`{"bad": {"border": "purple"}}`), 0o600)
+	if _, err := LoadThemes(path); err == nil || !strings.Contains(err.Error(), "purple") {
+		t.Errorf("expected an error naming the bad color, got %v", err)
+	}
+}
+
+func TestThemeRender(t *testing.T) {
+	now := time.Now()
+	theme := themePresets[DefaultTheme]
+	chat := theme.render(Message{Type: MessageTypeChat, From: "Alice", Content: "*hi*", Timestamp: now})
+	if !strings.Contains(chat, "["+theme.nickColor("Alice")+"Alice"+ansiReset+"]: \x1b[1mhi") {
+		t.Errorf("chat line not themed: %q", chat)
+	}
+	notice := theme.render(Message{Type: MessageTypeSystem, Content: "Bob joined the room", Timestamp: now})
+	if !strings.HasPrefix(notice, "\x1b[33m[") || !strings.HasSuffix(notice, ansiReset) {
+		t.Errorf("system notice not yellow: %q", notice)
+	}
+	mention := theme.render(Message{Type: MessageTypeMention, From: "Bob", To: "general", Content: "hey", Timestamp: now})
+	if strings.Contains(mention, "\a") {
+		t.Errorf("mention kept the bell: %q", mention)
+	}
+	if mono := themePresets["mono"]; mono.nickColor("Alice") != mono.nickColor("Bob") {
+		t.Error("mono should not color nicknames apart")
+	}
+}
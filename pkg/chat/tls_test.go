@@ -0,0 +1,141 @@
+package chat
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestCertificate creates a self-signed localhost certificate
+func writeTestCertificate(t *testing.T) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("key generation failed: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("certificate creation failed: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("key encoding failed: %v", err)
+	}
+
+	dir := t.TempDir()
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+	return certFile, keyFile
+}
+
+func newTLSTestClient(t *testing.T, address string) (*TestClient, error) {
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", address, &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to server: %v", err)
+	}
+
+	return &TestClient{
+		conn:   conn,
+		reader: bufio.NewReader(conn),
+	}, nil
+}
+
+func TestTLSListener(t *testing.T) {
+	config := DefaultConfig()
+	config.TLSCert, config.TLSKey = writeTestCertificate(t)
+	addr := setupTestServerWithConfig(t, config)
+
+	client, err := newTLSTestClient(t, addr)
+	if err != nil {
+		t.Fatalf("TLS client connection failed: %v", err)
+	}
+	defer client.close()
+
+	if err := client.expectMessage(t, "Welcome"); err != nil {
+		t.Fatalf("Welcome message failed: %v", err)
+	}
+	if err := client.sendMessage("SecureUser"); err != nil {
+		t.Fatalf("Send message failed: %v", err)
+	}
+	if err := client.expectMessage(t, "joined"); err != nil {
+		t.Fatalf("Join message failed: %v", err)
+	}
+
+	// A plaintext client must not get a readable session
+	plain, err := newTestClient(t, addr)
+	if err != nil {
+		t.Fatalf("Plain connection failed: %v", err)
+	}
+	defer plain.close()
+	plain.sendMessage("hello")
+	if err := plain.expectMessage(t, "Welcome"); err == nil {
+		t.Error("plaintext client received the welcome over a TLS listener")
+	}
+}
+
+func TestTLSMissingCertificate(t *testing.T) {
+	config := DefaultConfig()
+	config.TLSCert = filepath.Join(t.TempDir(), "missing.pem")
+	config.TLSKey = config.TLSCert
+	s := NewServerWithConfig(config)
+	defer s.Logfile.Close()
+
+	if err := s.Start("127.0.0.1:0"); err == nil {
+		t.Error("expected Start to fail without a certificate")
+	}
+}
+
+func TestTLSOptional(t *testing.T) {
+	config := DefaultConfig()
+	config.TLSCert, config.TLSKey = writeTestCertificate(t)
+	config.TLSOptional = true
+	addr := setupTestServerWithConfig(t, config)
+
+	secure, err := newTLSTestClient(t, addr)
+	if err != nil {
+		t.Fatalf("TLS client connection failed: %v", err)
+	}
+	defer secure.close()
+	plain, err := newTestClient(t, addr)
+	if err != nil {
+		t.Fatalf("Plain connection failed: %v", err)
+	}
+	defer plain.close()
+
+	for _, c := range []*TestClient{secure, plain} {
+		if err := c.expectMessage(t, "Welcome"); err != nil {
+			t.Fatalf("Welcome message failed: %v", err)
+		}
+	}
+	secure.sendMessage("SecureUser")
+	plain.sendMessage("PlainUser")
+	if err := secure.expectMessage(t, "PlainUser joined"); err != nil {
+		t.Fatalf("plaintext client did not join: %v", err)
+	}
+	plain.sendMessage("hello from plaintext")
+	if err := secure.expectMessage(t, "hello from plaintext"); err != nil {
+		t.Errorf("message not relayed to the TLS client: %v", err)
+	}
+}
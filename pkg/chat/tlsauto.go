@@ -0,0 +1,77 @@
+package chat
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"time"
+)
+
+// tlsSniffTimeout is how long a new connection on an auto-detecting
+// listener has to start a TLS handshake. Plaintext clients wait for the
+// server to speak first, so they are only delayed this long.
+const tlsSniffTimeout = 250 * time.Millisecond
+
+// tlsRecordHandshake opens every TLS ClientHello
+const tlsRecordHandshake = 0x16
+
+// autoTLSListener serves TLS and plaintext clients on one port
+// (Config.TLSOptional). It accepts plain connections; upgrade tells them
+// apart.
+type autoTLSListener struct {
+	net.Listener
+	config *tls.Config
+	// required wraps every connection in TLS without sniffing. A TLS-only
+	// listener uses it behind PROXY protocol, whose header arrives in
+	// plaintext before the handshake.
+	required bool
+}
+
+// peekedConn replays a byte read while sniffing before the rest of the
+// connection
+type peekedConn struct {
+	net.Conn
+	peeked []byte
+}
+
+func (p *peekedConn) Read(b []byte) (int, error) {
+	if len(p.peeked) == 0 {
+		return p.Conn.Read(b)
+	}
+	n := copy(b, p.peeked)
+	p.peeked = p.peeked[n:]
+	return n, nil
+}
+
+// upgrade returns conn as a TLS server connection if it opens with a TLS
+// handshake or the listener requires TLS, and as plaintext otherwise. A
+// nil listener leaves conn alone.
+func (l *autoTLSListener) upgrade(conn net.Conn) net.Conn {
+	if l == nil {
+		return conn
+	}
+	if l.required {
+		return tls.Server(conn, l.config)
+	}
+	first := make([]byte, 1)
+	conn.SetReadDeadline(time.Now().Add(tlsSniffTimeout))
+	n, _ := conn.Read(first)
+	conn.SetReadDeadline(time.Time{})
+	if n == 0 {
+		return conn
+	}
+	peeked := &peekedConn{Conn: conn, peeked: first}
+	if first[0] == tlsRecordHandshake {
+		return tls.Server(peeked, l.config)
+	}
+	return peeked
+}
+
+// serveAutoTLS sniffs a connection from an auto-detecting listener before
+// admitting it
+func (s *Server) serveAutoTLS(ctx context.Context, conn net.Conn, filter *ipFilter, auto *autoTLSListener) {
+	conn = auto.upgrade(conn)
+	if s.admit(conn, filter) {
+		s.serveConn(ctx, newTelnetConn(s.meter(conn)))
+	}
+}
@@ -0,0 +1,159 @@
+package chat
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Transcripts rotate once they reach DefaultTranscriptMaxSize bytes and
+// keep DefaultTranscriptKeep rotated files
+const (
+	DefaultTranscriptMaxSize = 10 << 20
+	DefaultTranscriptKeep    = 5
+)
+
+// Markers that start the lines of a transcript after their timestamp
+const (
+	transcriptReceived = "<"
+	transcriptSent     = ">"
+	transcriptNotice   = "*" // Said by the client itself, like reconnects
+)
+
+// passwordArgs maps the commands that take a password to the field of the
+// line that holds it
+var passwordArgs = map[string]int{
+	"/register": 1,
+	"/identify": 1,
+	"/join":     2,
+	"/create":   2,
+	"bot":       1, // Bots log in with BOT <token>
+}
+
+// transcript appends everything client mode receives and sends to a local
+// file, one timestamped line each. Once the file reaches maxSize it is
+// renamed to path.1, path.1 to path.2 and so on, keeping at most keep old
+// files.
+type transcript struct {
+	mutex   sync.Mutex
+	path    string
+	maxSize int64
+	keep    int
+	file    *os.File
+	size    int64
+}
+
+func openTranscript(path string, maxSize int64, keep int) (*transcript, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultTranscriptMaxSize
+	}
+	t := &transcript{path: path, maxSize: maxSize, keep: max(keep, 0)}
+	if err := t.open(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *transcript) open() error {
+	f, err := os.OpenFile(t.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open transcript: %v", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to open transcript: %v", err)
+	}
+	t.file, t.size = f, info.Size()
+	return nil
+}
+
+// record appends text, one line per line of it, stripped of colors
+func (t *transcript) record(marker, text string) error {
+	if t == nil {
+		return nil
+	}
+	var b strings.Builder
+	stamp := time.Now().Format("2006-01-02 15:04:05")
+	for _, line := range strings.Split(ansiPattern.ReplaceAllString(text, ""), "\n") {
+		if line = strings.TrimRight(line, "\r "); line != "" {
+			fmt.Fprintf(&b, "[%s] %s %s\n", stamp, marker, line)
+		}
+	}
+	if b.Len() == 0 {
+		return nil
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.file == nil {
+		return fmt.Errorf("transcript closed")
+	}
+	if t.size > 0 && t.size+int64(b.Len()) > t.maxSize {
+		if err := t.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := t.file.WriteString(b.String())
+	t.size += int64(n)
+	return err
+}
+
+// sent records a line the user sent, with passwords blanked out. Before
+// logging in every line but the name may be a password, so only the name
+// is kept.
+func (t *transcript) sent(line string, loggedIn, first bool) error {
+	if !loggedIn && !first {
+		line = "***"
+	}
+	return t.record(transcriptSent, redactPassword(line))
+}
+
+// rotate shifts the old files up by one and starts a new file. Caller
+// holds t.mutex.
+func (t *transcript) rotate() error {
+	t.file.Close()
+	t.file = nil
+	if t.keep == 0 {
+		os.Remove(t.path)
+	} else {
+		os.Remove(fmt.Sprintf("%s.%d", t.path, t.keep))
+		for i := t.keep - 1; i >= 1; i-- {
+			os.Rename(fmt.Sprintf("%s.%d", t.path, i), fmt.Sprintf("%s.%d", t.path, i+1))
+		}
+		if err := os.Rename(t.path, t.path+".1"); err != nil {
+			return fmt.Errorf("failed to rotate transcript: %v", err)
+		}
+	}
+	return t.open()
+}
+
+func (t *transcript) close() error {
+	if t == nil {
+		return nil
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.file == nil {
+		return nil
+	}
+	err := t.file.Close()
+	t.file = nil
+	return err
+}
+
+// redactPassword blanks out the password of commands that take one
+func redactPassword(line string) string {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return line
+	}
+	i, ok := passwordArgs[strings.ToLower(fields[0])]
+	if !ok || i >= len(fields) || fields[i] == "--" {
+		return line
+	}
+	fields[i] = "***"
+	return strings.Join(fields, " ")
+}
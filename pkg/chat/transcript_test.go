@@ -0,0 +1,96 @@
+package chat
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTranscript(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transcript.log")
+	tr, err := openTranscript(path, 1<<20, 2)
+	if err != nil {
+		t.Fatalf("openTranscript failed: %v", err)
+	}
+	tr.record(transcriptReceived, "\x1b[1mWelcome\x1b[0m\n\n[ENTER YOUR NAME]:")
+	tr.sent("Alice", false, true)
+	tr.sent("hunter2", false, false)
+	tr.sent("/join dev letmein", true, false)
+	tr.sent("hello", true, false)
+	tr.record(transcriptNotice, "Connection lost.")
+	tr.close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		// Drop the timestamp
+		if !strings.HasPrefix(line, "[") || len(line) < 22 {
+			t.Fatalf("line without a timestamp: %q", line)
+		}
+		got = append(got, line[22:])
+	}
+	want := []string{"< Welcome", "< [ENTER YOUR NAME]:", "> Alice", "> ***", "> /join dev ***", "> hello", "* Connection lost."}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("transcript = %q, want %q", got, want)
+	}
+}
+
+func TestTranscriptRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transcript.log")
+	tr, err := openTranscript(path, 100, 2)
+	if err != nil {
+		t.Fatalf("openTranscript failed: %v", err)
+	}
+	defer tr.close()
+
+	// Each line is 60 bytes with its timestamp, so every line but the
+	// first rotates
+	for _, text := range []string{"first", "second", "third", "fourth"} {
+		tr.record(transcriptReceived, text+strings.Repeat(".", 36-len(text)))
+	}
+	for file, want := range map[string]string{path: "fourth", path + ".1": "third", path + ".2": "second"} {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("missing %s: %v", file, err)
+		}
+		if strings.Count(string(data), "\n") != 1 || !strings.Contains(string(data), want) {
+			t.Errorf("%s = %q, want the %s line", filepath.Base(file), data, want)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("kept more than 2 rotated files")
+	}
+
+	// Appending to an existing transcript counts what is already there
+	tr.close()
+	tr, err = openTranscript(path, 100, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.record(transcriptReceived, "fifth"+strings.Repeat(".", 31))
+	if data, _ := os.ReadFile(path + ".1"); !strings.Contains(string(data), "fourth") {
+		t.Errorf("reopened transcript did not rotate: .1 = %q", data)
+	}
+}
+
+func TestRedactPassword(t *testing.T) {
+	tests := map[string]string{
+		"/identify secret":         "/identify ***",
+		"/REGISTER secret":         "/REGISTER ***",
+		"/join dev":                "/join dev",
+		"/join dev secret":         "/join dev ***",
+		"/create dev -- the room":  "/create dev -- the room",
+		"/create dev pw -- a room": "/create dev *** -- a room",
+		"BOT abc123":               "BOT ***",
+		"hello /identify":          "hello /identify",
+	}
+	for line, want := range tests {
+		if got := redactPassword(line); got != want {
+			t.Errorf("redactPassword(%q) = %q, want %q", line, got, want)
+		}
+	}
+}
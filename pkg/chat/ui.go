@@ -0,0 +1,563 @@
+// ui.go
+package chat
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+    "time"
+
+    "github.com/jroimartin/gocui"
+)
+
+// uiSidebarWidth is how wide the rooms and users views are
+const uiSidebarWidth = 20
+
+type ChatUI struct {
+    gui         *gocui.Gui
+    server      *Server
+    msgView     string
+    inputView   string
+    statusView  string
+    userView    string
+    roomView    string
+    helpView    string
+    activeView  string
+    showHelp    bool
+    tabs        roomTabs       // Rooms with a message view of their own
+    unread      map[string]int // Chat messages per room since its tab was last shown
+    roomLines   []string       // Room on each line of the rooms view, "" for details
+    themeName   string
+    theme       Theme
+}
+
+func NewChatUI(server *Server) (*ChatUI, error) {
+    themeName := server.config.UITheme
+    if themeName == "" {
+        themeName = DefaultTheme
+    }
+    theme, ok := lookupTheme(themeName, server.config.UIThemes)
+    if !ok {
+        return nil, fmt.Errorf("unknown theme %q", themeName)
+    }
+
+    g, err := gocui.NewGui(gocui.OutputNormal)
+    if err != nil {
+        return nil, err
+    }
+
+    ui := &ChatUI{
+        gui:         g,
+        server:      server,
+        msgView:     "messages",
+        inputView:   "input",
+        statusView:  "status",
+        userView:    "users",
+        roomView:    "rooms",
+        helpView:    "help",
+        activeView:  "input",
+        showHelp:    false,
+        unread:      make(map[string]int),
+        themeName:   themeName,
+        theme:       theme,
+    }
+    ui.tabs.open(server.lobby())
+
+    g.SetManagerFunc(ui.layout)
+    server.onRecord = ui.showMessage
+    return ui, nil
+}
+
+// showMessage appends a message to the tab of its room, opening one if
+// the room has none, or to every tab if it is for the whole server
+func (ui *ChatUI) showMessage(room string, msg Message) {
+    ui.gui.Update(func(g *gocui.Gui) error {
+        rooms := []string{room}
+        if room == "" {
+            rooms = append([]string(nil), ui.tabs.rooms...)
+        }
+        line := ui.theme.render(msg)
+        for _, r := range rooms {
+            v, err := ui.openTab(g, r)
+            if err != nil {
+                return err
+            }
+            fmt.Fprintln(v, line)
+        }
+        if room != "" && room != ui.tabs.room() && msg.Type == MessageTypeChat {
+            ui.unread[room]++
+            ui.updateRooms()
+        }
+        return ui.updateTabs(g)
+    })
+}
+
+// tabView returns the name of the message view of a room's tab
+func (ui *ChatUI) tabView(room string) string {
+    return ui.msgView + ":" + room
+}
+
+// isTabView reports whether name is the message view of a tab
+func (ui *ChatUI) isTabView(name string) bool {
+    return strings.HasPrefix(name, ui.msgView+":")
+}
+
+// messageBounds returns where the message views go
+func messageBounds(g *gocui.Gui) (int, int, int, int) {
+    maxX, maxY := g.Size()
+    return 0, 0, maxX - uiSidebarWidth - 1, maxY - 6
+}
+
+// openTab returns the message view of room's tab, opening the tab behind
+// the one shown if there is none
+func (ui *ChatUI) openTab(g *gocui.Gui, room string) (*gocui.View, error) {
+    ui.tabs.open(room)
+    x0, y0, x1, y1 := messageBounds(g)
+    name := ui.tabView(room)
+    v, err := g.SetView(name, x0, y0, x1, y1)
+    if err != gocui.ErrUnknownView {
+        return v, err
+    }
+    v.Wrap = true
+    v.Autoscroll = true
+    if room != ui.tabs.room() {
+        if _, err := g.SetViewOnBottom(name); err != nil {
+            return nil, err
+        }
+    }
+    return v, nil
+}
+
+// showTab brings tab i to the front, which marks its room read
+func (ui *ChatUI) showTab(g *gocui.Gui, i int) error {
+    if !ui.tabs.show(i) {
+        return nil
+    }
+    room := ui.tabs.room()
+    delete(ui.unread, room)
+    name := ui.tabView(room)
+    if _, err := g.SetViewOnTop(name); err != nil {
+        return err
+    }
+    if ui.showHelp {
+        g.SetViewOnTop(ui.helpView)
+    }
+    if ui.isTabView(ui.activeView) {
+        ui.activeView = name
+        if _, err := g.SetCurrentView(name); err != nil {
+            return err
+        }
+    }
+    ui.updateRooms()
+    ui.updateStatus(ui.statusLine())
+    return ui.updateTabs(g)
+}
+
+// closeTab closes the tab shown, unless it is the first
+func (ui *ChatUI) closeTab(g *gocui.Gui, _ *gocui.View) error {
+    room := ui.tabs.close()
+    if room == "" {
+        return nil
+    }
+    if err := g.DeleteView(ui.tabView(room)); err != nil {
+        return err
+    }
+    if ui.isTabView(ui.activeView) {
+        ui.activeView = ui.tabView(ui.tabs.room())
+    }
+    return ui.showTab(g, ui.tabs.current)
+}
+
+// updateTabs lists the tabs in the title of the one shown, saying how far
+// back it is scrolled
+func (ui *ChatUI) updateTabs(g *gocui.Gui) error {
+    v, err := g.View(ui.tabView(ui.tabs.room()))
+    if err != nil {
+        return err
+    }
+    v.Title = ui.tabs.title(ui.unread)
+    if below := linesBelow(v); below > 0 {
+        v.Title += fmt.Sprintf(" | scrolled back %d lines, End for latest", below)
+    }
+    return nil
+}
+
+func (ui *ChatUI) layout(g *gocui.Gui) error {
+    maxX, maxY := g.Size()
+    
+    _, _, msgWidth, msgHeight := messageBounds(g)
+    roomHeight := 10
+
+    // Message views, one per tab
+    for _, room := range ui.tabs.rooms {
+        if _, err := ui.openTab(g, room); err != nil {
+            return err
+        }
+    }
+    if err := ui.updateTabs(g); err != nil {
+        return err
+    }
+
+    // Rooms view
+    if v, err := g.SetView(ui.roomView, msgWidth+1, 0, maxX-1, roomHeight); err != nil {
+        if err != gocui.ErrUnknownView {
+            return err
+        }
+        v.Title = "Rooms"
+        v.Highlight = true
+        ui.updateRooms()
+    }
+
+    // Users view
+    if v, err := g.SetView(ui.userView, msgWidth+1, roomHeight+1, maxX-1, msgHeight); err != nil {
+        if err != gocui.ErrUnknownView {
+            return err
+        }
+        v.Title = "Online Users"
+        v.Wrap = true
+        ui.updateUsers()
+    }
+
+    // Status bar
+    if v, err := g.SetView(ui.statusView, 0, msgHeight+1, maxX-1, msgHeight+3); err != nil {
+        if err != gocui.ErrUnknownView {
+            return err
+        }
+        v.Title = "Status"
+        v.Wrap = true
+        ui.updateStatus(ui.statusLine())
+    }
+
+    // Input field
+    if v, err := g.SetView(ui.inputView, 0, msgHeight+3, maxX-1, maxY-1); err != nil {
+        if err != gocui.ErrUnknownView {
+            return err
+        }
+        v.Title = "Input"
+        v.Editable = true
+        v.Wrap = true
+        
+        if _, err := g.SetCurrentView(ui.inputView); err != nil {
+            return err
+        }
+    }
+
+    // Help window
+    if ui.showHelp {
+        helpX1 := maxX/6
+        helpY1 := maxY/6
+        helpX2 := maxX*5/6
+        helpY2 := maxY*5/6
+        if v, err := g.SetView(ui.helpView, helpX1, helpY1, helpX2, helpY2); err != nil {
+            if err != gocui.ErrUnknownView {
+                return err
+            }
+            v.Title = "Help"
+            fmt.Fprintln(v, `Commands:
+/help           - Show this help
+/list           - List online users
+/nick <name>    - Change your nickname
+/msg <user> <message> - Send private message
+/join <room>    - Join a chat room
+/rooms          - List available rooms
+/create <room>  - Create a new room
+/theme [name]   - List the color themes or switch to one
+/quit           - Leave chat
+
+Keybindings:
+Ctrl-C          - Quit
+Ctrl-H          - Toggle help
+Tab             - Next room tab
+Alt-1..Alt-9    - Room tab by number
+Ctrl-W          - Close the room tab
+Ctrl-O          - Switch views
+Up/Down, Enter  - Open a room's tab (rooms view)
+PgUp/PgDn       - Scroll messages
+Home/End        - Oldest/newest messages
+Enter           - Send message`)
+        }
+    }
+
+    ui.applyTheme(g)
+    return nil
+}
+
+// applyTheme colors the frames and the room selection. The text of the
+// views keeps the terminal's color.
+func (ui *ChatUI) applyTheme(g *gocui.Gui) {
+    g.FgColor = attribute(ui.theme.Border)
+    g.SelFgColor = attribute(ui.theme.Highlight)
+    g.Highlight = true
+    for _, v := range g.Views() {
+        v.FgColor = gocui.ColorDefault
+        v.SelBgColor = attribute(ui.theme.Selection)
+        v.SelFgColor = gocui.ColorBlack
+    }
+}
+
+// switchTheme handles the UI's /theme command
+func (ui *ChatUI) switchTheme(g *gocui.Gui, args []string) {
+    if len(args) == 0 {
+        ui.notice(g, fmt.Sprintf("Themes: %s (using %s)",
+            strings.Join(themeNames(ui.server.config.UIThemes), ", "), ui.themeName))
+        return
+    }
+    theme, ok := lookupTheme(args[0], ui.server.config.UIThemes)
+    if !ok {
+        ui.notice(g, fmt.Sprintf("Unknown theme %s", args[0]))
+        return
+    }
+    ui.themeName, ui.theme = args[0], theme
+    ui.applyTheme(g)
+    ui.notice(g, fmt.Sprintf("Using theme %s for new messages", args[0]))
+}
+
+// notice shows a line of the UI's own in the tab shown
+func (ui *ChatUI) notice(g *gocui.Gui, text string) {
+    if v, err := g.View(ui.tabView(ui.tabs.room())); err == nil {
+        fmt.Fprintln(v, paint(sgr(ui.theme.System), text))
+    }
+}
+
+func (ui *ChatUI) updateUsers() {
+    ui.gui.Update(func(g *gocui.Gui) error {
+        v, err := g.View(ui.userView)
+        if err != nil {
+            return err
+        }
+        v.Clear()
+
+        ui.server.mutex.RLock()
+        for _, client := range ui.server.clients.all() {
+            fmt.Fprintf(v, "%s (%s)\n", client.name, client.room)
+        }
+        ui.server.mutex.RUnlock()
+        return nil
+    })
+}
+
+func (ui *ChatUI) updateRooms() {
+    ui.gui.Update(func(g *gocui.Gui) error {
+        v, err := g.View(ui.roomView)
+        if err != nil {
+            return err
+        }
+        v.Clear()
+
+        ui.server.mutex.RLock()
+        names := make([]string, 0, len(ui.server.rooms))
+        for name := range ui.server.rooms {
+            names = append(names, name)
+        }
+        sort.Strings(names)
+        ui.roomLines = ui.roomLines[:0]
+        for _, name := range names {
+            room := ui.server.rooms[name]
+            if room.hidden && name != ui.tabs.room() {
+                continue
+            }
+            prefix := "  "
+            if name == ui.tabs.room() {
+                prefix = "* "
+            }
+            line := fmt.Sprintf("%s%s (%d)", prefix, name, room.size())
+            if n := ui.unread[name]; n > 0 {
+                line += fmt.Sprintf(" [%d]", n)
+            }
+            fmt.Fprintln(v, line)
+            ui.roomLines = append(ui.roomLines, name)
+            if room.description != "" {
+                fmt.Fprintf(v, "    %s\n", room.description)
+                ui.roomLines = append(ui.roomLines, "")
+            }
+            if room.topic != "" {
+                fmt.Fprintf(v, "    topic: %s\n", room.topic)
+                ui.roomLines = append(ui.roomLines, "")
+            }
+        }
+        ui.server.mutex.RUnlock()
+        return nil
+    })
+}
+
+// selectRoom shows the tab of the room on the selected line of the rooms
+// view, opening one if it has none
+func (ui *ChatUI) selectRoom(g *gocui.Gui, v *gocui.View) error {
+    _, cy := v.Cursor()
+    _, oy := v.Origin()
+    i := cy + oy
+    if i >= len(ui.roomLines) || ui.roomLines[i] == "" {
+        return nil
+    }
+    room := ui.roomLines[i]
+    if _, err := ui.openTab(g, room); err != nil {
+        return err
+    }
+    return ui.showTab(g, ui.tabs.find(room))
+}
+
+// moveRoomCursor returns a handler moving the rooms view selection
+func moveRoomCursor(dy int) func(*gocui.Gui, *gocui.View) error {
+    return func(_ *gocui.Gui, v *gocui.View) error {
+        v.MoveCursor(0, dy, false)
+        return nil
+    }
+}
+
+func (ui *ChatUI) statusLine() string {
+    return fmt.Sprintf("Connected to port %s | Room: %s | Ctrl-H: Help", ui.server.port, ui.tabs.room())
+}
+
+func (ui *ChatUI) updateStatus(status string) {
+    ui.gui.Update(func(g *gocui.Gui) error {
+        v, err := g.View(ui.statusView)
+        if err != nil {
+            return err
+        }
+        v.Clear()
+        fmt.Fprint(v, status)
+        return nil
+    })
+}
+
+func (ui *ChatUI) keybindings() error {
+    // Quit
+    if err := ui.gui.SetKeybinding("", gocui.KeyCtrlC, gocui.ModNone,
+        func(g *gocui.Gui, _ *gocui.View) error {
+            return gocui.ErrQuit
+        }); err != nil {
+        return err
+    }
+
+    // Toggle help
+    if err := ui.gui.SetKeybinding("", gocui.KeyCtrlH, gocui.ModNone,
+        func(_ *gocui.Gui, _ *gocui.View) error {
+            ui.showHelp = !ui.showHelp
+            return nil
+        }); err != nil {
+        return err
+    }
+
+    // Scroll back through the messages of the tab shown
+    if err := bindScrollKeys(ui.gui, func() string { return ui.tabView(ui.tabs.room()) },
+        ui.updateTabs); err != nil {
+        return err
+    }
+
+    // Switch and close room tabs
+    if err := ui.gui.SetKeybinding("", gocui.KeyTab, gocui.ModNone,
+        func(g *gocui.Gui, _ *gocui.View) error {
+            ui.tabs.next()
+            return ui.showTab(g, ui.tabs.current)
+        }); err != nil {
+        return err
+    }
+    for i := 0; i < 9; i++ {
+        if err := ui.gui.SetKeybinding("", rune('1'+i), gocui.ModAlt,
+            func(g *gocui.Gui, _ *gocui.View) error {
+                return ui.showTab(g, i)
+            }); err != nil {
+            return err
+        }
+    }
+    if err := ui.gui.SetKeybinding("", gocui.KeyCtrlW, gocui.ModNone, ui.closeTab); err != nil {
+        return err
+    }
+
+    // Pick the current room in the rooms view
+    roomKeys := map[gocui.Key]func(*gocui.Gui, *gocui.View) error{
+        gocui.KeyArrowUp:   moveRoomCursor(-1),
+        gocui.KeyArrowDown: moveRoomCursor(1),
+        gocui.KeyEnter:     ui.selectRoom,
+    }
+    for key, handler := range roomKeys {
+        if err := ui.gui.SetKeybinding(ui.roomView, key, gocui.ModNone, handler); err != nil {
+            return err
+        }
+    }
+
+    // Send message
+    if err := ui.gui.SetKeybinding(ui.inputView, gocui.KeyEnter, gocui.ModNone,
+        ui.handleInput); err != nil {
+        return err
+    }
+
+    // Switch views
+    if err := ui.gui.SetKeybinding("", gocui.KeyCtrlO, gocui.ModNone,
+        func(g *gocui.Gui, v *gocui.View) error {
+            name := v.Name()
+            if ui.isTabView(name) {
+                name = ui.msgView
+            }
+            nextView := map[string]string{
+                ui.msgView:   ui.roomView,
+                ui.roomView:  ui.userView,
+                ui.userView:  ui.inputView,
+                ui.inputView: ui.tabView(ui.tabs.room()),
+            }
+            if next, ok := nextView[name]; ok {
+                ui.activeView = next
+                _, err := g.SetCurrentView(next)
+                return err
+            }
+            return nil
+        }); err != nil {
+        return err
+    }
+
+    return nil
+}
+
+func (ui *ChatUI) handleInput(g *gocui.Gui, v *gocui.View) error {
+    input := strings.TrimSpace(v.Buffer())
+    if input == "" {
+        v.Clear()
+        v.SetCursor(0, 0)
+        return nil
+    }
+
+    v.Clear()
+    v.SetCursor(0, 0)
+
+    // Create a mock client for UI commands
+    client := &Client{
+        name:     "Server",
+        joinTime: time.Now(),
+        room:     ui.tabs.room(),
+        role:     RoleOwner,
+    }
+
+    if fields := strings.Fields(input); fields[0] == "/theme" {
+        ui.switchTheme(g, fields[1:])
+    } else if strings.HasPrefix(input, "/") {
+        ui.server.handleCommand(client, input)
+        ui.updateRooms()
+        ui.updateUsers()
+    } else {
+        msg := Message{
+            Type:      MessageTypeChat,
+            From:      "Server",
+            Content:   input,
+            Timestamp: time.Now(),
+        }
+        ui.server.broadcast(msg, nil)
+    }
+
+    return nil
+}
+
+func (ui *ChatUI) Run() error {
+    if err := ui.keybindings(); err != nil {
+        return err
+    }
+
+    if err := ui.gui.MainLoop(); err != nil && err != gocui.ErrQuit {
+        return err
+    }
+
+    return nil
+}
+
+func (ui *ChatUI) Close() {
+    ui.gui.Close()
+}
\ No newline at end of file
@@ -0,0 +1,221 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Limits for fetching link previews
+const (
+	unfurlTimeout        = 5 * time.Second
+	unfurlMaxBody        = 64 << 10 // The title is expected in the head
+	unfurlMaxRedirects   = 3
+	unfurlCacheTTL       = time.Hour
+	unfurlCacheSize      = 256
+	maxUnfurlsPerMessage = 3
+	maxTitleLength       = 120
+)
+
+var (
+	urlPattern   = regexp.MustCompile(`https?://[^\s<>"]+`)
+	titlePattern = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+)
+
+// messageURLs returns the distinct http(s) URLs in content, without the
+// punctuation that usually follows a link in a sentence
+func messageURLs(content string) []string {
+	var urls []string
+	for _, match := range urlPattern.FindAllString(content, -1) {
+		match = strings.TrimRight(match, ".,;:!?)]}'")
+		if !slices.Contains(urls, match) {
+			urls = append(urls, match)
+		}
+	}
+	return urls
+}
+
+// unfurler fetches the titles of pages linked in chat. Only hosts on the
+// allowlist are contacted, and connections to loopback, private and
+// link-local addresses are refused unless the allowlist names the address
+// itself, so chat users cannot make the server probe its own network.
+type unfurler struct {
+	allow  []string // Host names, "*.example.com" patterns or IP literals
+	client *http.Client
+	mutex  sync.Mutex
+	cache  map[string]unfurlEntry
+}
+
+type unfurlEntry struct {
+	title   string
+	expires time.Time
+}
+
+func newUnfurler(allow []string) *unfurler {
+	u := &unfurler{cache: make(map[string]unfurlEntry)}
+	for _, host := range allow {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			u.allow = append(u.allow, host)
+		}
+	}
+	dialer := &net.Dialer{Timeout: unfurlTimeout, Control: u.checkAddress}
+	u.client = &http.Client{
+		Timeout: unfurlTimeout,
+		// No Proxy: the address check must see the real destination
+		Transport: &http.Transport{DialContext: dialer.DialContext},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= unfurlMaxRedirects {
+				return fmt.Errorf("too many redirects")
+			}
+			if !u.allowed(req.URL.Hostname()) {
+				return fmt.Errorf("redirect to %s is not allowed", req.URL.Hostname())
+			}
+			return nil
+		},
+	}
+	return u
+}
+
+// allowed reports whether host is on the allowlist
+func (u *unfurler) allowed(host string) bool {
+	host = strings.ToLower(host)
+	for _, pattern := range u.allow {
+		if host == pattern {
+			return true
+		}
+		if suffix, ok := strings.CutPrefix(pattern, "*"); ok && strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkAddress runs before every connection, after name resolution
+func (u *unfurler) checkAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("unexpected address %s", address)
+	}
+	internal := ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast()
+	if internal && !slices.Contains(u.allow, ip.String()) {
+		return fmt.Errorf("refusing to connect to internal address %s", ip)
+	}
+	return nil
+}
+
+// title returns the title of the page at link, "" if it has none. Results,
+// including failures, are cached so a link pasted repeatedly is fetched once.
+func (u *unfurler) title(ctx context.Context, link string) (string, error) {
+	u.mutex.Lock()
+	entry, ok := u.cache[link]
+	u.mutex.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.title, nil
+	}
+
+	title, err := u.fetchTitle(ctx, link)
+	if ctx.Err() != nil {
+		// Not the page's fault, so not cached
+		return "", err
+	}
+	u.mutex.Lock()
+	if len(u.cache) >= unfurlCacheSize {
+		now := time.Now()
+		for key, e := range u.cache {
+			if now.After(e.expires) || len(u.cache) >= unfurlCacheSize {
+				delete(u.cache, key)
+			}
+		}
+	}
+	u.cache[link] = unfurlEntry{title: title, expires: time.Now().Add(unfurlCacheTTL)}
+	u.mutex.Unlock()
+	return title, err
+}
+
+func (u *unfurler) fetchTitle(ctx context.Context, link string) (string, error) {
+	parsed, err := url.Parse(link)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return "", fmt.Errorf("invalid link %q", link)
+	}
+	if !u.allowed(parsed.Hostname()) {
+		return "", nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", "TCP-Chat link preview")
+	req.Header.Set("Accept", "text/html")
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s returned %s", link, resp.Status)
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "text/html" {
+		return "", nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, unfurlMaxBody))
+	if err != nil {
+		return "", err
+	}
+
+	match := titlePattern.FindSubmatch(body)
+	if match == nil {
+		return "", nil
+	}
+	// Pages are untrusted input just like chat lines
+	title, _ := sanitizeInput(html.UnescapeString(string(match[1])))
+	title = strings.Join(strings.Fields(title), " ")
+	if runes := []rune(title); len(runes) > maxTitleLength {
+		title = string(runes[:maxTitleLength]) + "..."
+	}
+	return title, nil
+}
+
+// unfurlLinks posts the titles of the pages linked in msg to room, until
+// ctx is cancelled
+func (s *Server) unfurlLinks(ctx context.Context, room *ChatRoom, msg Message) {
+	links := messageURLs(msg.Content)
+	if len(links) > maxUnfurlsPerMessage {
+		links = links[:maxUnfurlsPerMessage]
+	}
+	for _, link := range links {
+		title, err := s.unfurler.title(ctx, link)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			s.log.Debug("Link preview failed", "url", link, "err", err)
+			continue
+		}
+		if title == "" {
+			continue
+		}
+		parsed, _ := url.Parse(link)
+		s.broadcastToRoom(room, Message{
+			Type:      MessageTypeSystem,
+			Content:   fmt.Sprintf("[link] %s (%s)", title, parsed.Hostname()),
+			Timestamp: time.Now(),
+		}, nil)
+	}
+}
@@ -0,0 +1,122 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMessageURLs(t *testing.T) {
+	got := messageURLs("see https://example.com/a?b=1, and (http://go.dev/doc). https://example.com/a?b=1")
+	want := []string{"https://example.com/a?b=1", "http://go.dev/doc"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("messageURLs = %v, want %v", got, want)
+	}
+}
+
+func TestUnfurlerAllowlist(t *testing.T) {
+	u := newUnfurler([]string{"example.com", "*.go.dev"})
+	for host, want := range map[string]bool{
+		"example.com":     true,
+		"EXAMPLE.com":     true,
+		"www.example.com": false,
+		"pkg.go.dev":      true,
+		"evil.com":        false,
+	} {
+		if got := u.allowed(host); got != want {
+			t.Errorf("allowed(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
+
+func TestUnfurlRefusesInternalAddresses(t *testing.T) {
+	page := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<title>secret</title>")
+	}))
+	defer page.Close()
+
+	// The name is allowed, but it resolves to loopback
+	u := newUnfurler([]string{"localhost"})
+	link := strings.Replace(page.URL, "127.0.0.1", "localhost", 1)
+	if title, err := u.title(context.Background(), link); err == nil || title != "" {
+		t.Errorf("title(%s) = %q, %v; want internal address refused", link, title, err)
+	}
+}
+
+func TestUnfurlLinks(t *testing.T) {
+	page := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, "<html><head><title>\n  Release notes &amp; news\x1b[2J </title></head></html>")
+	}))
+	defer page.Close()
+
+	config := DefaultConfig()
+	config.RoomsFile = ""
+	config.UnfurlLinks = true
+	config.UnfurlAllow = []string{"127.0.0.1"}
+	addr := setupTestServerWithConfig(t, config)
+	client, err := newTestClient(t, addr)
+	if err != nil {
+		t.Fatalf("Client connection failed: %v", err)
+	}
+	defer client.close()
+	client.sendMessage("Alice")
+	if err := client.expectMessage(t, "Alice joined"); err != nil {
+		t.Fatalf("Join failed: %v", err)
+	}
+
+	client.sendMessage("have a look: " + page.URL + "/notes.")
+	if err := client.expectMessage(t, "[link] Release notes & news (127.0.0.1)"); err != nil {
+		t.Fatalf("link preview not posted: %v", err)
+	}
+}
+
+func TestUnfurlStopsOnShutdown(t *testing.T) {
+	fetching, cancelled := make(chan struct{}), make(chan struct{})
+	page := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(fetching)
+		<-r.Context().Done()
+		close(cancelled)
+	}))
+	defer page.Close()
+
+	config := DefaultConfig()
+	config.AccountsFile = ""
+	config.RoomsFile = ""
+	config.Store = StoreMemory
+	config.UnfurlLinks = true
+	config.UnfurlAllow = []string{"127.0.0.1"}
+	s := NewServerWithConfig(config)
+	defer s.Logfile.Close()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	go s.Serve(l)
+	client, err := newTestClient(t, l.Addr().String())
+	if err != nil {
+		t.Fatalf("Client connection failed: %v", err)
+	}
+	defer client.close()
+	client.sendMessage("Alice")
+	client.sendMessage("slow page: " + page.URL)
+	select {
+	case <-fetching:
+	case <-time.After(time.Second):
+		t.Fatal("link not fetched")
+	}
+
+	// Shutdown waits for the preview, which gives up on the page
+	s.Shutdown()
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Error("link preview outlived Shutdown")
+	}
+}
@@ -0,0 +1,143 @@
+package chat
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jroimartin/gocui"
+)
+
+func formatMessage(msg Message) string {
+	return string(appendMessage(make([]byte, 0, 64+len(msg.From)+len(msg.Content)), msg))
+}
+
+// appendMessage appends msg as formatMessage would format it to dst
+func appendMessage(dst []byte, msg Message) []byte {
+	if msg.Type == MessageTypeMention || msg.Type == MessageTypeKeyword {
+		// The bell makes most terminals flag the window
+		dst = append(dst, '\a')
+	}
+	dst = append(dst, '[')
+	dst = msg.Timestamp.AppendFormat(dst, "2006-01-02 15:04:05")
+	switch msg.Type {
+	case MessageTypePrivate:
+		dst = append(dst, "][PM "...)
+		if msg.ID > 0 {
+			dst = appendID(dst, msg.ID)
+			dst = append(dst, ' ')
+		}
+		dst = append(dst, "from "...)
+		dst = append(dst, msg.From...)
+		dst = append(dst, "]: "...)
+	case MessageTypeSystem:
+		dst = append(dst, "] "...)
+	case MessageTypeError:
+		dst = append(dst, "][ERROR] "...)
+	case MessageTypeMention:
+		dst = append(dst, ']')
+		if msg.ID > 0 {
+			dst = append(dst, '[')
+			dst = appendID(dst, msg.ID)
+			dst = append(dst, ']')
+		}
+		dst = append(dst, '[')
+		dst = append(dst, msg.From...)
+		dst = append(dst, " in "...)
+		dst = append(dst, msg.To...)
+		dst = append(dst, " mentioned you]: "...)
+	case MessageTypeKeyword:
+		dst = append(dst, "]["...)
+		dst = appendID(dst, msg.ID)
+		dst = append(dst, "]["...)
+		dst = append(dst, msg.From...)
+		dst = append(dst, " in "...)
+		dst = append(dst, msg.To...)
+		dst = append(dst, "]: "...)
+	case MessageTypeReaction:
+		dst = append(dst, "] "...)
+		dst = append(dst, msg.From...)
+		dst = append(dst, " reacted "...)
+		dst = append(dst, msg.Content...)
+		dst = append(dst, " to "...)
+		return appendID(dst, msg.Ref)
+	case MessageTypeReceipt:
+		dst = append(dst, "] PM "...)
+		dst = appendID(dst, msg.Ref)
+		dst = append(dst, " to "...)
+		dst = append(dst, msg.From...)
+		dst = append(dst, ": "...)
+	default:
+		dst = append(dst, ']')
+		if msg.ID > 0 {
+			dst = append(dst, '[')
+			dst = appendID(dst, msg.ID)
+			dst = append(dst, ']')
+		}
+		dst = append(dst, '[')
+		dst = append(dst, msg.From...)
+		dst = append(dst, "]: "...)
+	}
+	return append(dst, msg.Content...)
+}
+
+// appendID appends a message ID as #<id>
+func appendID(dst []byte, id int64) []byte {
+	return strconv.AppendInt(append(dst, '#'), id, 10)
+}
+
+func RunWithUI(server *Server, port string) error {
+	ui, err := NewChatUI(server)
+	if err != nil {
+		return err
+	}
+	defer ui.Close()
+
+	// Start server in goroutine, closing the UI when it stops
+	go func() {
+		if err := server.Start(port); err != nil {
+			server.log.Error("Server error", "err", err)
+			return
+		}
+		ui.gui.Update(func(*gocui.Gui) error { return gocui.ErrQuit })
+	}()
+
+	// Run UI, shutting the server down when it is quit
+	defer server.Shutdown()
+	return ui.Run()
+}
+
+// sendMessage queues msg for the client, formatted for its protocol
+func (c *Client) sendMessage(msg Message) {
+	c.enqueue(outbound{msg: msg, room: c.room})
+}
+
+// sendShared is sendMessage for a broadcast, whose text is formatted once
+// for all recipients
+func (c *Client) sendShared(msg Message, text *sharedText) {
+	c.enqueue(outbound{msg: msg, room: c.room, text: text})
+}
+
+func (s *Server) isNameTaken(name string) bool {
+	return s.findClient(name) != nil
+}
+
+func (s *Server) ValidateName(name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("name cannot be empty")
+	}
+	if len(name) < 2 {
+		return fmt.Errorf("name too short (minimum 2 characters)")
+	}
+	if len(name) > 20 {
+		return fmt.Errorf("name too long (maximum 20 characters)")
+	}
+	s.mutex.RLock()
+	taken := s.isNameTaken(name)
+	s.mutex.RUnlock()
+	if taken {
+		return fmt.Errorf("name already taken")
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package chat
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// webhookMaxLines caps how many chat lines one webhook call can post
+const webhookMaxLines = 20
+
+// webhookToken returns the token a webhook request carries, from the
+// Authorization header or, for systems that cannot set headers, ?token=
+func webhookToken(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return token
+	}
+	return r.URL.Query().Get("token")
+}
+
+// webhookText reads the message from a JSON body ({"text": "..."}) or a
+// plain text body
+func webhookText(r *http.Request) (string, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 64<<10))
+	if err != nil {
+		return "", err
+	}
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		return string(body), nil
+	}
+	var payload struct {
+		Text    string `json:"text"`
+		Content string `json:"content"` // Discord-style payloads
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return "", fmt.Errorf("invalid JSON body: %v", err)
+	}
+	return stringOr(payload.Text, payload.Content), nil
+}
+
+// handleWebhook posts the request body into a room as WebhookName. Each
+// line of the body becomes one chat message.
+func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	if subtle.ConstantTimeCompare([]byte(webhookToken(r)), []byte(s.config.WebhookToken)) != 1 {
+		writeAPIError(w, http.StatusUnauthorized, fmt.Errorf("missing or invalid webhook token"))
+		return
+	}
+	text, err := webhookText(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		line, err := s.applySanitizePolicy(line)
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, err)
+			return
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("message is empty"))
+		return
+	}
+	if len(lines) > webhookMaxLines {
+		lines = append(lines[:webhookMaxLines-1], fmt.Sprintf("... (%d more lines)", len(lines)-webhookMaxLines+1))
+	}
+
+	name := r.PathValue("room")
+	s.mutex.Lock()
+	room, exists := s.rooms[name]
+	if exists && room.archived {
+		s.mutex.Unlock()
+		writeAPIError(w, http.StatusConflict, errArchived(name))
+		return
+	}
+	if exists {
+		for _, line := range lines {
+			s.broadcastToRoom(room, Message{
+				Type:      MessageTypeChat,
+				From:      s.config.WebhookName,
+				Content:   line,
+				Timestamp: time.Now(),
+			}, nil)
+		}
+	}
+	s.mutex.Unlock()
+	if !exists {
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("room %s does not exist", name))
+		return
+	}
+
+	s.logActivity("Webhook posted", "remote", r.RemoteAddr, "lines", len(lines), "room", name)
+	writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+}
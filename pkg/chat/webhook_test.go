@@ -0,0 +1,66 @@
+package chat
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWebhook(t *testing.T) {
+	s, _ := newEmailTestServer(t)
+	s.config.WebhookToken = "hook-secret"
+	s.config.WebhookName = "CI"
+	gateway := httptest.NewServer(s.newHTTPHandler())
+	defer gateway.Close()
+
+	post := func(path, contentType, body, token string) int {
+		req, _ := http.NewRequest("POST", gateway.URL+path, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST %s failed: %v", path, err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := post("/hooks/general", "text/plain", "hi", ""); code != http.StatusUnauthorized {
+		t.Errorf("unauthenticated hook got %d, want 401", code)
+	}
+	if code := post("/hooks/nowhere", "text/plain", "hi", "hook-secret"); code != http.StatusNotFound {
+		t.Errorf("hook to a missing room got %d, want 404", code)
+	}
+	if code := post("/hooks/general", "application/json", `{"text":"build #42 passed\nall green"}`, "hook-secret"); code != http.StatusOK {
+		t.Fatalf("JSON hook got %d", code)
+	}
+	if code := post("/hooks/general?token=hook-secret", "text/plain", "deploy finished", ""); code != http.StatusOK {
+		t.Fatalf("plain text hook with query token got %d", code)
+	}
+
+	history, _ := s.store.RecentMessages("general", 3)
+	var got []string
+	for _, msg := range history {
+		got = append(got, msg.From+": "+msg.Content)
+	}
+	want := []string{"CI: build #42 passed", "CI: all green", "CI: deploy finished"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("room history = %q, want %q", got, want)
+	}
+
+	// Archived rooms are read-only for hooks too
+	archived := newChatRoom("releases")
+	archived.archived = true
+	s.mutex.Lock()
+	s.rooms["releases"] = archived
+	s.mutex.Unlock()
+	if code := post("/hooks/releases", "text/plain", "v2.0 shipped", "hook-secret"); code != http.StatusConflict {
+		t.Errorf("hook to an archived room got %d, want 409", code)
+	}
+	if history, _ := s.store.RecentMessages("releases", 0); len(history) != 0 {
+		t.Errorf("archived room recorded hook posts: %+v", history)
+	}
+}
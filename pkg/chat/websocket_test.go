@@ -0,0 +1,123 @@
+package chat
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWSAccept(t *testing.T) {
+	// Example handshake from RFC 6455 section 1.3
+	if got := wsAccept("dGhlIHNhbXBsZSBub25jZQ=="); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("wsAccept = %q", got)
+	}
+}
+
+// wsTestClient is a minimal WebSocket client for the gateway tests
+type wsTestClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func dialWebSocket(t *testing.T, addr string) *wsTestClient {
+	var conn net.Conn
+	var err error
+	for i := 0; i < 20; i++ {
+		if conn, err = net.Dial("tcp", addr); err == nil {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	conn.Write([]byte("GET / HTTP/1.1\r\nHost: " + addr + "\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n"))
+	c := &wsTestClient{conn: conn, reader: bufio.NewReader(conn)}
+	status, _ := c.reader.ReadString('\n')
+	if !strings.Contains(status, "101") {
+		t.Fatalf("Upgrade refused: %q", status)
+	}
+	for {
+		line, err := c.reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Reading handshake failed: %v", err)
+		}
+		if line == "\r\n" {
+			return c
+		}
+	}
+}
+
+func (c *wsTestClient) send(text string) {
+	mask := []byte{1, 2, 3, 4}
+	frame := []byte{0x80 | wsText, 0x80 | byte(len(text))}
+	frame = append(frame, mask...)
+	for i := 0; i < len(text); i++ {
+		frame = append(frame, text[i]^mask[i%4])
+	}
+	c.conn.Write(frame)
+}
+
+// expect reads text frames until one contains substr
+func (c *wsTestClient) expect(t *testing.T, substr string) error {
+	c.conn.SetReadDeadline(time.Now().Add(messageTimeout))
+	defer c.conn.SetReadDeadline(time.Time{})
+	for {
+		var header [2]byte
+		if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+			return err
+		}
+		length := int(header[1] & 0x7F)
+		if length == 126 {
+			var ext [2]byte
+			io.ReadFull(c.reader, ext[:])
+			length = int(binary.BigEndian.Uint16(ext[:]))
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(c.reader, payload); err != nil {
+			return err
+		}
+		if strings.Contains(string(payload), substr) {
+			return nil
+		}
+	}
+}
+
+func TestWebSocketGateway(t *testing.T) {
+	config := DefaultConfig()
+	config.WSAddr = freeAddr(t)
+	addr := setupTestServerWithConfig(t, config)
+
+	browser := dialWebSocket(t, config.WSAddr)
+	defer browser.conn.Close()
+	if err := browser.expect(t, "ENTER YOUR NAME"); err != nil {
+		t.Fatalf("Logo not sent over WebSocket: %v", err)
+	}
+	browser.send("Alice")
+	if err := browser.expect(t, "Alice joined"); err != nil {
+		t.Fatalf("WebSocket login failed: %v", err)
+	}
+
+	tcp, err := newTestClient(t, addr)
+	if err != nil {
+		t.Fatalf("Client connection failed: %v", err)
+	}
+	defer tcp.close()
+	tcp.expectMessage(t, "ENTER YOUR NAME")
+	tcp.sendMessage("Bob")
+	tcp.expectMessage(t, "Bob joined")
+
+	browser.send("hello from the browser")
+	if err := tcp.expectMessage(t, "[Alice]: hello from the browser"); err != nil {
+		t.Errorf("WebSocket message not delivered to TCP client: %v", err)
+	}
+	tcp.sendMessage("hello from tcp")
+	if err := browser.expect(t, "[Bob]: hello from tcp"); err != nil {
+		t.Errorf("TCP message not delivered to WebSocket client: %v", err)
+	}
+}
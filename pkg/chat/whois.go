@@ -0,0 +1,55 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+func whoisCommand(s *Server, c *Client, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: /whois <user>")
+	}
+
+	s.mutex.RLock()
+	target := s.findClient(args[0])
+	if target == nil {
+		s.mutex.RUnlock()
+		return fmt.Errorf("user %s not found", args[0])
+	}
+	now := time.Now()
+	lines := []string{
+		fmt.Sprintf("%s is in %s", target.name, s.roomNameFor(target.room, c)),
+		fmt.Sprintf("  Role:     %s", target.role),
+		fmt.Sprintf("  Joined:   %s (%s ago)", target.joinTime.Format("2006-01-02 15:04:05"),
+			now.Sub(target.joinTime).Round(time.Second)),
+		fmt.Sprintf("  Idle:     %s", now.Sub(target.lastActive).Round(time.Second)),
+	}
+	if target.lastMessage.IsZero() {
+		lines = append(lines, "  Messages: none yet")
+	} else {
+		lines = append(lines, fmt.Sprintf("  Messages: %d, last %s ago", target.sent,
+			now.Sub(target.lastMessage).Round(time.Second)))
+	}
+	var flags []string
+	if target.bot {
+		flags = append(flags, "bot")
+	}
+	if target.identified {
+		flags = append(flags, "identified")
+	}
+	if target.away != "" {
+		lines = append(lines, fmt.Sprintf("  Away:     %s (since %s)", target.away, target.awaySince.Format("15:04")))
+	}
+	if len(flags) > 0 {
+		lines = append(lines, "  Flags:    "+strings.Join(flags, ", "))
+	}
+	// Addresses are only shown to admins
+	if c.role >= RoleAdmin {
+		lines = append(lines, "  Address:  "+remoteIP(target.conn))
+	}
+	s.mutex.RUnlock()
+
+	c.write([]byte(strings.Join(lines, "\n") + "\n"))
+	return nil
+}
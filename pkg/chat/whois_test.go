@@ -0,0 +1,70 @@
+package chat
+
+import (
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWhois(t *testing.T) {
+	addr := setupTestServer(t)
+	join := func(name string) *TestClient {
+		c, err := newTestClient(t, addr)
+		if err != nil {
+			t.Fatalf("Client connection failed: %v", err)
+		}
+		c.sendMessage(name)
+		if err := c.expectMessage(t, name+" joined"); err != nil {
+			t.Fatalf("Join as %s failed: %v", name, err)
+		}
+		return c
+	}
+	alice := join("Alice")
+	defer alice.close()
+	bob := join("Bob")
+	defer bob.close()
+
+	alice.sendMessage("hello")
+	if err := bob.expectMessage(t, "hello"); err != nil {
+		t.Fatalf("message not delivered: %v", err)
+	}
+	bob.sendMessage("/whois alice")
+	for _, want := range []string{"Alice is in general", "Role:     user", "Messages: 1, last"} {
+		if err := bob.expectMessage(t, want); err != nil {
+			t.Fatalf("missing %q: %v", want, err)
+		}
+	}
+
+	bob.sendMessage("/whois nobody")
+	if err := bob.expectMessage(t, "user nobody not found"); err != nil {
+		t.Fatalf("unknown user not reported: %v", err)
+	}
+}
+
+func TestWhoisAddressForAdmins(t *testing.T) {
+	s, _ := newEmailTestServer(t)
+	target := newPipeClient(t, "Alice")
+	target.lastActive = time.Now()
+	s.clients.add(target.conn, target)
+
+	whois := func(role Role) string {
+		server, client := net.Pipe()
+		defer client.Close()
+		viewer := &Client{conn: server, name: "Bob", role: role}
+		go func() {
+			whoisCommand(s, viewer, []string{"Alice"})
+			server.Close()
+		}()
+		out, _ := io.ReadAll(client)
+		return string(out)
+	}
+
+	if out := whois(RoleModerator); strings.Contains(out, "Address:") {
+		t.Errorf("moderator saw the address:\n%s", out)
+	}
+	if out := whois(RoleAdmin); !strings.Contains(out, "Address:") {
+		t.Errorf("admin did not see the address:\n%s", out)
+	}
+}
@@ -0,0 +1,302 @@
+package chat
+
+import (
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Outbound queue limits. A client that falls outboxSize writes behind is
+// disconnected instead of holding up everyone who messages it, but system
+// and error messages that find the queue full go to a separate urgentSize
+// queue that is drained first. Queued text is coalesced into writes of up
+// to maxBatchSize bytes.
+const (
+	outboxSize   = 256
+	urgentSize   = 64
+	flushTimeout = 2 * time.Second
+	maxBatchSize = 64 * 1024
+)
+
+// outbound is one queued write: raw text, a JSON event, or a message
+// formatted for the client's protocol as of the room it was sent in.
+// Broadcasts carry their text already formatted for plain clients.
+type outbound struct {
+	msg   Message
+	room  string
+	raw   []byte
+	text  *sharedText
+	event *jsonEvent // Only queued for JSON clients
+}
+
+// urgent reports whether item may skip ahead of a full queue: system
+// notices and errors such as kicks, shutdown warnings and command failures
+func (item outbound) urgent() bool {
+	return item.raw == nil && (item.msg.Type == MessageTypeSystem || item.msg.Type == MessageTypeError)
+}
+
+// clientWriter owns the writes to one client's connection
+type clientWriter struct {
+	queue      chan outbound
+	urgent     chan outbound
+	quit       chan struct{}
+	done       chan struct{}
+	stopOnce   sync.Once
+	overflowed atomic.Bool
+	interval   time.Duration // How long a batch waits for more messages
+	log        *slog.Logger
+}
+
+// startWriter gives c an outbound queue drained by its own goroutine,
+// which holds each batch open for up to interval (see Config.FlushInterval).
+// Clients without one, such as those in unit tests, are written to
+// directly. Overflows are reported to logger.
+func (c *Client) startWriter(interval time.Duration, logger *slog.Logger) {
+	c.writer = &clientWriter{
+		queue:    make(chan outbound, outboxSize),
+		urgent:   make(chan outbound, urgentSize),
+		quit:     make(chan struct{}),
+		done:     make(chan struct{}),
+		interval: interval,
+		log:      logger,
+	}
+	go c.writeLoop()
+}
+
+func (c *Client) writeLoop() {
+	w := c.writer
+	defer close(w.done)
+	for {
+		if item, ok := w.next(); ok {
+			c.writeBatch(item)
+			continue
+		}
+		queue := w.queue
+		if w.overflowed.Load() {
+			queue = nil
+		}
+		select {
+		case item := <-w.urgent:
+			c.writeBatch(item)
+		case item := <-queue:
+			c.writeBatch(item)
+		case <-w.quit:
+			// Flush what was queued before the stop
+			for {
+				item, ok := w.next()
+				if !ok {
+					return
+				}
+				c.writeBatch(item)
+			}
+		}
+	}
+}
+
+// next takes a queued item without waiting, urgent ones first. Once the
+// client has overflowed, the chat backlog is dropped.
+func (w *clientWriter) next() (outbound, bool) {
+	select {
+	case item := <-w.urgent:
+		return item, true
+	default:
+	}
+	if w.overflowed.Load() {
+		return outbound{}, false
+	}
+	select {
+	case item := <-w.urgent:
+		return item, true
+	case item := <-w.queue:
+		return item, true
+	default:
+		return outbound{}, false
+	}
+}
+
+// writeBatch writes first together with whatever else is queued, or
+// arrives within the flush interval, in as few writes as it can
+func (c *Client) writeBatch(first outbound) {
+	w := c.writer
+	buf := getBuffer()
+	batch := *buf
+	defer func() { putBuffer(buf, batch) }()
+	flush := func() {
+		if len(batch) > 0 {
+			c.conn.Write(batch)
+			batch = batch[:0]
+		}
+	}
+	var timeout <-chan time.Time
+	if w.interval > 0 {
+		timer := time.NewTimer(w.interval)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	item := first
+	for {
+		if text, ok := c.appendText(batch, item); ok {
+			batch = text
+			if len(batch) >= maxBatchSize {
+				flush()
+			}
+		} else {
+			flush()
+			c.deliver(item)
+		}
+
+		if next, ok := w.next(); ok {
+			item = next
+			continue
+		}
+		if timeout == nil {
+			break
+		}
+		// Like writeLoop, only wait for urgent items once the client has
+		// overflowed
+		queue := w.queue
+		if w.overflowed.Load() {
+			queue = nil
+		}
+		select {
+		case item = <-w.urgent:
+			continue
+		case item = <-queue:
+			continue
+		case <-timeout:
+		case <-w.quit:
+		}
+		break
+	}
+	flush()
+}
+
+// appendText appends item to dst as text the connection accepts as is,
+// which holds for raw text and for messages to plain clients. JSON and IRC
+// clients get their messages written one by one.
+func (c *Client) appendText(dst []byte, item outbound) ([]byte, bool) {
+	if item.raw != nil {
+		return append(dst, item.raw...), true
+	}
+	if item.event != nil {
+		return dst, false
+	}
+	switch c.conn.(type) {
+	case *jsonConn, *ircConn:
+		return dst, false
+	}
+	if item.text != nil {
+		return append(dst, item.text.get(c.color)...), true
+	}
+	if c.color {
+		dst = appendColorMessage(dst, item.msg)
+	} else {
+		dst = appendMessage(dst, item.msg)
+	}
+	return append(dst, '\n'), true
+}
+
+// enqueue hands item to the writer without ever blocking the caller.
+// Everything goes through one queue in order unless it is full.
+func (c *Client) enqueue(item outbound) {
+	if c.writer == nil {
+		c.deliver(item)
+		return
+	}
+	w := c.writer
+	if item.urgent() && len(w.urgent) > 0 {
+		// Keep behind the urgent messages already waiting
+		select {
+		case w.urgent <- item:
+		default:
+			c.overflow()
+		}
+		return
+	}
+	select {
+	case w.queue <- item:
+		return
+	default:
+	}
+	if item.urgent() {
+		select {
+		case w.urgent <- item:
+			return
+		default:
+		}
+	}
+	c.overflow()
+}
+
+// overflow disconnects a client whose queue is full. The backlog is
+// dropped and a notice queued in its place; the connection is closed once
+// that is written, or after flushTimeout if the client stopped reading.
+func (c *Client) overflow() {
+	w := c.writer
+	if !w.overflowed.CompareAndSwap(false, true) {
+		return
+	}
+	w.log.Warn("Outbound queue full, disconnecting", clientAttrs(c)...)
+	notice := Message{
+		Type:      MessageTypeError,
+		Content:   "Disconnected: too many messages are waiting to be sent to you",
+		Timestamp: time.Now(),
+	}
+	select {
+	case w.urgent <- outbound{msg: notice}:
+	default:
+	}
+	go c.closeConn()
+}
+
+// write queues raw text for the client
+func (c *Client) write(p []byte) {
+	c.enqueue(outbound{raw: p})
+}
+
+// deliver writes one queued item to the connection
+func (c *Client) deliver(item outbound) {
+	if item.event != nil {
+		if conn, ok := c.conn.(*jsonConn); ok {
+			conn.writeEvent(*item.event)
+		}
+		return
+	}
+	switch conn := c.conn.(type) {
+	case *jsonConn:
+		if item.raw == nil {
+			conn.writeEvent(messageEvent(item.msg, item.room))
+			return
+		}
+	case *ircConn:
+		if item.raw == nil {
+			conn.writeMessage(item.msg, item.room)
+			return
+		}
+	}
+	buf := getBuffer()
+	text, _ := c.appendText(*buf, item)
+	c.conn.Write(text)
+	putBuffer(buf, text)
+}
+
+// flush stops the writer once everything queued so far is written, giving
+// up after flushTimeout on a client that stopped reading
+func (c *Client) flush() {
+	if c.writer == nil {
+		return
+	}
+	c.writer.stopOnce.Do(func() { close(c.writer.quit) })
+	select {
+	case <-c.writer.done:
+	case <-time.After(flushTimeout):
+	}
+}
+
+// closeConn flushes the pending writes and then closes the connection
+func (c *Client) closeConn() {
+	c.flush()
+	c.conn.Close()
+}
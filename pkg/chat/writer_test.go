@@ -0,0 +1,201 @@
+package chat
+
+import (
+	"bufio"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingConn counts the writes that reach the connection
+type countingConn struct {
+	net.Conn
+	writes atomic.Int32
+}
+
+func (c *countingConn) Write(p []byte) (int, error) {
+	c.writes.Add(1)
+	return c.Conn.Write(p)
+}
+
+// waitForWrites waits until n writes have reached conn
+func waitForWrites(t *testing.T, conn *countingConn, n int32) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for conn.writes.Load() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d writes, want %d", conn.writes.Load(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestWriterKeepsOrder(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+	c := &Client{conn: server, name: "Alice"}
+	c.startWriter(0, slog.Default())
+
+	c.sendMessage(Message{Type: MessageTypeSystem, Content: "first", Timestamp: time.Now()})
+	c.write([]byte("second\n"))
+	c.sendMessage(Message{Type: MessageTypeSystem, Content: "third", Timestamp: time.Now()})
+	go c.closeConn()
+
+	reader := bufio.NewReader(client)
+	for _, want := range []string{"first", "second", "third"} {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read failed before %q: %v", want, err)
+		}
+		if !strings.Contains(line, want) {
+			t.Errorf("got %q, want %q", line, want)
+		}
+	}
+}
+
+func TestStalledClientDoesNotBlockSenders(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+	c := &Client{conn: server, name: "Stalled"}
+	c.startWriter(0, slog.Default())
+
+	// Nobody reads the other end of the pipe, so every write blocks
+	sent := make(chan struct{})
+	go func() {
+		for i := 0; i < outboxSize*2; i++ {
+			c.sendMessage(Message{Type: MessageTypeChat, From: "Bob", Content: "hello", Timestamp: time.Now()})
+		}
+		close(sent)
+	}()
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		t.Fatal("sendMessage blocked on a stalled client")
+	}
+
+	// The overflow closed the connection
+	if _, err := client.Write([]byte("x")); err == nil {
+		t.Error("stalled client still connected")
+	}
+}
+
+func TestUrgentMessagesSkipQueue(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+	conn := &countingConn{Conn: server}
+	c := &Client{conn: conn, name: "Alice"}
+	c.startWriter(0, slog.Default())
+
+	// The first message blocks the writer until it is read, so the rest
+	// of the chat fills the queue
+	c.sendMessage(Message{Type: MessageTypeChat, From: "Bob", Content: "chat 0", Timestamp: time.Now()})
+	waitForWrites(t, conn, 1)
+	for i := 1; i <= outboxSize; i++ {
+		c.sendMessage(Message{Type: MessageTypeChat, From: "Bob", Content: fmt.Sprintf("chat %d", i), Timestamp: time.Now()})
+	}
+	c.sendMessage(Message{Type: MessageTypeError, Content: "You have been kicked", Timestamp: time.Now()})
+	c.sendMessage(Message{Type: MessageTypeSystem, Content: "Server shutting down", Timestamp: time.Now()})
+
+	reader := bufio.NewReader(client)
+	for _, want := range []string{"chat 0", "You have been kicked", "Server shutting down", "chat 1"} {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read failed before %q: %v", want, err)
+		}
+		if !strings.Contains(line, want) {
+			t.Errorf("got %q, want %q", line, want)
+		}
+	}
+	go c.closeConn()
+}
+
+func TestOverflowNoticeSkipsBacklog(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+	conn := &countingConn{Conn: server}
+	c := &Client{conn: conn, name: "Slow"}
+	c.startWriter(0, slog.Default())
+
+	c.sendMessage(Message{Type: MessageTypeChat, From: "Bob", Content: "chat 0", Timestamp: time.Now()})
+	waitForWrites(t, conn, 1)
+	for i := 1; i <= outboxSize+1; i++ {
+		c.sendMessage(Message{Type: MessageTypeChat, From: "Bob", Content: fmt.Sprintf("chat %d", i), Timestamp: time.Now()})
+	}
+
+	// The dropped backlog is replaced by the notice, then the connection
+	// is closed
+	reader := bufio.NewReader(client)
+	for _, want := range []string{"chat 0", "Disconnected"} {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read failed before %q: %v", want, err)
+		}
+		if !strings.Contains(line, want) {
+			t.Errorf("got %q, want %q", line, want)
+		}
+	}
+	if _, err := reader.ReadString('\n'); err == nil {
+		t.Error("connection still open after the overflow notice")
+	}
+}
+
+func TestOverflowedBatchSkipsBacklog(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+	c := &Client{conn: server, name: "Slow", writer: &clientWriter{
+		queue:    make(chan outbound, outboxSize),
+		urgent:   make(chan outbound, urgentSize),
+		quit:     make(chan struct{}),
+		done:     make(chan struct{}),
+		interval: 50 * time.Millisecond,
+		log:      slog.Default(),
+	}}
+	w := c.writer
+	w.overflowed.Store(true)
+	w.queue <- outbound{msg: Message{Type: MessageTypeChat, From: "Bob", Content: "backlog", Timestamp: time.Now()}}
+
+	// A batch held open for more messages only takes urgent ones
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.writeBatch(outbound{msg: Message{Type: MessageTypeError, Content: "Disconnected", Timestamp: time.Now()}})
+	}()
+	line, err := bufio.NewReader(client).ReadString('\n')
+	if err != nil || !strings.Contains(line, "Disconnected") {
+		t.Fatalf("got %q, %v; want the notice", line, err)
+	}
+	<-done
+	if len(w.queue) != 1 {
+		t.Error("overflowed client's batch drained the chat backlog")
+	}
+}
+
+func TestWriterBatchesQueuedMessages(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+	conn := &countingConn{Conn: server}
+	c := &Client{conn: conn, name: "Alice"}
+	c.startWriter(100*time.Millisecond, slog.Default())
+
+	for i := 0; i < 10; i++ {
+		c.sendMessage(Message{Type: MessageTypeChat, From: "Bob", Content: fmt.Sprintf("message %d", i), Timestamp: time.Now()})
+	}
+	reader := bufio.NewReader(client)
+	for i := 0; i < 10; i++ {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read failed: %v", err)
+		}
+		if want := fmt.Sprintf("message %d", i); !strings.Contains(line, want) {
+			t.Errorf("got %q, want %q", line, want)
+		}
+	}
+	if n := conn.writes.Load(); n != 1 {
+		t.Errorf("10 messages took %d writes, want 1", n)
+	}
+	go c.closeConn()
+}
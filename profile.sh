@@ -0,0 +1,104 @@
+#!/bin/bash
+
+# Colors for output
+RED='\033[0;31m'
+GREEN='\033[0;32m'
+YELLOW='\033[1;33m'
+NC='\033[0m'
+
+PKG="./pkg/chat"
+BENCH="${BENCH:-.}"
+COUNT="${COUNT:-5}"
+THRESHOLD="${THRESHOLD:-20}" # Allowed regression in percent
+BASELINE="bench_baseline.txt"
+OUT_DIR="profiles/$(date +%Y%m%d-%H%M%S)"
+
+print_step() {
+    echo -e "${YELLOW}Step: $1${NC}"
+}
+
+show_help() {
+    echo "Usage: ./profile.sh [option]"
+    echo "Options:"
+    echo "  --help              Show this help message"
+    echo "  --run               Run benchmarks, capture profiles, compare to baseline (default)"
+    echo "  --update-baseline   Run benchmarks and store the results as the new baseline"
+    echo
+    echo "Environment:"
+    echo "  BENCH=regex         Benchmarks to run (default: all)"
+    echo "  COUNT=n             Repetitions per benchmark (default: 5)"
+    echo "  THRESHOLD=pct       Allowed ns/op and allocs/op regression (default: 20)"
+}
+
+# Runs the benchmarks once with CPU and memory profiling enabled
+run_benchmarks() {
+    print_step "Running benchmarks (count=$COUNT)"
+    mkdir -p "$OUT_DIR"
+    if ! go test "$PKG" -run '^$' -bench "$BENCH" -benchmem -count "$COUNT" \
+        -cpuprofile "$OUT_DIR/cpu.prof" -memprofile "$OUT_DIR/mem.prof" \
+        -o "$OUT_DIR/chat.test" | tee "$OUT_DIR/bench.txt"; then
+        echo -e "${RED}Benchmarks failed${NC}"
+        exit 1
+    fi
+    echo -e "${GREEN}Profiles written to $OUT_DIR${NC}"
+    echo "  go tool pprof $OUT_DIR/chat.test $OUT_DIR/cpu.prof"
+    echo "  go tool pprof -sample_index=alloc_space $OUT_DIR/chat.test $OUT_DIR/mem.prof"
+}
+
+# Averages ns/op and allocs/op per benchmark name
+summarize() {
+    awk '/^Benchmark/ {
+        name = $1; sub(/-[0-9]+$/, "", name)
+        for (i = 2; i <= NF; i++) {
+            if ($(i+1) == "ns/op") ns[name] += $i
+            if ($(i+1) == "allocs/op") allocs[name] += $i
+        }
+        n[name]++
+    }
+    END { for (k in n) printf "%s %.1f %.1f\n", k, ns[k] / n[k], allocs[k] / n[k] }' "$1" | sort
+}
+
+compare_baseline() {
+    if [ ! -f "$BASELINE" ]; then
+        echo -e "${YELLOW}No $BASELINE found, skipping regression check${NC}"
+        return
+    fi
+
+    print_step "Comparing against $BASELINE (threshold ${THRESHOLD}%)"
+    summarize "$BASELINE" > "$OUT_DIR/baseline.summary"
+    summarize "$OUT_DIR/bench.txt" > "$OUT_DIR/current.summary"
+
+    if ! join "$OUT_DIR/baseline.summary" "$OUT_DIR/current.summary" | awk -v t="$THRESHOLD" '
+        {
+            status = "ok"
+            if ($2 > 0 && ($4 - $2) / $2 * 100 > t) status = "REGRESSION (ns/op)"
+            if ($3 > 0 && ($5 - $3) / $3 * 100 > t) status = "REGRESSION (allocs/op)"
+            printf "%-50s %12.1f -> %12.1f ns/op %8.1f -> %8.1f allocs/op  %s\n", $1, $2, $4, $3, $5, status
+            if (status != "ok") failed = 1
+        }
+        END { exit failed }'; then
+        echo -e "${RED}Performance regression detected${NC}"
+        exit 1
+    fi
+    echo -e "${GREEN}No regressions${NC}"
+}
+
+case "$1" in
+    --help)
+        show_help
+        ;;
+    --update-baseline)
+        run_benchmarks
+        cp "$OUT_DIR/bench.txt" "$BASELINE"
+        echo -e "${GREEN}Baseline updated${NC}"
+        ;;
+    --run|"")
+        run_benchmarks
+        compare_baseline
+        ;;
+    *)
+        echo -e "${RED}Unknown option: $1${NC}"
+        show_help
+        exit 1
+        ;;
+esac